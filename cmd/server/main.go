@@ -3,11 +3,19 @@ package main
 import (
 	"ecommerce-app/internal/config"
 	"ecommerce-app/internal/db"
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/notify"
 	"ecommerce-app/internal/repository"
 	"ecommerce-app/internal/router"
+	"ecommerce-app/internal/search"
 	"ecommerce-app/internal/service"
+	"ecommerce-app/internal/storage"
 	"ecommerce-app/pkg/logger"
+	"ecommerce-app/pkg/money"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 func main() {
@@ -20,6 +28,9 @@ func main() {
         return
     }
 
+    // Amounts without an explicit currency are in the store currency
+    money.DefaultCurrency = cfg.Currency
+
     // Connect to database
     dbConn, err := db.Connect(cfg.DatabaseURL)
     if err != nil {
@@ -50,12 +61,183 @@ func main() {
     productRepo := repository.NewProductRepository(dbConn)
     orderRepo := repository.NewOrderRepository(dbConn)
     userRepo := repository.NewUserRepository(dbConn)
+    cartRepo := repository.NewCartRepository(dbConn)
+    checkoutRepo := repository.NewCheckoutRepository(dbConn)
+    categoryRepo := repository.NewCategoryRepository(dbConn)
+    variantRepo := repository.NewVariantRepository(dbConn)
+    mediaRepo := repository.NewMediaRepository(dbConn)
+    inventoryRepo := repository.NewInventoryRepository(dbConn)
+    warehouseRepo := repository.NewWarehouseRepository(dbConn)
+    alertRepo := repository.NewAlertRepository(dbConn)
+    couponRepo := repository.NewCouponRepository(dbConn)
+    promotionRepo := repository.NewPromotionRepository(dbConn)
+    saleRepo := repository.NewSaleRepository(dbConn)
+    priceListRepo := repository.NewPriceListRepository(dbConn)
+    taxRepo := repository.NewTaxRepository(dbConn)
+    shippingRepo := repository.NewShippingRepository(dbConn)
     
+    cartMergePolicy, err := service.ParseCartMergePolicy(cfg.CartMergePolicy)
+    if err != nil {
+        log.Error("Invalid configuration: " + err.Error())
+        return
+    }
+
+    // Initialize the product search backend
+    priceBounds := search.DefaultPriceBounds(cfg.Currency)
+    var searchIndex search.SearchIndex = repository.NewPostgresSearchIndex(dbConn, priceBounds)
+    if cfg.SearchBackend == "memory" {
+        searchIndex = search.NewMemoryIndex(priceBounds)
+    }
+
+    // Initialize the media storage backend
+    localStore := storage.NewLocalStore(cfg.MediaDir, cfg.MediaBaseURL)
+    var blobStore storage.BlobStore = localStore
+    if cfg.MediaStorage == "s3" {
+        blobStore, err = storage.NewS3Store(storage.S3Config{
+            Endpoint:  cfg.S3Endpoint,
+            Region:    cfg.S3Region,
+            Bucket:    cfg.S3Bucket,
+            AccessKey: cfg.S3AccessKey,
+            SecretKey: cfg.S3SecretKey,
+            PublicURL: cfg.S3PublicURL,
+        })
+        if err != nil {
+            log.Error("Invalid media storage configuration: " + err.Error())
+            return
+        }
+    }
+
+    // Initialize the notification channel
+    var notifier notify.Notifier = notify.NewLogNotifier()
+    if cfg.NotifyChannel == "smtp" {
+        notifier, err = notify.NewSMTPNotifier(notify.SMTPConfig{
+            Host:     cfg.SMTPHost,
+            Port:     cfg.SMTPPort,
+            Username: cfg.SMTPUsername,
+            Password: cfg.SMTPPassword,
+            From:     cfg.SMTPFrom,
+        })
+        if err != nil {
+            log.Error("Invalid notification configuration: " + err.Error())
+            return
+        }
+    }
+
     // Initialize services
-    productService := service.NewProductService(productRepo)
-    orderService := service.NewOrderService(orderRepo)
+    mediaService := service.NewMediaService(mediaRepo, productRepo, blobStore)
+    productService := service.NewProductService(productRepo, categoryRepo, searchIndex)
+    orderService := service.NewOrderService(orderRepo, productService)
     userService := service.NewUserService(userRepo)
-    authService := service.NewAuthService(userService)
+    taxCalculator := service.NewTaxCalculator(taxRepo, service.TaxConfig{
+        Currency:    cfg.Currency,
+        PriceMode:   cfg.TaxPriceMode,
+        Rounding:    cfg.TaxRounding,
+        DefaultRate: cfg.TaxRate,
+        Origin: models.Address{
+            Country:    cfg.StoreCountry,
+            Region:     cfg.StoreRegion,
+            PostalCode: cfg.StorePostalCode,
+        },
+    })
+    cartPricer := service.NewCartPricer(service.PricingConfig{
+        Currency:              cfg.Currency,
+        ShippingFee:           cfg.ShippingFee,
+        FreeShippingThreshold: cfg.FreeShippingThreshold,
+    }, taxCalculator, service.NewPromotionDiscounter(), service.NewCouponDiscounter())
+    cartService := service.NewCartService(cartRepo, productRepo, variantRepo, couponRepo, promotionRepo, priceListRepo, shippingRepo, cartPricer, cartMergePolicy, cfg.CartTokenSecret)
+    authService := service.NewAuthService(userService, cartService)
+    checkoutService := service.NewCheckoutService(checkoutRepo, promotionRepo, priceListRepo, shippingRepo, cartPricer, productService, time.Duration(cfg.ReservationTTLMinutes)*time.Minute)
+    categoryService := service.NewCategoryService(categoryRepo, productRepo, productService)
+    variantService := service.NewVariantService(variantRepo, productRepo, priceListRepo, productService)
+    transferService := service.NewProductTransferService(productRepo, categoryRepo, productService)
+    inventoryService := service.NewInventoryService(inventoryRepo, productService)
+    warehouseService := service.NewWarehouseService(warehouseRepo, productRepo, variantRepo, productService)
+    trashService := service.NewTrashService(productRepo, userRepo, orderRepo, productService, mediaService)
+    trashRetention := time.Duration(cfg.TrashRetentionDays) * 24 * time.Hour
+    alertService := service.NewAlertService(alertRepo, productRepo, variantRepo, notifier, cfg.AdminAlertEmail)
+    couponService := service.NewCouponService(couponRepo, productRepo, categoryRepo)
+    promotionService := service.NewPromotionService(promotionRepo, productRepo, variantRepo, categoryRepo, cartPricer)
+    saleService := service.NewSaleService(saleRepo, productRepo, productService)
+    priceListService := service.NewPriceListService(priceListRepo, productRepo, variantRepo)
+    taxService := service.NewTaxService(taxRepo)
+    shippingService := service.NewShippingService(shippingRepo)
+
+    // The in-memory index starts empty, so build it now. Writes that change a product's
+    // price or stock, including checkout and reservation expiry, reindex it from then on.
+    if cfg.SearchBackend == "memory" {
+        if err := productService.ReindexAll(); err != nil {
+            log.Error("Failed to build search index: " + err.Error())
+            return
+        }
+    }
+
+    // Give the stock of unpaid orders back once their reservations expire
+    go func() {
+        for range time.Tick(time.Minute) {
+            cancelled, err := inventoryService.ReleaseExpiredReservations()
+            if err != nil {
+                log.Error("Failed to release expired reservations: " + err.Error())
+            }
+            if len(cancelled) > 0 {
+                log.Info(fmt.Sprintf("Cancelled %d unpaid orders with expired reservations", len(cancelled)))
+            }
+        }
+    }()
+
+    // Start scheduled sales and revert the prices of the ones that are over
+    go func() {
+        for range time.Tick(time.Minute) {
+            schedule, err := saleService.ApplySchedule()
+            if err != nil {
+                log.Error("Failed to apply sale schedule: " + err.Error())
+            }
+            if schedule.Started > 0 || schedule.Ended > 0 {
+                log.Info(fmt.Sprintf("Started %d sales and ended %d sales", schedule.Started, schedule.Ended))
+            }
+        }
+    }()
+
+    // Raise low-stock alerts and tell shoppers about products that are back in stock
+    go func() {
+        for range time.Tick(time.Minute) {
+            report, err := alertService.CheckStock()
+            if err != nil {
+                log.Error("Failed to check stock alerts: " + err.Error())
+                continue
+            }
+            if report.Raised > 0 || report.Notified > 0 {
+                log.Info(fmt.Sprintf("Raised %d low-stock alerts and sent %d back-in-stock notifications", report.Raised, report.Notified))
+            }
+        }
+    }()
+
+    // Remove guest carts that shoppers abandoned; their tokens have expired with them
+    go func() {
+        for range time.Tick(time.Hour) {
+            purged, err := cartService.PurgeAbandonedGuestCarts()
+            if err != nil {
+                log.Error("Failed to purge abandoned guest carts: " + err.Error())
+                continue
+            }
+            if purged > 0 {
+                log.Info(fmt.Sprintf("Purged %d abandoned guest carts", purged))
+            }
+        }
+    }()
+
+    // Permanently remove deleted records once they are past the retention window
+    go func() {
+        for range time.Tick(time.Hour) {
+            report, err := trashService.Purge(trashRetention)
+            if err != nil {
+                log.Error("Failed to purge trash: " + err.Error())
+                continue
+            }
+            if purged := len(report.Orders) + len(report.Products) + len(report.Users); purged > 0 {
+                log.Info(fmt.Sprintf("Purged %d records from the trash", purged))
+            }
+        }
+    }()
     
     // Seed test user for development/testing
     // testEmail := "test@example.com"
@@ -78,7 +260,13 @@ func main() {
     // log.Info("Done seeding database")
     
     // Setup routes using the router package
-    router.SetupRoutes(authService, userService, productService, orderService)
+    router.SetupRoutes(authService, userService, productService, orderService, cartService, checkoutService, categoryService, variantService, mediaService, transferService, trashService, trashRetention, warehouseService, alertService, couponService, promotionService, saleService, priceListService, taxService, shippingService)
+
+    // Serve locally stored media unless the base URL points elsewhere, e.g. a CDN
+    if cfg.MediaStorage == "local" && strings.HasPrefix(cfg.MediaBaseURL, "/") {
+        prefix := strings.TrimSuffix(cfg.MediaBaseURL, "/")
+        http.Handle("GET "+prefix+"/", http.StripPrefix(prefix, localStore.FileServer()))
+    }
 
     // Start server
     log.Info("Server starting on port " + cfg.Port)
@@ -2,14 +2,47 @@ package config
 
 import (
     "ecommerce-app/pkg/logger"
+    "ecommerce-app/pkg/money"
+    "fmt"
     "github.com/joho/godotenv"
     "os"
+    "strconv"
 )
 
 // Config holds application configuration
 type Config struct {
-    Port        string
-    DatabaseURL string
+    Port                  string
+    DatabaseURL           string
+    CartTokenSecret       string
+    CartMergePolicy       string
+    Currency              string
+    TaxRate               float64
+    TaxPriceMode          string
+    TaxRounding           string
+    StoreCountry          string
+    StoreRegion           string
+    StorePostalCode       string
+    ShippingFee           money.Money
+    FreeShippingThreshold money.Money
+    SearchBackend         string
+    MediaStorage          string
+    MediaDir              string
+    MediaBaseURL          string
+    S3Endpoint            string
+    S3Region              string
+    S3Bucket              string
+    S3AccessKey           string
+    S3SecretKey           string
+    S3PublicURL           string
+    TrashRetentionDays    int
+    ReservationTTLMinutes int
+    NotifyChannel         string
+    SMTPHost              string
+    SMTPPort              int
+    SMTPUsername          string
+    SMTPPassword          string
+    SMTPFrom              string
+    AdminAlertEmail       string
 }
 
 // Load loads configuration from environment variables
@@ -23,8 +56,79 @@ func Load() (*Config, error) {
     }
 
     cfg := &Config{
-        Port:        getEnv("PORT", "8080"),
-        DatabaseURL: getEnv("DATABASE_URL", "host=localhost user=postgres password=root dbname=ecommerce_db port=5432 sslmode=disable"),
+        Port:            getEnv("PORT", "8080"),
+        DatabaseURL:     getEnv("DATABASE_URL", "host=localhost user=postgres password=root dbname=ecommerce_db port=5432 sslmode=disable"),
+        CartTokenSecret: getEnv("CART_TOKEN_SECRET", "default_cart_token_secret"), // Not recommended for production
+        CartMergePolicy: getEnv("CART_MERGE_POLICY", "sum"),
+        Currency:        getEnv("CURRENCY", "USD"),
+        TaxPriceMode:    getEnv("TAX_PRICE_MODE", "exclusive"),
+        TaxRounding:     getEnv("TAX_ROUNDING", "line"),
+        StoreCountry:    getEnv("STORE_COUNTRY", ""),
+        StoreRegion:     getEnv("STORE_REGION", ""),
+        StorePostalCode: getEnv("STORE_POSTAL_CODE", ""),
+        SearchBackend:   getEnv("SEARCH_BACKEND", "postgres"),
+        MediaStorage:    getEnv("MEDIA_STORAGE", "local"),
+        MediaDir:        getEnv("MEDIA_DIR", "./uploads"),
+        MediaBaseURL:    getEnv("MEDIA_BASE_URL", "/media"),
+        S3Endpoint:      getEnv("S3_ENDPOINT", ""),
+        S3Region:        getEnv("S3_REGION", "us-east-1"),
+        S3Bucket:        getEnv("S3_BUCKET", ""),
+        S3AccessKey:     getEnv("S3_ACCESS_KEY", ""),
+        S3SecretKey:     getEnv("S3_SECRET_KEY", ""),
+        S3PublicURL:     getEnv("S3_PUBLIC_URL", ""),
+        NotifyChannel:   getEnv("NOTIFY_CHANNEL", "log"),
+        SMTPHost:        getEnv("SMTP_HOST", ""),
+        SMTPUsername:    getEnv("SMTP_USERNAME", ""),
+        SMTPPassword:    getEnv("SMTP_PASSWORD", ""),
+        SMTPFrom:        getEnv("SMTP_FROM", ""),
+        AdminAlertEmail: getEnv("ADMIN_ALERT_EMAIL", ""),
+    }
+
+    if !money.IsSupported(cfg.Currency) {
+        return nil, fmt.Errorf("invalid value for CURRENCY: %w: %q", money.ErrUnsupportedCurrency, cfg.Currency)
+    }
+    if cfg.TaxRate, err = getEnvFloat("TAX_RATE", 0); err != nil {
+        return nil, err
+    }
+    if cfg.ShippingFee, err = getEnvMoney("SHIPPING_FEE", cfg.Currency); err != nil {
+        return nil, err
+    }
+    if cfg.FreeShippingThreshold, err = getEnvMoney("FREE_SHIPPING_THRESHOLD", cfg.Currency); err != nil {
+        return nil, err
+    }
+
+    if cfg.TrashRetentionDays, err = getEnvInt("TRASH_RETENTION_DAYS", 30); err != nil {
+        return nil, err
+    }
+    if cfg.TrashRetentionDays < 0 {
+        return nil, fmt.Errorf("invalid value for TRASH_RETENTION_DAYS: %d (must not be negative)", cfg.TrashRetentionDays)
+    }
+
+    if cfg.ReservationTTLMinutes, err = getEnvInt("RESERVATION_TTL_MINUTES", 15); err != nil {
+        return nil, err
+    }
+    if cfg.ReservationTTLMinutes <= 0 {
+        return nil, fmt.Errorf("invalid value for RESERVATION_TTL_MINUTES: %d (must be positive)", cfg.ReservationTTLMinutes)
+    }
+
+    if cfg.SMTPPort, err = getEnvInt("SMTP_PORT", 587); err != nil {
+        return nil, err
+    }
+
+    if cfg.SearchBackend != "postgres" && cfg.SearchBackend != "memory" {
+        return nil, fmt.Errorf("invalid value for SEARCH_BACKEND: %q (want postgres or memory)", cfg.SearchBackend)
+    }
+    if cfg.MediaStorage != "local" && cfg.MediaStorage != "s3" {
+        return nil, fmt.Errorf("invalid value for MEDIA_STORAGE: %q (want local or s3)", cfg.MediaStorage)
+    }
+    if cfg.NotifyChannel != "log" && cfg.NotifyChannel != "smtp" {
+        return nil, fmt.Errorf("invalid value for NOTIFY_CHANNEL: %q (want log or smtp)", cfg.NotifyChannel)
+    }
+    if cfg.TaxPriceMode != "exclusive" && cfg.TaxPriceMode != "inclusive" {
+        return nil, fmt.Errorf("invalid value for TAX_PRICE_MODE: %q (want exclusive or inclusive)", cfg.TaxPriceMode)
+    }
+    if cfg.TaxRounding != "line" && cfg.TaxRounding != "order" {
+        return nil, fmt.Errorf("invalid value for TAX_ROUNDING: %q (want line or order)", cfg.TaxRounding)
     }
 
     log.Info("Configuration loaded successfully")
@@ -37,4 +141,46 @@ func getEnv(key, defaultValue string) string {
         return value
     }
     return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvFloat retrieves a numeric environment variable or returns a default value
+func getEnvFloat(key string, defaultValue float64) (float64, error) {
+    value, exists := os.LookupEnv(key)
+    if !exists {
+        return defaultValue, nil
+    }
+
+    parsed, err := strconv.ParseFloat(value, 64)
+    if err != nil {
+        return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+    }
+    return parsed, nil
+}
+
+// getEnvInt retrieves an integer environment variable or returns a default value
+func getEnvInt(key string, defaultValue int) (int, error) {
+    value, exists := os.LookupEnv(key)
+    if !exists {
+        return defaultValue, nil
+    }
+
+    parsed, err := strconv.Atoi(value)
+    if err != nil {
+        return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+    }
+    return parsed, nil
+}
+
+// getEnvMoney retrieves an amount from an environment variable, defaulting to zero
+func getEnvMoney(key, currency string) (money.Money, error) {
+    value, exists := os.LookupEnv(key)
+    if !exists {
+        return money.Zero(currency), nil
+    }
+
+    parsed, err := money.Parse(value, currency)
+    if err != nil {
+        return money.Money{}, fmt.Errorf("invalid value for %s: %w", key, err)
+    }
+    return parsed, nil
+}
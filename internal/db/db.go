@@ -3,6 +3,7 @@ package db
 import (
     "ecommerce-app/internal/models"
     "ecommerce-app/pkg/logger"
+    "ecommerce-app/pkg/money"
     "gorm.io/driver/postgres"
     "gorm.io/gorm"
 )
@@ -25,24 +26,116 @@ func Connect(dsn string) (*gorm.DB, error) {
 func Migrate(db *gorm.DB) error {
     log := logger.New()
 
+    // Find the tables holding amounts that do not have a currency column yet
+    var missingCurrency []string
+    for table, model := range map[string]interface{}{
+        "products":    &models.Product{},
+        "cart_items":  &models.CartItem{},
+        "orders":      &models.Order{},
+        "order_items": &models.OrderItem{},
+    } {
+        if db.Migrator().HasTable(model) && !db.Migrator().HasColumn(model, "currency") {
+            missingCurrency = append(missingCurrency, table)
+        }
+    }
+
+    // Cart lines are now keyed by variant as well; the new index replaces this one
+    db.Exec("DROP INDEX IF EXISTS idx_cart_items_cart_product")
+
     err := db.AutoMigrate(
         &models.User{},
         &models.Product{},
+        &models.Category{},
+        &models.ProductOption{},
+        &models.ProductOptionValue{},
+        &models.ProductVariant{},
+        &models.ProductImage{},
         &models.Cart{},
+        &models.CartItem{},
         &models.Order{},
+        &models.OrderItem{},
+        &models.OrderStatusHistory{},
+        &models.StockReservation{},
+        &models.Warehouse{},
+        &models.StockLevel{},
+        &models.StockMovement{},
+        &models.LowStockAlert{},
+        &models.StockSubscription{},
+        &models.Coupon{},
+        &models.CouponRedemption{},
+        &models.Promotion{},
+        &models.PromotionTier{},
+        &models.ProductSale{},
+        &models.PriceChange{},
+        &models.CustomerGroup{},
+        &models.PriceList{},
+        &models.PriceListEntry{},
+        &models.TaxClass{},
+        &models.TaxZone{},
+        &models.TaxRate{},
+        &models.OrderTax{},
+        &models.ShippingZone{},
+        &models.ShippingMethod{},
     )
     if err != nil {
         log.Error("Failed to migrate database: " + err.Error())
         return err
     }
     
+    // Amounts written before currencies were recorded are in the store currency
+    for _, table := range missingCurrency {
+        db.Exec("UPDATE "+table+" SET currency = ?", money.DefaultCurrency)
+    }
+    // Orders placed before the price breakdown was stored only have a total
+    db.Exec("UPDATE orders SET subtotal = total WHERE subtotal = 0 AND total <> 0")
+
+    // Backfill the price snapshot of cart items added before it was recorded
+    db.Exec("UPDATE cart_items SET price_at_add = products.price FROM products WHERE products.id = cart_items.product_id AND cart_items.price_at_add = 0")
+
+    // Stock is tracked per warehouse; existing stock opens the ledger of a default one
+    db.Exec(`INSERT INTO warehouses (code, name, priority, active, created_at, updated_at)
+        SELECT 'MAIN', 'Main warehouse', 0, true, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+        WHERE NOT EXISTS (SELECT 1 FROM warehouses)`)
+    db.Exec(`INSERT INTO stock_movements (warehouse_id, product_id, variant_id, type, quantity, note, created_by, created_at)
+        SELECT w.id, s.product_id, s.variant_id, 'adjustment', s.stock, 'Opening balance', 'system', CURRENT_TIMESTAMP
+        FROM (
+            SELECT p.id AS product_id, 0 AS variant_id, p.stock FROM products p
+            WHERE NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id)
+            UNION ALL
+            SELECT v.product_id, v.id, v.stock FROM product_variants v
+        ) s, (SELECT id FROM warehouses ORDER BY priority, id LIMIT 1) w
+        WHERE s.stock <> 0 AND NOT EXISTS (SELECT 1 FROM stock_movements m WHERE m.product_id = s.product_id)`)
+    db.Exec(`INSERT INTO stock_levels (warehouse_id, product_id, variant_id, quantity, updated_at)
+        SELECT warehouse_id, product_id, variant_id, SUM(quantity), CURRENT_TIMESTAMP FROM stock_movements
+        GROUP BY warehouse_id, product_id, variant_id
+        ON CONFLICT (warehouse_id, product_id, variant_id) DO NOTHING`)
+
     // Create indexes for better query performance
     // Index for product name searches
     db.Exec("CREATE INDEX IF NOT EXISTS idx_products_name ON products(name)")
     // Index for product category filtering
-    db.Exec("CREATE INDEX IF NOT EXISTS idx_products_category ON products(category)")
+    db.Exec("DROP INDEX IF EXISTS idx_products_category")
+    db.Exec("CREATE INDEX IF NOT EXISTS idx_product_categories_category ON product_categories(category_id)")
     // Index for price range queries
     db.Exec("CREATE INDEX IF NOT EXISTS idx_products_price ON products(price)")
+    // A product has at most one open low-stock alert
+    db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_low_stock_alerts_open ON low_stock_alerts(product_id) WHERE resolved_at IS NULL")
+    // A shopper has at most one pending back-in-stock subscription per product variant
+    db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_subscriptions_pending ON stock_subscriptions(product_id, variant_id, user_id) WHERE notified_at IS NULL")
+    // An email address belongs to at most one user outside the trash; the plain unique
+    // constraint this replaces counted the users in the trash as well
+    db.Exec("ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key")
+    db.Exec("ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_email")
+    db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_active ON users(email) WHERE deleted_at IS NULL")
+
+    // Full-text search document over name (weight A) and description (weight B),
+    // kept up to date by Postgres itself
+    db.Exec(`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector
+        GENERATED ALWAYS AS (
+            setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+            setweight(to_tsvector('english', coalesce(description, '')), 'B')
+        ) STORED`)
+    db.Exec("CREATE INDEX IF NOT EXISTS idx_products_search ON products USING GIN (search_vector)")
 
     log.Info("Database migration and indexes creation completed")
     return nil
@@ -65,7 +158,7 @@ func TestConnection(dsn string) error {
     }
 
     // Verify tables exist
-    tables := []string{"users", "products", "carts", "orders"}
+    tables := []string{"users", "products", "categories", "product_categories", "product_options", "product_option_values", "product_variants", "product_images", "carts", "cart_items", "orders", "order_items", "order_status_history", "stock_reservations", "warehouses", "stock_levels", "stock_movements", "low_stock_alerts", "stock_subscriptions", "coupons", "coupon_products", "coupon_categories", "cart_coupons", "coupon_redemptions", "promotions", "promotion_tiers", "promotion_products", "promotion_categories", "product_sales", "price_changes", "customer_groups", "price_lists", "price_list_entries", "tax_classes", "tax_zones", "tax_rates", "order_taxes", "shipping_zones", "shipping_methods"}
     for _, table := range tables {
         var count int64
         if err := db.Table(table).Count(&count).Error; err != nil {
@@ -4,6 +4,7 @@ import (
 	"ecommerce-app/internal/models"
 	"ecommerce-app/internal/service"
 	"ecommerce-app/pkg/logger"
+	"ecommerce-app/pkg/money"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -64,10 +65,8 @@ func generateRandomProduct() *models.Product {
 		"This %s model offers exceptional performance and reliability for all your needs. "+
 		"Model: %s", brand, adjective, category, strings.ToLower(category), strings.ToLower(category), modelNum)
 
-	// Generate price between $50 and $2000
-	price := 50.0 + rand.Float64()*1950.0
-	// Round to 2 decimal places
-	price = float64(int(price*100)) / 100
+	// Generate price between 50.00 and 2000.00 in minor units
+	price := money.New(5000+rand.Int63n(195001), money.DefaultCurrency)
 
 	// Generate stock between 5 and 200
 	stock := 5 + rand.Intn(196)
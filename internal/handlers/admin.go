@@ -1,13 +1,14 @@
 package handlers
 
 import (
+	"ecommerce-app/internal/middleware"
 	"ecommerce-app/internal/models"
 	"ecommerce-app/internal/service"
 	"ecommerce-app/pkg/logger"
 	"encoding/json"
+	"errors"
 	"math"
 	"net/http"
-	"strconv"
 )
 
 type AdminHandler struct {
@@ -63,24 +64,7 @@ func (h *AdminHandler) GetDashboardStats(w http.ResponseWriter, r *http.Request)
 // ListProducts returns products for admin management with pagination
 func (h *AdminHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
     // Parse pagination parameters
-    page := 1
-    pageSize := 10
-    
-    // Get page from query string
-    pageStr := r.URL.Query().Get("page")
-    if pageStr != "" {
-        if pageVal, err := strconv.Atoi(pageStr); err == nil && pageVal > 0 {
-            page = pageVal
-        }
-    }
-    
-    // Get page size from query string
-    pageSizeStr := r.URL.Query().Get("pageSize")
-    if pageSizeStr != "" {
-        if pageSizeVal, err := strconv.Atoi(pageSizeStr); err == nil && pageSizeVal > 0 {
-            pageSize = pageSizeVal
-        }
-    }
+    page, pageSize := parsePagination(r, 10)
     
     // Get products with pagination
     products, err := h.productService.GetProductsPaginated(page, pageSize)
@@ -130,6 +114,10 @@ func (h *AdminHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
     }
 
     if err := h.productService.CreateProduct(&product); err != nil {
+        if errors.Is(err, service.ErrCurrencyMismatch) || errors.Is(err, service.ErrInvalidProduct) {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
         h.log.Error("Failed to create product: " + err.Error())
         http.Error(w, "Failed to create product", http.StatusInternalServerError)
         return
@@ -140,27 +128,152 @@ func (h *AdminHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
     json.NewEncoder(w).Encode(product)
 }
 
+// ReplaceProduct handles full updates of the product identified in the path. Name,
+// price and stock are required; a missing description clears it.
+func (h *AdminHandler) ReplaceProduct(w http.ResponseWriter, r *http.Request) {
+    id, err := parseIDParam(r, "id")
+    if err != nil {
+        http.Error(w, "Invalid product ID", http.StatusBadRequest)
+        return
+    }
+
+    var patch service.ProductPatch
+    if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+        h.log.Error("Invalid product data: " + err.Error())
+        http.Error(w, "Invalid product data", http.StatusBadRequest)
+        return
+    }
+    if patch.Name == nil || patch.Price == nil || patch.Stock == nil {
+        http.Error(w, "name, price and stock are required", http.StatusBadRequest)
+        return
+    }
+    if patch.Description == nil {
+        patch.Description = new(string)
+    }
+
+    h.patchProduct(w, id, patch)
+}
+
+// PatchProduct handles partial updates of the product identified in the path
+func (h *AdminHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+    id, err := parseIDParam(r, "id")
+    if err != nil {
+        http.Error(w, "Invalid product ID", http.StatusBadRequest)
+        return
+    }
+
+    var patch service.ProductPatch
+    if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+        h.log.Error("Invalid product data: " + err.Error())
+        http.Error(w, "Invalid product data", http.StatusBadRequest)
+        return
+    }
+
+    h.patchProduct(w, id, patch)
+}
+
+// DeleteProduct deletes the product identified in the path
+func (h *AdminHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+    id, err := parseIDParam(r, "id")
+    if err != nil {
+        http.Error(w, "Invalid product ID", http.StatusBadRequest)
+        return
+    }
+
+    if err := h.productService.DeleteProduct(id); err != nil {
+        h.writeProductError(w, err, "Failed to delete product")
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// BulkUpdatePrices changes the prices of many products by a percentage
+func (h *AdminHandler) BulkUpdatePrices(w http.ResponseWriter, r *http.Request) {
+    var change service.BulkPriceChange
+    if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+        h.log.Error("Invalid bulk request: " + err.Error())
+        http.Error(w, "Invalid bulk request", http.StatusBadRequest)
+        return
+    }
+
+    result, err := h.productService.BulkUpdatePrices(change)
+    h.writeBulkResult(w, result, err)
+}
+
+// BulkAdjustStock adds to or removes from the stock of many products
+func (h *AdminHandler) BulkAdjustStock(w http.ResponseWriter, r *http.Request) {
+    var change service.BulkStockChange
+    if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+        h.log.Error("Invalid bulk request: " + err.Error())
+        http.Error(w, "Invalid bulk request", http.StatusBadRequest)
+        return
+    }
+
+    result, err := h.productService.BulkAdjustStock(change)
+    h.writeBulkResult(w, result, err)
+}
+
+// BulkDeleteProducts deletes many products
+func (h *AdminHandler) BulkDeleteProducts(w http.ResponseWriter, r *http.Request) {
+    var selection service.BulkSelection
+    if err := json.NewDecoder(r.Body).Decode(&selection); err != nil {
+        h.log.Error("Invalid bulk request: " + err.Error())
+        http.Error(w, "Invalid bulk request", http.StatusBadRequest)
+        return
+    }
+
+    result, err := h.productService.BulkDelete(selection)
+    h.writeBulkResult(w, result, err)
+}
+
+// patchProduct applies a product patch and writes the updated product
+func (h *AdminHandler) patchProduct(w http.ResponseWriter, id uint, patch service.ProductPatch) {
+    product, err := h.productService.PatchProduct(id, patch)
+    if err != nil {
+        h.writeProductError(w, err, "Failed to update product")
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(product)
+}
+
+// writeBulkResult writes the report of a bulk operation. Operations rolled back
+// because of failed products are answered with 409 and the report.
+func (h *AdminHandler) writeBulkResult(w http.ResponseWriter, result *service.BulkResult, err error) {
+    if err != nil {
+        h.writeProductError(w, err, "Bulk operation failed")
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if !result.Committed {
+        w.WriteHeader(http.StatusConflict)
+    }
+    json.NewEncoder(w).Encode(result)
+}
+
+// writeProductError maps product service errors to admin responses
+func (h *AdminHandler) writeProductError(w http.ResponseWriter, err error, fallback string) {
+    switch {
+    case errors.Is(err, service.ErrProductNotFound):
+        http.Error(w, "Product not found", http.StatusNotFound)
+    case errors.Is(err, service.ErrCategoryNotFound):
+        http.Error(w, "Category not found", http.StatusNotFound)
+    case errors.Is(err, service.ErrInvalidProduct), errors.Is(err, service.ErrInvalidBulkRequest),
+        errors.Is(err, service.ErrCurrencyMismatch):
+        http.Error(w, err.Error(), http.StatusBadRequest)
+    default:
+        h.log.Error(fallback + ": " + err.Error())
+        http.Error(w, fallback, http.StatusInternalServerError)
+    }
+}
+
 // ListOrders returns orders for admin management with pagination
 func (h *AdminHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
     // Parse pagination parameters
-    page := 1
-    pageSize := 10
-    
-    // Get page from query string
-    pageStr := r.URL.Query().Get("page")
-    if pageStr != "" {
-        if pageVal, err := strconv.Atoi(pageStr); err == nil && pageVal > 0 {
-            page = pageVal
-        }
-    }
-    
-    // Get page size from query string
-    pageSizeStr := r.URL.Query().Get("pageSize")
-    if pageSizeStr != "" {
-        if pageSizeVal, err := strconv.Atoi(pageSizeStr); err == nil && pageSizeVal > 0 {
-            pageSize = pageSizeVal
-        }
-    }
+    page, pageSize := parsePagination(r, 10)
     
     // Get orders with pagination
     orders, err := h.orderService.GetOrdersWithUserPaginated(page, pageSize)
@@ -200,11 +313,107 @@ func (h *AdminHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
     json.NewEncoder(w).Encode(response)
 }
 
+// DeleteOrder moves the order identified in the path to the trash
+func (h *AdminHandler) DeleteOrder(w http.ResponseWriter, r *http.Request) {
+    id, err := parseIDParam(r, "id")
+    if err != nil {
+        http.Error(w, "Invalid order ID", http.StatusBadRequest)
+        return
+    }
+
+    if err := h.orderService.DeleteOrder(id); err != nil {
+        if errors.Is(err, service.ErrOrderNotFound) {
+            http.Error(w, "Order not found", http.StatusNotFound)
+            return
+        }
+        h.log.Error("Failed to delete order: " + err.Error())
+        http.Error(w, "Failed to delete order", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteUser moves the user identified in the path to the trash
+func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+    id, err := parseIDParam(r, "id")
+    if err != nil {
+        http.Error(w, "Invalid user ID", http.StatusBadRequest)
+        return
+    }
+
+    if err := h.userService.DeleteUser(id); err != nil {
+        if errors.Is(err, service.ErrUserNotFound) {
+            http.Error(w, "User not found", http.StatusNotFound)
+            return
+        }
+        h.log.Error("Failed to delete user: " + err.Error())
+        http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// GetOrder returns a single order with its items and status history
+func (h *AdminHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+    id, err := parseIDParam(r, "id")
+    if err != nil {
+        http.Error(w, "Invalid order ID", http.StatusBadRequest)
+        return
+    }
+
+    detail, err := h.orderService.GetOrderDetail(id)
+    if err != nil {
+        if errors.Is(err, service.ErrOrderNotFound) {
+            http.Error(w, "Order not found", http.StatusNotFound)
+            return
+        }
+        h.log.Error("Failed to fetch order: " + err.Error())
+        http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+        return
+    }
+
+    response := struct {
+        *service.OrderDetail
+        AllowedTransitions []string `json:"allowed_transitions"`
+    }{
+        OrderDetail:        detail,
+        AllowedTransitions: h.orderService.AllowedTransitions(detail.Order.Status),
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(response)
+}
+
 // UpdateOrderStatus handles order status updates
 func (h *AdminHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
     var update struct {
         OrderID uint   `json:"order_id"`
         Status  string `json:"status"`
+        Note    string `json:"note"`
+    }
+
+    if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+        h.log.Error("Invalid update data: " + err.Error())
+        http.Error(w, "Invalid update data", http.StatusBadRequest)
+        return
+    }
+
+    h.transitionOrder(w, r, update.OrderID, update.Status, update.Note)
+}
+
+// TransitionOrderStatus handles status updates for the order identified in the path
+func (h *AdminHandler) TransitionOrderStatus(w http.ResponseWriter, r *http.Request) {
+    id, err := parseIDParam(r, "id")
+    if err != nil {
+        http.Error(w, "Invalid order ID", http.StatusBadRequest)
+        return
+    }
+
+    var update struct {
+        Status string `json:"status"`
+        Note   string `json:"note"`
     }
 
     if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
@@ -213,11 +422,29 @@ func (h *AdminHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request)
         return
     }
 
-    if err := h.orderService.UpdateOrderStatus(update.OrderID, update.Status); err != nil {
-        h.log.Error("Failed to update order status: " + err.Error())
-        http.Error(w, "Failed to update order status", http.StatusInternalServerError)
+    h.transitionOrder(w, r, id, update.Status, update.Note)
+}
+
+// transitionOrder applies a status change on behalf of the authenticated admin
+func (h *AdminHandler) transitionOrder(w http.ResponseWriter, r *http.Request, orderID uint, status, note string) {
+    admin, _ := middleware.GetAdminUser(r)
+
+    err := h.orderService.UpdateOrderStatus(orderID, status, "admin:"+admin, note)
+    if err != nil {
+        switch {
+        case errors.Is(err, service.ErrOrderNotFound):
+            http.Error(w, "Order not found", http.StatusNotFound)
+        case errors.Is(err, service.ErrInvalidOrderStatus):
+            http.Error(w, err.Error(), http.StatusBadRequest)
+        case errors.Is(err, service.ErrInvalidStatusTransition), errors.Is(err, service.ErrReservationExpired),
+            errors.Is(err, service.ErrInsufficientStock):
+            http.Error(w, err.Error(), http.StatusConflict)
+        default:
+            h.log.Error("Failed to update order status: " + err.Error())
+            http.Error(w, "Failed to update order status", http.StatusInternalServerError)
+        }
         return
     }
 
     w.WriteHeader(http.StatusOK)
-}
\ No newline at end of file
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/middleware"
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// AlertHandler handles the admin side of low-stock alerts
+type AlertHandler struct {
+	alertService service.AlertService
+	log          *logger.Logger
+}
+
+// NewAlertHandler creates a new instance of AlertHandler
+func NewAlertHandler(alertService service.AlertService) *AlertHandler {
+	return &AlertHandler{
+		alertService: alertService,
+		log:          logger.New(),
+	}
+}
+
+// ListAlerts returns one page of low-stock alerts, newest first. Only open alerts
+// are listed unless status=all.
+func (h *AlertHandler) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "open" && status != "all" {
+		http.Error(w, "Invalid status (want open or all)", http.StatusBadRequest)
+		return
+	}
+	page, pageSize := parsePagination(r, 50)
+
+	alerts, total, err := h.alertService.ListLowStockAlerts(status != "all", page, pageSize)
+	if err != nil {
+		h.log.Error("Failed to fetch low-stock alerts: " + err.Error())
+		http.Error(w, "Failed to fetch low-stock alerts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Alerts     []models.LowStockAlert `json:"alerts"`
+		Pagination Pagination             `json:"pagination"`
+	}{
+		Alerts:     alerts,
+		Pagination: newPagination(total, page, pageSize),
+	})
+}
+
+// SetReorderThreshold sets or, with a null threshold, removes the reorder threshold
+// of the product identified in the path
+func (h *AlertHandler) SetReorderThreshold(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Threshold *int `json:"threshold"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Invalid threshold data: " + err.Error())
+		http.Error(w, "Invalid threshold data", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.alertService.SetReorderThreshold(id, req.Threshold); err != nil {
+		switch {
+		case errors.Is(err, service.ErrProductNotFound):
+			http.Error(w, "Product not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrInvalidThreshold):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			h.log.Error("Failed to set reorder threshold: " + err.Error())
+			http.Error(w, "Failed to set reorder threshold", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"product_id": id, "threshold": req.Threshold})
+}
+
+// StockSubscriptionHandler handles the back-in-stock subscriptions of shoppers
+type StockSubscriptionHandler struct {
+	alertService service.AlertService
+	log          *logger.Logger
+}
+
+// NewStockSubscriptionHandler creates a new instance of StockSubscriptionHandler
+func NewStockSubscriptionHandler(alertService service.AlertService) *StockSubscriptionHandler {
+	return &StockSubscriptionHandler{
+		alertService: alertService,
+		log:          logger.New(),
+	}
+}
+
+// ListSubscriptions returns the user's pending back-in-stock subscriptions
+func (h *StockSubscriptionHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Unauthorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	subscriptions, err := h.alertService.ListSubscriptions(userID)
+	if err != nil {
+		h.log.Error("Failed to fetch stock subscriptions: " + err.Error())
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to fetch subscriptions"}, http.StatusInternalServerError)
+		return
+	}
+
+	ResponseWithJSON(w, map[string]interface{}{"subscriptions": subscriptions}, http.StatusOK)
+}
+
+// Subscribe asks for the user to be notified when an out-of-stock product or
+// variant is back in stock. An existing pending subscription is returned with 200.
+func (h *StockSubscriptionHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Unauthorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ProductID uint `json:"product_id"`
+		VariantID uint `json:"variant_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Invalid request"}, http.StatusBadRequest)
+		return
+	}
+
+	subscription, created, err := h.alertService.Subscribe(userID, req.ProductID, req.VariantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrProductNotFound):
+			ResponseWithJSON(w, map[string]interface{}{"error": "Product not found"}, http.StatusNotFound)
+		case errors.Is(err, service.ErrVariantNotFound):
+			ResponseWithJSON(w, map[string]interface{}{"error": "Variant not found"}, http.StatusNotFound)
+		case errors.Is(err, service.ErrAlreadyInStock):
+			ResponseWithJSON(w, map[string]interface{}{"error": "Product is in stock"}, http.StatusConflict)
+		default:
+			h.log.Error("Failed to subscribe to stock notification: " + err.Error())
+			ResponseWithJSON(w, map[string]interface{}{"error": "Failed to subscribe"}, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	ResponseWithJSON(w, subscription, status)
+}
+
+// Unsubscribe removes the user's pending subscription identified in the path
+func (h *StockSubscriptionHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Unauthorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Invalid subscription ID"}, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.alertService.Unsubscribe(userID, id); err != nil {
+		if errors.Is(err, service.ErrSubscriptionNotFound) {
+			ResponseWithJSON(w, map[string]interface{}{"error": "Subscription not found"}, http.StatusNotFound)
+			return
+		}
+		h.log.Error("Failed to remove stock subscription: " + err.Error())
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to unsubscribe"}, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
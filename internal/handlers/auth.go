@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"ecommerce-app/internal/middleware"
 	"ecommerce-app/internal/service"
 	"ecommerce-app/pkg/logger"
 	"encoding/json"
@@ -75,12 +76,16 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Register user
-	user, err := h.authService.Register(req.Email, req.Password)
+	guestCartToken := middleware.GuestCartToken(r)
+	user, cartDone, err := h.authService.Register(req.Email, req.Password, guestCartToken)
 	if err != nil {
 		h.log.Error("Failed to register user: " + err.Error())
 		responseWithError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if cartDone {
+		middleware.ClearGuestCartToken(w)
+	}
 
 	// Return success response
 	responseWithJSON(w, AuthResponse{
@@ -117,12 +122,16 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Login user
-	token, err := h.authService.Login(req.Email, req.Password)
+	guestCartToken := middleware.GuestCartToken(r)
+	token, cartDone, err := h.authService.Login(req.Email, req.Password, guestCartToken)
 	if err != nil {
 		h.log.Error("Failed to login user: " + err.Error())
 		responseWithError(w, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
+	if cartDone {
+		middleware.ClearGuestCartToken(w)
+	}
 
 	// Return success response with token
 	responseWithJSON(w, AuthResponse{
@@ -4,6 +4,7 @@ import (
 	"ecommerce-app/internal/middleware"
 	"ecommerce-app/internal/service"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 )
@@ -20,33 +21,35 @@ func NewCartHandler(cartService service.CartService) *CartHandler {
 	}
 }
 
-// GetCart handles retrieving a user's cart
+// GetCart handles retrieving the shopper's cart
 func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
-	userID, ok := middleware.GetUserID(r)
-	if !ok {
-		ResponseWithJSON(w, map[string]interface{}{"error": "Unauthorized"}, http.StatusUnauthorized)
+	ref := cartRef(r)
+
+	cartItems, err := h.cartService.GetCart(ref)
+	if err != nil {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to get cart"}, http.StatusInternalServerError)
 		return
 	}
 
-	cartItems, err := h.cartService.GetCart(userID)
+	summary, err := h.cartService.Summarize(ref, cartItems)
 	if err != nil {
-		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to get cart"}, http.StatusInternalServerError)
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to price cart"}, http.StatusInternalServerError)
 		return
 	}
 
-	ResponseWithJSON(w, map[string]interface{}{"cart": cartItems}, http.StatusOK)
+	ResponseWithJSON(w, map[string]interface{}{
+		"cart":    cartItems,
+		"summary": summary,
+	}, http.StatusOK)
 }
 
-// AddToCart handles adding a product to the user's cart
+// AddToCart handles adding a product to the shopper's cart
 func (h *CartHandler) AddToCart(w http.ResponseWriter, r *http.Request) {
-	userID, ok := middleware.GetUserID(r)
-	if !ok {
-		ResponseWithJSON(w, map[string]interface{}{"error": "Unauthorized"}, http.StatusUnauthorized)
-		return
-	}
+	ref := cartRef(r)
 
 	var cartItem struct {
 		ProductID uint `json:"product_id"`
+		VariantID uint `json:"variant_id"`
 		Quantity  int  `json:"quantity"`
 	}
 	
@@ -55,22 +58,30 @@ func (h *CartHandler) AddToCart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.cartService.AddToCart(userID, cartItem.ProductID, cartItem.Quantity)
+	if ref.UserID == 0 {
+		token, err := h.cartService.AddToGuestCart(ref.GuestToken, cartItem.ProductID, cartItem.VariantID, cartItem.Quantity)
+		if err != nil {
+			writeCartError(w, err, "Failed to add to cart")
+			return
+		}
+		// Hand the token back on every add, so the cookie lasts as long as the cart
+		middleware.SetGuestCartToken(w, token)
+		ResponseWithJSON(w, map[string]interface{}{"message": "Item added to cart"}, http.StatusCreated)
+		return
+	}
+
+	err := h.cartService.AddToCart(ref, cartItem.ProductID, cartItem.VariantID, cartItem.Quantity)
 	if err != nil {
-		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to add to cart"}, http.StatusInternalServerError)
+		writeCartError(w, err, "Failed to add to cart")
 		return
 	}
 
 	ResponseWithJSON(w, map[string]interface{}{"message": "Item added to cart"}, http.StatusCreated)
 }
 
-// RemoveFromCart handles removing a product from the user's cart
+// RemoveFromCart handles removing a product from the shopper's cart
 func (h *CartHandler) RemoveFromCart(w http.ResponseWriter, r *http.Request) {
-	userID, ok := middleware.GetUserID(r)
-	if !ok {
-		ResponseWithJSON(w, map[string]interface{}{"error": "Unauthorized"}, http.StatusUnauthorized)
-		return
-	}
+	ref := cartRef(r)
 
 	productID := r.URL.Query().Get("product_id")
 	if productID == "" {
@@ -85,11 +96,140 @@ func (h *CartHandler) RemoveFromCart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.cartService.RemoveFromCart(userID, uint(prodID))
+	var variantID uint64
+	if value := r.URL.Query().Get("variant_id"); value != "" {
+		if variantID, err = strconv.ParseUint(value, 10, 32); err != nil {
+			ResponseWithJSON(w, map[string]interface{}{"error": "Invalid variant ID"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = h.cartService.RemoveFromCart(ref, uint(prodID), uint(variantID))
 	if err != nil {
 		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to remove from cart"}, http.StatusInternalServerError)
 		return
 	}
 
 	ResponseWithJSON(w, map[string]interface{}{"message": "Item removed from cart"}, http.StatusOK)
-}
\ No newline at end of file
+}
+
+// UpdateQuantity handles setting the quantity of a product in the shopper's cart
+func (h *CartHandler) UpdateQuantity(w http.ResponseWriter, r *http.Request) {
+	ref := cartRef(r)
+
+	productID, err := parseIDParam(r, "product_id")
+	if err != nil {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Invalid product ID"}, http.StatusBadRequest)
+		return
+	}
+
+	var update struct {
+		VariantID uint `json:"variant_id"`
+		Quantity  int  `json:"quantity"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Invalid request"}, http.StatusBadRequest)
+		return
+	}
+
+	err = h.cartService.UpdateQuantity(ref, productID, update.VariantID, update.Quantity)
+	if err != nil {
+		writeCartError(w, err, "Failed to update cart")
+		return
+	}
+
+	ResponseWithJSON(w, map[string]interface{}{"message": "Cart updated"}, http.StatusOK)
+}
+
+// ApplyCoupon handles applying a coupon code to the user's cart
+func (h *CartHandler) ApplyCoupon(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Unauthorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Coupon code required"}, http.StatusBadRequest)
+		return
+	}
+
+	summary, err := h.cartService.ApplyCoupon(userID, req.Code)
+	if err != nil {
+		writeCartError(w, err, "Failed to apply coupon")
+		return
+	}
+
+	ResponseWithJSON(w, map[string]interface{}{"summary": summary}, http.StatusOK)
+}
+
+// RemoveCoupon handles taking a coupon code off the user's cart
+func (h *CartHandler) RemoveCoupon(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Unauthorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	summary, err := h.cartService.RemoveCoupon(userID, r.PathValue("code"))
+	if err != nil {
+		writeCartError(w, err, "Failed to remove coupon")
+		return
+	}
+
+	ResponseWithJSON(w, map[string]interface{}{"summary": summary}, http.StatusOK)
+}
+
+// QuoteShipping handles pricing the shopper's cart with every shipping method offered
+// for the address in the body
+func (h *CartHandler) QuoteShipping(w http.ResponseWriter, r *http.Request) {
+	var input service.AddressInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Invalid address"}, http.StatusBadRequest)
+		return
+	}
+
+	quotes, err := h.cartService.QuoteShipping(cartRef(r), input)
+	if err != nil {
+		writeCartError(w, err, "Failed to quote shipping")
+		return
+	}
+
+	ResponseWithJSON(w, map[string]interface{}{"quotes": quotes}, http.StatusOK)
+}
+
+// writeCartError maps cart service errors to HTTP responses
+func writeCartError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidQuantity), errors.Is(err, service.ErrInvalidCartToken),
+		errors.Is(err, service.ErrVariantRequired), errors.Is(err, service.ErrInvalidAddress):
+		ResponseWithJSON(w, map[string]interface{}{"error": err.Error()}, http.StatusBadRequest)
+	case errors.Is(err, service.ErrProductNotFound):
+		ResponseWithJSON(w, map[string]interface{}{"error": "Product not found"}, http.StatusNotFound)
+	case errors.Is(err, service.ErrVariantNotFound):
+		ResponseWithJSON(w, map[string]interface{}{"error": "Variant not found"}, http.StatusNotFound)
+	case errors.Is(err, service.ErrCouponNotFound):
+		ResponseWithJSON(w, map[string]interface{}{"error": "Coupon not found"}, http.StatusNotFound)
+	case errors.Is(err, service.ErrEmptyCart):
+		ResponseWithJSON(w, map[string]interface{}{"error": "Cart is empty"}, http.StatusBadRequest)
+	case errors.Is(err, service.ErrInsufficientStock), errors.Is(err, service.ErrCouponNotApplicable),
+		errors.Is(err, service.ErrCouponLimitReached), errors.Is(err, service.ErrCouponNotStackable):
+		ResponseWithJSON(w, map[string]interface{}{"error": err.Error()}, http.StatusConflict)
+	default:
+		ResponseWithJSON(w, map[string]interface{}{"error": fallback}, http.StatusInternalServerError)
+	}
+}
+
+// cartRef identifies the cart of the request: the authenticated user's cart when
+// the user middleware ran, the guest cart otherwise
+func cartRef(r *http.Request) service.CartRef {
+	if userID, ok := middleware.GetUserID(r); ok {
+		return service.CartRef{UserID: userID}
+	}
+	token, _ := middleware.GetGuestCartToken(r)
+	return service.CartRef{GuestToken: token}
+}
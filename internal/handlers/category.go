@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/middleware"
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// CategoryHandler handles category-related HTTP requests for shoppers and admins
+type CategoryHandler struct {
+	categoryService  service.CategoryService
+	priceListService service.PriceListService
+	log              *logger.Logger
+}
+
+// NewCategoryHandler creates a new instance of CategoryHandler
+func NewCategoryHandler(categoryService service.CategoryService, priceListService service.PriceListService) *CategoryHandler {
+	return &CategoryHandler{
+		categoryService:  categoryService,
+		priceListService: priceListService,
+		log:              logger.New(),
+	}
+}
+
+// categoryRequest is the payload accepted when creating or updating a category
+type categoryRequest struct {
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id"`
+	Position    int    `json:"position"`
+}
+
+// GetCategoryTree handles listing the category tree
+func (h *CategoryHandler) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.categoryService.GetCategoryTree()
+	if err != nil {
+		h.log.Error("Failed to fetch categories: " + err.Error())
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to fetch categories"}, http.StatusInternalServerError)
+		return
+	}
+
+	ResponseWithJSON(w, map[string]interface{}{"categories": tree}, http.StatusOK)
+}
+
+// ListCategoryProducts handles listing the products of a category and its descendants
+func (h *CategoryHandler) ListCategoryProducts(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	page, pageSize := parsePagination(r, 20)
+
+	category, err := h.categoryService.GetCategoryBySlug(slug)
+	if err != nil {
+		h.writePublicError(w, err)
+		return
+	}
+
+	products, total, err := h.categoryService.GetProductsByCategory(slug, page, pageSize)
+	if err != nil {
+		h.writePublicError(w, err)
+		return
+	}
+	userID, _ := middleware.GetUserID(r)
+	if err := h.priceListService.ApplyGroupPrices(userID, products); err != nil {
+		h.writePublicError(w, err)
+		return
+	}
+
+	ResponseWithJSON(w, struct {
+		Category   *models.Category `json:"category"`
+		Products   []models.Product `json:"products"`
+		Pagination Pagination       `json:"pagination"`
+	}{
+		Category:   category,
+		Products:   products,
+		Pagination: newPagination(total, page, pageSize),
+	}, http.StatusOK)
+}
+
+// writePublicError maps category lookup errors to shopper responses
+func (h *CategoryHandler) writePublicError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrCategoryNotFound) {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Category not found"}, http.StatusNotFound)
+		return
+	}
+	h.log.Error("Failed to fetch category products: " + err.Error())
+	ResponseWithJSON(w, map[string]interface{}{"error": "Failed to fetch products"}, http.StatusInternalServerError)
+}
+
+// ListCategories returns all categories as a flat list for admin management
+func (h *CategoryHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.categoryService.GetAllCategories()
+	if err != nil {
+		h.log.Error("Failed to fetch categories: " + err.Error())
+		http.Error(w, "Failed to fetch categories", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(categories)
+}
+
+// CreateCategory handles new category creation
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var req categoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Invalid category data: " + err.Error())
+		http.Error(w, "Invalid category data", http.StatusBadRequest)
+		return
+	}
+
+	category := models.Category{
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Description: req.Description,
+		ParentID:    req.ParentID,
+		Position:    req.Position,
+	}
+	if err := h.categoryService.CreateCategory(&category); err != nil {
+		h.writeAdminError(w, err, "Failed to create category")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// UpdateCategory handles updates to the category identified in the path
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	var req categoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Invalid category data: " + err.Error())
+		http.Error(w, "Invalid category data", http.StatusBadRequest)
+		return
+	}
+
+	category := models.Category{
+		ID:          id,
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Description: req.Description,
+		ParentID:    req.ParentID,
+		Position:    req.Position,
+	}
+	if err := h.categoryService.UpdateCategory(&category); err != nil {
+		h.writeAdminError(w, err, "Failed to update category")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// DeleteCategory handles deletion of the category identified in the path
+func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.categoryService.DeleteCategory(id); err != nil {
+		h.writeAdminError(w, err, "Failed to delete category")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetProductCategories handles replacing the categories of the product identified in the path
+func (h *CategoryHandler) SetProductCategories(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		CategoryIDs []uint `json:"category_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Invalid category assignment: " + err.Error())
+		http.Error(w, "Invalid category assignment", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.categoryService.SetProductCategories(id, req.CategoryIDs); err != nil {
+		if errors.Is(err, service.ErrProductNotFound) {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, service.ErrCategoryNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.log.Error("Failed to assign categories: " + err.Error())
+		http.Error(w, "Failed to assign categories", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAdminError maps category service errors to admin responses
+func (h *CategoryHandler) writeAdminError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrCategoryNotFound):
+		http.Error(w, "Category not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidCategory), errors.Is(err, service.ErrInvalidCategoryParent):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrCategorySlugTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.log.Error(fallback + ": " + err.Error())
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
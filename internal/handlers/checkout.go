@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/middleware"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// CheckoutHandler handles checkout-related HTTP requests
+type CheckoutHandler struct {
+	checkoutService service.CheckoutService
+	log             *logger.Logger
+}
+
+// NewCheckoutHandler creates a new instance of CheckoutHandler
+func NewCheckoutHandler(checkoutService service.CheckoutService) *CheckoutHandler {
+	return &CheckoutHandler{
+		checkoutService: checkoutService,
+		log:             logger.New(),
+	}
+}
+
+// Checkout handles turning the user's cart into an order. The body gives the
+// shipping address and the shipping method chosen from the quotes for it; it may be
+// left out while the store has no shipping zones. The response tells the shopper
+// until when the stock is held for them.
+func (h *CheckoutHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Unauthorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	var input service.CheckoutInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Invalid checkout data"}, http.StatusBadRequest)
+		return
+	}
+
+	order, reservedUntil, err := h.checkoutService.Checkout(userID, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmptyCart):
+			ResponseWithJSON(w, map[string]interface{}{"error": "Cart is empty"}, http.StatusBadRequest)
+		case errors.Is(err, service.ErrInvalidAddress), errors.Is(err, service.ErrShippingMethodRequired):
+			ResponseWithJSON(w, map[string]interface{}{"error": err.Error()}, http.StatusBadRequest)
+		case errors.Is(err, service.ErrInsufficientStock), errors.Is(err, service.ErrCurrencyMismatch),
+			errors.Is(err, service.ErrCouponNotApplicable), errors.Is(err, service.ErrCouponLimitReached),
+			errors.Is(err, service.ErrShippingUnavailable):
+			ResponseWithJSON(w, map[string]interface{}{"error": err.Error()}, http.StatusConflict)
+		default:
+			h.log.Error("Failed to checkout: " + err.Error())
+			ResponseWithJSON(w, map[string]interface{}{"error": "Failed to checkout"}, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	ResponseWithJSON(w, map[string]interface{}{"order": order, "reserved_until": reservedUntil}, http.StatusCreated)
+}
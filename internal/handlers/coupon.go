@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// CouponHandler handles the admin management of coupons
+type CouponHandler struct {
+	couponService service.CouponService
+	log           *logger.Logger
+}
+
+// NewCouponHandler creates a new instance of CouponHandler
+func NewCouponHandler(couponService service.CouponService) *CouponHandler {
+	return &CouponHandler{
+		couponService: couponService,
+		log:           logger.New(),
+	}
+}
+
+// ListCoupons returns one page of coupons, newest first
+func (h *CouponHandler) ListCoupons(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := parsePagination(r, 50)
+
+	coupons, total, err := h.couponService.ListCoupons(page, pageSize)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch coupons")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Coupons    []models.Coupon `json:"coupons"`
+		Pagination Pagination      `json:"pagination"`
+	}{
+		Coupons:    coupons,
+		Pagination: newPagination(total, page, pageSize),
+	})
+}
+
+// GetCoupon returns the coupon identified in the path
+func (h *CouponHandler) GetCoupon(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid coupon ID", http.StatusBadRequest)
+		return
+	}
+
+	coupon, err := h.couponService.GetCoupon(id)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch coupon")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(coupon)
+}
+
+// CreateCoupon handles the creation of a new coupon
+func (h *CouponHandler) CreateCoupon(w http.ResponseWriter, r *http.Request) {
+	var input service.CouponInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid coupon data: " + err.Error())
+		http.Error(w, "Invalid coupon data", http.StatusBadRequest)
+		return
+	}
+
+	coupon, err := h.couponService.CreateCoupon(input)
+	if err != nil {
+		h.writeError(w, err, "Failed to create coupon")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(coupon)
+}
+
+// UpdateCoupon handles replacing the coupon identified in the path
+func (h *CouponHandler) UpdateCoupon(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid coupon ID", http.StatusBadRequest)
+		return
+	}
+
+	var input service.CouponInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid coupon data: " + err.Error())
+		http.Error(w, "Invalid coupon data", http.StatusBadRequest)
+		return
+	}
+
+	coupon, err := h.couponService.UpdateCoupon(id, input)
+	if err != nil {
+		h.writeError(w, err, "Failed to update coupon")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(coupon)
+}
+
+// DeleteCoupon handles deleting the coupon identified in the path. Coupons that
+// orders have redeemed cannot be deleted, only deactivated.
+func (h *CouponHandler) DeleteCoupon(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid coupon ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.couponService.DeleteCoupon(id); err != nil {
+		h.writeError(w, err, "Failed to delete coupon")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListRedemptions returns one page of the redemptions of the coupon identified in the path
+func (h *CouponHandler) ListRedemptions(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid coupon ID", http.StatusBadRequest)
+		return
+	}
+	page, pageSize := parsePagination(r, 50)
+
+	redemptions, total, err := h.couponService.ListRedemptions(id, page, pageSize)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch coupon redemptions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Redemptions []models.CouponRedemption `json:"redemptions"`
+		Pagination  Pagination                `json:"pagination"`
+	}{
+		Redemptions: redemptions,
+		Pagination:  newPagination(total, page, pageSize),
+	})
+}
+
+// writeError maps coupon service errors to HTTP responses
+func (h *CouponHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrCouponNotFound):
+		http.Error(w, "Coupon not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidCoupon):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrCouponCodeTaken), errors.Is(err, service.ErrCouponRedeemed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.log.Error(fallback + ": " + err.Error())
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
 )
 
 // ResponseWithJSON is a helper function to send JSON responses
@@ -14,4 +16,51 @@ func ResponseWithJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	if err != nil {
 		http.Error(w, "Error encoding response", http.StatusInternalServerError)
 	}
-}
\ No newline at end of file
+}
+
+// Pagination describes the pagination metadata returned with list responses
+type Pagination struct {
+	Total      int64 `json:"total"`
+	Page       int   `json:"page"`
+	PageSize   int   `json:"pageSize"`
+	TotalPages int   `json:"totalPages"`
+}
+
+// newPagination builds pagination metadata for a page of results
+func newPagination(total int64, page, pageSize int) Pagination {
+	return Pagination{
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int(math.Ceil(float64(total) / float64(pageSize))),
+	}
+}
+
+// maxPageSize bounds the page size a client can ask for
+const maxPageSize = 100
+
+// parsePagination reads the page and pageSize query parameters, falling back to
+// page 1 and the given default page size when they are missing or invalid. Page
+// sizes above maxPageSize are clamped to it.
+func parsePagination(r *http.Request, defaultPageSize int) (int, int) {
+	page := 1
+	pageSize := defaultPageSize
+
+	if pageVal, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && pageVal > 0 {
+		page = pageVal
+	}
+	if pageSizeVal, err := strconv.Atoi(r.URL.Query().Get("pageSize")); err == nil && pageSizeVal > 0 {
+		pageSize = min(pageSizeVal, maxPageSize)
+	}
+
+	return page, pageSize
+}
+
+// parseIDParam parses a numeric path parameter such as {id}
+func parseIDParam(r *http.Request, name string) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue(name), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint(id), nil
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"ecommerce-app/internal/middleware"
 	"ecommerce-app/internal/models"
 	"ecommerce-app/internal/service"
 	"encoding/json"
@@ -8,8 +9,9 @@ import (
 	"net/http"
 )
 
-// HomeHandler returns a handler function that fetches and returns products with pagination
-func HomeHandler(productService service.ProductService) http.HandlerFunc {
+// HomeHandler returns a handler function that fetches and returns products with pagination,
+// along with the prices of the signed-in shopper's customer group
+func HomeHandler(productService service.ProductService, priceListService service.PriceListService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Ensure the request is for the root path
 		if r.URL.Path != "/" {
@@ -27,6 +29,11 @@ func HomeHandler(productService service.ProductService) http.HandlerFunc {
 			http.Error(w, "Failed to fetch products", http.StatusInternalServerError)
 			return
 		}
+		userID, _ := middleware.GetUserID(r)
+		if err := priceListService.ApplyGroupPrices(userID, products); err != nil {
+			http.Error(w, "Failed to fetch products", http.StatusInternalServerError)
+			return
+		}
 
 		// Get total count for pagination metadata
 		total, err := productService.CountProducts()
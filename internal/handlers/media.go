@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// maxImageSize limits the size of a single uploaded image
+	maxImageSize = 10 << 20
+	// maxUploadSize limits the size of a whole upload request
+	maxUploadSize = 50 << 20
+	// uploadMemory is the part of an upload kept in memory while parsing; the rest goes to temporary files
+	uploadMemory = 16 << 20
+)
+
+// MediaHandler handles the admin management of product images
+type MediaHandler struct {
+	mediaService service.MediaService
+	log          *logger.Logger
+}
+
+// NewMediaHandler creates a new instance of MediaHandler
+func NewMediaHandler(mediaService service.MediaService) *MediaHandler {
+	return &MediaHandler{
+		mediaService: mediaService,
+		log:          logger.New(),
+	}
+}
+
+// ListImages returns the images of the product identified in the path
+func (h *MediaHandler) ListImages(w http.ResponseWriter, r *http.Request) {
+	productID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	images, err := h.mediaService.GetImages(productID)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch images")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(images)
+}
+
+// UploadImages handles multipart uploads of product images. Files are read from
+// the "images" field; the optional "alt_text" values apply to the files in order.
+func (h *MediaHandler) UploadImages(w http.ResponseWriter, r *http.Request) {
+	productID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(uploadMemory); err != nil {
+		h.log.Error("Invalid image upload: " + err.Error())
+		http.Error(w, "Invalid multipart upload", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["images"]
+	if len(files) == 0 {
+		http.Error(w, "No images uploaded", http.StatusBadRequest)
+		return
+	}
+	altTexts := r.MultipartForm.Value["alt_text"]
+
+	uploads := make([]service.ImageUpload, 0, len(files))
+	for i, header := range files {
+		if header.Size > maxImageSize {
+			http.Error(w, header.Filename+" exceeds the maximum image size of "+strconv.Itoa(maxImageSize>>20)+" MB", http.StatusRequestEntityTooLarge)
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			h.log.Error("Failed to open uploaded file: " + err.Error())
+			http.Error(w, "Failed to read upload", http.StatusBadRequest)
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(file, maxImageSize+1))
+		file.Close()
+		if err != nil {
+			h.log.Error("Failed to read uploaded file: " + err.Error())
+			http.Error(w, "Failed to read upload", http.StatusBadRequest)
+			return
+		}
+
+		upload := service.ImageUpload{Filename: header.Filename, Data: data}
+		if i < len(altTexts) {
+			upload.AltText = altTexts[i]
+		}
+		uploads = append(uploads, upload)
+	}
+
+	images, err := h.mediaService.UploadImages(productID, uploads)
+	if err != nil {
+		h.writeError(w, err, "Failed to upload images")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(images)
+}
+
+// ReorderImages sets the display order of the images of the product identified in the path
+func (h *MediaHandler) ReorderImages(w http.ResponseWriter, r *http.Request) {
+	productID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		ImageIDs []uint `json:"image_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.Error("Invalid image order: " + err.Error())
+		http.Error(w, "Invalid image order", http.StatusBadRequest)
+		return
+	}
+
+	images, err := h.mediaService.ReorderImages(productID, request.ImageIDs)
+	if err != nil {
+		h.writeError(w, err, "Failed to reorder images")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(images)
+}
+
+// SetPrimaryImage makes the image identified in the path the primary image of its product
+func (h *MediaHandler) SetPrimaryImage(w http.ResponseWriter, r *http.Request) {
+	productID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	imageID, err := parseIDParam(r, "image_id")
+	if err != nil {
+		http.Error(w, "Invalid image ID", http.StatusBadRequest)
+		return
+	}
+
+	images, err := h.mediaService.SetPrimaryImage(productID, imageID)
+	if err != nil {
+		h.writeError(w, err, "Failed to set primary image")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(images)
+}
+
+// DeleteImage deletes the image identified in the path
+func (h *MediaHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
+	productID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	imageID, err := parseIDParam(r, "image_id")
+	if err != nil {
+		http.Error(w, "Invalid image ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.mediaService.DeleteImage(productID, imageID); err != nil {
+		h.writeError(w, err, "Failed to delete image")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError maps media service errors to admin responses
+func (h *MediaHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrProductNotFound):
+		http.Error(w, "Product not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrImageNotFound):
+		http.Error(w, "Image not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidImage), errors.Is(err, service.ErrInvalidImageOrder):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		h.log.Error(fallback + ": " + err.Error())
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/middleware"
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// OrderHandler handles the order-related HTTP requests of shoppers
+type OrderHandler struct {
+	orderService service.OrderService
+	log          *logger.Logger
+}
+
+// NewOrderHandler creates a new instance of OrderHandler
+func NewOrderHandler(orderService service.OrderService) *OrderHandler {
+	return &OrderHandler{
+		orderService: orderService,
+		log:          logger.New(),
+	}
+}
+
+// ListOrders handles listing the user's orders with pagination and filters
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Unauthorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	page, pageSize := parsePagination(r, 10)
+
+	filter := repository.OrderFilter{Status: r.URL.Query().Get("status")}
+
+	from, err := parseDateParam(r.URL.Query().Get("from"), false)
+	if err != nil {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Invalid from date"}, http.StatusBadRequest)
+		return
+	}
+	filter.From = from
+
+	to, err := parseDateParam(r.URL.Query().Get("to"), true)
+	if err != nil {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Invalid to date"}, http.StatusBadRequest)
+		return
+	}
+	filter.To = to
+
+	orders, total, err := h.orderService.GetUserOrdersPaginated(userID, filter, page, pageSize)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidOrderStatus) {
+			ResponseWithJSON(w, map[string]interface{}{"error": err.Error()}, http.StatusBadRequest)
+			return
+		}
+		h.log.Error("Failed to fetch orders: " + err.Error())
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to fetch orders"}, http.StatusInternalServerError)
+		return
+	}
+
+	ResponseWithJSON(w, struct {
+		Orders     []models.Order `json:"orders"`
+		Pagination Pagination     `json:"pagination"`
+	}{
+		Orders:     orders,
+		Pagination: newPagination(total, page, pageSize),
+	}, http.StatusOK)
+}
+
+// GetOrder handles retrieving a single order of the user
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r)
+	if !ok {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Unauthorized"}, http.StatusUnauthorized)
+		return
+	}
+
+	orderID, err := parseIDParam(r, "id")
+	if err != nil {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Invalid order ID"}, http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderService.GetUserOrder(userID, orderID)
+	if err != nil {
+		if errors.Is(err, service.ErrOrderNotFound) {
+			ResponseWithJSON(w, map[string]interface{}{"error": "Order not found"}, http.StatusNotFound)
+			return
+		}
+		h.log.Error("Failed to fetch order: " + err.Error())
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to fetch order"}, http.StatusInternalServerError)
+		return
+	}
+
+	ResponseWithJSON(w, map[string]interface{}{"order": order}, http.StatusOK)
+}
+
+// parseDateParam parses a date query parameter given either as YYYY-MM-DD or RFC 3339.
+// A plain date used as an upper bound is moved to the end of that day so it is inclusive.
+func parseDateParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// PriceListHandler handles the admin management of customer groups and their price lists
+type PriceListHandler struct {
+	priceListService service.PriceListService
+	log              *logger.Logger
+}
+
+// NewPriceListHandler creates a new instance of PriceListHandler
+func NewPriceListHandler(priceListService service.PriceListService) *PriceListHandler {
+	return &PriceListHandler{
+		priceListService: priceListService,
+		log:              logger.New(),
+	}
+}
+
+// ListGroups returns every customer group
+func (h *PriceListHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.priceListService.ListGroups()
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch customer groups")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"customer_groups": groups})
+}
+
+// CreateGroup handles the creation of a new customer group
+func (h *PriceListHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var input service.CustomerGroupInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid customer group data: " + err.Error())
+		http.Error(w, "Invalid customer group data", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.priceListService.CreateGroup(input)
+	if err != nil {
+		h.writeError(w, err, "Failed to create customer group")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(group)
+}
+
+// UpdateGroup handles replacing the customer group identified in the path
+func (h *PriceListHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid customer group ID", http.StatusBadRequest)
+		return
+	}
+
+	var input service.CustomerGroupInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid customer group data: " + err.Error())
+		http.Error(w, "Invalid customer group data", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.priceListService.UpdateGroup(id, input)
+	if err != nil {
+		h.writeError(w, err, "Failed to update customer group")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// DeleteGroup handles deleting the customer group identified in the path
+func (h *PriceListHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid customer group ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.priceListService.DeleteGroup(id); err != nil {
+		h.writeError(w, err, "Failed to delete customer group")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AssignUser puts the user identified in the path in the customer group given in the
+// body, or takes them out of their group when it is null
+func (h *PriceListHandler) AssignUser(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		CustomerGroupID *uint `json:"customer_group_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Invalid customer group assignment: " + err.Error())
+		http.Error(w, "Invalid customer group assignment", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.priceListService.AssignUser(id, req.CustomerGroupID); err != nil {
+		h.writeError(w, err, "Failed to assign customer group")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPriceLists returns one page of price lists, restricted to one customer group
+// with the group_id query parameter
+func (h *PriceListHandler) ListPriceLists(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := parsePagination(r, 50)
+	var groupID uint
+	if value := r.URL.Query().Get("group_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid customer group ID", http.StatusBadRequest)
+			return
+		}
+		groupID = uint(id)
+	}
+
+	lists, total, err := h.priceListService.ListPriceLists(groupID, page, pageSize)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch price lists")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		PriceLists []models.PriceList `json:"price_lists"`
+		Pagination Pagination         `json:"pagination"`
+	}{
+		PriceLists: lists,
+		Pagination: newPagination(total, page, pageSize),
+	})
+}
+
+// GetPriceList returns the price list identified in the path
+func (h *PriceListHandler) GetPriceList(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid price list ID", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.priceListService.GetPriceList(id)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch price list")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// CreatePriceList handles the creation of a new price list
+func (h *PriceListHandler) CreatePriceList(w http.ResponseWriter, r *http.Request) {
+	var input service.PriceListInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid price list data: " + err.Error())
+		http.Error(w, "Invalid price list data", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.priceListService.CreatePriceList(input)
+	if err != nil {
+		h.writeError(w, err, "Failed to create price list")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(list)
+}
+
+// UpdatePriceList handles replacing the price list identified in the path
+func (h *PriceListHandler) UpdatePriceList(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid price list ID", http.StatusBadRequest)
+		return
+	}
+
+	var input service.PriceListInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid price list data: " + err.Error())
+		http.Error(w, "Invalid price list data", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.priceListService.UpdatePriceList(id, input)
+	if err != nil {
+		h.writeError(w, err, "Failed to update price list")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// DeletePriceList handles deleting the price list identified in the path
+func (h *PriceListHandler) DeletePriceList(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid price list ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.priceListService.DeletePriceList(id); err != nil {
+		h.writeError(w, err, "Failed to delete price list")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError maps price list service errors to HTTP responses
+func (h *PriceListHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrCustomerGroupNotFound):
+		http.Error(w, "Customer group not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrPriceListNotFound):
+		http.Error(w, "Price list not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidCustomerGroup), errors.Is(err, service.ErrInvalidPriceList):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrCustomerGroupNameTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.log.Error(fallback + ": " + err.Error())
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/middleware"
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/search"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// ProductHandler handles the public product catalog. Signed-in shoppers also see the
+// prices of their customer group.
+type ProductHandler struct {
+	productService   service.ProductService
+	variantService   service.VariantService
+	priceListService service.PriceListService
+	log              *logger.Logger
+}
+
+// NewProductHandler creates a new instance of ProductHandler
+func NewProductHandler(productService service.ProductService, variantService service.VariantService,
+	priceListService service.PriceListService) *ProductHandler {
+	return &ProductHandler{
+		productService:   productService,
+		variantService:   variantService,
+		priceListService: priceListService,
+		log:              logger.New(),
+	}
+}
+
+// ListProducts handles listing the catalog. Supported query parameters are q, category,
+// min_price, max_price, in_stock, sort (price, -price, newest, name), page and pageSize.
+func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, pageSize := parsePagination(r, 20)
+
+	filter := service.ProductFilter{
+		Search:   query.Get("q"),
+		Category: query.Get("category"),
+		Sort:     query.Get("sort"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	var err error
+	if filter.MinPrice, filter.MaxPrice, filter.InStock, err = parseCatalogFilters(r); err != nil {
+		ResponseWithJSON(w, map[string]interface{}{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	products, total, err := h.productService.SearchProducts(filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidProductFilter):
+			ResponseWithJSON(w, map[string]interface{}{"error": err.Error()}, http.StatusBadRequest)
+		case errors.Is(err, service.ErrCategoryNotFound):
+			ResponseWithJSON(w, map[string]interface{}{"error": "Category not found"}, http.StatusNotFound)
+		default:
+			h.log.Error("Failed to fetch products: " + err.Error())
+			ResponseWithJSON(w, map[string]interface{}{"error": "Failed to fetch products"}, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	if err := h.priceListService.ApplyGroupPrices(userID, products); err != nil {
+		h.log.Error("Failed to fetch group prices: " + err.Error())
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to fetch products"}, http.StatusInternalServerError)
+		return
+	}
+
+	ResponseWithJSON(w, struct {
+		Products   []models.Product `json:"products"`
+		Pagination Pagination       `json:"pagination"`
+	}{
+		Products:   products,
+		Pagination: newPagination(total, page, pageSize),
+	}, http.StatusOK)
+}
+
+// Search handles faceted full-text search with highlighted matches. Supported query
+// parameters are q, category, min_price, max_price, in_stock, page and pageSize.
+func (h *ProductHandler) Search(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := parsePagination(r, 20)
+
+	query := search.Query{
+		Text:     r.URL.Query().Get("q"),
+		Category: r.URL.Query().Get("category"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	var err error
+	if query.MinPrice, query.MaxPrice, query.InStock, err = parseCatalogFilters(r); err != nil {
+		ResponseWithJSON(w, map[string]interface{}{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.productService.Search(query)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidProductFilter) {
+			ResponseWithJSON(w, map[string]interface{}{"error": err.Error()}, http.StatusBadRequest)
+			return
+		}
+		h.log.Error("Failed to search products: " + err.Error())
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to search products"}, http.StatusInternalServerError)
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	products := make([]models.Product, len(result.Hits))
+	for i, hit := range result.Hits {
+		products[i] = hit.Product
+	}
+	if err := h.priceListService.ApplyGroupPrices(userID, products); err != nil {
+		h.log.Error("Failed to fetch group prices: " + err.Error())
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to search products"}, http.StatusInternalServerError)
+		return
+	}
+	for i := range result.Hits {
+		result.Hits[i].Product.GroupPrices = products[i].GroupPrices
+	}
+
+	ResponseWithJSON(w, struct {
+		Results    []service.ProductSearchHit `json:"results"`
+		Facets     search.Facets              `json:"facets"`
+		Pagination Pagination                 `json:"pagination"`
+	}{
+		Results:    result.Hits,
+		Facets:     result.Facets,
+		Pagination: newPagination(result.Total, page, pageSize),
+	}, http.StatusOK)
+}
+
+// Suggest handles autocomplete of partially typed searches
+func (h *ProductHandler) Suggest(w http.ResponseWriter, r *http.Request) {
+	suggestions, err := h.productService.Suggest(r.URL.Query().Get("q"))
+	if err != nil {
+		h.log.Error("Failed to suggest products: " + err.Error())
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to suggest products"}, http.StatusInternalServerError)
+		return
+	}
+
+	ResponseWithJSON(w, map[string]interface{}{"suggestions": suggestions}, http.StatusOK)
+}
+
+// GetProduct handles retrieving a single product with its options and the
+// availability of each option combination
+func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		ResponseWithJSON(w, map[string]interface{}{"error": "Invalid product ID"}, http.StatusBadRequest)
+		return
+	}
+
+	product, err := h.productService.GetProductByID(id)
+	if err != nil {
+		if errors.Is(err, service.ErrProductNotFound) {
+			ResponseWithJSON(w, map[string]interface{}{"error": "Product not found"}, http.StatusNotFound)
+			return
+		}
+		h.log.Error("Failed to fetch product: " + err.Error())
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to fetch product"}, http.StatusInternalServerError)
+		return
+	}
+
+	userID, _ := middleware.GetUserID(r)
+	products := []models.Product{*product}
+	if err := h.priceListService.ApplyGroupPrices(userID, products); err != nil {
+		h.log.Error("Failed to fetch group prices: " + err.Error())
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to fetch product"}, http.StatusInternalServerError)
+		return
+	}
+	product = &products[0]
+
+	availability, err := h.variantService.GetAvailability(id, userID)
+	if err != nil {
+		h.log.Error("Failed to fetch product variants: " + err.Error())
+		ResponseWithJSON(w, map[string]interface{}{"error": "Failed to fetch product"}, http.StatusInternalServerError)
+		return
+	}
+
+	ResponseWithJSON(w, map[string]interface{}{
+		"product":  product,
+		"options":  availability.Options,
+		"variants": availability.Variants,
+	}, http.StatusOK)
+}
+
+// parseCatalogFilters reads the min_price, max_price and in_stock query parameters
+func parseCatalogFilters(r *http.Request) (*money.Money, *money.Money, bool, error) {
+	query := r.URL.Query()
+
+	minPrice, err := parsePriceParam(query.Get("min_price"))
+	if err != nil {
+		return nil, nil, false, errors.New("invalid min_price")
+	}
+	maxPrice, err := parsePriceParam(query.Get("max_price"))
+	if err != nil {
+		return nil, nil, false, errors.New("invalid max_price")
+	}
+
+	inStock := false
+	if value := query.Get("in_stock"); value != "" {
+		if inStock, err = strconv.ParseBool(value); err != nil {
+			return nil, nil, false, errors.New("invalid in_stock")
+		}
+	}
+	return minPrice, maxPrice, inStock, nil
+}
+
+// parsePriceParam parses an optional price in the store currency
+func parsePriceParam(value string) (*money.Money, error) {
+	if value == "" {
+		return nil, nil
+	}
+	price, err := money.Parse(value, money.DefaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if price.IsNegative() {
+		return nil, money.ErrInvalidAmount
+	}
+	return &price, nil
+}
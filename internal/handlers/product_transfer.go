@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxImportSize limits the size of an import upload
+const maxImportSize = 100 << 20
+
+// ProductTransferHandler handles bulk product import and export
+type ProductTransferHandler struct {
+	transferService service.ProductTransferService
+	log             *logger.Logger
+}
+
+// NewProductTransferHandler creates a new instance of ProductTransferHandler
+func NewProductTransferHandler(transferService service.ProductTransferService) *ProductTransferHandler {
+	return &ProductTransferHandler{
+		transferService: transferService,
+		log:             logger.New(),
+	}
+}
+
+// ImportProducts upserts products from a CSV or JSON-lines file, sent either as the
+// request body or as the "file" field of a multipart form. The format comes from
+// the format query parameter, or else from the content type or file name. With
+// dry_run=true the file is only validated.
+func (h *ProductTransferHandler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid dry_run", http.StatusBadRequest)
+			return
+		}
+		dryRun = parsed
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	body, format, err := importSource(r)
+	if err != nil {
+		h.log.Error("Invalid import upload: " + err.Error())
+		http.Error(w, "Invalid import upload", http.StatusBadRequest)
+		return
+	}
+	if value := r.URL.Query().Get("format"); value != "" {
+		format = value
+	}
+	if format == "" {
+		http.Error(w, "Unknown import format; pass format=csv or format=jsonl", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.transferService.ImportProducts(body, format, dryRun)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			http.Error(w, "Import file too large", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, service.ErrInvalidImport):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			h.log.Error("Failed to import products: " + err.Error())
+			http.Error(w, "Failed to import products", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ExportProducts streams every product as CSV (the default) or JSON lines
+func (h *ProductTransferHandler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = service.FormatCSV
+	}
+
+	contentType := "text/csv"
+	switch format {
+	case service.FormatCSV:
+	case service.FormatJSONL:
+		contentType = "application/x-ndjson"
+	default:
+		http.Error(w, "Unknown export format; pass format=csv or format=jsonl", http.StatusBadRequest)
+		return
+	}
+
+	filename := "products-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	// The status is sent with the first batch, so errors can only be logged
+	if err := h.transferService.ExportProducts(w, format); err != nil {
+		h.log.Error("Failed to export products: " + err.Error())
+	}
+}
+
+// importSource returns the import file of a request and the format implied by its
+// content type or file name, if any. Multipart uploads are streamed, not buffered.
+func importSource(r *http.Request) (io.Reader, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, formatOf(mediaType, ""), nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			if err == io.EOF {
+				return nil, "", errors.New(`missing "file" field`)
+			}
+			return nil, "", err
+		}
+		if part.FormName() == "file" {
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			return part, formatOf(partType, part.FileName()), nil
+		}
+	}
+}
+
+// formatOf guesses the import format from a media type or file name
+func formatOf(mediaType, filename string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return service.FormatCSV
+	case ".jsonl", ".ndjson":
+		return service.FormatJSONL
+	}
+	switch mediaType {
+	case "text/csv":
+		return service.FormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return service.FormatJSONL
+	}
+	return ""
+}
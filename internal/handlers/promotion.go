@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// PromotionHandler handles the admin management of automatic promotions
+type PromotionHandler struct {
+	promotionService service.PromotionService
+	log              *logger.Logger
+}
+
+// NewPromotionHandler creates a new instance of PromotionHandler
+func NewPromotionHandler(promotionService service.PromotionService) *PromotionHandler {
+	return &PromotionHandler{
+		promotionService: promotionService,
+		log:              logger.New(),
+	}
+}
+
+// ListPromotions returns one page of promotions in evaluation order
+func (h *PromotionHandler) ListPromotions(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := parsePagination(r, 50)
+
+	promotions, total, err := h.promotionService.ListPromotions(page, pageSize)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch promotions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Promotions []models.Promotion `json:"promotions"`
+		Pagination Pagination         `json:"pagination"`
+	}{
+		Promotions: promotions,
+		Pagination: newPagination(total, page, pageSize),
+	})
+}
+
+// GetPromotion returns the promotion identified in the path
+func (h *PromotionHandler) GetPromotion(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	promotion, err := h.promotionService.GetPromotion(id)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch promotion")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// CreatePromotion handles the creation of a new promotion
+func (h *PromotionHandler) CreatePromotion(w http.ResponseWriter, r *http.Request) {
+	var input service.PromotionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid promotion data: " + err.Error())
+		http.Error(w, "Invalid promotion data", http.StatusBadRequest)
+		return
+	}
+
+	promotion, err := h.promotionService.CreatePromotion(input)
+	if err != nil {
+		h.writeError(w, err, "Failed to create promotion")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// UpdatePromotion handles replacing the promotion identified in the path
+func (h *PromotionHandler) UpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	var input service.PromotionInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid promotion data: " + err.Error())
+		http.Error(w, "Invalid promotion data", http.StatusBadRequest)
+		return
+	}
+
+	promotion, err := h.promotionService.UpdatePromotion(id, input)
+	if err != nil {
+		h.writeError(w, err, "Failed to update promotion")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// DeletePromotion handles deleting the promotion identified in the path
+func (h *PromotionHandler) DeletePromotion(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.promotionService.DeletePromotion(id); err != nil {
+		h.writeError(w, err, "Failed to delete promotion")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PreviewPromotion prices the sample cart in the body with the promotion identified
+// in the path, alone or, with combine, together with the running promotions
+func (h *PromotionHandler) PreviewPromotion(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid promotion ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Items   []service.SampleCartItem `json:"items"`
+		Combine bool                     `json:"combine"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Invalid sample cart: " + err.Error())
+		http.Error(w, "Invalid sample cart", http.StatusBadRequest)
+		return
+	}
+
+	preview, err := h.promotionService.PreviewPromotion(id, req.Items, req.Combine)
+	if err != nil {
+		h.writeError(w, err, "Failed to preview promotion")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
+
+// writeError maps promotion service errors to HTTP responses
+func (h *PromotionHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrPromotionNotFound):
+		http.Error(w, "Promotion not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrProductNotFound):
+		http.Error(w, "Product not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrVariantNotFound):
+		http.Error(w, "Variant not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidPromotion), errors.Is(err, service.ErrInvalidQuantity),
+		errors.Is(err, service.ErrVariantRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrEmptyCart):
+		http.Error(w, "Sample cart is empty", http.StatusBadRequest)
+	case errors.Is(err, service.ErrCurrencyMismatch):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.log.Error(fallback + ": " + err.Error())
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// SaleHandler handles the admin scheduling of sale prices and the price history of products
+type SaleHandler struct {
+	saleService service.SaleService
+	log         *logger.Logger
+}
+
+// NewSaleHandler creates a new instance of SaleHandler
+func NewSaleHandler(saleService service.SaleService) *SaleHandler {
+	return &SaleHandler{
+		saleService: saleService,
+		log:         logger.New(),
+	}
+}
+
+// ListSales returns one page of the sales of the product identified in the path
+func (h *SaleHandler) ListSales(w http.ResponseWriter, r *http.Request) {
+	productID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	page, pageSize := parsePagination(r, 50)
+
+	sales, total, err := h.saleService.ListSales(productID, page, pageSize)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch sales")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Sales      []models.ProductSale `json:"sales"`
+		Pagination Pagination           `json:"pagination"`
+	}{
+		Sales:      sales,
+		Pagination: newPagination(total, page, pageSize),
+	})
+}
+
+// CreateSale schedules a sale price for the product identified in the path
+func (h *SaleHandler) CreateSale(w http.ResponseWriter, r *http.Request) {
+	productID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var input service.SaleInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid sale data: " + err.Error())
+		http.Error(w, "Invalid sale data", http.StatusBadRequest)
+		return
+	}
+
+	sale, err := h.saleService.CreateSale(productID, input)
+	if err != nil {
+		h.writeError(w, err, "Failed to create sale")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sale)
+}
+
+// CancelSale cancels the sale identified in the path
+func (h *SaleHandler) CancelSale(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid sale ID", http.StatusBadRequest)
+		return
+	}
+
+	sale, err := h.saleService.CancelSale(id)
+	if err != nil {
+		h.writeError(w, err, "Failed to cancel sale")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sale)
+}
+
+// PriceHistory returns one page of the price changes of the product identified in the path
+func (h *SaleHandler) PriceHistory(w http.ResponseWriter, r *http.Request) {
+	productID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	page, pageSize := parsePagination(r, 50)
+
+	changes, total, err := h.saleService.PriceHistory(productID, page, pageSize)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch price history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Changes    []models.PriceChange `json:"changes"`
+		Pagination Pagination           `json:"pagination"`
+	}{
+		Changes:    changes,
+		Pagination: newPagination(total, page, pageSize),
+	})
+}
+
+// writeError maps sale service errors to HTTP responses
+func (h *SaleHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrSaleNotFound):
+		http.Error(w, "Sale not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrProductNotFound):
+		http.Error(w, "Product not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidSale):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrSaleOverlap), errors.Is(err, service.ErrSaleClosed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.log.Error(fallback + ": " + err.Error())
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ShippingHandler handles the admin management of shipping zones and their methods
+type ShippingHandler struct {
+	shippingService service.ShippingService
+	log             *logger.Logger
+}
+
+// NewShippingHandler creates a new instance of ShippingHandler
+func NewShippingHandler(shippingService service.ShippingService) *ShippingHandler {
+	return &ShippingHandler{
+		shippingService: shippingService,
+		log:             logger.New(),
+	}
+}
+
+// ListZones returns one page of the shipping zones with their methods
+func (h *ShippingHandler) ListZones(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := parsePagination(r, 50)
+
+	zones, total, err := h.shippingService.ListZones(page, pageSize)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch shipping zones")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		ShippingZones []models.ShippingZone `json:"shipping_zones"`
+		Pagination    Pagination            `json:"pagination"`
+	}{
+		ShippingZones: zones,
+		Pagination:    newPagination(total, page, pageSize),
+	})
+}
+
+// GetZone returns the shipping zone identified in the path
+func (h *ShippingHandler) GetZone(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid shipping zone ID", http.StatusBadRequest)
+		return
+	}
+
+	zone, err := h.shippingService.GetZone(id)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch shipping zone")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zone)
+}
+
+// CreateZone handles the creation of a new shipping zone
+func (h *ShippingHandler) CreateZone(w http.ResponseWriter, r *http.Request) {
+	var input service.ShippingZoneInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid shipping zone data: " + err.Error())
+		http.Error(w, "Invalid shipping zone data", http.StatusBadRequest)
+		return
+	}
+
+	zone, err := h.shippingService.CreateZone(input)
+	if err != nil {
+		h.writeError(w, err, "Failed to create shipping zone")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(zone)
+}
+
+// UpdateZone handles replacing the shipping zone identified in the path
+func (h *ShippingHandler) UpdateZone(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid shipping zone ID", http.StatusBadRequest)
+		return
+	}
+
+	var input service.ShippingZoneInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid shipping zone data: " + err.Error())
+		http.Error(w, "Invalid shipping zone data", http.StatusBadRequest)
+		return
+	}
+
+	zone, err := h.shippingService.UpdateZone(id, input)
+	if err != nil {
+		h.writeError(w, err, "Failed to update shipping zone")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zone)
+}
+
+// DeleteZone handles deleting the shipping zone identified in the path
+func (h *ShippingHandler) DeleteZone(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid shipping zone ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.shippingService.DeleteZone(id); err != nil {
+		h.writeError(w, err, "Failed to delete shipping zone")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError maps shipping service errors to HTTP responses
+func (h *ShippingHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrShippingZoneNotFound):
+		http.Error(w, "Shipping zone not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidShippingZone):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		h.log.Error(fallback + ": " + err.Error())
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
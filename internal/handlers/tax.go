@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// TaxHandler handles the admin management of tax classes and tax zones
+type TaxHandler struct {
+	taxService service.TaxService
+	log        *logger.Logger
+}
+
+// NewTaxHandler creates a new instance of TaxHandler
+func NewTaxHandler(taxService service.TaxService) *TaxHandler {
+	return &TaxHandler{
+		taxService: taxService,
+		log:        logger.New(),
+	}
+}
+
+// ListClasses returns every tax class
+func (h *TaxHandler) ListClasses(w http.ResponseWriter, r *http.Request) {
+	classes, err := h.taxService.ListClasses()
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch tax classes")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"tax_classes": classes})
+}
+
+// CreateClass handles the creation of a new tax class
+func (h *TaxHandler) CreateClass(w http.ResponseWriter, r *http.Request) {
+	var input service.TaxClassInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid tax class data: " + err.Error())
+		http.Error(w, "Invalid tax class data", http.StatusBadRequest)
+		return
+	}
+
+	class, err := h.taxService.CreateClass(input)
+	if err != nil {
+		h.writeError(w, err, "Failed to create tax class")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(class)
+}
+
+// UpdateClass handles replacing the tax class identified in the path
+func (h *TaxHandler) UpdateClass(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid tax class ID", http.StatusBadRequest)
+		return
+	}
+
+	var input service.TaxClassInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid tax class data: " + err.Error())
+		http.Error(w, "Invalid tax class data", http.StatusBadRequest)
+		return
+	}
+
+	class, err := h.taxService.UpdateClass(id, input)
+	if err != nil {
+		h.writeError(w, err, "Failed to update tax class")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(class)
+}
+
+// DeleteClass handles deleting the tax class identified in the path
+func (h *TaxHandler) DeleteClass(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid tax class ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.taxService.DeleteClass(id); err != nil {
+		h.writeError(w, err, "Failed to delete tax class")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetProductClass puts the product identified in the path in the tax class given in
+// the body, or back on the standard rate when it is null
+func (h *TaxHandler) SetProductClass(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		TaxClassID *uint `json:"tax_class_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Invalid tax class assignment: " + err.Error())
+		http.Error(w, "Invalid tax class assignment", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.taxService.SetProductClass(id, req.TaxClassID); err != nil {
+		h.writeError(w, err, "Failed to set tax class")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"product_id": id, "tax_class_id": req.TaxClassID})
+}
+
+// ListZones returns one page of the tax zones with their rates
+func (h *TaxHandler) ListZones(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := parsePagination(r, 50)
+
+	zones, total, err := h.taxService.ListZones(page, pageSize)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch tax zones")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		TaxZones   []models.TaxZone `json:"tax_zones"`
+		Pagination Pagination       `json:"pagination"`
+	}{
+		TaxZones:   zones,
+		Pagination: newPagination(total, page, pageSize),
+	})
+}
+
+// GetZone returns the tax zone identified in the path
+func (h *TaxHandler) GetZone(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid tax zone ID", http.StatusBadRequest)
+		return
+	}
+
+	zone, err := h.taxService.GetZone(id)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch tax zone")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zone)
+}
+
+// CreateZone handles the creation of a new tax zone
+func (h *TaxHandler) CreateZone(w http.ResponseWriter, r *http.Request) {
+	var input service.TaxZoneInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid tax zone data: " + err.Error())
+		http.Error(w, "Invalid tax zone data", http.StatusBadRequest)
+		return
+	}
+
+	zone, err := h.taxService.CreateZone(input)
+	if err != nil {
+		h.writeError(w, err, "Failed to create tax zone")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(zone)
+}
+
+// UpdateZone handles replacing the tax zone identified in the path
+func (h *TaxHandler) UpdateZone(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid tax zone ID", http.StatusBadRequest)
+		return
+	}
+
+	var input service.TaxZoneInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid tax zone data: " + err.Error())
+		http.Error(w, "Invalid tax zone data", http.StatusBadRequest)
+		return
+	}
+
+	zone, err := h.taxService.UpdateZone(id, input)
+	if err != nil {
+		h.writeError(w, err, "Failed to update tax zone")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(zone)
+}
+
+// DeleteZone handles deleting the tax zone identified in the path
+func (h *TaxHandler) DeleteZone(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid tax zone ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.taxService.DeleteZone(id); err != nil {
+		h.writeError(w, err, "Failed to delete tax zone")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError maps tax service errors to HTTP responses
+func (h *TaxHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrTaxClassNotFound):
+		http.Error(w, "Tax class not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrTaxZoneNotFound):
+		http.Error(w, "Tax zone not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrProductNotFound):
+		http.Error(w, "Product not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidTaxClass), errors.Is(err, service.ErrInvalidTaxZone):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrTaxClassCodeTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.log.Error(fallback + ": " + err.Error())
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// TrashHandler handles the admin trash of deleted products, users and orders
+type TrashHandler struct {
+	trashService service.TrashService
+	retention    time.Duration
+	log          *logger.Logger
+}
+
+// NewTrashHandler creates a new instance of TrashHandler. Purges started from the
+// admin remove records that have been in the trash for longer than retention.
+func NewTrashHandler(trashService service.TrashService, retention time.Duration) *TrashHandler {
+	return &TrashHandler{
+		trashService: trashService,
+		retention:    retention,
+		log:          logger.New(),
+	}
+}
+
+// ListTrash returns one page of the deleted records of the kind named in the path
+func (h *TrashHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := parsePagination(r, 20)
+
+	items, total, err := h.trashService.ListTrash(r.PathValue("kind"), page, pageSize)
+	if err != nil {
+		h.writeError(w, err, "Failed to list trash")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Items      interface{} `json:"items"`
+		Pagination Pagination  `json:"pagination"`
+	}{
+		Items:      items,
+		Pagination: newPagination(total, page, pageSize),
+	})
+}
+
+// Restore takes the record identified in the path out of the trash
+func (h *TrashHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.trashService.Restore(r.PathValue("kind"), id); err != nil {
+		h.writeError(w, err, "Failed to restore item")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Purge permanently removes the records past the retention window and reports
+// what was removed and what was kept
+func (h *TrashHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	report, err := h.trashService.Purge(h.retention)
+	if err != nil {
+		h.writeError(w, err, "Failed to purge trash")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// writeError maps trash service errors to admin responses
+func (h *TrashHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrUnknownTrashKind), errors.Is(err, service.ErrTrashItemNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		h.log.Error(fallback + ": " + err.Error())
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
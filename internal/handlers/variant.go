@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// VariantHandler handles the admin management of product options and variants
+type VariantHandler struct {
+	variantService service.VariantService
+	log            *logger.Logger
+}
+
+// NewVariantHandler creates a new instance of VariantHandler
+func NewVariantHandler(variantService service.VariantService) *VariantHandler {
+	return &VariantHandler{
+		variantService: variantService,
+		log:            logger.New(),
+	}
+}
+
+// ListVariants returns the variants of the product identified in the path
+func (h *VariantHandler) ListVariants(w http.ResponseWriter, r *http.Request) {
+	productID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	variants, err := h.variantService.GetVariants(productID)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch variants")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(variants)
+}
+
+// GenerateVariants replaces the options of the product identified in the path and
+// generates a variant for every combination of option values
+func (h *VariantHandler) GenerateVariants(w http.ResponseWriter, r *http.Request) {
+	productID, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+
+	var input service.GenerateVariantsInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.log.Error("Invalid variant options: " + err.Error())
+		http.Error(w, "Invalid variant options", http.StatusBadRequest)
+		return
+	}
+
+	variants, err := h.variantService.GenerateVariants(productID, input)
+	if err != nil {
+		h.writeError(w, err, "Failed to generate variants")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(variants)
+}
+
+// UpdateVariant handles updates to the variant identified in the path
+func (h *VariantHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid variant ID", http.StatusBadRequest)
+		return
+	}
+
+	var update service.VariantUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		h.log.Error("Invalid variant data: " + err.Error())
+		http.Error(w, "Invalid variant data", http.StatusBadRequest)
+		return
+	}
+
+	variant, err := h.variantService.UpdateVariant(id, update)
+	if err != nil {
+		h.writeError(w, err, "Failed to update variant")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(variant)
+}
+
+// writeError maps variant service errors to admin responses
+func (h *VariantHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrProductNotFound):
+		http.Error(w, "Product not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrVariantNotFound):
+		http.Error(w, "Variant not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidVariantOptions), errors.Is(err, service.ErrCurrencyMismatch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrSKUTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.log.Error(fallback + ": " + err.Error())
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"ecommerce-app/internal/middleware"
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// WarehouseHandler handles the admin management of warehouses and the stock ledger
+type WarehouseHandler struct {
+	warehouseService service.WarehouseService
+	log              *logger.Logger
+}
+
+// NewWarehouseHandler creates a new instance of WarehouseHandler
+func NewWarehouseHandler(warehouseService service.WarehouseService) *WarehouseHandler {
+	return &WarehouseHandler{
+		warehouseService: warehouseService,
+		log:              logger.New(),
+	}
+}
+
+// warehouseRequest is the body of warehouse create and update requests
+type warehouseRequest struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+	Active   *bool  `json:"active"`
+}
+
+// ListWarehouses returns all warehouses in priority order
+func (h *WarehouseHandler) ListWarehouses(w http.ResponseWriter, r *http.Request) {
+	warehouses, err := h.warehouseService.ListWarehouses()
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch warehouses")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(warehouses)
+}
+
+// CreateWarehouse handles the creation of a new warehouse
+func (h *WarehouseHandler) CreateWarehouse(w http.ResponseWriter, r *http.Request) {
+	var req warehouseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Invalid warehouse data: " + err.Error())
+		http.Error(w, "Invalid warehouse data", http.StatusBadRequest)
+		return
+	}
+
+	warehouse := models.Warehouse{
+		Code:     req.Code,
+		Name:     req.Name,
+		Priority: req.Priority,
+		Active:   req.Active == nil || *req.Active,
+	}
+	if err := h.warehouseService.CreateWarehouse(&warehouse); err != nil {
+		h.writeError(w, err, "Failed to create warehouse")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(warehouse)
+}
+
+// UpdateWarehouse handles updates to the warehouse identified in the path
+func (h *WarehouseHandler) UpdateWarehouse(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		return
+	}
+
+	var req warehouseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Invalid warehouse data: " + err.Error())
+		http.Error(w, "Invalid warehouse data", http.StatusBadRequest)
+		return
+	}
+
+	warehouse := models.Warehouse{
+		ID:       id,
+		Code:     req.Code,
+		Name:     req.Name,
+		Priority: req.Priority,
+		Active:   req.Active == nil || *req.Active,
+	}
+	if err := h.warehouseService.UpdateWarehouse(&warehouse); err != nil {
+		h.writeError(w, err, "Failed to update warehouse")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(warehouse)
+}
+
+// GetStockLevels returns one page of the stock held at the warehouse identified in the path
+func (h *WarehouseHandler) GetStockLevels(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIDParam(r, "id")
+	if err != nil {
+		http.Error(w, "Invalid warehouse ID", http.StatusBadRequest)
+		return
+	}
+	page, pageSize := parsePagination(r, 50)
+
+	levels, total, err := h.warehouseService.GetStockLevels(id, page, pageSize)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch stock levels")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Levels     []models.StockLevel `json:"levels"`
+		Pagination Pagination          `json:"pagination"`
+	}{
+		Levels:     levels,
+		Pagination: newPagination(total, page, pageSize),
+	})
+}
+
+// ListMovements returns one page of the stock ledger, newest first. It can be
+// narrowed down with the warehouse_id, product_id, variant_id and type query parameters.
+func (h *WarehouseHandler) ListMovements(w http.ResponseWriter, r *http.Request) {
+	var filter repository.MovementFilter
+	for name, target := range map[string]*uint{
+		"warehouse_id": &filter.WarehouseID,
+		"product_id":   &filter.ProductID,
+		"variant_id":   &filter.VariantID,
+	} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid "+name, http.StatusBadRequest)
+			return
+		}
+		*target = uint(id)
+	}
+	filter.Type = r.URL.Query().Get("type")
+
+	page, pageSize := parsePagination(r, 50)
+
+	movements, total, err := h.warehouseService.ListMovements(filter, page, pageSize)
+	if err != nil {
+		h.writeError(w, err, "Failed to fetch stock movements")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Movements  []models.StockMovement `json:"movements"`
+		Pagination Pagination             `json:"pagination"`
+	}{
+		Movements:  movements,
+		Pagination: newPagination(total, page, pageSize),
+	})
+}
+
+// RecordMovement records a receipt, return or adjustment on behalf of the authenticated admin
+func (h *WarehouseHandler) RecordMovement(w http.ResponseWriter, r *http.Request) {
+	var req service.StockMovementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Invalid stock movement data: " + err.Error())
+		http.Error(w, "Invalid stock movement data", http.StatusBadRequest)
+		return
+	}
+
+	admin, _ := middleware.GetAdminUser(r)
+	movement, err := h.warehouseService.RecordMovement(req, "admin:"+admin)
+	if err != nil {
+		h.writeError(w, err, "Failed to record stock movement")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
+
+// TransferStock moves units between warehouses on behalf of the authenticated admin
+func (h *WarehouseHandler) TransferStock(w http.ResponseWriter, r *http.Request) {
+	var req service.StockTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error("Invalid stock transfer data: " + err.Error())
+		http.Error(w, "Invalid stock transfer data", http.StatusBadRequest)
+		return
+	}
+
+	admin, _ := middleware.GetAdminUser(r)
+	movements, err := h.warehouseService.Transfer(req, "admin:"+admin)
+	if err != nil {
+		h.writeError(w, err, "Failed to transfer stock")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movements)
+}
+
+// Reconcile reports how product stock compares with warehouse stock levels and the
+// ledger. With unbalanced=true only the disagreeing product variants are listed.
+func (h *WarehouseHandler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	onlyUnbalanced, _ := strconv.ParseBool(r.URL.Query().Get("unbalanced"))
+
+	report, err := h.warehouseService.Reconcile(onlyUnbalanced)
+	if err != nil {
+		h.writeError(w, err, "Failed to reconcile stock")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// writeError maps warehouse service errors to admin responses
+func (h *WarehouseHandler) writeError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrWarehouseNotFound):
+		http.Error(w, "Warehouse not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrProductNotFound):
+		http.Error(w, "Product not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrVariantNotFound):
+		http.Error(w, "Variant not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrInvalidWarehouse), errors.Is(err, service.ErrInvalidStockMovement),
+		errors.Is(err, service.ErrVariantRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrWarehouseCodeTaken), errors.Is(err, service.ErrInsufficientLocationStock):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.log.Error(fallback + ": " + err.Error())
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
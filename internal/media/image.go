@@ -0,0 +1,204 @@
+// Package media decodes uploaded product images and renders their resized versions.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	// Register the GIF decoder with the image package
+	_ "image/gif"
+)
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	ErrImageTooLarge    = errors.New("image dimensions too large")
+)
+
+const (
+	// ThumbnailSize and MediumSize bound the longest side of the generated renditions
+	ThumbnailSize = 200
+	MediumSize    = 800
+
+	// maxPixels guards against decompression bombs: small files declaring huge images
+	maxPixels = 40_000_000
+
+	jpegQuality = 85
+)
+
+// Rendition is an encoded version of an image
+type Rendition struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+}
+
+// ProcessedImage holds an upload with its generated renditions
+type ProcessedImage struct {
+	Original  Rendition
+	Thumbnail Rendition
+	Medium    Rendition
+}
+
+// contentTypes maps the accepted content types to file extensions
+var contentTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// Process validates an uploaded image and renders its thumbnail and medium versions.
+// The original bytes are kept as uploaded. Renditions are PNG for sources that may
+// be transparent and JPEG otherwise.
+func Process(data []byte) (*ProcessedImage, error) {
+	contentType := http.DetectContentType(data)
+	extension, ok := contentTypes[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedImage, contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	rgba := toRGBA(src)
+
+	processed := &ProcessedImage{
+		Original: Rendition{
+			Data:        data,
+			ContentType: contentType,
+			Extension:   extension,
+			Width:       config.Width,
+			Height:      config.Height,
+		},
+	}
+	transparent := contentType != "image/jpeg"
+	if processed.Thumbnail, err = render(rgba, ThumbnailSize, transparent); err != nil {
+		return nil, err
+	}
+	if processed.Medium, err = render(rgba, MediumSize, transparent); err != nil {
+		return nil, err
+	}
+	return processed, nil
+}
+
+// render scales an image to fit within a square of the given size, never enlarging
+// it, and encodes the result
+func render(src *image.RGBA, size int, transparent bool) (Rendition, error) {
+	width, height := fit(src.Bounds().Dx(), src.Bounds().Dy(), size)
+	scaled := resize(src, width, height)
+
+	var buf bytes.Buffer
+	rendition := Rendition{Width: width, Height: height}
+	if transparent {
+		if err := png.Encode(&buf, scaled); err != nil {
+			return Rendition{}, err
+		}
+		rendition.ContentType, rendition.Extension = "image/png", "png"
+	} else {
+		if err := jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return Rendition{}, err
+		}
+		rendition.ContentType, rendition.Extension = "image/jpeg", "jpg"
+	}
+	rendition.Data = buf.Bytes()
+	return rendition, nil
+}
+
+// fit returns the dimensions of a width x height image scaled down to fit within
+// a size x size square, keeping its aspect ratio
+func fit(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+// toRGBA converts an image to RGBA with its origin at zero
+func toRGBA(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+	return dst
+}
+
+// resize scales an image down with a box filter: every destination pixel is the
+// average of the source pixels it covers, weighted by how much of each it covers.
+// Averaging premultiplied colours keeps transparent edges clean.
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	if srcW == width && srcH == height {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	xSpans := spans(srcW, width)
+	ySpans := spans(srcH, height)
+
+	sums := make([]float64, width*4)
+	for y, ySpan := range ySpans {
+		for i := range sums {
+			sums[i] = 0
+		}
+		for _, yw := range ySpan {
+			row := src.Pix[yw.index*src.Stride:]
+			for x, xSpan := range xSpans {
+				sum := sums[x*4 : x*4+4]
+				for _, xw := range xSpan {
+					weight := yw.weight * xw.weight
+					pixel := row[xw.index*4 : xw.index*4+4]
+					sum[0] += float64(pixel[0]) * weight
+					sum[1] += float64(pixel[1]) * weight
+					sum[2] += float64(pixel[2]) * weight
+					sum[3] += float64(pixel[3]) * weight
+				}
+			}
+		}
+		out := dst.Pix[y*dst.Stride:]
+		for i, value := range sums {
+			out[i] = uint8(min(255, value+0.5))
+		}
+	}
+	return dst
+}
+
+// weight is the share of a source pixel in a destination pixel
+type weight struct {
+	index  int
+	weight float64
+}
+
+// spans maps each of the dst destination pixels along one axis to the src source
+// pixels it covers. The weights of each destination pixel sum to one.
+func spans(src, dst int) [][]weight {
+	scale := float64(src) / float64(dst)
+	result := make([][]weight, dst)
+	for i := range result {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < src && float64(j) < end; j++ {
+			covered := min(end, float64(j+1)) - max(start, float64(j))
+			if covered > 0 {
+				result[i] = append(result[i], weight{index: j, weight: covered / scale})
+			}
+		}
+	}
+	return result
+}
@@ -1,12 +1,16 @@
 package middleware
 
 import (
+    "context"
     "net/http"
     "ecommerce-app/pkg/logger"
     "os"
     "crypto/subtle"
 )
 
+// AdminUserKey is the key used to store the admin username in the request context
+const AdminUserKey UserAuthKey = "admin_user"
+
 // AdminAuth middleware ensures that only authenticated admin users can access protected routes
 func AdminAuth(next http.HandlerFunc) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
@@ -35,6 +39,15 @@ func AdminAuth(next http.HandlerFunc) http.HandlerFunc {
             return
         }
 
-        next.ServeHTTP(w, r)
+        // Add the admin username to the request context
+        ctx := context.WithValue(r.Context(), AdminUserKey, user)
+
+        next.ServeHTTP(w, r.WithContext(ctx))
     }
+}
+
+// GetAdminUser extracts the admin username from the request context
+func GetAdminUser(r *http.Request) (string, bool) {
+    user, ok := r.Context().Value(AdminUserKey).(string)
+    return user, ok
 }
\ No newline at end of file
@@ -60,4 +60,22 @@ func UserAuth(authService service.AuthService) func(http.HandlerFunc) http.Handl
 func GetUserID(r *http.Request) (uint, bool) {
 	userID, ok := r.Context().Value(UserIDKey).(uint)
 	return userID, ok
-}
\ No newline at end of file
+}
+// OptionalUserAuth middleware identifies the user of public routes that show
+// user-specific data. Requests without a valid bearer token go through as guests.
+func OptionalUserAuth(authService service.AuthService) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			parts := strings.Split(r.Header.Get("Authorization"), " ")
+			if len(parts) == 2 && parts[0] == "Bearer" {
+				claims, err := authService.ValidateToken(parts[1])
+				if err == nil {
+					r = r.WithContext(context.WithValue(r.Context(), UserIDKey, claims.UserID))
+				} else {
+					logger.New().Info("Serving request as guest: " + err.Error())
+				}
+			}
+			next.ServeHTTP(w, r)
+		}
+	}
+}
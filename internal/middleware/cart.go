@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"ecommerce-app/internal/service"
+	"ecommerce-app/pkg/logger"
+	"net/http"
+	"time"
+)
+
+// CartTokenKey is the key used to store the guest cart token in the request context
+const CartTokenKey UserAuthKey = "cart_token"
+
+const (
+	// CartTokenCookie is the cookie carrying the guest cart token
+	CartTokenCookie = "cart_token"
+	// CartTokenHeader is the header carrying the guest cart token for non-browser clients
+	CartTokenHeader = "X-Cart-Token"
+	// cartTokenMaxAge keeps the guest cart cookie as long as the cart lasts
+	cartTokenMaxAge = int(service.GuestCartTTL / time.Second)
+)
+
+// GuestCart middleware resolves the guest cart of anonymous shoppers. Tokens of carts
+// that no longer exist or have expired are dropped; the cart handlers create a new
+// guest cart when a product is first added.
+func GuestCart(cartService service.CartService) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			log := logger.New()
+
+			token := GuestCartToken(r)
+			if token != "" {
+				if err := cartService.ValidateGuestToken(token); err != nil {
+					log.Info("Discarding invalid guest cart token: " + err.Error())
+					ClearGuestCartToken(w)
+					token = ""
+				}
+			}
+
+			// Add the guest cart token to the request context
+			ctx := context.WithValue(r.Context(), CartTokenKey, token)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+	}
+}
+
+// GuestCartToken reads the guest cart token sent with the request, preferring the header
+func GuestCartToken(r *http.Request) string {
+	if token := r.Header.Get(CartTokenHeader); token != "" {
+		return token
+	}
+	if cookie, err := r.Cookie(CartTokenCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// SetGuestCartToken hands a guest cart token to the client as both cookie and header
+func SetGuestCartToken(w http.ResponseWriter, token string) {
+	w.Header().Set(CartTokenHeader, token)
+	http.SetCookie(w, &http.Cookie{
+		Name:     CartTokenCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   cartTokenMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearGuestCartToken tells the client to forget its guest cart token
+func ClearGuestCartToken(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CartTokenCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// GetGuestCartToken extracts the guest cart token from the request context
+func GetGuestCartToken(r *http.Request) (string, bool) {
+	token, ok := r.Context().Value(CartTokenKey).(string)
+	return token, ok && token != ""
+}
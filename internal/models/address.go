@@ -0,0 +1,18 @@
+package models
+
+// Address is where an order goes. Its country, region and postal code decide the
+// tax and shipping zones of the order.
+type Address struct {
+    Name       string `gorm:"type:varchar(255)"` // Recipient
+    Line1      string `gorm:"type:varchar(255)"`
+    Line2      string `gorm:"type:varchar(255)"`
+    City       string `gorm:"type:varchar(100)"`
+    Region     string `gorm:"type:varchar(100)"` // State or province code, e.g. CA
+    PostalCode string `gorm:"type:varchar(20)"`
+    Country    string `gorm:"type:varchar(2)"` // ISO 3166-1 alpha-2 code, e.g. US
+}
+
+// IsZero reports whether no part of the address is set
+func (a Address) IsZero() bool {
+    return a == Address{}
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+    "time"
+)
+
+// LowStockAlert records that the stock of a product fell below its reorder
+// threshold. An alert stays open until the stock is back at or above the threshold.
+type LowStockAlert struct {
+    ID          uint       `gorm:"primaryKey"`
+    ProductID   uint       `gorm:"not null;index"`
+    ProductName string     `gorm:"type:varchar(255);not null"`
+    Threshold   int        `gorm:"not null"`
+    Stock       int        `gorm:"not null"` // Stock when the alert was raised
+    CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP;index"`
+    ResolvedAt  *time.Time `gorm:"index"`
+}
+
+// StockSubscription asks for a shopper to be notified when an out-of-stock product
+// or variant is back in stock. It is kept, with the time of the notification, once sent.
+type StockSubscription struct {
+    ID         uint            `gorm:"primaryKey"`
+    ProductID  uint            `gorm:"not null;index"`
+    Product    Product         `gorm:"foreignKey:ProductID"`
+    VariantID  uint            `gorm:"not null;default:0"` // Zero to be notified about the product as a whole
+    Variant    *ProductVariant `gorm:"foreignKey:VariantID;-:migration"`
+    UserID     uint            `gorm:"not null;index"`
+    User       User            `gorm:"foreignKey:UserID"`
+    CreatedAt  time.Time       `gorm:"default:CURRENT_TIMESTAMP"`
+    NotifiedAt *time.Time      `gorm:"index"`
+}
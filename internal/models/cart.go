@@ -1,16 +1,19 @@
 package models
 
 import (
+    "ecommerce-app/pkg/money"
     "time"
     "gorm.io/gorm"
 )
 
-// Cart represents the cart model in the database
+// Cart represents the cart model in the database. Guest carts have no UserID
+// and are identified by a signed cart token instead.
 type Cart struct {
     ID        uint           `gorm:"primaryKey"`
-    UserID    uint           `gorm:"not null"`
+    UserID    *uint          `gorm:"uniqueIndex"`
     User      User           `gorm:"foreignKey:UserID"`
     CartItems []CartItem     `gorm:"foreignKey:CartID"`
+    Coupons   []Coupon       `gorm:"many2many:cart_coupons"` // Applied coupons; shoppers' carts only
     CreatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
     UpdatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
 }
@@ -23,14 +26,48 @@ func (c *Cart) BeforeUpdate(tx *gorm.DB) error {
 
 // CartItem represents the cart item model in the database
 type CartItem struct {
-    ID        uint           `gorm:"primaryKey"`
-    CartID    uint           `gorm:"not null"`
-    Cart      Cart           `gorm:"foreignKey:CartID"`
-    ProductID uint           `gorm:"not null"`
-    Product   Product        `gorm:"foreignKey:ProductID"`
-    Quantity  int            `gorm:"not null;check:quantity > 0"`
-    CreatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
-    UpdatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
+    ID         uint            `gorm:"primaryKey"`
+    CartID     uint            `gorm:"not null;uniqueIndex:idx_cart_items_cart_line"`
+    Cart       Cart            `gorm:"foreignKey:CartID"`
+    ProductID  uint            `gorm:"not null;uniqueIndex:idx_cart_items_cart_line"`
+    Product    Product         `gorm:"foreignKey:ProductID"`
+    VariantID  uint            `gorm:"not null;default:0;uniqueIndex:idx_cart_items_cart_line"` // Zero for products without variants
+    Variant    *ProductVariant `gorm:"foreignKey:VariantID;-:migration"`
+    Quantity   int             `gorm:"not null;check:quantity > 0"`
+    PriceAtAdd money.Money     `gorm:"type:decimal(10,2);not null;default:0"`
+    Currency   string          `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+    CreatedAt  time.Time       `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt  time.Time       `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// UnitPrice returns the current price of the item's product or variant. The product
+// and variant must be loaded.
+func (ci *CartItem) UnitPrice() money.Money {
+    if ci.Variant != nil {
+        return ci.Variant.UnitPrice(ci.Product)
+    }
+    return ci.Product.CurrentPrice()
+}
+
+// AvailableStock returns the unreserved stock of the item's product or variant. The
+// product and variant must be loaded with their availability.
+func (ci *CartItem) AvailableStock() int {
+    if ci.Variant != nil {
+        return ci.Variant.Available
+    }
+    return ci.Product.Available
+}
+
+// BeforeSave keeps the currency column in line with the price snapshot
+func (ci *CartItem) BeforeSave(tx *gorm.DB) error {
+    ci.Currency = currencyOf(ci.PriceAtAdd)
+    return nil
+}
+
+// AfterFind applies the row currency to the price snapshot read from the database
+func (ci *CartItem) AfterFind(tx *gorm.DB) error {
+    ci.PriceAtAdd = ci.PriceAtAdd.WithCurrency(ci.Currency)
+    return nil
 }
 
 // BeforeUpdate will be called before updating the cart item
@@ -0,0 +1,26 @@
+package models
+
+import (
+    "time"
+    "gorm.io/gorm"
+)
+
+// Category represents a node of the product taxonomy. Top-level categories have no ParentID.
+type Category struct {
+    ID          uint           `gorm:"primaryKey"`
+    Name        string         `gorm:"type:varchar(255);not null"`
+    Slug        string         `gorm:"type:varchar(255);uniqueIndex;not null"`
+    Description string         `gorm:"type:text"`
+    ParentID    *uint          `gorm:"index"`
+    Position    int            `gorm:"not null;default:0"`
+    Children    []Category     `gorm:"foreignKey:ParentID"`
+    Products    []Product      `gorm:"many2many:product_categories" json:"-"`
+    CreatedAt   time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt   time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// BeforeUpdate will be called before updating the category
+func (c *Category) BeforeUpdate(tx *gorm.DB) error {
+    c.UpdatedAt = time.Now()
+    return nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+    "ecommerce-app/pkg/money"
+    "time"
+    "gorm.io/gorm"
+)
+
+// Coupon types
+const (
+    CouponPercentage   = "percentage"    // Takes Percent off the eligible products
+    CouponFixedAmount  = "fixed_amount"  // Takes Amount off the eligible products
+    CouponFreeShipping = "free_shipping" // Waives the shipping fee
+)
+
+// Coupon is a discount code shoppers apply to their cart. Without products or
+// categories it applies to the whole cart; otherwise only to the products listed
+// and to the products of the categories listed, including their subcategories.
+type Coupon struct {
+    ID           uint        `gorm:"primaryKey"`
+    Code         string      `gorm:"type:varchar(50);uniqueIndex;not null"` // Stored in upper case
+    Description  string      `gorm:"type:varchar(255)"`
+    Type         string      `gorm:"type:varchar(20);not null"`
+    Percent      float64     `gorm:"type:decimal(5,2);not null;default:0"`
+    Amount       money.Money `gorm:"type:decimal(10,2);not null;default:0"`
+    MinSubtotal  money.Money `gorm:"type:decimal(10,2);not null;default:0"` // Zero for no minimum
+    Currency     string      `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+    StartsAt     *time.Time  // Optional; valid from this time on
+    EndsAt       *time.Time  // Optional; valid until this time
+    UsageLimit   int         `gorm:"not null;default:0"`     // Redemptions across all shoppers; zero for no limit
+    PerUserLimit int         `gorm:"not null;default:0"`     // Redemptions per shopper; zero for no limit
+    Stackable    bool        `gorm:"not null;default:false"` // Whether it can be combined with other coupons
+    Active       bool        `gorm:"not null;default:true"`
+    Products     []Product   `gorm:"many2many:coupon_products"`
+    Categories   []Category  `gorm:"many2many:coupon_categories"`
+    CreatedAt    time.Time   `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt    time.Time   `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// BeforeSave keeps the currency column in line with the amounts
+func (c *Coupon) BeforeSave(tx *gorm.DB) error {
+    c.Currency = currencyOf(c.Amount)
+    return nil
+}
+
+// AfterFind applies the row currency to the amounts read from the database
+func (c *Coupon) AfterFind(tx *gorm.DB) error {
+    c.Amount = c.Amount.WithCurrency(c.Currency)
+    c.MinSubtotal = c.MinSubtotal.WithCurrency(c.Currency)
+    return nil
+}
+
+// BeforeUpdate will be called before updating the coupon
+func (c *Coupon) BeforeUpdate(tx *gorm.DB) error {
+    c.UpdatedAt = time.Now()
+    return nil
+}
+
+// Restricted reports whether the coupon only applies to some products. The products
+// and categories must be loaded.
+func (c *Coupon) Restricted() bool {
+    return len(c.Products) > 0 || len(c.Categories) > 0
+}
+
+// CouponRedemption records the use of a coupon by an order. Redemptions of cancelled
+// orders no longer count against the usage limits.
+type CouponRedemption struct {
+    ID        uint        `gorm:"primaryKey"`
+    CouponID  uint        `gorm:"not null;index"`
+    OrderID   uint        `gorm:"not null;index"`
+    UserID    uint        `gorm:"not null;index"`
+    Code      string      `gorm:"type:varchar(50);not null"`
+    Amount    money.Money `gorm:"type:decimal(10,2);not null;default:0"` // Discount granted; zero for free shipping
+    Currency  string      `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+    CreatedAt time.Time   `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// BeforeSave keeps the currency column in line with the amount
+func (cr *CouponRedemption) BeforeSave(tx *gorm.DB) error {
+    cr.Currency = currencyOf(cr.Amount)
+    return nil
+}
+
+// AfterFind applies the row currency to the amount read from the database
+func (cr *CouponRedemption) AfterFind(tx *gorm.DB) error {
+    cr.Amount = cr.Amount.WithCurrency(cr.Currency)
+    return nil
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+    "time"
+)
+
+// Stock reservation statuses
+const (
+    ReservationActive    = "active"    // Holds stock until the order is paid or the reservation expires
+    ReservationCommitted = "committed" // Taken off the stock when the order was paid
+    ReservationReleased  = "released"  // Given back on cancellation or expiry
+)
+
+// StockReservation holds units of a product or variant for a pending order. Active
+// reservations that have not expired count against the available stock.
+type StockReservation struct {
+    ID        uint      `gorm:"primaryKey"`
+    OrderID   uint      `gorm:"not null;index"`
+    ProductID uint      `gorm:"not null;index:idx_stock_reservations_product_status"`
+    VariantID uint      `gorm:"not null;default:0"` // Zero for products without variants
+    Quantity  int       `gorm:"not null"`
+    Status    string    `gorm:"type:varchar(20);not null;default:active;index:idx_stock_reservations_product_status"`
+    ExpiresAt time.Time `gorm:"not null;index"`
+    CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
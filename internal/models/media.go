@@ -0,0 +1,30 @@
+package models
+
+import (
+    "time"
+)
+
+// ProductImage is an uploaded product image with its resized renditions. The
+// storage keys stay internal; clients use the URLs.
+type ProductImage struct {
+    ID           uint      `gorm:"primaryKey"`
+    ProductID    uint      `gorm:"not null;index"`
+    URL          string    `gorm:"type:varchar(1024);not null"`
+    ThumbnailURL string    `gorm:"type:varchar(1024);not null"`
+    MediumURL    string    `gorm:"type:varchar(1024);not null"`
+    OriginalKey  string    `gorm:"type:varchar(512);not null" json:"-"`
+    ThumbnailKey string    `gorm:"type:varchar(512);not null" json:"-"`
+    MediumKey    string    `gorm:"type:varchar(512);not null" json:"-"`
+    ContentType  string    `gorm:"type:varchar(50);not null"`
+    Width        int       `gorm:"not null"`
+    Height       int       `gorm:"not null"`
+    AltText      string    `gorm:"type:varchar(255)"`
+    Position     int       `gorm:"not null;default:0"`
+    IsPrimary    bool      `gorm:"not null;default:false"`
+    CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// Keys returns the storage keys of the image and its renditions
+func (i *ProductImage) Keys() []string {
+    return       []string{i.OriginalKey, i.ThumbnailKey, i.MediumKey}
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+    "ecommerce-app/pkg/money"
+)
+
+// currencyOf returns the currency code to store for an amount
+func currencyOf(amount money.Money) string {
+    if amount.Currency == "" {
+        return money.DefaultCurrency
+    }
+    return amount.Currency
+}
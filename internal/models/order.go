@@ -1,20 +1,49 @@
 package models
 
 import (
+    "ecommerce-app/pkg/money"
     "time"
     "gorm.io/gorm"
 )
 
 // Order represents the order model in the database
 type Order struct {
-    ID         uint           `gorm:"primaryKey"`
-    UserID     uint           `gorm:"not null"`
-    User       User           `gorm:"foreignKey:UserID"`
-    Total      float64        `gorm:"type:decimal(10,2);not null"`
-    Status     string         `gorm:"type:varchar(50);default:pending"`
-    OrderItems []OrderItem    `gorm:"foreignKey:OrderID"`
-    CreatedAt  time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
-    UpdatedAt  time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
+    ID               uint               `gorm:"primaryKey"`
+    UserID           uint               `gorm:"not null"`
+    User             User               `gorm:"foreignKey:UserID"`
+    ShippingAddress  Address            `gorm:"embedded;embeddedPrefix:shipping_"` // Empty when the shopper gave none
+    ShippingMethodID *uint              // Method chosen at checkout; nil for orders charged the store's flat shipping fee
+    ShippingMethod   string             `gorm:"type:varchar(100)"` // Name of the method at the time of the order
+    Subtotal         money.Money        `gorm:"type:decimal(10,2);not null;default:0"`
+    Discount         money.Money        `gorm:"type:decimal(10,2);not null;default:0"`
+    Tax              money.Money        `gorm:"type:decimal(10,2);not null;default:0"`
+    PricesIncludeTax bool               `gorm:"not null;default:false"` // Whether Tax is part of Subtotal rather than added to it
+    Shipping         money.Money        `gorm:"type:decimal(10,2);not null;default:0"`
+    Total            money.Money        `gorm:"type:decimal(10,2);not null"`
+    Currency         string             `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+    Status           string             `gorm:"type:varchar(50);default:pending"`
+    OrderItems       []OrderItem        `gorm:"foreignKey:OrderID"`
+    Coupons          []CouponRedemption `gorm:"foreignKey:OrderID"`
+    Taxes            []OrderTax         `gorm:"foreignKey:OrderID"`
+    CreatedAt        time.Time          `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt        time.Time          `gorm:"default:CURRENT_TIMESTAMP"`
+    DeletedAt        gorm.DeletedAt     `gorm:"index"`
+}
+
+// BeforeSave keeps the currency column in line with the order total
+func (o *Order) BeforeSave(tx *gorm.DB) error {
+    o.Currency = currencyOf(o.Total)
+    return nil
+}
+
+// AfterFind applies the row currency to the amounts read from the database
+func (o *Order) AfterFind(tx *gorm.DB) error {
+    o.Subtotal = o.Subtotal.WithCurrency(o.Currency)
+    o.Discount = o.Discount.WithCurrency(o.Currency)
+    o.Tax = o.Tax.WithCurrency(o.Currency)
+    o.Shipping = o.Shipping.WithCurrency(o.Currency)
+    o.Total = o.Total.WithCurrency(o.Currency)
+    return nil
 }
 
 // BeforeUpdate will be called before updating the order
@@ -30,7 +59,77 @@ type OrderItem struct {
     Order        Order          `gorm:"foreignKey:OrderID"`
     ProductID    uint           `gorm:"not null"`
     Product      Product        `gorm:"foreignKey:ProductID"`
+    ProductName  string         `gorm:"type:varchar(255)"`
+    VariantID    uint           `gorm:"not null;default:0"` // Zero for products without variants
+    SKU          string         `gorm:"type:varchar(100)"`
+    VariantTitle string         `gorm:"type:varchar(255)"`
     Quantity     int            `gorm:"not null"`
-    PriceAtTime  float64        `gorm:"type:decimal(10,2);not null"`
+    PriceAtTime  money.Money    `gorm:"type:decimal(10,2);not null"`
+    Tax          money.Money    `gorm:"type:decimal(10,2);not null;default:0"` // Tax on the whole line, after discounts
+    TaxRate      float64        `gorm:"type:decimal(8,6);not null;default:0"`
+    Currency     string         `gorm:"type:varchar(3);not null;default:USD" json:"-"`
     CreatedAt    time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
-}
\ No newline at end of file
+}
+// BeforeSave keeps the currency column in line with the price
+func (oi *OrderItem) BeforeSave(tx *gorm.DB) error {
+    oi.Currency = currencyOf(oi.PriceAtTime)
+    return nil
+}
+
+// AfterFind applies the row currency to the price read from the database
+func (oi *OrderItem) AfterFind(tx *gorm.DB) error {
+    oi.PriceAtTime = oi.PriceAtTime.WithCurrency(oi.Currency)
+    oi.Tax = oi.Tax.WithCurrency(oi.Currency)
+    return nil
+}
+
+// OrderTax is one line of the tax summary of an order: the tax owed at one rate
+type OrderTax struct {
+    ID       uint        `gorm:"primaryKey"`
+    OrderID  uint        `gorm:"not null;index"`
+    Name     string      `gorm:"type:varchar(100);not null"`
+    Rate     float64     `gorm:"type:decimal(8,6);not null"`
+    Taxable  money.Money `gorm:"type:decimal(10,2);not null"` // Amount taxed at the rate, net of tax
+    Amount   money.Money `gorm:"type:decimal(10,2);not null"`
+    Currency string      `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+}
+
+// BeforeSave keeps the currency column in line with the amount
+func (t *OrderTax) BeforeSave(tx *gorm.DB) error {
+    t.Currency = currencyOf(t.Amount)
+    return nil
+}
+
+// AfterFind applies the row currency to the amounts read from the database
+func (t *OrderTax) AfterFind(tx *gorm.DB) error {
+    t.Taxable = t.Taxable.WithCurrency(t.Currency)
+    t.Amount = t.Amount.WithCurrency(t.Currency)
+    return nil
+}
+
+// Order statuses making up the order lifecycle
+const (
+    OrderStatusPending   = "pending"
+    OrderStatusPaid      = "paid"
+    OrderStatusFulfilled = "fulfilled"
+    OrderStatusShipped   = "shipped"
+    OrderStatusDelivered = "delivered"
+    OrderStatusCancelled = "cancelled"
+    OrderStatusRefunded  = "refunded"
+)
+
+// OrderStatusHistory records a single status transition of an order
+type OrderStatusHistory struct {
+    ID         uint           `gorm:"primaryKey"`
+    OrderID    uint           `gorm:"not null;index"`
+    FromStatus string         `gorm:"type:varchar(50)"`
+    ToStatus   string         `gorm:"type:varchar(50);not null"`
+    ChangedBy  string         `gorm:"type:varchar(255);not null"`
+    Note       string         `gorm:"type:text"`
+    CreatedAt  time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName overrides the table name used by OrderStatusHistory
+func (OrderStatusHistory) TableName() string {
+    return "order_status_history"
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+    "ecommerce-app/pkg/money"
+    "time"
+    "gorm.io/gorm"
+)
+
+// CustomerGroup is a segment of customers, such as trade buyers, whose prices come
+// from the price lists of the group
+type CustomerGroup struct {
+    ID          uint      `gorm:"primaryKey"`
+    Name        string    `gorm:"type:varchar(100);uniqueIndex;not null"`
+    Description string    `gorm:"type:varchar(255)"`
+    CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// BeforeUpdate will be called before updating the customer group
+func (g *CustomerGroup) BeforeUpdate(tx *gorm.DB) error {
+    g.UpdatedAt = time.Now()
+    return nil
+}
+
+// PriceList sets the prices the customers of a group pay. When several active lists
+// of the group price a product, the one with the highest priority wins.
+type PriceList struct {
+    ID              uint             `gorm:"primaryKey"`
+    CustomerGroupID uint             `gorm:"not null;index"`
+    Name            string           `gorm:"type:varchar(100);not null"`
+    Priority        int              `gorm:"not null;default:0"`
+    Active          bool             `gorm:"not null;default:true"`
+    Entries         []PriceListEntry `gorm:"foreignKey:PriceListID"`
+    CreatedAt       time.Time        `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt       time.Time        `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// BeforeUpdate will be called before updating the price list
+func (l *PriceList) BeforeUpdate(tx *gorm.DB) error {
+    l.UpdatedAt = time.Now()
+    return nil
+}
+
+// PriceListEntry is the unit price of a product, or of one of its variants, when at
+// least MinQuantity units are bought. Entries with a higher minimum quantity of the
+// same product and variant form its quantity tiers.
+type PriceListEntry struct {
+    ID          uint        `gorm:"primaryKey"`
+    PriceListID uint        `gorm:"not null;uniqueIndex:idx_price_list_entry"`
+    ProductID   uint        `gorm:"not null;uniqueIndex:idx_price_list_entry;index"`
+    VariantID   uint        `gorm:"not null;default:0;uniqueIndex:idx_price_list_entry"` // Zero for the product and its variants without a price override
+    MinQuantity int         `gorm:"not null;default:1;uniqueIndex:idx_price_list_entry"`
+    Price       money.Money `gorm:"type:decimal(10,2);not null"`
+    Currency    string      `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+}
+
+// BeforeSave keeps the currency column in line with the price
+func (e *PriceListEntry) BeforeSave(tx *gorm.DB) error {
+    e.Currency = currencyOf(e.Price)
+    return nil
+}
+
+// AfterFind applies the row currency to the price read from the database
+func (e *PriceListEntry) AfterFind(tx *gorm.DB) error {
+    e.Price = e.Price.WithCurrency(e.Currency)
+    return nil
+}
+
+// TierPrice is the unit price a customer pays when buying at least MinQuantity units
+type TierPrice struct {
+    MinQuantity int
+    Price       money.Money
+}
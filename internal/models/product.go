@@ -1,25 +1,73 @@
 package models
 
 import (
+    "ecommerce-app/pkg/money"
     "time"
     "gorm.io/gorm"
 )
 
 // Product represents the product model in the database
 type Product struct {
-    ID          uint           `gorm:"primaryKey"`
-    Name        string         `gorm:"type:varchar(255);not null"`
-    Description string         `gorm:"type:text"`
-    Price       float64        `gorm:"type:decimal(10,2);not null"`
-    Stock       int            `gorm:"not null"`
-    CreatedAt   time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
-    UpdatedAt   time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
-    CartItems   []CartItem     `gorm:"foreignKey:ProductID"`
-    OrderItems  []OrderItem    `gorm:"foreignKey:ProductID"`
+    ID               uint             `gorm:"primaryKey"`
+    SKU              *string          `gorm:"type:varchar(100);uniqueIndex"` // Optional; identifies the product in imports
+    Name             string           `gorm:"type:varchar(255);not null"`
+    Description      string           `gorm:"type:text"`
+    Price            money.Money      `gorm:"type:decimal(10,2);not null"`
+    Currency         string           `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+    SalePrice        *money.Money     `gorm:"type:decimal(10,2)"` // Price of the running sale; set and cleared by the sale scheduler
+    SaleEndsAt       *time.Time       // End of the running sale, if it has one
+    Stock            int              `gorm:"not null"` // Sum of the variant stock when the product has variants
+    Available        int              `gorm:"-"`        // Stock minus active reservations; set when loaded for display
+    GroupPrices      []TierPrice      `gorm:"-"`        // Tiers of the shopper's customer group, lowest quantity first; set when loaded for display
+    ReorderThreshold *int             // Optional; a low-stock alert is raised when stock falls below it
+    TaxClassID       *uint            `gorm:"index"` // Optional; products without a class are taxed at the standard rate
+    Weight           float64          `gorm:"type:decimal(10,3);not null;default:0"` // Kilograms, packed; variants weigh the same
+    Length           float64          `gorm:"type:decimal(10,2);not null;default:0"` // Centimetres, packed
+    Width            float64          `gorm:"type:decimal(10,2);not null;default:0"`
+    Height           float64          `gorm:"type:decimal(10,2);not null;default:0"`
+    CreatedAt        time.Time        `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt        time.Time        `gorm:"default:CURRENT_TIMESTAMP"`
+    DeletedAt        gorm.DeletedAt   `gorm:"index"`
+    CartItems        []CartItem       `gorm:"foreignKey:ProductID"`
+    OrderItems       []OrderItem      `gorm:"foreignKey:ProductID"`
+    Categories       []Category       `gorm:"many2many:product_categories"`
+    Options          []ProductOption  `gorm:"foreignKey:ProductID"`
+    Variants         []ProductVariant `gorm:"foreignKey:ProductID"`
+    Images           []ProductImage   `gorm:"foreignKey:ProductID"`
+}
+
+// BeforeSave keeps the currency column in line with the price
+func (p *Product) BeforeSave(tx *gorm.DB) error {
+    p.Currency = currencyOf(p.Price)
+    return nil
+}
+
+// AfterFind applies the row currency to the price read from the database
+func (p *Product) AfterFind(tx *gorm.DB) error {
+    p.Price = p.Price.WithCurrency(p.Currency)
+    if p.SalePrice != nil {
+        sale := p.SalePrice.WithCurrency(p.Currency)
+        p.SalePrice = &sale
+    }
+    return nil
 }
 
 // BeforeUpdate will be called before updating the product
 func (p *Product) BeforeUpdate(tx *gorm.DB) error {
     p.UpdatedAt = time.Now()
     return nil
-}
\ No newline at end of file
+}
+
+// OnSale reports whether the product has a sale price in effect at a time
+func (p *Product) OnSale(at time.Time) bool {
+    return p.SalePrice != nil && (p.SaleEndsAt == nil || at.Before(*p.SaleEndsAt))
+}
+
+// CurrentPrice returns the price the product sells for now: its sale price while a
+// sale runs, its regular price otherwise
+func (p *Product) CurrentPrice() money.Money {
+    if p.OnSale(time.Now()) {
+        return *p.SalePrice
+    }
+    return p.Price
+}
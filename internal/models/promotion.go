@@ -0,0 +1,93 @@
+package models
+
+import (
+    "ecommerce-app/pkg/money"
+    "time"
+    "gorm.io/gorm"
+)
+
+// Promotion types
+const (
+    PromotionBuyXGetY     = "buy_x_get_y"   // Of every BuyQuantity+GetQuantity eligible units, the GetQuantity cheapest get Percent off
+    PromotionSpendTiers   = "spend_tiers"   // The highest tier the eligible subtotal reaches takes its amount off
+    PromotionBundle       = "bundle"        // Every complete set of the products gets Percent or Amount off
+    PromotionCategorySale = "category_sale" // Percent off the eligible products
+)
+
+// Promotion is a discount rule applied to every cart it matches, without the shopper
+// entering a code. Without products or categories it applies to every product;
+// otherwise only to the products listed and to the products of the categories
+// listed, including their subcategories. Bundles consist of the products listed.
+type Promotion struct {
+    ID          uint            `gorm:"primaryKey"`
+    Name        string          `gorm:"type:varchar(100);not null"` // Shown to shoppers on the discounted lines
+    Description string          `gorm:"type:varchar(255)"`
+    Type        string          `gorm:"type:varchar(20);not null"`
+    Priority    int             `gorm:"not null;default:0;index"` // Higher priorities are evaluated first
+    Exclusive   bool            `gorm:"not null;default:false"`   // Whether it only applies when no other promotion does
+    BuyQuantity int             `gorm:"not null;default:0"`
+    GetQuantity int             `gorm:"not null;default:0"`
+    Percent     float64         `gorm:"type:decimal(5,2);not null;default:0"`
+    Amount      money.Money     `gorm:"type:decimal(10,2);not null;default:0"` // Off every bundle
+    Currency    string          `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+    StartsAt    *time.Time      // Optional; runs from this time on
+    EndsAt      *time.Time      // Optional; runs until this time
+    Active      bool            `gorm:"not null;default:true"`
+    Tiers       []PromotionTier `gorm:"foreignKey:PromotionID"`
+    Products    []Product       `gorm:"many2many:promotion_products"`
+    Categories  []Category      `gorm:"many2many:promotion_categories"`
+    CreatedAt   time.Time       `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt   time.Time       `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// BeforeSave keeps the currency column in line with the amount
+func (p *Promotion) BeforeSave(tx *gorm.DB) error {
+    p.Currency = currencyOf(p.Amount)
+    return nil
+}
+
+// AfterFind applies the row currency to the amount read from the database
+func (p *Promotion) AfterFind(tx *gorm.DB) error {
+    p.Amount = p.Amount.WithCurrency(p.Currency)
+    return nil
+}
+
+// BeforeUpdate will be called before updating the promotion
+func (p *Promotion) BeforeUpdate(tx *gorm.DB) error {
+    p.UpdatedAt = time.Now()
+    return nil
+}
+
+// Restricted reports whether the promotion only applies to some products. The
+// products and categories must be loaded.
+func (p *Promotion) Restricted() bool {
+    return len(p.Products) > 0 || len(p.Categories) > 0
+}
+
+// Running reports whether the promotion is active and within its schedule at a time
+func (p *Promotion) Running(at time.Time) bool {
+    return p.Active && (p.StartsAt == nil || !at.Before(*p.StartsAt)) && (p.EndsAt == nil || at.Before(*p.EndsAt))
+}
+
+// PromotionTier is a step of a spend_tiers promotion: spending Threshold on the
+// eligible products takes Amount off
+type PromotionTier struct {
+    ID          uint        `gorm:"primaryKey"`
+    PromotionID uint        `gorm:"not null;index"`
+    Threshold   money.Money `gorm:"type:decimal(10,2);not null"`
+    Amount      money.Money `gorm:"type:decimal(10,2);not null"`
+    Currency    string      `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+}
+
+// BeforeSave keeps the currency column in line with the amounts
+func (t *PromotionTier) BeforeSave(tx *gorm.DB) error {
+    t.Currency = currencyOf(t.Threshold)
+    return nil
+}
+
+// AfterFind applies the row currency to the amounts read from the database
+func (t *PromotionTier) AfterFind(tx *gorm.DB) error {
+    t.Threshold = t.Threshold.WithCurrency(t.Currency)
+    t.Amount = t.Amount.WithCurrency(t.Currency)
+    return nil
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+    "ecommerce-app/pkg/money"
+    "time"
+    "gorm.io/gorm"
+)
+
+// Sale statuses
+const (
+    SaleScheduled = "scheduled" // Waiting for its start time
+    SaleActive    = "active"    // Its price is the product's sale price
+    SaleEnded     = "ended"
+    SaleCancelled = "cancelled"
+)
+
+// ProductSale is a sale price scheduled for a product. The sale scheduler sets the
+// product's sale price when the sale starts and clears it when the sale ends.
+type ProductSale struct {
+    ID        uint        `gorm:"primaryKey"`
+    ProductID uint        `gorm:"not null;index"`
+    Price     money.Money `gorm:"type:decimal(10,2);not null"`
+    Currency  string      `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+    StartsAt  time.Time   `gorm:"not null;index"`
+    EndsAt    *time.Time  // Optional; runs until cancelled without it
+    Status    string      `gorm:"type:varchar(20);not null;default:scheduled;index"`
+    CreatedAt time.Time   `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt time.Time   `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// BeforeSave keeps the currency column in line with the price
+func (s *ProductSale) BeforeSave(tx *gorm.DB) error {
+    s.Currency = currencyOf(s.Price)
+    return nil
+}
+
+// AfterFind applies the row currency to the price read from the database
+func (s *ProductSale) AfterFind(tx *gorm.DB) error {
+    s.Price = s.Price.WithCurrency(s.Currency)
+    return nil
+}
+
+// BeforeUpdate will be called before updating the sale
+func (s *ProductSale) BeforeUpdate(tx *gorm.DB) error {
+    s.UpdatedAt = time.Now()
+    return nil
+}
+
+// Price change reasons
+const (
+    PriceUpdated       = "updated"        // Changed through the admin API
+    PriceImported      = "import"         // Changed by a catalog import
+    PriceSaleStarted   = "sale_started"
+    PriceSaleEnded     = "sale_ended"
+    PriceSaleCancelled = "sale_cancelled"
+)
+
+// PriceChange records the regular and sale price of a product after a change to either
+type PriceChange struct {
+    ID        uint         `gorm:"primaryKey"`
+    ProductID uint         `gorm:"not null;index"`
+    Price     money.Money  `gorm:"type:decimal(10,2);not null"`
+    SalePrice *money.Money `gorm:"type:decimal(10,2)"` // Set while a sale runs
+    Currency  string       `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+    Reason    string       `gorm:"type:varchar(20);not null"`
+    SaleID    *uint        // The sale that started or ended, for sale changes
+    CreatedAt time.Time    `gorm:"default:CURRENT_TIMESTAMP;index"`
+}
+
+// BeforeSave keeps the currency column in line with the prices
+func (c *PriceChange) BeforeSave(tx *gorm.DB) error {
+    c.Currency = currencyOf(c.Price)
+    return nil
+}
+
+// AfterFind applies the row currency to the prices read from the database
+func (c *PriceChange) AfterFind(tx *gorm.DB) error {
+    c.Price = c.Price.WithCurrency(c.Currency)
+    if c.SalePrice != nil {
+        sale := c.SalePrice.WithCurrency(c.Currency)
+        c.SalePrice = &sale
+    }
+    return nil
+}
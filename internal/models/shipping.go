@@ -0,0 +1,66 @@
+package models
+
+import (
+    "ecommerce-app/pkg/money"
+    "time"
+    "gorm.io/gorm"
+)
+
+// Shipping method types
+const (
+    ShippingFlatRate          = "flat_rate"           // Rate per order
+    ShippingWeightBased       = "weight_based"        // Rate plus PerKg for every started kilogram
+    ShippingFreeOverThreshold = "free_over_threshold" // Rate per order, free from a subtotal of Threshold on
+    ShippingLocalPickup       = "local_pickup"        // Collected from the store, free of charge
+)
+
+// ShippingZone is an area shipped to alike: a country, optionally narrowed down to a
+// region and to the postal codes matching a pattern. When several zones match an
+// address, the one with the highest priority wins, then the most specific one, and
+// its active methods are offered.
+type ShippingZone struct {
+    ID            uint             `gorm:"primaryKey"`
+    Name          string           `gorm:"type:varchar(100);not null"`
+    Country       string           `gorm:"type:varchar(2);not null;index"`
+    Region        string           `gorm:"type:varchar(100)"` // Empty for the whole country
+    PostalPattern string           `gorm:"type:varchar(50)"`  // Glob such as 90*; empty for every postal code
+    Priority      int              `gorm:"not null;default:0"`
+    Methods       []ShippingMethod `gorm:"foreignKey:ShippingZoneID"`
+    CreatedAt     time.Time        `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt     time.Time        `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// BeforeUpdate will be called before updating the shipping zone
+func (z *ShippingZone) BeforeUpdate(tx *gorm.DB) error {
+    z.UpdatedAt = time.Now()
+    return nil
+}
+
+// ShippingMethod is a way of getting an order to a shipping zone and what it costs
+type ShippingMethod struct {
+    ID                uint        `gorm:"primaryKey"`
+    ShippingZoneID    uint        `gorm:"not null;index"`
+    Name              string      `gorm:"type:varchar(100);not null"`
+    Type              string      `gorm:"type:varchar(30);not null"`
+    Rate              money.Money `gorm:"type:decimal(10,2);not null;default:0"`
+    PerKg             money.Money `gorm:"type:decimal(10,2);not null;default:0"` // Weight-based methods only
+    Threshold         money.Money `gorm:"type:decimal(10,2);not null;default:0"` // Free-over-threshold methods only
+    MaxWeight         float64     `gorm:"type:decimal(10,3);not null;default:0"` // Kilograms; zero for no limit
+    VolumetricDivisor int         `gorm:"not null;default:0"`                    // Cubic centimetres per kilogram of volumetric weight; zero to charge actual weight only
+    Active            bool        `gorm:"not null"`
+    Currency          string      `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+}
+
+// BeforeSave keeps the currency column in line with the rate
+func (m *ShippingMethod) BeforeSave(tx *gorm.DB) error {
+    m.Currency = currencyOf(m.Rate)
+    return nil
+}
+
+// AfterFind applies the row currency to the amounts read from the database
+func (m *ShippingMethod) AfterFind(tx *gorm.DB) error {
+    m.Rate = m.Rate.WithCurrency(m.Currency)
+    m.PerKg = m.PerKg.WithCurrency(m.Currency)
+    m.Threshold = m.Threshold.WithCurrency(m.Currency)
+    return nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+    "time"
+    "gorm.io/gorm"
+)
+
+// Tax price modes: whether catalog prices already include tax or have it added on top
+const (
+    TaxExclusive = "exclusive"
+    TaxInclusive = "inclusive"
+)
+
+// Tax rounding modes: round the tax of every line, or the tax of the whole order at
+// each rate once
+const (
+    TaxRoundLine  = "line"
+    TaxRoundOrder = "order"
+)
+
+// TaxClass groups the products taxed alike, such as food at a reduced rate. Products
+// without a class are taxed at the standard rate of a zone.
+type TaxClass struct {
+    ID        uint      `gorm:"primaryKey"`
+    Code      string    `gorm:"type:varchar(50);uniqueIndex;not null"`
+    Name      string    `gorm:"type:varchar(100);not null"`
+    CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// BeforeUpdate will be called before updating the tax class
+func (c *TaxClass) BeforeUpdate(tx *gorm.DB) error {
+    c.UpdatedAt = time.Now()
+    return nil
+}
+
+// TaxZone is an area taxed alike: a country, optionally narrowed down to a region
+// and to the postal codes matching a pattern. When several zones match an address,
+// the one with the highest priority wins, then the most specific one.
+type TaxZone struct {
+    ID            uint      `gorm:"primaryKey"`
+    Name          string    `gorm:"type:varchar(100);not null"`
+    Country       string    `gorm:"type:varchar(2);not null;index"`
+    Region        string    `gorm:"type:varchar(100)"` // Empty for the whole country
+    PostalPattern string    `gorm:"type:varchar(50)"`  // Glob such as 90*; empty for every postal code
+    Priority      int       `gorm:"not null;default:0"`
+    Rates         []TaxRate `gorm:"foreignKey:TaxZoneID"`
+    CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// BeforeUpdate will be called before updating the tax zone
+func (z *TaxZone) BeforeUpdate(tx *gorm.DB) error {
+    z.UpdatedAt = time.Now()
+    return nil
+}
+
+// TaxRate is the rate at which a zone taxes the products of a class
+type TaxRate struct {
+    ID         uint    `gorm:"primaryKey"`
+    TaxZoneID  uint    `gorm:"not null;uniqueIndex:idx_tax_rate"`
+    TaxClassID uint    `gorm:"not null;default:0;uniqueIndex:idx_tax_rate"` // Zero for the standard rate
+    Name       string  `gorm:"type:varchar(100);not null"`                  // Shown in tax summaries, e.g. VAT
+    Rate       float64 `gorm:"type:decimal(8,6);not null"`                  // Fraction, e.g. 0.2 for 20%
+}
@@ -8,12 +8,14 @@ import (
 // User represents the user model in the database
 type User struct {
     ID               uint           `gorm:"primaryKey"`
-    Email            string         `gorm:"type:varchar(255);unique;not null"`
-    PasswordHash     string         `gorm:"type:varchar(255);not null"`
-    ResetToken       *string        `gorm:"type:varchar(255)"`
-    ResetTokenExpiry *time.Time     `gorm:"type:timestamp"`
+    Email            string         `gorm:"type:varchar(255);not null"` // Unique outside the trash; see db.Migrate
+    PasswordHash     string         `gorm:"type:varchar(255);not null" json:"-"`
+    ResetToken       *string        `gorm:"type:varchar(255)" json:"-"`
+    ResetTokenExpiry *time.Time     `gorm:"type:timestamp" json:"-"`
+    CustomerGroupID  *uint          `gorm:"index"` // Optional; prices come from the group's price lists
     CreatedAt        time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
     UpdatedAt        time.Time      `gorm:"default:CURRENT_TIMESTAMP"`
+    DeletedAt        gorm.DeletedAt `gorm:"index"`
     Carts            []Cart         `gorm:"foreignKey:UserID"`
     Orders           []Order        `gorm:"foreignKey:UserID"`
 }
@@ -0,0 +1,77 @@
+package models
+
+import (
+    "ecommerce-app/pkg/money"
+    "time"
+    "gorm.io/gorm"
+)
+
+// ProductOption is an axis along which a product varies, such as size or colour
+type ProductOption struct {
+    ID         uint                 `gorm:"primaryKey"`
+    ProductID  uint                 `gorm:"not null;index"`
+    Name       string               `gorm:"type:varchar(100);not null"`
+    Position   int                  `gorm:"not null;default:0"`
+    Values     []ProductOptionValue `gorm:"foreignKey:OptionID"`
+}
+
+// ProductOptionValue is one of the values of a product option, such as "M" for size
+type ProductOptionValue struct {
+    ID         uint           `gorm:"primaryKey"`
+    OptionID   uint           `gorm:"not null;index"`
+    Value      string         `gorm:"type:varchar(100);not null"`
+    Position   int            `gorm:"not null;default:0"`
+}
+
+// ProductVariant is a purchasable combination of option values with its own SKU and stock
+type ProductVariant struct {
+    ID           uint                 `gorm:"primaryKey"`
+    ProductID    uint                 `gorm:"not null;index"`
+    SKU          string               `gorm:"type:varchar(100);uniqueIndex;not null"`
+    Barcode      string               `gorm:"type:varchar(100)"`
+    Title        string               `gorm:"type:varchar(255)"`
+    Price        *money.Money         `gorm:"type:decimal(10,2)"` // Overrides the product price when set
+    Currency     string               `gorm:"type:varchar(3);not null;default:USD" json:"-"`
+    Stock        int                  `gorm:"not null;default:0"`
+    Available    int                  `gorm:"-"` // Stock minus active reservations; set when loaded for display
+    OptionValues []ProductOptionValue `gorm:"many2many:product_variant_option_values"`
+    CreatedAt    time.Time            `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt    time.Time            `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// UnitPrice returns the current price of the variant, falling back to the price of its
+// product. While the product is on sale, a price override is reduced in proportion to
+// the product's sale price.
+func (v *ProductVariant) UnitPrice(product Product) money.Money {
+    if v.Price == nil {
+        return product.CurrentPrice()
+    }
+    if product.OnSale(time.Now()) && product.Price.IsPositive() {
+        return v.Price.MulRate(float64(product.SalePrice.Amount) / float64(product.Price.Amount))
+    }
+    return *v.Price
+}
+
+// BeforeSave keeps the currency column in line with the price override
+func (v *ProductVariant) BeforeSave(tx *gorm.DB) error {
+    v.Currency = money.DefaultCurrency
+    if v.Price != nil {
+        v.Currency = currencyOf(*v.Price)
+    }
+    return nil
+}
+
+// AfterFind applies the row currency to the price override read from the database
+func (v *ProductVariant) AfterFind(tx *gorm.DB) error {
+    if v.Price != nil {
+        price := v.Price.WithCurrency(v.Currency)
+        v.Price = &price
+    }
+    return nil
+}
+
+// BeforeUpdate will be called before updating the variant
+func (v *ProductVariant) BeforeUpdate(tx *gorm.DB) error {
+    v.UpdatedAt = time.Now()
+    return nil
+}
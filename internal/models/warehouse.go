@@ -0,0 +1,60 @@
+package models
+
+import (
+    "time"
+    "gorm.io/gorm"
+)
+
+// Warehouse is a location that holds stock. Sales take stock from active warehouses
+// in priority order, lowest first; the first of them is the default location.
+type Warehouse struct {
+    ID        uint      `gorm:"primaryKey"`
+    Code      string    `gorm:"type:varchar(50);uniqueIndex;not null"`
+    Name      string    `gorm:"type:varchar(255);not null"`
+    Priority  int       `gorm:"not null;default:0"`
+    Active    bool      `gorm:"not null;default:true"`
+    CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+    UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// BeforeUpdate will be called before updating the warehouse
+func (w *Warehouse) BeforeUpdate(tx *gorm.DB) error {
+    w.UpdatedAt = time.Now()
+    return nil
+}
+
+// StockLevel is the on-hand quantity of a product or variant at a warehouse. It is
+// the running total of the stock movements recorded for them there.
+type StockLevel struct {
+    ID          uint      `gorm:"primaryKey"`
+    WarehouseID uint      `gorm:"not null;uniqueIndex:idx_stock_levels_location"`
+    ProductID   uint      `gorm:"not null;uniqueIndex:idx_stock_levels_location;index"`
+    VariantID   uint      `gorm:"not null;default:0;uniqueIndex:idx_stock_levels_location"` // Zero for products without variants
+    Quantity    int       `gorm:"not null;default:0"`
+    UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// Stock movement types
+const (
+    MovementReceipt    = "receipt"    // Goods received from a supplier
+    MovementSale       = "sale"       // Units shipped for a paid order
+    MovementReturn     = "return"     // Units returned by a customer
+    MovementAdjustment = "adjustment" // Counting corrections and direct stock edits
+    MovementTransfer   = "transfer"   // One leg of a move between warehouses
+)
+
+// StockMovement is an entry of the append-only stock ledger. Quantity is positive
+// for units coming into the warehouse and negative for units leaving it; the two
+// legs of a transfer share a Reference.
+type StockMovement struct {
+    ID          uint      `gorm:"primaryKey"`
+    WarehouseID uint      `gorm:"not null;index"`
+    ProductID   uint      `gorm:"not null;index"`
+    VariantID   uint      `gorm:"not null;default:0"`
+    Type        string    `gorm:"type:varchar(20);not null;index"`
+    Quantity    int       `gorm:"not null"`
+    Reference   string    `gorm:"type:varchar(100);index"`
+    Note        string    `gorm:"type:text"`
+    CreatedBy   string    `gorm:"type:varchar(255);not null"`
+    CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP;index"`
+}
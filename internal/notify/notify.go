@@ -0,0 +1,44 @@
+// Package notify delivers messages to admins and shoppers through a pluggable channel.
+package notify
+
+import (
+	"ecommerce-app/pkg/logger"
+	"fmt"
+	"strings"
+)
+
+// Message is a notification for a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier sends messages through a delivery channel
+type Notifier interface {
+	Send(msg Message) error
+}
+
+// LogNotifier writes messages to the application log instead of delivering them.
+// It is meant for development and for stores without a mail server.
+type LogNotifier struct {
+	log *logger.Logger
+}
+
+// NewLogNotifier creates a new instance of LogNotifier
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{
+		log: logger.New(),
+	}
+}
+
+// Send logs the message
+func (n *LogNotifier) Send(msg Message) error {
+	n.log.Info(fmt.Sprintf("Notification to %s: %s\n%s", msg.To, msg.Subject, msg.Body))
+	return nil
+}
+
+// headerValue strips line breaks so a value cannot inject extra mail headers
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
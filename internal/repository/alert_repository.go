@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// belowThreshold selects the live products whose stock is below their reorder threshold
+const belowThreshold = "deleted_at IS NULL AND reorder_threshold IS NOT NULL AND stock < reorder_threshold"
+
+// AlertRepository defines the interface for low-stock alert and back-in-stock
+// subscription database operations
+type AlertRepository interface {
+	SetReorderThreshold(productID uint, threshold *int) error
+	RaiseLowStockAlerts() ([]models.LowStockAlert, error)
+	ResolveLowStockAlerts(now time.Time) (int64, error)
+	ListAlerts(openOnly bool, page, pageSize int) ([]models.LowStockAlert, int64, error)
+	Subscribe(subscription *models.StockSubscription) (bool, error)
+	ListSubscriptions(userID uint) ([]models.StockSubscription, error)
+	DeleteSubscription(userID, id uint) error
+	DueSubscriptions(limit int) ([]models.StockSubscription, error)
+	MarkNotified(id uint, at time.Time) error
+}
+
+// GormAlertRepository implements AlertRepository using GORM
+type GormAlertRepository struct {
+	db *gorm.DB
+}
+
+// NewAlertRepository creates a new instance of GormAlertRepository
+func NewAlertRepository(db *gorm.DB) AlertRepository {
+	return &GormAlertRepository{
+		db: db,
+	}
+}
+
+// SetReorderThreshold sets the reorder threshold of a product, or removes it when nil
+func (r *GormAlertRepository) SetReorderThreshold(productID uint, threshold *int) error {
+	// UpdateColumn skips the product hooks, which would rewrite other columns
+	result := r.db.Model(&models.Product{}).
+		Where("id = ?", productID).
+		UpdateColumn("reorder_threshold", threshold)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// RaiseLowStockAlerts opens an alert for every product whose stock is below its
+// reorder threshold and that has no open alert yet. It returns the new alerts.
+func (r *GormAlertRepository) RaiseLowStockAlerts() ([]models.LowStockAlert, error) {
+	var alerts []models.LowStockAlert
+	err := r.db.Raw(`INSERT INTO low_stock_alerts (product_id, product_name, threshold, stock, created_at)
+		SELECT id, name, reorder_threshold, stock, CURRENT_TIMESTAMP FROM products
+		WHERE `+belowThreshold+`
+		ORDER BY id
+		ON CONFLICT (product_id) WHERE resolved_at IS NULL DO NOTHING
+		RETURNING *`).
+		Scan(&alerts).Error
+	return alerts, err
+}
+
+// ResolveLowStockAlerts closes the open alerts of products that are no longer below
+// their threshold, including products whose threshold was removed or that were
+// deleted. It returns the number of resolved alerts.
+func (r *GormAlertRepository) ResolveLowStockAlerts(now time.Time) (int64, error) {
+	low := r.db.Model(&models.Product{}).Select("id").Where(belowThreshold)
+	result := r.db.Model(&models.LowStockAlert{}).
+		Where("resolved_at IS NULL AND product_id NOT IN (?)", low).
+		Update("resolved_at", now)
+	return result.RowsAffected, result.Error
+}
+
+// ListAlerts retrieves one page of low-stock alerts, newest first
+func (r *GormAlertRepository) ListAlerts(openOnly bool, page, pageSize int) ([]models.LowStockAlert, int64, error) {
+	db := r.db.Model(&models.LowStockAlert{})
+	if openOnly {
+		db = db.Where("resolved_at IS NULL")
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var alerts []models.LowStockAlert
+	err := db.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&alerts).Error
+	return alerts, total, err
+}
+
+// Subscribe stores a back-in-stock subscription. A shopper has at most one pending
+// subscription per product variant; when one exists it is loaded into subscription
+// and false is returned.
+func (r *GormAlertRepository) Subscribe(subscription *models.StockSubscription) (bool, error) {
+	result := r.db.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "product_id"}, {Name: "variant_id"}, {Name: "user_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "notified_at IS NULL"}}},
+		DoNothing:   true,
+	}).Create(subscription)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	err := r.db.Where("product_id = ? AND variant_id = ? AND user_id = ? AND notified_at IS NULL",
+		subscription.ProductID, subscription.VariantID, subscription.UserID).
+		First(subscription).Error
+	return false, err
+}
+
+// ListSubscriptions retrieves the pending subscriptions of a user with their products
+func (r *GormAlertRepository) ListSubscriptions(userID uint) ([]models.StockSubscription, error) {
+	var subscriptions []models.StockSubscription
+	err := r.db.Preload("Product").Preload("Variant").
+		Joins("JOIN products ON products.id = stock_subscriptions.product_id AND products.deleted_at IS NULL").
+		Where("stock_subscriptions.user_id = ? AND stock_subscriptions.notified_at IS NULL", userID).
+		Order("stock_subscriptions.created_at DESC").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// DeleteSubscription removes a pending subscription of a user
+func (r *GormAlertRepository) DeleteSubscription(userID, id uint) error {
+	result := r.db.Where("id = ? AND user_id = ? AND notified_at IS NULL", id, userID).
+		Delete(&models.StockSubscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// DueSubscriptions retrieves at most limit pending subscriptions whose product or
+// variant is back in stock, with their product and user loaded. Subscriptions of
+// deleted products and users are left alone.
+func (r *GormAlertRepository) DueSubscriptions(limit int) ([]models.StockSubscription, error) {
+	var subscriptions []models.StockSubscription
+	err := r.db.Preload("Product").Preload("Variant").Preload("User").
+		Joins("JOIN products ON products.id = stock_subscriptions.product_id AND products.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = stock_subscriptions.user_id AND users.deleted_at IS NULL").
+		Where("stock_subscriptions.notified_at IS NULL").
+		Where(`(stock_subscriptions.variant_id = 0 AND products.stock > 0) OR EXISTS (
+			SELECT 1 FROM product_variants
+			WHERE product_variants.id = stock_subscriptions.variant_id AND product_variants.stock > 0)`).
+		Order("stock_subscriptions.id").
+		Limit(limit).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// MarkNotified records that the shopper of a subscription has been notified
+func (r *GormAlertRepository) MarkNotified(id uint, at time.Time) error {
+	return r.db.Model(&models.StockSubscription{}).
+		Where("id = ?", id).
+		Update("notified_at", at).Error
+}
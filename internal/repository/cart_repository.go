@@ -2,14 +2,28 @@ package repository
 
 import (
 	"ecommerce-app/internal/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// CartMergeFunc decides the quantity of a product after merging carts, given the
+// quantity already in the target cart (zero when absent) and the incoming item
+type CartMergeFunc func(existing int, incoming models.CartItem) int
+
 type CartRepository interface {
-	GetCart(userID uint) ([]models.CartItem, error)
-	AddToCart(cart *models.CartItem) error
-	RemoveFromCart(userID uint, productID uint) error
+	CreateCart(cart *models.Cart, item *models.CartItem) error
+	FindCart(userID uint) (*models.Cart, error)
+	FindCartByID(id uint) (*models.Cart, error)
+	GetOrCreateCart(userID uint) (*models.Cart, error)
+	MergeCarts(sourceID uint, targetID uint, merge CartMergeFunc) error
+	GetItems(cartID uint) ([]models.CartItem, error)
+	FindItem(cartID uint, productID uint, variantID uint) (*models.CartItem, error)
+	AddItem(item *models.CartItem) error
+	SetItemQuantity(item *models.CartItem) error
+	RemoveItem(cartID uint, productID uint, variantID uint) error
+	PurgeGuestCarts(before time.Time, limit int) (int, error)
 }
 
 type cartRepository struct {
@@ -20,16 +34,206 @@ func NewCartRepository(db *gorm.DB) CartRepository {
 	return &cartRepository{db: db}
 }
 
-func (r *cartRepository) GetCart(userID uint) ([]models.CartItem, error) {
+// CreateCart inserts a new cart along with its first item, so a cart is never left
+// behind empty
+func (r *cartRepository) CreateCart(cart *models.Cart, item *models.CartItem) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(cart).Error; err != nil {
+			return err
+		}
+		item.CartID = cart.ID
+		return tx.Create(item).Error
+	})
+}
+
+// FindCart retrieves the cart of a user
+func (r *cartRepository) FindCart(userID uint) (*models.Cart, error) {
+	var cart models.Cart
+	err := r.db.Where("user_id = ?", userID).First(&cart).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// FindCartByID retrieves a cart by its ID
+func (r *cartRepository) FindCartByID(id uint) (*models.Cart, error) {
+	var cart models.Cart
+	err := r.db.First(&cart, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cart, nil
+}
+
+// GetOrCreateCart retrieves the cart of a user, creating it on first use
+func (r *cartRepository) GetOrCreateCart(userID uint) (*models.Cart, error) {
+	err := r.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, DoNothing: true}).
+		Create(&models.Cart{UserID: &userID}).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.FindCart(userID)
+}
+
+// MergeCarts moves the items of the source cart into the target cart in a single
+// transaction, using merge to settle the quantity of each product, and deletes the
+// source cart afterwards
+func (r *cartRepository) MergeCarts(sourceID uint, targetID uint, merge CartMergeFunc) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var carts []models.Cart
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{sourceID, targetID}).
+			Order("id").
+			Find(&carts).Error
+		if err != nil {
+			return err
+		}
+
+		var incoming []models.CartItem
+		if err := tx.Preload("Product").Preload("Variant").Where("cart_id = ?", sourceID).Find(&incoming).Error; err != nil {
+			return err
+		}
+		if err := setCartAvailability(tx, incoming); err != nil {
+			return err
+		}
+
+		var existing []models.CartItem
+		if err := tx.Where("cart_id = ?", targetID).Find(&existing).Error; err != nil {
+			return err
+		}
+		type line struct{ productID, variantID uint }
+		quantities := make(map[line]int, len(existing))
+		for _, item := range existing {
+			quantities[line{item.ProductID, item.VariantID}] = item.Quantity
+		}
+
+		for _, item := range incoming {
+			quantity := merge(quantities[line{item.ProductID, item.VariantID}], item)
+			if quantity <= 0 {
+				continue
+			}
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "cart_id"}, {Name: "product_id"}, {Name: "variant_id"}},
+				DoUpdates: clause.Set{
+					{Column: clause.Column{Name: "quantity"}, Value: gorm.Expr("EXCLUDED.quantity")},
+					{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("CURRENT_TIMESTAMP")},
+				},
+			}).Create(&models.CartItem{
+				CartID:     targetID,
+				ProductID:  item.ProductID,
+				VariantID:  item.VariantID,
+				Quantity:   quantity,
+				PriceAtAdd: item.PriceAtAdd,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("cart_id = ?", sourceID).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Cart{}, sourceID).Error
+	})
+}
+
+// GetItems retrieves the items of a cart with their products and variants, including
+// their available stock
+func (r *cartRepository) GetItems(cartID uint) ([]models.CartItem, error) {
 	var cartItems []models.CartItem
-	err := r.db.Preload("Product").Where("user_id = ?", userID).Find(&cartItems).Error
-	return cartItems, err
+	err := r.db.Preload("Product").Preload("Variant").Where("cart_id = ?", cartID).Order("id").Find(&cartItems).Error
+	if err != nil {
+		return nil, err
+	}
+	return cartItems, setCartAvailability(r.db, cartItems)
+}
+
+// FindItem retrieves the cart item holding the given product variant
+func (r *cartRepository) FindItem(cartID uint, productID uint, variantID uint) (*models.CartItem, error) {
+	var cartItem models.CartItem
+	err := r.db.Where("cart_id = ? AND product_id = ? AND variant_id = ?", cartID, productID, variantID).First(&cartItem).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cartItem, nil
+}
+
+// AddItem adds the item's quantity to its cart, merging with an existing line for the
+// same product variant. The price snapshot of an existing line is kept.
+func (r *cartRepository) AddItem(item *models.CartItem) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "cart_id"}, {Name: "product_id"}, {Name: "variant_id"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "quantity"}, Value: gorm.Expr("cart_items.quantity + EXCLUDED.quantity")},
+				{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("CURRENT_TIMESTAMP")},
+			},
+		}).Create(item).Error
+		if err != nil {
+			return err
+		}
+		return touchCart(tx, item.CartID)
+	})
+}
+
+// SetItemQuantity sets the quantity of the item's product variant in its cart, adding the line if needed
+func (r *cartRepository) SetItemQuantity(item *models.CartItem) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "cart_id"}, {Name: "product_id"}, {Name: "variant_id"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "quantity"}, Value: gorm.Expr("EXCLUDED.quantity")},
+				{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("CURRENT_TIMESTAMP")},
+			},
+		}).Create(item).Error
+		if err != nil {
+			return err
+		}
+		return touchCart(tx, item.CartID)
+	})
 }
 
-func (r *cartRepository) AddToCart(cart *models.CartItem) error {
-	return r.db.Create(cart).Error
+// RemoveItem removes a product variant from a cart
+func (r *cartRepository) RemoveItem(cartID uint, productID uint, variantID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("cart_id = ? AND product_id = ? AND variant_id = ?", cartID, productID, variantID).
+			Delete(&models.CartItem{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return touchCart(tx, cartID)
+	})
 }
 
-func (r *cartRepository) RemoveFromCart(userID uint, productID uint) error {
-	return r.db.Where("user_id = ? AND product_id = ?", userID, productID).Delete(&models.CartItem{}).Error
-}
\ No newline at end of file
+// PurgeGuestCarts permanently removes at most limit guest carts, with their items,
+// that last changed before the given time, and returns how many it removed
+func (r *cartRepository) PurgeGuestCarts(before time.Time, limit int) (int, error) {
+	var ids []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the carts so a shopper cannot add to one while it is removed
+		err := tx.Model(&models.Cart{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id IS NULL AND updated_at < ?", before).
+			Order("id").
+			Limit(limit).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		if err := tx.Where("cart_id IN ?", ids).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Cart{}, ids).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// touchCart records within tx that the contents of a cart changed. Guest carts expire
+// once they have not changed for a while.
+func touchCart(tx *gorm.DB, cartID uint) error {
+	return tx.Model(&models.Cart{}).Where("id = ?", cartID).UpdateColumn("updated_at", gorm.Expr("CURRENT_TIMESTAMP")).Error
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CategoryRepository defines the interface for category-related database operations
+type CategoryRepository interface {
+	Create(category *models.Category) error
+	FindByID(id uint) (*models.Category, error)
+	FindBySlug(slug string) (*models.Category, error)
+	Update(category *models.Category) error
+	Delete(id uint) error
+	List() ([]models.Category, error)
+	DescendantIDs(id uint) ([]uint, error)
+	SetProductCategories(productID uint, categoryIDs []uint) error
+}
+
+// GormCategoryRepository implements CategoryRepository using GORM
+type GormCategoryRepository struct {
+	db *gorm.DB
+}
+
+// NewCategoryRepository creates a new instance of GormCategoryRepository
+func NewCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &GormCategoryRepository{
+		db: db,
+	}
+}
+
+// Create inserts a new category into the database
+func (r *GormCategoryRepository) Create(category *models.Category) error {
+	return r.db.Omit("Children", "Products").Create(category).Error
+}
+
+// FindByID retrieves a category by its ID
+func (r *GormCategoryRepository) FindByID(id uint) (*models.Category, error) {
+	var category models.Category
+	if err := r.db.First(&category, id).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// FindBySlug retrieves a category by its slug
+func (r *GormCategoryRepository) FindBySlug(slug string) (*models.Category, error) {
+	var category models.Category
+	if err := r.db.Where("slug = ?", slug).First(&category).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// Update modifies an existing category in the database
+func (r *GormCategoryRepository) Update(category *models.Category) error {
+	return r.db.Omit("Children", "Products").Save(category).Error
+}
+
+// Delete removes a category. Its children move up to the deleted category's parent
+// and its product assignments are dropped.
+func (r *GormCategoryRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		if err := tx.First(&category, id).Error; err != nil {
+			return err
+		}
+
+		// Offers for the category carry over to its children before they move up
+		if err := removeCategoryFromOfferScopes(tx, id); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Category{}).
+			Where("parent_id = ?", id).
+			Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.Category{}, id).Error
+	})
+}
+
+// List retrieves all categories ordered for display
+func (r *GormCategoryRepository) List() ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db.Order("position, name").Find(&categories).Error
+	return categories, err
+}
+
+// DescendantIDs returns the ID of a category followed by the IDs of all categories nested below it
+func (r *GormCategoryRepository) DescendantIDs(id uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT id FROM tree`, id).Scan(&ids).Error
+	return ids, err
+}
+
+// SetProductCategories replaces the categories a product is assigned to
+func (r *GormCategoryRepository) SetProductCategories(productID uint, categoryIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM product_categories WHERE product_id = ?", productID).Error; err != nil {
+			return err
+		}
+		for _, categoryID := range categoryIDs {
+			if err := tx.Exec("INSERT INTO product_categories (product_id, category_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+				productID, categoryID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
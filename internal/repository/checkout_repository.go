@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrderBuilder turns the locked cart items of a user, and the coupons applied to the
+// cart, into an order ready to be persisted. The coupons the order redeems go in its
+// Coupons.
+type OrderBuilder func(items []models.CartItem, coupons []AppliedCoupon) (*models.Order, error)
+
+// CheckoutRepository defines the database operations needed to turn a cart into an order
+type CheckoutRepository interface {
+	PlaceOrder(userID uint, reserveUntil time.Time, build OrderBuilder) (*models.Order, error)
+}
+
+// GormCheckoutRepository implements CheckoutRepository using GORM
+type GormCheckoutRepository struct {
+	db *gorm.DB
+}
+
+// NewCheckoutRepository creates a new instance of GormCheckoutRepository
+func NewCheckoutRepository(db *gorm.DB) CheckoutRepository {
+	return &GormCheckoutRepository{
+		db: db,
+	}
+}
+
+// PlaceOrder runs the whole checkout in a single transaction. The user's cart items are
+// loaded with their products and variants locked FOR UPDATE and their available stock set,
+// and the coupons applied to the cart are locked and checked against their usage limits.
+// Both are handed to build. The resulting order and its coupon redemptions are stored, its
+// units reserved until reserveUntil and the cart emptied. Stock itself only goes down once
+// the order is paid. Any error rolls everything back.
+func (r *GormCheckoutRepository) PlaceOrder(userID uint, reserveUntil time.Time, build OrderBuilder) (*models.Order, error) {
+	var order *models.Order
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var items []models.CartItem
+		err := tx.Joins("JOIN carts ON carts.id = cart_items.cart_id").
+			Where("carts.user_id = ?", userID).
+			Order("cart_items.id").
+			Find(&items).Error
+		if err != nil {
+			return err
+		}
+
+		// Lock the products in a stable order so concurrent checkouts cannot deadlock
+		productIDs := make([]uint, 0, len(items))
+		for _, item := range items {
+			productIDs = append(productIDs, item.ProductID)
+		}
+
+		products := make(map[uint]models.Product, len(productIDs))
+		if len(productIDs) > 0 {
+			var locked []models.Product
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id IN ?", productIDs).
+				Order("id").
+				Find(&locked).Error
+			if err != nil {
+				return err
+			}
+			for _, product := range locked {
+				products[product.ID] = product
+			}
+		}
+
+		variantIDs := make([]uint, 0, len(items))
+		for _, item := range items {
+			if item.VariantID != 0 {
+				variantIDs = append(variantIDs, item.VariantID)
+			}
+		}
+
+		variants := make(map[uint]models.ProductVariant, len(variantIDs))
+		if len(variantIDs) > 0 {
+			var locked []models.ProductVariant
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id IN ?", variantIDs).
+				Order("id").
+				Find(&locked).Error
+			if err != nil {
+				return err
+			}
+			for _, variant := range locked {
+				variants[variant.ID] = variant
+			}
+		}
+
+		// Reservations are only placed while their products are locked, so the
+		// availability read here holds until the transaction ends
+		reservedProducts, reservedVariants, err := reservedStock(tx, productIDs)
+		if err != nil {
+			return err
+		}
+
+		for i := range items {
+			product, ok := products[items[i].ProductID]
+			if !ok {
+				return fmt.Errorf("product %d no longer exists", items[i].ProductID)
+			}
+			product.Available = unreserved(product.Stock, reservedProducts[product.ID])
+			items[i].Product = product
+
+			if items[i].VariantID != 0 {
+				variant, ok := variants[items[i].VariantID]
+				if !ok {
+					return fmt.Errorf("variant %d of %s no longer exists", items[i].VariantID, product.Name)
+				}
+				variant.Available = unreserved(variant.Stock, reservedVariants[variant.ID])
+				items[i].Variant = &variant
+			}
+		}
+
+		var coupons []AppliedCoupon
+		var cart models.Cart
+		err = tx.Select("id").Where("user_id = ?", userID).First(&cart).Error
+		if err == nil {
+			coupons, err = redeemCartCoupons(tx, cart.ID, userID, productIDs)
+		}
+		if err != nil && !errors.Is(err, ErrRecordNotFound) {
+			return err
+		}
+
+		order, err = build(items, coupons)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Omit(clause.Associations).Create(order).Error; err != nil {
+			return err
+		}
+		for i := range order.OrderItems {
+			order.OrderItems[i].OrderID = order.ID
+		}
+		if len(order.OrderItems) > 0 {
+			if err := tx.Omit(clause.Associations).Create(&order.OrderItems).Error; err != nil {
+				return err
+			}
+		}
+		for i := range order.Coupons {
+			order.Coupons[i].OrderID = order.ID
+			order.Coupons[i].UserID = userID
+		}
+		if len(order.Coupons) > 0 {
+			if err := tx.Create(&order.Coupons).Error; err != nil {
+				return err
+			}
+		}
+		for i := range order.Taxes {
+			order.Taxes[i].OrderID = order.ID
+		}
+		if len(order.Taxes) > 0 {
+			if err := tx.Create(&order.Taxes).Error; err != nil {
+				return err
+			}
+		}
+		if err := reserveStock(tx, order, reserveUntil); err != nil {
+			return err
+		}
+
+		err = tx.Create(&models.OrderStatusHistory{
+			OrderID:   order.ID,
+			ToStatus:  order.Status,
+			ChangedBy: fmt.Sprintf("user:%d", userID),
+			Note:      "Order placed at checkout",
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM cart_coupons WHERE cart_id = ?", cart.ID).Error; err != nil {
+			return err
+		}
+		return tx.Where("cart_id IN (?)", tx.Model(&models.Cart{}).Select("id").Where("user_id = ?", userID)).
+			Delete(&models.CartItem{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrCouponLimitReached is returned when a coupon has been redeemed as often as
+	// its usage limits allow
+	ErrCouponLimitReached = errors.New("coupon usage limit reached")
+	// ErrCouponRedeemed is returned when deleting a coupon that orders have redeemed
+	ErrCouponRedeemed = errors.New("coupon has been redeemed")
+)
+
+// AppliedCoupon is a coupon applied to a cart, along with the products of the cart it applies to
+type AppliedCoupon struct {
+	Coupon   models.Coupon
+	Eligible map[uint]bool // Eligible product IDs; nil when the coupon applies to every product
+}
+
+// Applies reports whether the coupon applies to a product
+func (c AppliedCoupon) Applies(productID uint) bool {
+	return c.Eligible == nil || c.Eligible[productID]
+}
+
+// CouponUsage counts the redemptions of a coupon that count against its limits
+type CouponUsage struct {
+	Total  int64 `json:"total"`
+	ByUser int64 `json:"by_user"`
+}
+
+// CouponRepository defines the interface for coupon-related database operations
+type CouponRepository interface {
+	Create(coupon *models.Coupon, productIDs, categoryIDs []uint) error
+	Update(coupon *models.Coupon, productIDs, categoryIDs []uint) error
+	FindByID(id uint) (*models.Coupon, error)
+	FindByCode(code string) (*models.Coupon, error)
+	List(page, pageSize int) ([]models.Coupon, int64, error)
+	Delete(id uint) error
+	ListRedemptions(couponID uint, page, pageSize int) ([]models.CouponRedemption, int64, error)
+	Usage(couponID, userID uint) (CouponUsage, error)
+	CheckLimits(coupon *models.Coupon, userID uint) error
+	Eligibility(coupon *models.Coupon, productIDs []uint) (AppliedCoupon, error)
+	CartCoupons(cartID uint, productIDs []uint) ([]AppliedCoupon, error)
+	AddToCart(cartID, couponID uint) error
+	RemoveFromCart(cartID, couponID uint) error
+}
+
+// GormCouponRepository implements CouponRepository using GORM
+type GormCouponRepository struct {
+	db *gorm.DB
+}
+
+// NewCouponRepository creates a new instance of GormCouponRepository
+func NewCouponRepository(db *gorm.DB) CouponRepository {
+	return &GormCouponRepository{
+		db: db,
+	}
+}
+
+// Create inserts a new coupon restricted to the given products and categories
+func (r *GormCouponRepository) Create(coupon *models.Coupon, productIDs, categoryIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(coupon).Error; err != nil {
+			return err
+		}
+		return couponScope.set(tx, coupon.ID, productIDs, categoryIDs)
+	})
+}
+
+// Update modifies an existing coupon and replaces the products and categories it is restricted to
+func (r *GormCouponRepository) Update(coupon *models.Coupon, productIDs, categoryIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Omit(clause.Associations, "CreatedAt").Save(coupon)
+		if result.Error != nil {
+			return result.Error
+		}
+		return couponScope.set(tx, coupon.ID, productIDs, categoryIDs)
+	})
+}
+
+// FindByID retrieves a coupon with its products and categories
+func (r *GormCouponRepository) FindByID(id uint) (*models.Coupon, error) {
+	var coupon models.Coupon
+	err := r.db.Preload("Products").Preload("Categories").First(&coupon, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// FindByCode retrieves a coupon with its products and categories by its upper-case code
+func (r *GormCouponRepository) FindByCode(code string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	err := r.db.Preload("Products").Preload("Categories").Where("code = ?", code).First(&coupon).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// List retrieves one page of coupons, newest first
+func (r *GormCouponRepository) List(page, pageSize int) ([]models.Coupon, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.Coupon{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var coupons []models.Coupon
+	err := r.db.Preload("Products").Preload("Categories").
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&coupons).Error
+	return coupons, total, err
+}
+
+// Delete removes a coupon that no order has redeemed, taking it out of every cart
+func (r *GormCouponRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var coupon models.Coupon
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&coupon, id).Error; err != nil {
+			return err
+		}
+
+		var redeemed int64
+		if err := tx.Model(&models.CouponRedemption{}).Where("coupon_id = ?", id).Count(&redeemed).Error; err != nil {
+			return err
+		}
+		if redeemed > 0 {
+			return ErrCouponRedeemed
+		}
+
+		if err := tx.Exec("DELETE FROM cart_coupons WHERE coupon_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := couponScope.set(tx, id, nil, nil); err != nil {
+			return err
+		}
+		return tx.Delete(&coupon).Error
+	})
+}
+
+// ListRedemptions retrieves one page of the redemptions of a coupon, newest first
+func (r *GormCouponRepository) ListRedemptions(couponID uint, page, pageSize int) ([]models.CouponRedemption, int64, error) {
+	db := r.db.Model(&models.CouponRedemption{}).Where("coupon_id = ?", couponID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var redemptions []models.CouponRedemption
+	err := db.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&redemptions).Error
+	return redemptions, total, err
+}
+
+// Usage counts the redemptions of a coupon, overall and by a user, that count
+// against its limits
+func (r *GormCouponRepository) Usage(couponID, userID uint) (CouponUsage, error) {
+	return couponUsage(r.db, couponID, userID)
+}
+
+// CheckLimits returns ErrCouponLimitReached when the user cannot redeem the coupon again
+func (r *GormCouponRepository) CheckLimits(coupon *models.Coupon, userID uint) error {
+	return checkCouponLimits(r.db, coupon, userID)
+}
+
+// Eligibility works out which of the given products a coupon applies to
+func (r *GormCouponRepository) Eligibility(coupon *models.Coupon, productIDs []uint) (AppliedCoupon, error) {
+	return couponEligibility(r.db, *coupon, productIDs)
+}
+
+// CartCoupons retrieves the coupons applied to a cart, with the given products of
+// the cart they apply to
+func (r *GormCouponRepository) CartCoupons(cartID uint, productIDs []uint) ([]AppliedCoupon, error) {
+	return cartCoupons(r.db, cartID, productIDs)
+}
+
+// AddToCart applies a coupon to a cart. Applying it twice has no effect.
+func (r *GormCouponRepository) AddToCart(cartID, couponID uint) error {
+	return r.db.Exec("INSERT INTO cart_coupons (cart_id, coupon_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+		cartID, couponID).Error
+}
+
+// RemoveFromCart takes a coupon off a cart
+func (r *GormCouponRepository) RemoveFromCart(cartID, couponID uint) error {
+	return r.db.Exec("DELETE FROM cart_coupons WHERE cart_id = ? AND coupon_id = ?", cartID, couponID).Error
+}
+
+// couponUsage counts the redemptions of a coupon by orders that were not cancelled
+func couponUsage(db *gorm.DB, couponID, userID uint) (CouponUsage, error) {
+	var usage CouponUsage
+	err := db.Model(&models.CouponRedemption{}).
+		Select("COUNT(*) AS total, COUNT(*) FILTER (WHERE coupon_redemptions.user_id = ?) AS by_user", userID).
+		Joins("JOIN orders ON orders.id = coupon_redemptions.order_id").
+		Where("coupon_redemptions.coupon_id = ? AND orders.status <> ?", couponID, models.OrderStatusCancelled).
+		Scan(&usage).Error
+	return usage, err
+}
+
+// checkCouponLimits returns ErrCouponLimitReached when the user cannot redeem the
+// coupon again. Run it with the coupon locked to enforce the limits.
+func checkCouponLimits(db *gorm.DB, coupon *models.Coupon, userID uint) error {
+	if coupon.UsageLimit == 0 && coupon.PerUserLimit == 0 {
+		return nil
+	}
+
+	usage, err := couponUsage(db, coupon.ID, userID)
+	if err != nil {
+		return err
+	}
+	return usage.check(coupon)
+}
+
+// check returns ErrCouponLimitReached when another redemption would take the coupon
+// past one of its limits
+func (u CouponUsage) check(coupon *models.Coupon) error {
+	if coupon.UsageLimit > 0 && u.Total >= int64(coupon.UsageLimit) {
+		return fmt.Errorf("%w: %s has been fully redeemed", ErrCouponLimitReached, coupon.Code)
+	}
+	if coupon.PerUserLimit > 0 && u.ByUser >= int64(coupon.PerUserLimit) {
+		return fmt.Errorf("%w: %s can be used %d times per customer", ErrCouponLimitReached, coupon.Code, coupon.PerUserLimit)
+	}
+	return nil
+}
+
+// couponEligibility works out which of the given products a coupon, with its
+// products and categories loaded, applies to
+func couponEligibility(db *gorm.DB, coupon models.Coupon, productIDs []uint) (AppliedCoupon, error) {
+	applied := AppliedCoupon{Coupon: coupon}
+	if !coupon.Restricted() {
+		return applied, nil
+	}
+
+	eligible, err := couponScope.eligible(db, coupon.ID, productIDs)
+	if err != nil {
+		return applied, err
+	}
+	applied.Eligible = eligible
+	return applied, nil
+}
+
+// cartCoupons loads the coupons applied to a cart with their eligibility
+func cartCoupons(db *gorm.DB, cartID uint, productIDs []uint) ([]AppliedCoupon, error) {
+	var coupons []models.Coupon
+	err := db.Preload("Products").Preload("Categories").
+		Joins("JOIN cart_coupons ON cart_coupons.coupon_id = coupons.id").
+		Where("cart_coupons.cart_id = ?", cartID).
+		Order("coupons.id").
+		Find(&coupons).Error
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]AppliedCoupon, 0, len(coupons))
+	for _, coupon := range coupons {
+		eligibility, err := couponEligibility(db, coupon, productIDs)
+		if err != nil {
+			return nil, err
+		}
+		applied = append(applied, eligibility)
+	}
+	return applied, nil
+}
+
+// redeemCartCoupons locks the coupons applied to a cart within tx, checks their usage
+// limits for the user and returns them with their eligibility for the given products
+func redeemCartCoupons(tx *gorm.DB, cartID, userID uint, productIDs []uint) ([]AppliedCoupon, error) {
+	// Lock the coupons so concurrent checkouts cannot redeem one past its limits
+	var locked []uint
+	err := tx.Model(&models.Coupon{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN (?)", tx.Table("cart_coupons").Select("coupon_id").Where("cart_id = ?", cartID)).
+		Order("id").
+		Pluck("id", &locked).Error
+	if err != nil || len(locked) == 0 {
+		return nil, err
+	}
+
+	coupons, err := cartCoupons(tx, cartID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, applied := range coupons {
+		if err := checkCouponLimits(tx, &applied.Coupon, userID); err != nil {
+			return nil, err
+		}
+	}
+	return coupons, nil
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+	"errors"
+	"testing"
+)
+
+func TestCouponUsageCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		usageLimit   int
+		perUserLimit int
+		usage        CouponUsage
+		err          error
+	}{
+		{"no limits", 0, 0, CouponUsage{Total: 1000, ByUser: 50}, nil},
+		{"under the usage limit", 10, 0, CouponUsage{Total: 9, ByUser: 9}, nil},
+		{"at the usage limit", 10, 0, CouponUsage{Total: 10}, ErrCouponLimitReached},
+		{"over the usage limit", 10, 0, CouponUsage{Total: 12}, ErrCouponLimitReached},
+		{"under the per-user limit", 0, 2, CouponUsage{Total: 500, ByUser: 1}, nil},
+		{"at the per-user limit", 0, 2, CouponUsage{Total: 2, ByUser: 2}, ErrCouponLimitReached},
+		{"per-user limit left, usage limit reached", 100, 2, CouponUsage{Total: 100, ByUser: 1}, ErrCouponLimitReached},
+		{"usage limit left, per-user limit reached", 100, 1, CouponUsage{Total: 5, ByUser: 1}, ErrCouponLimitReached},
+		{"both left", 100, 3, CouponUsage{Total: 99, ByUser: 2}, nil},
+	}
+	for _, tt := range tests {
+		coupon := &models.Coupon{Code: "SAVE10", UsageLimit: tt.usageLimit, PerUserLimit: tt.perUserLimit}
+		if err := tt.usage.check(coupon); !errors.Is(err, tt.err) {
+			t.Errorf("%s: check() = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrReservationExpired is returned when paying for an order whose stock reservation has lapsed
+	ErrReservationExpired = errors.New("stock reservation expired")
+	// ErrStockUnavailable is returned when reserved units are no longer in stock, e.g.
+	// because an admin lowered the stock after the reservation was placed
+	ErrStockUnavailable = errors.New("reserved stock is no longer available")
+)
+
+// ExpiredReservations is the outcome of a sweep over lapsed reservations
+type ExpiredReservations struct {
+	Cancelled  []uint // Unpaid orders cancelled because their reservation lapsed
+	ProductIDs []uint // Products whose reserved units were given back
+}
+
+// InventoryRepository defines the interface for stock reservation database operations
+type InventoryRepository interface {
+	ExpireReservations(now time.Time, limit int) (ExpiredReservations, error)
+}
+
+// GormInventoryRepository implements InventoryRepository using GORM
+type GormInventoryRepository struct {
+	db *gorm.DB
+}
+
+// NewInventoryRepository creates a new instance of GormInventoryRepository
+func NewInventoryRepository(db *gorm.DB) InventoryRepository {
+	return &GormInventoryRepository{
+		db: db,
+	}
+}
+
+// ExpireReservations releases the active reservations of at most limit orders that
+// expired before now, and cancels those orders if they are still pending
+func (r *GormInventoryRepository) ExpireReservations(now time.Time, limit int) (ExpiredReservations, error) {
+	var expired ExpiredReservations
+	var orderIDs []uint
+	err := r.db.Model(&models.StockReservation{}).
+		Distinct("order_id").
+		Where("status = ? AND expires_at <= ?", models.ReservationActive, now).
+		Order("order_id").
+		Limit(limit).
+		Pluck("order_id", &orderIDs).Error
+	if err != nil {
+		return expired, err
+	}
+
+	released := make(map[uint]bool)
+	for _, orderID := range orderIDs {
+		var productIDs []uint
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			// Lock the order first, as status transitions do, so a concurrent payment
+			// either commits the reservation before this runs or sees it released
+			var order models.Order
+			err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+				Select("id", "status").
+				First(&order, orderID).Error
+			if err != nil {
+				return err
+			}
+
+			lapsed := "order_id = ? AND status = ? AND expires_at <= ?"
+			err = tx.Model(&models.StockReservation{}).
+				Distinct("product_id").
+				Where(lapsed, orderID, models.ReservationActive, now).
+				Pluck("product_id", &productIDs).Error
+			if err != nil {
+				return err
+			}
+			result := tx.Model(&models.StockReservation{}).
+				Where(lapsed, orderID, models.ReservationActive, now).
+				Update("status", models.ReservationReleased)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 || order.Status != models.OrderStatusPending {
+				return nil
+			}
+
+			err = tx.Unscoped().Model(&order).
+				Updates(map[string]interface{}{"status": models.OrderStatusCancelled, "updated_at": gorm.Expr("CURRENT_TIMESTAMP")}).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Create(&models.OrderStatusHistory{
+				OrderID:    orderID,
+				FromStatus: models.OrderStatusPending,
+				ToStatus:   models.OrderStatusCancelled,
+				ChangedBy:  "system",
+				Note:       "Stock reservation expired before payment",
+			}).Error
+			if err != nil {
+				return err
+			}
+			expired.Cancelled = append(expired.Cancelled, orderID)
+			return nil
+		})
+		if err != nil {
+			return expired, fmt.Errorf("order %d: %w", orderID, err)
+		}
+		for _, productID := range productIDs {
+			if !released[productID] {
+				released[productID] = true
+				expired.ProductIDs = append(expired.ProductIDs, productID)
+			}
+		}
+	}
+	return expired, nil
+}
+
+// reservedStock sums the live reservations of the given products, per product and
+// per variant. Reservations past their expiry no longer count, even before the
+// sweeper releases them.
+func reservedStock(db *gorm.DB, productIDs []uint) (map[uint]int, map[uint]int, error) {
+	products := make(map[uint]int)
+	variants := make(map[uint]int)
+	if len(productIDs) == 0 {
+		return products, variants, nil
+	}
+
+	var rows []struct {
+		ProductID uint
+		VariantID uint
+		Quantity  int
+	}
+	err := liveReservations(db.Model(&models.StockReservation{})).
+		Select("product_id, variant_id, SUM(quantity) AS quantity").
+		Where("product_id IN ?", productIDs).
+		Group("product_id, variant_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, row := range rows {
+		products[row.ProductID] += row.Quantity
+		if row.VariantID != 0 {
+			variants[row.VariantID] += row.Quantity
+		}
+	}
+	return products, variants, nil
+}
+
+// liveReservations restricts a reservation query to the ones that still hold stock
+func liveReservations(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ? AND expires_at > ?", models.ReservationActive, time.Now())
+}
+
+// reservedQuantity returns a subquery of the units that live reservations hold of the
+// product whose ID is in the given column, so queries can compare stock to it
+func reservedQuantity(db *gorm.DB, productColumn string) *gorm.DB {
+	return liveReservations(db.Model(&models.StockReservation{})).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("product_id = " + productColumn)
+}
+
+// unreserved returns the part of stock not held by reservations
+func unreserved(stock, reserved int) int {
+	return max(stock-reserved, 0)
+}
+
+// setAvailability fills in the available stock of products and of their loaded variants
+func setAvailability(db *gorm.DB, products []models.Product) error {
+	ids := make([]uint, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+	reservedProducts, reservedVariants, err := reservedStock(db, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		products[i].Available = unreserved(products[i].Stock, reservedProducts[products[i].ID])
+		for j := range products[i].Variants {
+			variant := &products[i].Variants[j]
+			variant.Available = unreserved(variant.Stock, reservedVariants[variant.ID])
+		}
+	}
+	return nil
+}
+
+// setVariantAvailability fills in the available stock of variants
+func setVariantAvailability(db *gorm.DB, variants []models.ProductVariant) error {
+	ids := make([]uint, len(variants))
+	for i, variant := range variants {
+		ids[i] = variant.ProductID
+	}
+	_, reserved, err := reservedStock(db, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range variants {
+		variants[i].Available = unreserved(variants[i].Stock, reserved[variants[i].ID])
+	}
+	return nil
+}
+
+// setCartAvailability fills in the available stock of the loaded products and
+// variants of cart items
+func setCartAvailability(db *gorm.DB, items []models.CartItem) error {
+	ids := make([]uint, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+	reservedProducts, reservedVariants, err := reservedStock(db, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		items[i].Product.Available = unreserved(items[i].Product.Stock, reservedProducts[items[i].ProductID])
+		if items[i].Variant != nil {
+			items[i].Variant.Available = unreserved(items[i].Variant.Stock, reservedVariants[items[i].VariantID])
+		}
+	}
+	return nil
+}
+
+// reserveStock holds the units of every item of a stored order until the given
+// time. The products must be locked, and their availability checked, within tx.
+func reserveStock(tx *gorm.DB, order *models.Order, until time.Time) error {
+	if len(order.OrderItems) == 0 {
+		return nil
+	}
+
+	reservations := make([]models.StockReservation, len(order.OrderItems))
+	for i, item := range order.OrderItems {
+		reservations[i] = models.StockReservation{
+			OrderID:   order.ID,
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Status:    models.ReservationActive,
+			ExpiresAt: until,
+		}
+	}
+	return tx.Create(&reservations).Error
+}
+
+// commitReservations takes the reserved units of an order off the stock within tx and
+// records the sale in the stock ledger.
+// Orders placed before reservations existed have none and already took their stock.
+func commitReservations(tx *gorm.DB, orderID uint) error {
+	var reservations []models.StockReservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status = ?", orderID, models.ReservationActive).
+		Order("product_id, variant_id, id").
+		Find(&reservations).Error
+	if err != nil {
+		return err
+	}
+
+	if len(reservations) == 0 {
+		// Released reservations, e.g. of an order restored from the trash, no longer hold stock
+		var released int64
+		err := tx.Model(&models.StockReservation{}).
+			Where("order_id = ? AND status = ?", orderID, models.ReservationReleased).
+			Count(&released).Error
+		if err != nil {
+			return err
+		}
+		if released > 0 {
+			return ErrReservationExpired
+		}
+		return nil
+	}
+
+	now := time.Now()
+	for _, reservation := range reservations {
+		if !reservation.ExpiresAt.After(now) {
+			return ErrReservationExpired
+		}
+	}
+
+	if err := lockReservedStock(tx, reservations); err != nil {
+		return err
+	}
+	for _, reservation := range reservations {
+		// Product stock is the sum of variant stock, so both go down together
+		if reservation.VariantID != 0 {
+			result := tx.Model(&models.ProductVariant{}).
+				Where("id = ? AND stock >= ?", reservation.VariantID, reservation.Quantity).
+				UpdateColumn("stock", gorm.Expr("stock - ?", reservation.Quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrStockUnavailable
+			}
+		}
+
+		// The product may have been moved to the trash since the order was placed
+		result := tx.Unscoped().Model(&models.Product{}).
+			Where("id = ? AND stock >= ?", reservation.ProductID, reservation.Quantity).
+			UpdateColumn("stock", gorm.Expr("stock - ?", reservation.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStockUnavailable
+		}
+
+		err := takeStock(tx, models.StockMovement{
+			ProductID: reservation.ProductID,
+			VariantID: reservation.VariantID,
+			Type:      models.MovementSale,
+			Reference: fmt.Sprintf("order:%d", orderID),
+			CreatedBy: "system",
+		}, reservation.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&models.StockReservation{}).
+		Where("order_id = ? AND status = ?", orderID, models.ReservationActive).
+		Update("status", models.ReservationCommitted).Error
+}
+
+// releaseReservations gives the reserved units of an order back within tx. Its
+// products are locked first, as checkout reads their availability under that lock.
+func releaseReservations(tx *gorm.DB, orderID uint) error {
+	var reservations []models.StockReservation
+	err := tx.Select("product_id, variant_id").
+		Where("order_id = ? AND status = ?", orderID, models.ReservationActive).
+		Find(&reservations).Error
+	if err != nil {
+		return err
+	}
+	if err := lockReservedStock(tx, reservations); err != nil {
+		return err
+	}
+	return tx.Model(&models.StockReservation{}).
+		Where("order_id = ? AND status = ?", orderID, models.ReservationActive).
+		Update("status", models.ReservationReleased).Error
+}
+
+// lockReservedStock locks the product rows of the reservations, then their variant
+// rows, each by ascending id. Checkout locks in the same order, so the two cannot
+// deadlock. Products in the trash are locked too, as their reservations still hold.
+func lockReservedStock(tx *gorm.DB, reservations []models.StockReservation) error {
+	var productIDs, variantIDs []uint
+	for _, reservation := range reservations {
+		productIDs = append(productIDs, reservation.ProductID)
+		if reservation.VariantID != 0 {
+			variantIDs = append(variantIDs, reservation.VariantID)
+		}
+	}
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	var products []models.Product
+	err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id IN ?", productIDs).
+		Order("id").
+		Find(&products).Error
+	if err != nil || len(variantIDs) == 0 {
+		return err
+	}
+	var variants []models.ProductVariant
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id IN ?", variantIDs).
+		Order("id").
+		Find(&variants).Error
+}
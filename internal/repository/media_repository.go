@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MediaRepository defines the interface for product image database operations
+type MediaRepository interface {
+	Create(image *models.ProductImage) error
+	FindByID(productID uint, imageID uint) (*models.ProductImage, error)
+	ListByProduct(productID uint) ([]models.ProductImage, error)
+	Reorder(productID uint, imageIDs []uint) error
+	SetPrimary(productID uint, imageID uint) error
+	Delete(productID uint, imageID uint) (*models.ProductImage, error)
+}
+
+// GormMediaRepository implements MediaRepository using GORM
+type GormMediaRepository struct {
+	db *gorm.DB
+}
+
+// NewMediaRepository creates a new instance of GormMediaRepository
+func NewMediaRepository(db *gorm.DB) MediaRepository {
+	return &GormMediaRepository{
+		db: db,
+	}
+}
+
+// orderImages sorts images in display order
+func orderImages(db *gorm.DB) *gorm.DB {
+	return db.Order("position, id")
+}
+
+// Create appends an image to the end of its product's gallery. The first image of
+// a product becomes its primary image. The product row is locked so concurrent
+// uploads get distinct positions.
+func (r *GormMediaRepository) Create(image *models.ProductImage) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var product models.Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&product, image.ProductID).Error
+		if err != nil {
+			return err
+		}
+
+		var stats struct {
+			Count       int64
+			MaxPosition int
+		}
+		err = tx.Model(&models.ProductImage{}).
+			Select("COUNT(*) AS count, COALESCE(MAX(position), -1) AS max_position").
+			Where("product_id = ?", image.ProductID).
+			Scan(&stats).Error
+		if err != nil {
+			return err
+		}
+
+		image.Position = stats.MaxPosition + 1
+		image.IsPrimary = stats.Count == 0
+		return tx.Create(image).Error
+	})
+}
+
+// FindByID retrieves an image of a product
+func (r *GormMediaRepository) FindByID(productID uint, imageID uint) (*models.ProductImage, error) {
+	var image models.ProductImage
+	if err := r.db.Where("product_id = ?", productID).First(&image, imageID).Error; err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// ListByProduct retrieves the images of a product in display order
+func (r *GormMediaRepository) ListByProduct(productID uint) ([]models.ProductImage, error) {
+	var images []models.ProductImage
+	err := r.db.Scopes(orderImages).Where("product_id = ?", productID).Find(&images).Error
+	return images, err
+}
+
+// Reorder sets the positions of a product's images to their index in imageIDs.
+// The caller makes sure imageIDs lists every image of the product exactly once.
+func (r *GormMediaRepository) Reorder(productID uint, imageIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for position, id := range imageIDs {
+			err := tx.Model(&models.ProductImage{}).
+				Where("id = ? AND product_id = ?", id, productID).
+				Update("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetPrimary makes an image the primary image of its product
+func (r *GormMediaRepository) SetPrimary(productID uint, imageID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.ProductImage{}).
+			Where("product_id = ?", productID).
+			Update("is_primary", gorm.Expr("id = ?", imageID))
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var count int64
+		if err := tx.Model(&models.ProductImage{}).Where("id = ? AND product_id = ?", imageID, productID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Delete removes an image of a product and returns it, so the caller can remove
+// the stored files. When the primary image is deleted, the first remaining image
+// takes its place.
+func (r *GormMediaRepository) Delete(productID uint, imageID uint) (*models.ProductImage, error) {
+	var image models.ProductImage
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id = ?", productID).
+			First(&image, imageID).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&image).Error; err != nil {
+			return err
+		}
+		if !image.IsPrimary {
+			return nil
+		}
+
+		var next models.ProductImage
+		err = tx.Scopes(orderImages).Where("product_id = ?", productID).First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_primary", true).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
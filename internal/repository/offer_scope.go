@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+)
+
+// offerScope names the tables that restrict an offer, a coupon or a promotion, to
+// products and categories. An offer without any of them applies to every product.
+type offerScope struct {
+	table      string // Table of the offers
+	column     string // Column of the scope tables referencing the offer
+	products   string // Join table of the offer's products
+	categories string // Join table of the offer's categories
+}
+
+var (
+	couponScope    = offerScope{table: "coupons", column: "coupon_id", products: "coupon_products", categories: "coupon_categories"}
+	promotionScope = offerScope{table: "promotions", column: "promotion_id", products: "promotion_products", categories: "promotion_categories"}
+
+	// offerScopes lists every kind of offer that can be restricted to products and categories
+	offerScopes = []offerScope{couponScope, promotionScope}
+)
+
+// set replaces the products and categories an offer is restricted to within tx
+func (s offerScope) set(tx *gorm.DB, offerID uint, productIDs, categoryIDs []uint) error {
+	if err := tx.Exec("DELETE FROM "+s.products+" WHERE "+s.column+" = ?", offerID).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM "+s.categories+" WHERE "+s.column+" = ?", offerID).Error; err != nil {
+		return err
+	}
+	for _, productID := range productIDs {
+		if err := tx.Exec("INSERT INTO "+s.products+" ("+s.column+", product_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			offerID, productID).Error; err != nil {
+			return err
+		}
+	}
+	for _, categoryID := range categoryIDs {
+		if err := tx.Exec("INSERT INTO "+s.categories+" ("+s.column+", category_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			offerID, categoryID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eligible returns which of the given products a restricted offer applies to: the
+// products listed and the products of the categories listed, including their subcategories
+func (s offerScope) eligible(db *gorm.DB, offerID uint, productIDs []uint) (map[uint]bool, error) {
+	eligible := make(map[uint]bool)
+	if len(productIDs) == 0 {
+		return eligible, nil
+	}
+
+	var ids []uint
+	err := db.Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT category_id AS id FROM `+s.categories+` WHERE `+s.column+` = ?
+			UNION
+			SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT product_id FROM `+s.products+` WHERE `+s.column+` = ? AND product_id IN ?
+		UNION
+		SELECT product_id FROM product_categories WHERE category_id IN (SELECT id FROM tree) AND product_id IN ?`,
+		offerID, offerID, productIDs, productIDs).
+		Scan(&ids).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		eligible[id] = true
+	}
+	return eligible, nil
+}
+
+// remove takes a product or category out of the offers restricted to it within tx.
+// Offers left without any product or category are deactivated rather than opened
+// up to every product.
+func (s offerScope) remove(tx *gorm.DB, table, column string, id uint) error {
+	var offerIDs []uint
+	if err := tx.Table(table).Where(column+" = ?", id).Pluck(s.column, &offerIDs).Error; err != nil {
+		return err
+	}
+	if len(offerIDs) == 0 {
+		return nil
+	}
+
+	if err := tx.Exec("DELETE FROM "+table+" WHERE "+column+" = ?", id).Error; err != nil {
+		return err
+	}
+	return tx.Table(s.table).
+		Where("id IN ?", offerIDs).
+		Where("NOT EXISTS (SELECT 1 FROM " + s.products + " WHERE " + s.products + "." + s.column + " = " + s.table + ".id)").
+		Where("NOT EXISTS (SELECT 1 FROM " + s.categories + " WHERE " + s.categories + "." + s.column + " = " + s.table + ".id)").
+		UpdateColumns(map[string]interface{}{"active": false, "updated_at": gorm.Expr("CURRENT_TIMESTAMP")}).Error
+}
+
+// removeProductFromOfferScopes takes a product out of every offer restricted to it within tx
+func removeProductFromOfferScopes(tx *gorm.DB, productID uint) error {
+	for _, scope := range offerScopes {
+		if err := scope.remove(tx, scope.products, "product_id", productID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeCategoryFromOfferScopes takes a category out of every offer restricted to it
+// within tx. Offers for the category keep applying to the categories nested below it.
+func removeCategoryFromOfferScopes(tx *gorm.DB, categoryID uint) error {
+	for _, scope := range offerScopes {
+		if err := tx.Exec(`INSERT INTO `+scope.categories+` (`+scope.column+`, category_id)
+			SELECT `+scope.categories+`.`+scope.column+`, categories.id FROM `+scope.categories+`
+			JOIN categories ON categories.parent_id = `+scope.categories+`.category_id
+			WHERE `+scope.categories+`.category_id = ?
+			ON CONFLICT DO NOTHING`, categoryID).Error; err != nil {
+			return err
+		}
+		if err := scope.remove(tx, scope.categories, "category_id", categoryID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
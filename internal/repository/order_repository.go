@@ -2,16 +2,36 @@ package repository
 
 import (
     "ecommerce-app/internal/models"
+    "errors"
     "gorm.io/gorm"
+    "time"
 )
 
+// ErrStatusChanged is returned when an order's status no longer matches the expected one
+var ErrStatusChanged = errors.New("order status was changed concurrently")
+
+// OrderFilter narrows down order listings. Zero values leave a criterion unused.
+type OrderFilter struct {
+    Status string
+    From   *time.Time
+    To     *time.Time
+}
+
 // OrderRepository defines the interface for order-related database operations
 type OrderRepository interface {
     Create(order *models.Order) error
     FindByID(id uint) (*models.Order, error)
-    Update(order *models.Order) error
-    UpdateStatus(id uint, status string) error
+    FindByIDWithDetails(id uint) (*models.Order, error)
+    FindByIDForUser(id, userID uint) (*models.Order, error)
+    ListByUser(userID uint, filter OrderFilter, page, pageSize int) ([]models.Order, error)
+    CountByUser(userID uint, filter OrderFilter) (int64, error)
+    TransitionStatus(id uint, from, to, changedBy, note string) error
+    ListStatusHistory(orderID uint) ([]models.OrderStatusHistory, error)
+    ProductIDs(orderID uint) ([]uint, error)
     Delete(id uint) error
+    ListDeleted(page, pageSize int) ([]models.Order, int64, error)
+    Restore(id uint) error
+    PurgeDeleted(before time.Time, limit int) (PurgeResult, error)
     List() ([]models.Order, error)
     ListPaginated(page, pageSize int) ([]models.Order, error)
     ListWithUser() ([]models.Order, error)
@@ -46,19 +66,181 @@ func (r *GormOrderRepository) FindByID(id uint) (*models.Order, error) {
     return &order, nil
 }
 
-// Update modifies an existing order in the database
-func (r *GormOrderRepository) Update(order *models.Order) error {
-    return r.db.Save(order).Error
+// FindByIDWithDetails retrieves an order with its user and items including products.
+// Users and products in the trash are included, as the order still refers to them.
+func (r *GormOrderRepository) FindByIDWithDetails(id uint) (*models.Order, error) {
+    var order models.Order
+    err := r.db.Preload("User", unscoped).Preload("OrderItems.Product", unscoped).Preload("Taxes").First(&order, id).Error
+    if err != nil {
+        return nil, err
+    }
+    return &order, nil
+}
+
+// FindByIDForUser retrieves an order with its items only if it belongs to the given user
+func (r *GormOrderRepository) FindByIDForUser(id, userID uint) (*models.Order, error) {
+    var order models.Order
+    err := r.db.Preload("OrderItems.Product", unscoped).Preload("Taxes").Where("user_id = ?", userID).First(&order, id).Error
+    if err != nil {
+        return nil, err
+    }
+    return &order, nil
+}
+
+// ListByUser retrieves a user's orders, newest first, with filtering and pagination
+func (r *GormOrderRepository) ListByUser(userID uint, filter OrderFilter, page, pageSize int) ([]models.Order, error) {
+    var orders []models.Order
+    offset := (page - 1) * pageSize
+    err := r.userOrders(userID, filter).
+        Preload("OrderItems").
+        Order("created_at DESC, id DESC").
+        Offset(offset).Limit(pageSize).
+        Find(&orders).Error
+    return orders, err
+}
+
+// CountByUser returns the number of a user's orders matching the filter
+func (r *GormOrderRepository) CountByUser(userID uint, filter OrderFilter) (int64, error) {
+    var count int64
+    err := r.userOrders(userID, filter).Count(&count).Error
+    return count, err
+}
+
+// userOrders builds the base query for a user's orders matching the filter
+func (r *GormOrderRepository) userOrders(userID uint, filter OrderFilter) *gorm.DB {
+    query := r.db.Model(&models.Order{}).Where("user_id = ?", userID)
+    if filter.Status != "" {
+        query = query.Where("status = ?", filter.Status)
+    }
+    if filter.From != nil {
+        query = query.Where("created_at >= ?", *filter.From)
+    }
+    if filter.To != nil {
+        query = query.Where("created_at < ?", *filter.To)
+    }
+    return query
+}
+
+// ProductIDs returns the IDs of the products on an order
+func (r *GormOrderRepository) ProductIDs(orderID uint) ([]uint, error) {
+    var ids []uint
+    err := r.db.Model(&models.OrderItem{}).Distinct("product_id").Where("order_id = ?", orderID).Pluck("product_id", &ids).Error
+    return ids, err
+}
+
+// TransitionStatus moves an order from one status to another and records the change
+// in the status history. The update only applies while the order is still in the
+// expected status, so two concurrent transitions cannot both succeed. Paying for an
+// order takes its reserved units off the stock, and cancelling it releases them.
+func (r *GormOrderRepository) TransitionStatus(id uint, from, to, changedBy, note string) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        result := tx.Model(&models.Order{}).
+            Where("id = ? AND status = ?", id, from).
+            Updates(map[string]interface{}{"status": to, "updated_at": gorm.Expr("CURRENT_TIMESTAMP")})
+        if result.Error != nil {
+            return result.Error
+        }
+        if result.RowsAffected == 0 {
+            return ErrStatusChanged
+        }
+
+        switch to {
+        case models.OrderStatusPaid:
+            if err := commitReservations(tx, id); err != nil {
+                return err
+            }
+        case models.OrderStatusCancelled:
+            if err := releaseReservations(tx, id); err != nil {
+                return err
+            }
+        }
+
+        return tx.Create(&models.OrderStatusHistory{
+            OrderID:    id,
+            FromStatus: from,
+            ToStatus:   to,
+            ChangedBy:  changedBy,
+            Note:       note,
+        }).Error
+    })
 }
 
-// UpdateStatus updates just the status field of an order
-func (r *GormOrderRepository) UpdateStatus(id uint, status string) error {
-    return r.db.Model(&models.Order{}).Where("id = ?", id).Update("status", status).Error
+// ListStatusHistory retrieves the status transitions of an order, oldest first
+func (r *GormOrderRepository) ListStatusHistory(orderID uint) ([]models.OrderStatusHistory, error) {
+    var history []models.OrderStatusHistory
+    err := r.db.Where("order_id = ?", orderID).Order("created_at, id").Find(&history).Error
+    return history, err
 }
 
-// Delete removes an order from the database
+// Delete moves an order to the trash and releases its reserved stock
 func (r *GormOrderRepository) Delete(id uint) error {
-    return r.db.Delete(&models.Order{}, id).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        result := tx.Delete(&models.Order{}, id)
+        if result.Error != nil {
+            return result.Error
+        }
+        if result.RowsAffected == 0 {
+            return ErrRecordNotFound
+        }
+        return releaseReservations(tx, id)
+    })
+}
+
+// ListDeleted retrieves the orders in the trash with their users, most recently
+// deleted first, along with their total number
+func (r *GormOrderRepository) ListDeleted(page, pageSize int) ([]models.Order, int64, error) {
+    var total int64
+    if err := r.db.Unscoped().Model(&models.Order{}).Where("deleted_at IS NOT NULL").Count(&total).Error; err != nil {
+        return nil, 0, err
+    }
+
+    var orders []models.Order
+    err := r.db.Unscoped().Preload("User", unscoped).
+        Where("deleted_at IS NOT NULL").
+        Order("deleted_at DESC, id DESC").
+        Offset((page - 1) * pageSize).
+        Limit(pageSize).
+        Find(&orders).Error
+    return orders, total, err
+}
+
+// Restore takes an order out of the trash
+func (r *GormOrderRepository) Restore(id uint) error {
+    return restore(r.db, &models.Order{}, id)
+}
+
+// PurgeDeleted permanently removes at most limit orders that were moved to the
+// trash before the given time, along with their items, status history, taxes and reservations
+func (r *GormOrderRepository) PurgeDeleted(before time.Time, limit int) (PurgeResult, error) {
+    var result PurgeResult
+
+    ids, err := trashedBefore(r.db, &models.Order{}, before, limit)
+    if err != nil || len(ids) == 0 {
+        return result, err
+    }
+    err = r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Where("order_id IN ?", ids).Delete(&models.OrderItem{}).Error; err != nil {
+            return err
+        }
+        if err := tx.Where("order_id IN ?", ids).Delete(&models.OrderStatusHistory{}).Error; err != nil {
+            return err
+        }
+        if err := tx.Where("order_id IN ?", ids).Delete(&models.CouponRedemption{}).Error; err != nil {
+            return err
+        }
+        if err := tx.Where("order_id IN ?", ids).Delete(&models.OrderTax{}).Error; err != nil {
+            return err
+        }
+        if err := tx.Where("order_id IN ?", ids).Delete(&models.StockReservation{}).Error; err != nil {
+            return err
+        }
+        return tx.Unscoped().Delete(&models.Order{}, ids).Error
+    })
+    if err != nil {
+        return result, err
+    }
+    result.Purged = ids
+    return result, nil
 }
 
 // List retrieves all orders
@@ -79,7 +261,7 @@ func (r *GormOrderRepository) ListPaginated(page, pageSize int) ([]models.Order,
 // ListWithUser retrieves all orders with preloaded user data
 func (r *GormOrderRepository) ListWithUser() ([]models.Order, error) {
     var orders []models.Order
-    err := r.db.Preload("User").Find(&orders).Error
+    err := r.db.Preload("User", unscoped).Find(&orders).Error
     return orders, err
 }
 
@@ -87,7 +269,7 @@ func (r *GormOrderRepository) ListWithUser() ([]models.Order, error) {
 func (r *GormOrderRepository) ListWithUserPaginated(page, pageSize int) ([]models.Order, error) {
     var orders []models.Order
     offset := (page - 1) * pageSize
-    err := r.db.Preload("User").Offset(offset).Limit(pageSize).Find(&orders).Error
+    err := r.db.Preload("User", unscoped).Offset(offset).Limit(pageSize).Find(&orders).Error
     return orders, err
 }
 
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PriceListRepository defines the interface for customer group and price list database operations
+type PriceListRepository interface {
+	CreateGroup(group *models.CustomerGroup) error
+	UpdateGroup(group *models.CustomerGroup) error
+	FindGroup(id uint) (*models.CustomerGroup, error)
+	FindGroupByName(name string) (*models.CustomerGroup, error)
+	ListGroups() ([]models.CustomerGroup, error)
+	DeleteGroup(id uint) error
+	AssignUser(userID uint, groupID *uint) error
+	Create(list *models.PriceList) error
+	Update(list *models.PriceList) error
+	FindByID(id uint) (*models.PriceList, error)
+	List(groupID uint, page, pageSize int) ([]models.PriceList, int64, error)
+	Delete(id uint) error
+	UserEntries(userID uint, productIDs []uint) ([]models.PriceListEntry, error)
+}
+
+// GormPriceListRepository implements PriceListRepository using GORM
+type GormPriceListRepository struct {
+	db *gorm.DB
+}
+
+// NewPriceListRepository creates a new instance of GormPriceListRepository
+func NewPriceListRepository(db *gorm.DB) PriceListRepository {
+	return &GormPriceListRepository{
+		db: db,
+	}
+}
+
+// CreateGroup inserts a new customer group
+func (r *GormPriceListRepository) CreateGroup(group *models.CustomerGroup) error {
+	return r.db.Create(group).Error
+}
+
+// UpdateGroup modifies an existing customer group
+func (r *GormPriceListRepository) UpdateGroup(group *models.CustomerGroup) error {
+	return r.db.Omit("CreatedAt").Save(group).Error
+}
+
+// FindGroup retrieves a customer group by its ID
+func (r *GormPriceListRepository) FindGroup(id uint) (*models.CustomerGroup, error) {
+	var group models.CustomerGroup
+	if err := r.db.First(&group, id).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// FindGroupByName retrieves a customer group by its name
+func (r *GormPriceListRepository) FindGroupByName(name string) (*models.CustomerGroup, error) {
+	var group models.CustomerGroup
+	if err := r.db.Where("name = ?", name).First(&group).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ListGroups retrieves every customer group by name
+func (r *GormPriceListRepository) ListGroups() ([]models.CustomerGroup, error) {
+	var groups []models.CustomerGroup
+	err := r.db.Order("name").Find(&groups).Error
+	return groups, err
+}
+
+// DeleteGroup removes a customer group with its price lists. Its customers, including
+// those in the trash, go back to list prices.
+func (r *GormPriceListRepository) DeleteGroup(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var group models.CustomerGroup
+		if err := tx.Select("id").First(&group, id).Error; err != nil {
+			return err
+		}
+		err := tx.Unscoped().Model(&models.User{}).
+			Where("customer_group_id = ?", id).
+			Update("customer_group_id", nil).Error
+		if err != nil {
+			return err
+		}
+		lists := tx.Model(&models.PriceList{}).Select("id").Where("customer_group_id = ?", id)
+		if err := tx.Where("price_list_id IN (?)", lists).Delete(&models.PriceListEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("customer_group_id = ?", id).Delete(&models.PriceList{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&group).Error
+	})
+}
+
+// AssignUser puts a user in a customer group, or takes them out of theirs when
+// groupID is nil
+func (r *GormPriceListRepository) AssignUser(userID uint, groupID *uint) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", userID).Update("customer_group_id", groupID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Create inserts a new price list with its entries
+func (r *GormPriceListRepository) Create(list *models.PriceList) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(list).Error; err != nil {
+			return err
+		}
+		return setPriceListEntries(tx, list)
+	})
+}
+
+// Update modifies an existing price list and replaces its entries
+func (r *GormPriceListRepository) Update(list *models.PriceList) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations, "CreatedAt").Save(list).Error; err != nil {
+			return err
+		}
+		return setPriceListEntries(tx, list)
+	})
+}
+
+// FindByID retrieves a price list with its entries
+func (r *GormPriceListRepository) FindByID(id uint) (*models.PriceList, error) {
+	var list models.PriceList
+	err := r.db.Preload("Entries", orderPriceListEntries).First(&list, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// List retrieves one page of the price lists of a customer group, or of every group
+// when groupID is zero, in the order they win
+func (r *GormPriceListRepository) List(groupID uint, page, pageSize int) ([]models.PriceList, int64, error) {
+	query := func() *gorm.DB {
+		db := r.db.Model(&models.PriceList{})
+		if groupID != 0 {
+			db = db.Where("customer_group_id = ?", groupID)
+		}
+		return db
+	}
+
+	var total int64
+	if err := query().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var lists []models.PriceList
+	err := query().
+		Preload("Entries", orderPriceListEntries).
+		Order("customer_group_id, priority DESC, id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&lists).Error
+	return lists, total, err
+}
+
+// Delete removes a price list with its entries
+func (r *GormPriceListRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var list models.PriceList
+		if err := tx.Select("id").First(&list, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("price_list_id = ?", id).Delete(&models.PriceListEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&list).Error
+	})
+}
+
+// UserEntries retrieves the entries for the given products of the active price lists
+// of a user's customer group. Entries come by list, the winning list first, and
+// within a list by ascending minimum quantity. Users without a group have none.
+func (r *GormPriceListRepository) UserEntries(userID uint, productIDs []uint) ([]models.PriceListEntry, error) {
+	var entries []models.PriceListEntry
+	if userID == 0 || len(productIDs) == 0 {
+		return entries, nil
+	}
+	err := r.db.Select("price_list_entries.*").
+		Joins("JOIN price_lists ON price_lists.id = price_list_entries.price_list_id").
+		Joins("JOIN users ON users.customer_group_id = price_lists.customer_group_id").
+		Where("users.id = ? AND users.deleted_at IS NULL AND price_lists.active", userID).
+		Where("price_list_entries.product_id IN ?", productIDs).
+		Order("price_lists.priority DESC, price_lists.id, price_list_entries.min_quantity").
+		Find(&entries).Error
+	return entries, err
+}
+
+// orderPriceListEntries sorts the preloaded entries of a price list by product,
+// variant and minimum quantity
+func orderPriceListEntries(db *gorm.DB) *gorm.DB {
+	return db.Order("product_id, variant_id, min_quantity")
+}
+
+// setPriceListEntries replaces the entries of a price list with the ones it holds within tx
+func setPriceListEntries(tx *gorm.DB, list *models.PriceList) error {
+	if err := tx.Where("price_list_id = ?", list.ID).Delete(&models.PriceListEntry{}).Error; err != nil {
+		return err
+	}
+	for i := range list.Entries {
+		list.Entries[i].ID = 0
+		list.Entries[i].PriceListID = list.ID
+		if err := tx.Create(&list.Entries[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,19 +2,85 @@ package repository
 
 import (
 	"ecommerce-app/internal/models"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// errProductInUse keeps a trashed product that orders refer to from being purged
+var errProductInUse = errors.New("product is referenced by orders")
+
+// errBulkAborted rolls back a bulk transaction in which some product failed
+var errBulkAborted = errors.New("bulk operation aborted")
+
+// ProductSort is the order in which product queries return results
+type ProductSort string
+
+const (
+    ProductSortDefault   ProductSort = ""
+    ProductSortPriceAsc  ProductSort = "price"
+    ProductSortPriceDesc ProductSort = "-price"
+    ProductSortNewest    ProductSort = "newest"
+    ProductSortName      ProductSort = "name"
+)
+
+// ProductQuery describes a filtered, sorted and paginated product listing. Zero
+// values leave the corresponding filter out.
+type ProductQuery struct {
+    Search      string       // Matched against the name and description
+    CategoryIDs []uint       // Products assigned to any of these categories
+    MinPrice    *money.Money
+    MaxPrice    *money.Money
+    InStock     bool
+    Sort        ProductSort
+    Page        int
+    PageSize    int
+}
+
+// ProductChangeFunc applies a bulk change to a product locked for update, with its
+// variants loaded. Returning an error skips the product and reports the error.
+type ProductChangeFunc func(product *models.Product) error
+
+// BulkOutcome reports what a bulk operation did to one product
+type BulkOutcome struct {
+    ID  uint
+    Err error // Nil when the product was changed; ErrRecordNotFound for unknown IDs
+}
+
+// ProductImportRow is a product to insert or update by SKU. The categories of the
+// product are replaced when SetCategories is set and left alone otherwise.
+type ProductImportRow struct {
+    Product       models.Product
+    CategoryIDs   []uint
+    SetCategories bool
+}
+
 // ProductRepository defines the interface for product-related database operations
 type ProductRepository interface {
     Create(product *models.Product) error
     FindByID(id uint) (*models.Product, error)
-    FindByCategory(category string) ([]models.Product, error)
-    SearchByName(name string) ([]models.Product, error)
+    FindByIDs(ids []uint) ([]models.Product, error)
+    Query(query ProductQuery) ([]models.Product, int64, error)
+    Suggest(prefix string, limit int) ([]string, error)
     Update(product *models.Product) error
     Delete(id uint) error
+    ListDeleted(page, pageSize int) ([]models.Product, int64, error)
+    Restore(id uint) error
+    PurgeDeleted(before time.Time, limit int) (PurgeResult, []models.ProductImage, error)
+    HasVariants(id uint) (bool, error)
+    MatchingIDs(query ProductQuery, limit int) ([]uint, error)
+    BulkUpdate(ids []uint, change ProductChangeFunc, atomic bool) ([]BulkOutcome, bool, error)
+    BulkDelete(ids []uint, atomic bool) ([]BulkOutcome, bool, error)
+    ExistingSKUs(skus []string) (map[string]bool, error)
+    UpsertBySKU(rows []ProductImportRow) (int, error)
+    StreamAll(batchSize int, fn func(products []models.Product) error) error
     List() ([]models.Product, error)
+    ListWithCategories() ([]models.Product, error)
     ListPaginated(page, pageSize int) ([]models.Product, error)
     Count() (int64, error)
 }
@@ -31,29 +97,408 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
     }
 }
 
-// Create inserts a new product into the database
+// Create inserts a new product into the database, recording its opening stock in
+// the stock ledger and its price in the price history
 func (r *GormProductRepository) Create(product *models.Product) error {
-    return r.db.Create(product).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Omit(clause.Associations, "SalePrice", "SaleEndsAt").Create(product).Error; err != nil {
+            return err
+        }
+        if err := recordPrice(tx, product.ID, models.PriceUpdated, nil); err != nil {
+            return err
+        }
+        return syncLedger(tx, product.ID, "system")
+    })
 }
 
-// FindByID retrieves a product by its ID
+// FindByID retrieves a product by its ID, with its images and available stock
 func (r *GormProductRepository) FindByID(id uint) (*models.Product, error) {
     var product models.Product
-    err := r.db.First(&product, id).Error
+    err := r.db.Preload("Images", orderImages).First(&product, id).Error
     if err != nil {
         return nil, err
     }
-    return &product, nil
+    products := []models.Product{product}
+    if err := setAvailability(r.db, products); err != nil {
+        return nil, err
+    }
+    return &products[0], nil
 }
 
-// Update modifies an existing product in the database
+// FindByIDs retrieves the products with the given IDs, with their categories, images
+// and available stock, in no particular order
+func (r *GormProductRepository) FindByIDs(ids []uint) ([]models.Product, error) {
+    var products []models.Product
+    if len(ids) == 0 {
+        return products, nil
+    }
+    err := r.db.Preload("Categories").Preload("Images", orderImages).Where("id IN ?", ids).Find(&products).Error
+    if err != nil {
+        return nil, err
+    }
+    return products, setAvailability(r.db, products)
+}
+
+// Update modifies an existing product in the database. A change to its stock is
+// recorded in the stock ledger as an adjustment, and a change to its price in the
+// price history. The sale price is left to the sale scheduler.
 func (r *GormProductRepository) Update(product *models.Product) error {
-    return r.db.Save(product).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := lockProduct(tx, product.ID); err != nil {
+            return err
+        }
+        if err := tx.Omit(clause.Associations, "SalePrice", "SaleEndsAt").Save(product).Error; err != nil {
+            return err
+        }
+        if err := recordPrice(tx, product.ID, models.PriceUpdated, nil); err != nil {
+            return err
+        }
+        return syncLedger(tx, product.ID, "system")
+    })
 }
 
-// Delete removes a product from the database
+// Delete moves a product to the trash and removes it from every cart. Its variants,
+// images and categories are kept so it can be restored.
 func (r *GormProductRepository) Delete(id uint) error {
-    return r.db.Delete(&models.Product{}, id).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        return trashProduct(tx, id)
+    })
+}
+
+// ListDeleted retrieves the products in the trash, most recently deleted first,
+// along with their total number
+func (r *GormProductRepository) ListDeleted(page, pageSize int) ([]models.Product, int64, error) {
+    trashed := r.db.Unscoped().Model(&models.Product{}).Where("deleted_at IS NOT NULL")
+
+    var total int64
+    if err := trashed.Count(&total).Error; err != nil {
+        return nil, 0, err
+    }
+
+    var products []models.Product
+    err := r.db.Unscoped().Where("deleted_at IS NOT NULL").
+        Order("deleted_at DESC, id DESC").
+        Offset((page - 1) * pageSize).
+        Limit(pageSize).
+        Find(&products).Error
+    return products, total, err
+}
+
+// Restore takes a product out of the trash
+func (r *GormProductRepository) Restore(id uint) error {
+    return restore(r.db, &models.Product{}, id)
+}
+
+// PurgeDeleted permanently removes at most limit products that were moved to the
+// trash before the given time, along with their variants, images, categories and
+// stock ledger.
+// Products that orders refer to stay in the trash. The images of the removed
+// products are returned so their files can be removed.
+func (r *GormProductRepository) PurgeDeleted(before time.Time, limit int) (PurgeResult, []models.ProductImage, error) {
+    var result PurgeResult
+    var images []models.ProductImage
+
+    ids, err := trashedBefore(r.db, &models.Product{}, before, limit)
+    if err != nil {
+        return result, nil, err
+    }
+    for _, id := range ids {
+        var purged []models.ProductImage
+        err := r.db.Transaction(func(tx *gorm.DB) error {
+            var err error
+            purged, err = purgeProduct(tx, id)
+            return err
+        })
+        switch {
+        case errors.Is(err, errProductInUse):
+            result.Kept = append(result.Kept, id)
+        case err != nil:
+            return result, images, err
+        default:
+            result.Purged = append(result.Purged, id)
+            images = append(images, purged...)
+        }
+    }
+    return result, images, nil
+}
+
+// HasVariants reports whether a product has variants, in which case its stock is
+// the sum of the variant stock
+func (r *GormProductRepository) HasVariants(id uint) (bool, error) {
+    var count int64
+    err := r.db.Model(&models.ProductVariant{}).Where("product_id = ?", id).Count(&count).Error
+    return count > 0, err
+}
+
+// MatchingIDs returns the IDs of at most limit products matching the filters of a
+// query, in ID order. Sorting and pagination of the query are ignored.
+func (r *GormProductRepository) MatchingIDs(query ProductQuery, limit int) ([]uint, error) {
+    var ids []uint
+    err := r.applyFilters(r.db.Model(&models.Product{}), query).
+        Order("id").
+        Limit(limit).
+        Pluck("id", &ids).Error
+    return ids, err
+}
+
+// BulkUpdate applies change to each of the products in a single transaction. Every
+// product is changed within a savepoint, so a failing product does not undo the
+// others. When atomic is set, any failure rolls the whole transaction back. The
+// returned flag tells whether the changes were committed.
+func (r *GormProductRepository) BulkUpdate(ids []uint, change ProductChangeFunc, atomic bool) ([]BulkOutcome, bool, error) {
+    return r.bulk(ids, atomic, func(tx *gorm.DB, id uint) error {
+        var product models.Product
+        err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Variants").First(&product, id).Error
+        if err != nil {
+            return err
+        }
+        stock := product.Stock
+        if err := change(&product); err != nil {
+            return err
+        }
+
+        if err := tx.Omit(clause.Associations, "SalePrice", "SaleEndsAt").Save(&product).Error; err != nil {
+            return err
+        }
+        if err := recordPrice(tx, id, models.PriceUpdated, nil); err != nil {
+            return err
+        }
+        for i := range product.Variants {
+            if err := tx.Omit(clause.Associations).Save(&product.Variants[i]).Error; err != nil {
+                return err
+            }
+        }
+        if product.Stock != stock {
+            return syncLedger(tx, id, "system")
+        }
+        return nil
+    })
+}
+
+// BulkDelete moves each of the products to the trash like Delete, in a single
+// transaction. Failures are handled as in BulkUpdate.
+func (r *GormProductRepository) BulkDelete(ids []uint, atomic bool) ([]BulkOutcome, bool, error) {
+    return r.bulk(ids, atomic, trashProduct)
+}
+
+// bulk runs apply for each product ID within a savepoint of one transaction and
+// collects the outcomes
+func (r *GormProductRepository) bulk(ids []uint, atomic bool,
+    apply func(tx *gorm.DB, id uint) error) ([]BulkOutcome, bool, error) {
+    outcomes := make([]BulkOutcome, 0, len(ids))
+
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        failed := false
+        for _, id := range ids {
+            outcome := BulkOutcome{ID: id}
+            outcome.Err = tx.Transaction(func(tx *gorm.DB) error {
+                return apply(tx, id)
+            })
+            if outcome.Err != nil {
+                failed = true
+            }
+            outcomes = append(outcomes, outcome)
+        }
+        if failed && atomic {
+            return errBulkAborted
+        }
+        return nil
+    })
+    if errors.Is(err, errBulkAborted) {
+        return outcomes, false, nil
+    }
+    if err != nil {
+        return nil, false, err
+    }
+    return outcomes, true, nil
+}
+
+// ExistingSKUs reports which of the given SKUs are used by products
+func (r *GormProductRepository) ExistingSKUs(skus []string) (map[string]bool, error) {
+    existing := make(map[string]bool, len(skus))
+    if len(skus) == 0 {
+        return existing, nil
+    }
+
+    var found []string
+    if err := r.db.Model(&models.Product{}).Where("sku IN ?", skus).Pluck("sku", &found).Error; err != nil {
+        return nil, err
+    }
+    for _, sku := range found {
+        existing[sku] = true
+    }
+    return existing, nil
+}
+
+// UpsertBySKU inserts the products of rows whose SKU is new and updates the ones
+// whose SKU exists, in a single transaction, and returns the number of inserted
+// products. The product IDs are filled in. The stock of existing products with
+// variants is kept, as it is the sum of the variant stock; other stock changes are
+// recorded in the stock ledger and price changes in the price history. SKUs must be
+// unique within rows.
+func (r *GormProductRepository) UpsertBySKU(rows []ProductImportRow) (int, error) {
+    if len(rows) == 0 {
+        return 0, nil
+    }
+
+    created := 0
+    err := r.db.Transaction(func(tx *gorm.DB) error {
+        skus := make([]string, len(rows))
+        products := make([]models.Product, len(rows))
+        for i, row := range rows {
+            skus[i] = *row.Product.SKU
+            products[i] = row.Product
+        }
+
+        var existing int64
+        if err := tx.Model(&models.Product{}).Where("sku IN ?", skus).Count(&existing).Error; err != nil {
+            return err
+        }
+        created = len(rows) - int(existing)
+
+        err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+            Columns: []clause.Column{{Name: "sku"}},
+            DoUpdates: clause.Set{
+                {Column: clause.Column{Name: "name"}, Value: gorm.Expr("EXCLUDED.name")},
+                {Column: clause.Column{Name: "description"}, Value: gorm.Expr("EXCLUDED.description")},
+                {Column: clause.Column{Name: "price"}, Value: gorm.Expr("EXCLUDED.price")},
+                {Column: clause.Column{Name: "currency"}, Value: gorm.Expr("EXCLUDED.currency")},
+                {Column: clause.Column{Name: "stock"}, Value: gorm.Expr(
+                    "CASE WHEN EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id) " +
+                        "THEN products.stock ELSE EXCLUDED.stock END")},
+                {Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("CURRENT_TIMESTAMP")},
+                {Column: clause.Column{Name: "deleted_at"}, Value: nil},
+            },
+        }).Create(&products).Error
+        if err != nil {
+            return err
+        }
+
+        for i := range rows {
+            rows[i].Product.ID = products[i].ID
+            if err := syncLedger(tx, products[i].ID, "import"); err != nil {
+                return err
+            }
+            if err := recordPrice(tx, products[i].ID, models.PriceImported, nil); err != nil {
+                return err
+            }
+            if !rows[i].SetCategories {
+                continue
+            }
+            if err := tx.Exec("DELETE FROM product_categories WHERE product_id = ?", products[i].ID).Error; err != nil {
+                return err
+            }
+            for _, categoryID := range rows[i].CategoryIDs {
+                if err := tx.Exec("INSERT INTO product_categories (product_id, category_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+                    products[i].ID, categoryID).Error; err != nil {
+                    return err
+                }
+            }
+        }
+        return nil
+    })
+    return created, err
+}
+
+// StreamAll calls fn with every product, with its categories, in batches of
+// batchSize in ID order, so the whole table is never held in memory
+func (r *GormProductRepository) StreamAll(batchSize int, fn func(products []models.Product) error) error {
+    var batch []models.Product
+    return r.db.Preload("Categories").FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+        return fn(batch)
+    }).Error
+}
+
+// trashProduct moves a product to the trash within tx. Its cart lines are removed
+// as the product can no longer be bought.
+func trashProduct(tx *gorm.DB, id uint) error {
+    var product models.Product
+    if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&product, id).Error; err != nil {
+        return err
+    }
+    if err := tx.Where("product_id = ?", id).Delete(&models.CartItem{}).Error; err != nil {
+        return err
+    }
+    return tx.Delete(&product).Error
+}
+
+// purgeProduct permanently removes a trashed product and everything that belongs
+// to it within tx, unless orders refer to it
+func purgeProduct(tx *gorm.DB, id uint) ([]models.ProductImage, error) {
+    var product models.Product
+    err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+        Select("id").
+        Where("deleted_at IS NOT NULL").
+        First(&product, id).Error
+    if err != nil {
+        return nil, err
+    }
+
+    var ordered int64
+    if err := tx.Model(&models.OrderItem{}).Where("product_id = ?", id).Count(&ordered).Error; err != nil {
+        return nil, err
+    }
+    if ordered > 0 {
+        return nil, errProductInUse
+    }
+
+    var images []models.ProductImage
+    if err := tx.Where("product_id = ?", id).Find(&images).Error; err != nil {
+        return nil, err
+    }
+
+    if err := tx.Where("product_id = ?", id).Delete(&models.CartItem{}).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Exec("DELETE FROM product_categories WHERE product_id = ?", id).Error; err != nil {
+        return nil, err
+    }
+
+    variants := tx.Model(&models.ProductVariant{}).Select("id").Where("product_id = ?", id)
+    if err := tx.Exec("DELETE FROM product_variant_option_values WHERE product_variant_id IN (?)", variants).Error; err != nil {
+        return nil, err
+    }
+    options := tx.Model(&models.ProductOption{}).Select("id").Where("product_id = ?", id)
+    if err := tx.Where("option_id IN (?)", options).Delete(&models.ProductOptionValue{}).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Where("product_id = ?", id).Delete(&models.ProductOption{}).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Where("product_id = ?", id).Delete(&models.ProductVariant{}).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Where("product_id = ?", id).Delete(&models.ProductImage{}).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Where("product_id = ?", id).Delete(&models.StockLevel{}).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Where("product_id = ?", id).Delete(&models.StockMovement{}).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Where("product_id = ?", id).Delete(&models.StockSubscription{}).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Where("product_id = ?", id).Delete(&models.LowStockAlert{}).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Where("product_id = ?", id).Delete(&models.ProductSale{}).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Where("product_id = ?", id).Delete(&models.PriceChange{}).Error; err != nil {
+        return nil, err
+    }
+    if err := tx.Where("product_id = ?", id).Delete(&models.PriceListEntry{}).Error; err != nil {
+        return nil, err
+    }
+    if err := removeProductFromOfferScopes(tx, id); err != nil {
+        return nil, err
+    }
+    if err := tx.Unscoped().Delete(&product).Error; err != nil {
+        return nil, err
+    }
+    return images, nil
 }
 
 // List retrieves all products
@@ -63,33 +508,129 @@ func (r *GormProductRepository) List() ([]models.Product, error) {
     return products, err
 }
 
-// ListPaginated retrieves products with pagination
+// ListWithCategories retrieves all products with their categories and available stock
+func (r *GormProductRepository) ListWithCategories() ([]models.Product, error) {
+    var products []models.Product
+    err := r.db.Preload("Categories").Find(&products).Error
+    if err != nil {
+        return nil, err
+    }
+    return products, setAvailability(r.db, products)
+}
+
+// ListPaginated retrieves products with their images and available stock, with pagination
 func (r *GormProductRepository) ListPaginated(page, pageSize int) ([]models.Product, error) {
     var products []models.Product
     offset := (page - 1) * pageSize
-    err := r.db.Offset(offset).Limit(pageSize).Find(&products).Error
-    return products, err
+    err := r.db.Preload("Images", orderImages).Offset(offset).Limit(pageSize).Find(&products).Error
+    if err != nil {
+        return nil, err
+    }
+    return products, setAvailability(r.db, products)
 }
 
-// FindByCategory retrieves products by category
-func (r *GormProductRepository) FindByCategory(category string) ([]models.Product, error) {
+// Query retrieves one page of the products matching the query with their images and
+// available stock, along with the total number of matches
+func (r *GormProductRepository) Query(query ProductQuery) ([]models.Product, int64, error) {
+    filtered := r.applyFilters(r.db.Model(&models.Product{}), query)
+
+    var total int64
+    if err := filtered.Count(&total).Error; err != nil {
+        return nil, 0, err
+    }
+
     var products []models.Product
-    err := r.db.Where("category = ?", category).Find(&products).Error
-    return products, err
+    offset := (query.Page - 1) * query.PageSize
+    err := r.applyFilters(r.db.Preload("Images", orderImages), query).
+        Order(productOrder(query.Sort)).
+        Offset(offset).
+        Limit(query.PageSize).
+        Find(&products).Error
+    if err != nil {
+        return nil, 0, err
+    }
+    return products, total, setAvailability(r.db, products)
 }
 
-// SearchByName searches for products with names containing the search term
-func (r *GormProductRepository) SearchByName(name string) ([]models.Product, error) {
-    var products []models.Product
-    searchTerm := "%" + name + "%"
-    err := r.db.Where("name ILIKE ?", searchTerm).Find(&products).Error
-    return products, err
+// applyFilters adds the WHERE clauses of a product query
+func (r *GormProductRepository) applyFilters(db *gorm.DB, query ProductQuery) *gorm.DB {
+    if tsquery := prefixTSQuery(query.Search); tsquery != "" {
+        db = db.Where("search_vector @@ to_tsquery('english', ?)", tsquery)
+    }
+    if len(query.CategoryIDs) > 0 {
+        assigned := r.db.Table("product_categories").Select("product_id").Where("category_id IN ?", query.CategoryIDs)
+        db = db.Where("id IN (?)", assigned)
+    }
+    if query.MinPrice != nil {
+        db = db.Where(currentPrice+" >= ?", *query.MinPrice)
+    }
+    if query.MaxPrice != nil {
+        db = db.Where(currentPrice+" <= ?", *query.MaxPrice)
+    }
+    if query.InStock {
+        db = db.Where("stock > (?)", reservedQuantity(r.db, "products.id"))
+    }
+    return db
+}
+
+// Suggest returns the names of the best matching products for autocomplete
+func (r *GormProductRepository) Suggest(prefix string, limit int) ([]string, error) {
+    names := []string{}
+    tsquery := prefixTSQuery(prefix)
+    if tsquery == "" {
+        return names, nil
+    }
+
+    err := r.db.Raw(`
+        SELECT p.name
+        FROM products p, to_tsquery('english', ?) q
+        WHERE p.search_vector @@ q AND p.deleted_at IS NULL
+        ORDER BY ts_rank(p.search_vector, q) DESC, p.name
+        LIMIT ?`, tsquery, limit).
+        Scan(&names).Error
+    return names, err
+}
+
+// prefixTSQuery turns free text into a tsquery that requires every word and treats
+// the last one as a prefix. Anything but letters and digits is dropped so user input
+// can never produce tsquery syntax errors.
+func prefixTSQuery(text string) string {
+    terms := strings.FieldsFunc(text, func(r rune) bool {
+        return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+    })
+    if len(terms) == 0 {
+        return ""
+    }
+    terms[len(terms)-1] += ":*"
+    return strings.Join(terms, " & ")
+}
+
+// currentPrice is the SQL expression for the price a product sells for now: its sale
+// price while a sale runs, its regular price otherwise
+const currentPrice = "CASE WHEN sale_price IS NOT NULL AND (sale_ends_at IS NULL OR sale_ends_at > CURRENT_TIMESTAMP) " +
+    "THEN sale_price ELSE price END"
+
+// productOrder returns the ORDER BY clause for a sort, using the ID as a tie-breaker
+// so pages are stable
+func productOrder(sort ProductSort) string {
+    switch sort {
+    case ProductSortPriceAsc:
+        return currentPrice + " ASC, id ASC"
+    case ProductSortPriceDesc:
+        return currentPrice + " DESC, id ASC"
+    case ProductSortNewest:
+        return "created_at DESC, id DESC"
+    case ProductSortName:
+        return "name ASC, id ASC"
+    default:
+        return "id ASC"
+    }
 }
 
 // Count returns the total number of products with optimized query
 func (r *GormProductRepository) Count() (int64, error) {
     var count int64
     // Using a more efficient counting query that doesn't load the entire table
-    err := r.db.Table("products").Count(&count).Error
+    err := r.db.Model(&models.Product{}).Count(&count).Error
     return count, err
 }
\ No newline at end of file
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AppliedPromotion is a promotion evaluated against a cart, along with the products
+// of the cart it applies to
+type AppliedPromotion struct {
+	Promotion models.Promotion
+	Eligible  map[uint]bool // Eligible product IDs; nil when the promotion applies to every product
+}
+
+// Applies reports whether the promotion applies to a product
+func (p AppliedPromotion) Applies(productID uint) bool {
+	return p.Eligible == nil || p.Eligible[productID]
+}
+
+// PromotionRepository defines the interface for promotion-related database operations
+type PromotionRepository interface {
+	Create(promotion *models.Promotion, productIDs, categoryIDs []uint) error
+	Update(promotion *models.Promotion, productIDs, categoryIDs []uint) error
+	FindByID(id uint) (*models.Promotion, error)
+	List(page, pageSize int) ([]models.Promotion, int64, error)
+	Delete(id uint) error
+	Running(at time.Time, productIDs []uint) ([]AppliedPromotion, error)
+	Eligibility(promotion *models.Promotion, productIDs []uint) (AppliedPromotion, error)
+}
+
+// GormPromotionRepository implements PromotionRepository using GORM
+type GormPromotionRepository struct {
+	db *gorm.DB
+}
+
+// NewPromotionRepository creates a new instance of GormPromotionRepository
+func NewPromotionRepository(db *gorm.DB) PromotionRepository {
+	return &GormPromotionRepository{
+		db: db,
+	}
+}
+
+// Create inserts a new promotion with its tiers, restricted to the given products and categories
+func (r *GormPromotionRepository) Create(promotion *models.Promotion, productIDs, categoryIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(promotion).Error; err != nil {
+			return err
+		}
+		if err := setPromotionTiers(tx, promotion); err != nil {
+			return err
+		}
+		return promotionScope.set(tx, promotion.ID, productIDs, categoryIDs)
+	})
+}
+
+// Update modifies an existing promotion and replaces its tiers and the products and
+// categories it is restricted to
+func (r *GormPromotionRepository) Update(promotion *models.Promotion, productIDs, categoryIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations, "CreatedAt").Save(promotion).Error; err != nil {
+			return err
+		}
+		if err := setPromotionTiers(tx, promotion); err != nil {
+			return err
+		}
+		return promotionScope.set(tx, promotion.ID, productIDs, categoryIDs)
+	})
+}
+
+// FindByID retrieves a promotion with its tiers, products and categories
+func (r *GormPromotionRepository) FindByID(id uint) (*models.Promotion, error) {
+	var promotion models.Promotion
+	err := preloadPromotion(r.db).First(&promotion, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// List retrieves one page of promotions in evaluation order
+func (r *GormPromotionRepository) List(page, pageSize int) ([]models.Promotion, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.Promotion{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var promotions []models.Promotion
+	err := preloadPromotion(r.db).
+		Order("priority DESC, id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&promotions).Error
+	return promotions, total, err
+}
+
+// Delete removes a promotion with its tiers. Orders keep the discounts it granted.
+func (r *GormPromotionRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var promotion models.Promotion
+		if err := tx.Select("id").First(&promotion, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("promotion_id = ?", id).Delete(&models.PromotionTier{}).Error; err != nil {
+			return err
+		}
+		if err := promotionScope.set(tx, id, nil, nil); err != nil {
+			return err
+		}
+		return tx.Delete(&promotion).Error
+	})
+}
+
+// Running retrieves the promotions that are active and within their schedule at a
+// time, in evaluation order, with the given products they apply to
+func (r *GormPromotionRepository) Running(at time.Time, productIDs []uint) ([]AppliedPromotion, error) {
+	var promotions []models.Promotion
+	err := preloadPromotion(r.db).
+		Where("active AND (starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", at, at).
+		Order("priority DESC, id").
+		Find(&promotions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]AppliedPromotion, 0, len(promotions))
+	for i := range promotions {
+		eligibility, err := r.Eligibility(&promotions[i], productIDs)
+		if err != nil {
+			return nil, err
+		}
+		applied = append(applied, eligibility)
+	}
+	return applied, nil
+}
+
+// Eligibility works out which of the given products a promotion, with its products
+// and categories loaded, applies to
+func (r *GormPromotionRepository) Eligibility(promotion *models.Promotion, productIDs []uint) (AppliedPromotion, error) {
+	applied := AppliedPromotion{Promotion: *promotion}
+	if !promotion.Restricted() {
+		return applied, nil
+	}
+
+	eligible, err := promotionScope.eligible(r.db, promotion.ID, productIDs)
+	if err != nil {
+		return applied, err
+	}
+	applied.Eligible = eligible
+	return applied, nil
+}
+
+// preloadPromotion loads the tiers, lowest threshold first, products and categories
+// of the promotions queried
+func preloadPromotion(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tiers", func(db *gorm.DB) *gorm.DB {
+		return db.Order("threshold")
+	}).Preload("Products").Preload("Categories")
+}
+
+// setPromotionTiers replaces the tiers of a promotion with the ones it holds within tx
+func setPromotionTiers(tx *gorm.DB, promotion *models.Promotion) error {
+	if err := tx.Where("promotion_id = ?", promotion.ID).Delete(&models.PromotionTier{}).Error; err != nil {
+		return err
+	}
+	for i := range promotion.Tiers {
+		promotion.Tiers[i].ID = 0
+		promotion.Tiers[i].PromotionID = promotion.ID
+		if err := tx.Create(&promotion.Tiers[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
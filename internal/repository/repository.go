@@ -1,9 +1,51 @@
 package repository
 
 import (
+    "time"
     "gorm.io/gorm"
 )
 
+// ErrRecordNotFound is returned when a lookup matches no rows, so services can
+// detect missing records without depending on GORM directly
+var ErrRecordNotFound = gorm.ErrRecordNotFound
+
+// PurgeResult reports which trashed rows a purge removed for good
+type PurgeResult struct {
+    Purged []uint
+    Kept   []uint // Still referenced elsewhere, so left in the trash
+}
+
+// unscoped lets a preload include soft-deleted rows
+func unscoped(db *gorm.DB) *gorm.DB {
+    return db.Unscoped()
+}
+
+// restore takes the soft-deleted row of model with the given ID out of the trash
+func restore(db *gorm.DB, model interface{}, id uint) error {
+    result := db.Unscoped().Model(model).
+        Where("id = ? AND deleted_at IS NOT NULL", id).
+        Update("deleted_at", nil)
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return ErrRecordNotFound
+    }
+    return nil
+}
+
+// trashedBefore returns the IDs of at most limit rows of model that were
+// soft-deleted before the given time, oldest first
+func trashedBefore(db *gorm.DB, model interface{}, before time.Time, limit int) ([]uint, error) {
+    var ids []uint
+    err := db.Unscoped().Model(model).
+        Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+        Order("deleted_at, id").
+        Limit(limit).
+        Pluck("id", &ids).Error
+    return ids, err
+}
+
 // Repository provides a generic interface for database operations
 type Repository[T any] interface {
     Create(entity *T) error
@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrSaleOverlap is returned when a sale would run at the same time as another
+	// scheduled or active sale of the product
+	ErrSaleOverlap = errors.New("sale overlaps another sale of the product")
+	// ErrSaleClosed is returned when cancelling a sale that has ended or was cancelled
+	ErrSaleClosed = errors.New("sale has already ended")
+)
+
+// SaleSchedule is the outcome of applying the sale schedule
+type SaleSchedule struct {
+	Started    int    // Sales whose price took effect
+	Ended      int    // Sales whose price was reverted or that were missed entirely
+	ProductIDs []uint // Products whose sale price changed
+}
+
+// SaleRepository defines the interface for sale price and price history database operations
+type SaleRepository interface {
+	Create(sale *models.ProductSale) error
+	FindByID(id uint) (*models.ProductSale, error)
+	ListByProduct(productID uint, page, pageSize int) ([]models.ProductSale, int64, error)
+	Cancel(id uint) (*models.ProductSale, error)
+	ApplySchedule(now time.Time, limit int) (SaleSchedule, error)
+	PriceHistory(productID uint, page, pageSize int) ([]models.PriceChange, int64, error)
+}
+
+// GormSaleRepository implements SaleRepository using GORM
+type GormSaleRepository struct {
+	db *gorm.DB
+}
+
+// NewSaleRepository creates a new instance of GormSaleRepository
+func NewSaleRepository(db *gorm.DB) SaleRepository {
+	return &GormSaleRepository{
+		db: db,
+	}
+}
+
+// Create schedules a sale. It fails with ErrSaleOverlap when the product has another
+// scheduled or active sale running at any time within the sale.
+func (r *GormSaleRepository) Create(sale *models.ProductSale) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the product so concurrent sales for it are checked one after the other
+		if err := lockProduct(tx, sale.ProductID); err != nil {
+			return err
+		}
+
+		overlapping := tx.Model(&models.ProductSale{}).
+			Where("product_id = ? AND status IN ?", sale.ProductID, []string{models.SaleScheduled, models.SaleActive}).
+			Where("ends_at IS NULL OR ends_at > ?", sale.StartsAt)
+		if sale.EndsAt != nil {
+			overlapping = overlapping.Where("starts_at < ?", *sale.EndsAt)
+		}
+		var count int64
+		if err := overlapping.Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSaleOverlap
+		}
+
+		sale.Status = models.SaleScheduled
+		return tx.Create(sale).Error
+	})
+}
+
+// FindByID retrieves a sale by its ID
+func (r *GormSaleRepository) FindByID(id uint) (*models.ProductSale, error) {
+	var sale models.ProductSale
+	if err := r.db.First(&sale, id).Error; err != nil {
+		return nil, err
+	}
+	return &sale, nil
+}
+
+// ListByProduct retrieves one page of the sales of a product, latest start first
+func (r *GormSaleRepository) ListByProduct(productID uint, page, pageSize int) ([]models.ProductSale, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.ProductSale{}).Where("product_id = ?", productID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var sales []models.ProductSale
+	err := r.db.Where("product_id = ?", productID).
+		Order("starts_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&sales).Error
+	return sales, total, err
+}
+
+// Cancel cancels a scheduled or active sale. The price of an active sale is reverted
+// at once.
+func (r *GormSaleRepository) Cancel(id uint) (*models.ProductSale, error) {
+	var sale models.ProductSale
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id", "product_id").First(&sale, id).Error; err != nil {
+			return err
+		}
+		// Lock the product before the sale, in the order the scheduler does
+		if err := lockSaleProduct(tx, sale.ProductID); err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sale, id).Error; err != nil {
+			return err
+		}
+
+		switch sale.Status {
+		case models.SaleScheduled:
+		case models.SaleActive:
+			if err := setSalePrice(tx, sale.ProductID, nil, nil); err != nil {
+				return err
+			}
+			if err := recordPrice(tx, sale.ProductID, models.PriceSaleCancelled, &sale.ID); err != nil {
+				return err
+			}
+		default:
+			return ErrSaleClosed
+		}
+
+		sale.Status = models.SaleCancelled
+		return tx.Save(&sale).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sale, nil
+}
+
+// ApplySchedule ends the active sales that are over at now, reverting the sale price
+// of their products, and starts the scheduled sales that are due, setting it. A due
+// sale that is already over is ended without ever taking effect. Each call handles
+// at most limit sales of each kind; the rest are picked up by the next run.
+func (r *GormSaleRepository) ApplySchedule(now time.Time, limit int) (SaleSchedule, error) {
+	var schedule SaleSchedule
+
+	// End sales first, so a sale starting as the previous one ends finds its product free
+	var ending []models.ProductSale
+	err := r.db.Select("id", "product_id").
+		Where("status = ? AND ends_at <= ?", models.SaleActive, now).
+		Order("ends_at, id").
+		Limit(limit).
+		Find(&ending).Error
+	if err != nil {
+		return schedule, err
+	}
+	for _, due := range ending {
+		changed := false
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			sale, err := lockDueSale(tx, due, models.SaleActive)
+			if err != nil || sale == nil {
+				return err
+			}
+			if err := setSalePrice(tx, sale.ProductID, nil, nil); err != nil {
+				return err
+			}
+			if err := recordPrice(tx, sale.ProductID, models.PriceSaleEnded, &sale.ID); err != nil {
+				return err
+			}
+			sale.Status = models.SaleEnded
+			changed = true
+			return tx.Save(sale).Error
+		})
+		if err != nil {
+			return schedule, err
+		}
+		if changed {
+			schedule.Ended++
+			schedule.ProductIDs = append(schedule.ProductIDs, due.ProductID)
+		}
+	}
+
+	var starting []models.ProductSale
+	err = r.db.Select("id", "product_id").
+		Where("status = ? AND starts_at <= ?", models.SaleScheduled, now).
+		Order("starts_at, id").
+		Limit(limit).
+		Find(&starting).Error
+	if err != nil {
+		return schedule, err
+	}
+	for _, due := range starting {
+		started, missed := false, false
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			sale, err := lockDueSale(tx, due, models.SaleScheduled)
+			if err != nil || sale == nil {
+				return err
+			}
+			if sale.EndsAt != nil && !now.Before(*sale.EndsAt) {
+				sale.Status = models.SaleEnded
+				missed = true
+				return tx.Save(sale).Error
+			}
+			if err := setSalePrice(tx, sale.ProductID, &sale.Price, sale.EndsAt); err != nil {
+				return err
+			}
+			if err := recordPrice(tx, sale.ProductID, models.PriceSaleStarted, &sale.ID); err != nil {
+				return err
+			}
+			sale.Status = models.SaleActive
+			started = true
+			return tx.Save(sale).Error
+		})
+		if err != nil {
+			return schedule, err
+		}
+		switch {
+		case started:
+			schedule.Started++
+			schedule.ProductIDs = append(schedule.ProductIDs, due.ProductID)
+		case missed:
+			schedule.Ended++
+		}
+	}
+
+	return schedule, nil
+}
+
+// PriceHistory retrieves one page of the price changes of a product, latest first
+func (r *GormSaleRepository) PriceHistory(productID uint, page, pageSize int) ([]models.PriceChange, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.PriceChange{}).Where("product_id = ?", productID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var changes []models.PriceChange
+	err := r.db.Where("product_id = ?", productID).
+		Order("id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&changes).Error
+	return changes, total, err
+}
+
+// lockDueSale locks the product of a sale picked by the scheduler and then the sale
+// itself within tx. It returns nil when the sale no longer has the expected status,
+// e.g. because it was cancelled in the meantime.
+func lockDueSale(tx *gorm.DB, due models.ProductSale, status string) (*models.ProductSale, error) {
+	if err := lockSaleProduct(tx, due.ProductID); err != nil {
+		return nil, err
+	}
+	var sale models.ProductSale
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sale, due.ID).Error; err != nil {
+		return nil, err
+	}
+	if sale.Status != status {
+		return nil, nil
+	}
+	return &sale, nil
+}
+
+// lockSaleProduct locks a product row for a sale price change within tx. Sales of
+// products in the trash still run, so they are in effect if the product is restored.
+func lockSaleProduct(tx *gorm.DB, productID uint) error {
+	var product models.Product
+	return tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&product, productID).Error
+}
+
+// setSalePrice sets the sale price of a product and the time the sale ends within
+// tx, or clears both when price is nil
+func setSalePrice(tx *gorm.DB, productID uint, price *money.Money, endsAt *time.Time) error {
+	values := map[string]interface{}{"sale_price": nil, "sale_ends_at": nil, "updated_at": gorm.Expr("CURRENT_TIMESTAMP")}
+	if price != nil {
+		values["sale_price"] = *price
+		values["sale_ends_at"] = endsAt
+	}
+	return tx.Unscoped().Model(&models.Product{}).Where("id = ?", productID).Updates(values).Error
+}
+
+// recordPrice appends the current regular and sale price of a product to its price
+// history within tx, unless they are the ones last recorded
+func recordPrice(tx *gorm.DB, productID uint, reason string, saleID *uint) error {
+	var product models.Product
+	if err := tx.Unscoped().Select("id", "price", "sale_price", "currency").First(&product, productID).Error; err != nil {
+		return err
+	}
+
+	var last models.PriceChange
+	err := tx.Where("product_id = ?", productID).Order("id DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return err
+	}
+	if last.ID != 0 && last.Price == product.Price && samePrice(last.SalePrice, product.SalePrice) {
+		return nil
+	}
+
+	return tx.Create(&models.PriceChange{
+		ProductID: productID,
+		Price:     product.Price,
+		SalePrice: product.SalePrice,
+		Reason:    reason,
+		SaleID:    saleID,
+	}).Error
+}
+
+// samePrice reports whether two optional prices are equal
+func samePrice(a, b *money.Money) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"ecommerce-app/internal/search"
+	"ecommerce-app/pkg/money"
+	"fmt"
+	"html"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ts_headline marks matches with control characters rather than <mark> tags, so the
+// product text can be HTML-escaped before the markers are turned into tags
+const (
+	headlineStart = "\x02"
+	headlineStop  = "\x03"
+
+	nameHeadlineOptions    = `StartSel="` + headlineStart + `", StopSel="` + headlineStop + `", HighlightAll=true`
+	snippetHeadlineOptions = `StartSel="` + headlineStart + `", StopSel="` + headlineStop + `", MaxWords=35, MinWords=15, MaxFragments=2`
+)
+
+// headlineMarks turns the markers of an escaped headline into <mark> tags
+var headlineMarks = strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>")
+
+// markHeadline escapes a ts_headline result for HTML and wraps its matches in <mark> tags
+func markHeadline(headline string) string {
+	return headlineMarks.Replace(html.EscapeString(headline))
+}
+
+// PostgresSearchIndex implements search.SearchIndex with Postgres full-text search
+// over the generated products.search_vector column
+type PostgresSearchIndex struct {
+	db     *gorm.DB
+	bounds []money.Money
+}
+
+// NewPostgresSearchIndex creates a new instance of PostgresSearchIndex
+func NewPostgresSearchIndex(db *gorm.DB, bounds []money.Money) search.SearchIndex {
+	return &PostgresSearchIndex{
+		db:     db,
+		bounds: bounds,
+	}
+}
+
+// Index is a no-op because Postgres maintains the search vector itself
+func (idx *PostgresSearchIndex) Index(doc search.Document) error {
+	return nil
+}
+
+// Remove is a no-op because deleted rows leave the search vector with them, and
+// soft-deleted rows are filtered out when searching
+func (idx *PostgresSearchIndex) Remove(id uint) error {
+	return nil
+}
+
+// Replace is a no-op because the search vector is part of the product rows themselves
+func (idx *PostgresSearchIndex) Replace(docs []search.Document) error {
+	return nil
+}
+
+// Facets, each counted with every filter applied except its own
+const (
+	facetCategory     = "category"
+	facetPrice        = "price"
+	facetAvailability = "availability"
+)
+
+// categoryAncestors is a derived table pairing every category with itself and each of
+// its ancestors, so a product filed under a subcategory is found under its parents
+const categoryAncestors = `(WITH RECURSIVE ancestors AS (
+		SELECT id AS category_id, id AS ancestor_id, parent_id FROM categories
+		UNION
+		SELECT a.category_id, c.id, c.parent_id FROM ancestors a JOIN categories c ON c.id = a.parent_id
+	) SELECT category_id, ancestor_id FROM ancestors)`
+
+// Search ranks the products matching the query text with ts_rank. The last term
+// matches as a prefix so partially typed words find results. Filtering, counting and
+// paging run in SQL, and each facet is counted by a GROUP BY query of its own.
+func (idx *PostgresSearchIndex) Search(query search.Query) (*search.Result, error) {
+	tsquery := prefixTSQuery(query.Text)
+
+	// matching selects the products matching the text and every filter but skip. A
+	// product is in stock while live reservations leave some of it to sell.
+	matching := func(skip string) *gorm.DB {
+		db := idx.db.Table("products p").Where("p.deleted_at IS NULL")
+		if tsquery != "" {
+			db = db.Where("p.search_vector @@ to_tsquery('english', ?)", tsquery)
+		}
+		if query.Category != "" && skip != facetCategory {
+			filed := idx.db.Table("product_categories pc").
+				Select("pc.product_id").
+				Joins("JOIN "+categoryAncestors+" a ON a.category_id = pc.category_id").
+				Joins("JOIN categories c ON c.id = a.ancestor_id").
+				Where("c.slug = ?", query.Category)
+			db = db.Where("p.id IN (?)", filed)
+		}
+		if skip != facetPrice {
+			if query.MinPrice != nil {
+				db = db.Where(currentPrice+" >= ?", *query.MinPrice)
+			}
+			if query.MaxPrice != nil {
+				db = db.Where(currentPrice+" <= ?", *query.MaxPrice)
+			}
+		}
+		if query.InStock && skip != facetAvailability {
+			db = db.Where("p.stock > (?)", reservedQuantity(idx.db, "p.id"))
+		}
+		return db
+	}
+
+	result := &search.Result{Hits: []search.Hit{}, Facets: search.NewFacets(idx.bounds)}
+	if err := matching("").Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID   uint
+		Rank float64
+	}
+	page := matching("")
+	if tsquery != "" {
+		page = page.Select("p.id, ts_rank(p.search_vector, to_tsquery('english', ?)) AS rank", tsquery)
+	} else {
+		page = page.Select("p.id, 0 AS rank")
+	}
+	err := page.Order("rank DESC, p.id").
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result.Hits = append(result.Hits, search.Hit{ID: row.ID, Score: row.Rank})
+	}
+
+	var categories []search.FacetCount
+	err = matching(facetCategory).
+		Select("c.slug AS value, COUNT(DISTINCT p.id) AS count").
+		Joins("JOIN product_categories pc ON pc.product_id = p.id").
+		Joins("JOIN " + categoryAncestors + " a ON a.category_id = pc.category_id").
+		Joins("JOIN categories c ON c.id = a.ancestor_id").
+		Group("c.slug").
+		Order("count DESC, value").
+		Scan(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	result.Facets.Categories = append(result.Facets.Categories, categories...)
+
+	var buckets []struct {
+		Bucket int
+		Count  int
+	}
+	bucket, bounds := priceBucket(idx.bounds)
+	err = matching(facetPrice).
+		Select(bucket+" AS bucket, COUNT(*) AS count", bounds...).
+		Group("bucket").
+		Scan(&buckets).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range buckets {
+		result.Facets.Prices[b.Bucket].Count = b.Count
+	}
+
+	var availability []struct {
+		InStock bool
+		Count   int
+	}
+	err = matching(facetAvailability).
+		Select("p.stock > (?) AS in_stock, COUNT(*) AS count", reservedQuantity(idx.db, "p.id")).
+		Group("in_stock").
+		Scan(&availability).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range availability {
+		if a.InStock {
+			result.Facets.Availability[0].Count = a.Count
+		} else {
+			result.Facets.Availability[1].Count = a.Count
+		}
+	}
+
+	if tsquery == "" || len(result.Hits) == 0 {
+		return result, nil
+	}
+
+	ids := make([]uint, len(result.Hits))
+	for i, hit := range result.Hits {
+		ids[i] = hit.ID
+	}
+	var headlines []struct {
+		ID            uint
+		NameHighlight string
+		Snippet       string
+	}
+	err = idx.db.Raw(`
+		SELECT p.id,
+			ts_headline('english', translate(p.name, ?, ''), q, ?) AS name_highlight,
+			ts_headline('english', translate(coalesce(p.description, ''), ?, ''), q, ?) AS snippet
+		FROM products p, to_tsquery('english', ?) q
+		WHERE p.id IN ?`,
+		// Markers already in the text are dropped so they cannot open tags of their own
+		headlineStart+headlineStop, nameHeadlineOptions, headlineStart+headlineStop, snippetHeadlineOptions, tsquery, ids).
+		Scan(&headlines).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]int, len(result.Hits))
+	for i, hit := range result.Hits {
+		byID[hit.ID] = i
+	}
+	for _, h := range headlines {
+		hit := &result.Hits[byID[h.ID]]
+		hit.NameHighlight = markHeadline(h.NameHighlight)
+		hit.Snippet = markHeadline(h.Snippet)
+	}
+	return result, nil
+}
+
+// priceBucket returns the SQL expression numbering the price bucket a product's
+// current price falls into, as search.Collect does, with the bounds as its arguments
+func priceBucket(bounds []money.Money) (string, []interface{}) {
+	if len(bounds) == 0 {
+		return "0", nil
+	}
+	expr := "CASE"
+	args := make([]interface{}, len(bounds))
+	for i, bound := range bounds {
+		expr += fmt.Sprintf(" WHEN %s < ? THEN %d", currentPrice, i)
+		args[i] = bound
+	}
+	return expr + fmt.Sprintf(" ELSE %d END", len(bounds)), args
+}
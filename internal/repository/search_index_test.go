@@ -0,0 +1,19 @@
+package repository
+
+import "testing"
+
+func TestMarkHeadline(t *testing.T) {
+	tests := []struct {
+		headline, want string
+	}{
+		{"Wireless \x02keyboard\x03", "Wireless <mark>keyboard</mark>"},
+		{"\x02Keyboard\x03 <script>alert(1)</script>", "<mark>Keyboard</mark> &lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"\x02<b>Gaming</b>\x03 mouse", "<mark>&lt;b&gt;Gaming&lt;/b&gt;</mark> mouse"},
+		{"Tom & Jerry's <mark>", "Tom &amp; Jerry&#39;s &lt;mark&gt;"},
+	}
+	for _, tt := range tests {
+		if got := markHeadline(tt.headline); got != tt.want {
+			t.Errorf("markHeadline(%q) = %q, want %q", tt.headline, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ShippingRepository defines the interface for shipping zone and method database operations
+type ShippingRepository interface {
+	CreateZone(zone *models.ShippingZone) error
+	UpdateZone(zone *models.ShippingZone) error
+	FindZone(id uint) (*models.ShippingZone, error)
+	ListZones(page, pageSize int) ([]models.ShippingZone, int64, error)
+	DeleteZone(id uint) error
+	HasZones() (bool, error)
+	ZonesForCountry(country string) ([]models.ShippingZone, error)
+}
+
+// GormShippingRepository implements ShippingRepository using GORM
+type GormShippingRepository struct {
+	db *gorm.DB
+}
+
+// NewShippingRepository creates a new instance of GormShippingRepository
+func NewShippingRepository(db *gorm.DB) ShippingRepository {
+	return &GormShippingRepository{
+		db: db,
+	}
+}
+
+// CreateZone inserts a new shipping zone with its methods
+func (r *GormShippingRepository) CreateZone(zone *models.ShippingZone) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(zone).Error; err != nil {
+			return err
+		}
+		return setShippingMethods(tx, zone)
+	})
+}
+
+// UpdateZone modifies an existing shipping zone and replaces its methods
+func (r *GormShippingRepository) UpdateZone(zone *models.ShippingZone) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations, "CreatedAt").Save(zone).Error; err != nil {
+			return err
+		}
+		return setShippingMethods(tx, zone)
+	})
+}
+
+// FindZone retrieves a shipping zone with its methods
+func (r *GormShippingRepository) FindZone(id uint) (*models.ShippingZone, error) {
+	var zone models.ShippingZone
+	err := r.db.Preload("Methods", orderShippingMethods).First(&zone, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// ListZones retrieves one page of the shipping zones with their methods, by country
+// and in the order they win
+func (r *GormShippingRepository) ListZones(page, pageSize int) ([]models.ShippingZone, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.ShippingZone{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var zones []models.ShippingZone
+	err := r.db.Preload("Methods", orderShippingMethods).
+		Order("country, priority DESC, id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&zones).Error
+	return zones, total, err
+}
+
+// DeleteZone removes a shipping zone with its methods
+func (r *GormShippingRepository) DeleteZone(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var zone models.ShippingZone
+		if err := tx.Select("id").First(&zone, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("shipping_zone_id = ?", id).Delete(&models.ShippingMethod{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&zone).Error
+	})
+}
+
+// HasZones reports whether any shipping zone is set up
+func (r *GormShippingRepository) HasZones() (bool, error) {
+	var count int64
+	err := r.db.Model(&models.ShippingZone{}).Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// ZonesForCountry retrieves the shipping zones of a country with their active
+// methods, in the order they win on priority
+func (r *GormShippingRepository) ZonesForCountry(country string) ([]models.ShippingZone, error) {
+	var zones []models.ShippingZone
+	err := r.db.Preload("Methods", func(db *gorm.DB) *gorm.DB {
+		return orderShippingMethods(db.Where("active"))
+	}).
+		Where("country = ?", country).
+		Order("priority DESC, id").
+		Find(&zones).Error
+	return zones, err
+}
+
+// orderShippingMethods sorts the preloaded methods of a shipping zone in the order
+// they were given
+func orderShippingMethods(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
+
+// setShippingMethods replaces the methods of a shipping zone with the ones it holds within tx
+func setShippingMethods(tx *gorm.DB, zone *models.ShippingZone) error {
+	if err := tx.Where("shipping_zone_id = ?", zone.ID).Delete(&models.ShippingMethod{}).Error; err != nil {
+		return err
+	}
+	for i := range zone.Methods {
+		zone.Methods[i].ID = 0
+		zone.Methods[i].ShippingZoneID = zone.ID
+		if err := tx.Create(&zone.Methods[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TaxRepository defines the interface for tax class, tax zone and tax rate database operations
+type TaxRepository interface {
+	CreateClass(class *models.TaxClass) error
+	UpdateClass(class *models.TaxClass) error
+	FindClass(id uint) (*models.TaxClass, error)
+	FindClassByCode(code string) (*models.TaxClass, error)
+	ListClasses() ([]models.TaxClass, error)
+	DeleteClass(id uint) error
+	SetProductClass(productID uint, classID *uint) error
+	CreateZone(zone *models.TaxZone) error
+	UpdateZone(zone *models.TaxZone) error
+	FindZone(id uint) (*models.TaxZone, error)
+	ListZones(page, pageSize int) ([]models.TaxZone, int64, error)
+	DeleteZone(id uint) error
+	ZonesForCountry(country string) ([]models.TaxZone, error)
+}
+
+// GormTaxRepository implements TaxRepository using GORM
+type GormTaxRepository struct {
+	db *gorm.DB
+}
+
+// NewTaxRepository creates a new instance of GormTaxRepository
+func NewTaxRepository(db *gorm.DB) TaxRepository {
+	return &GormTaxRepository{
+		db: db,
+	}
+}
+
+// CreateClass inserts a new tax class
+func (r *GormTaxRepository) CreateClass(class *models.TaxClass) error {
+	return r.db.Create(class).Error
+}
+
+// UpdateClass modifies an existing tax class
+func (r *GormTaxRepository) UpdateClass(class *models.TaxClass) error {
+	return r.db.Omit("CreatedAt").Save(class).Error
+}
+
+// FindClass retrieves a tax class by its ID
+func (r *GormTaxRepository) FindClass(id uint) (*models.TaxClass, error) {
+	var class models.TaxClass
+	if err := r.db.First(&class, id).Error; err != nil {
+		return nil, err
+	}
+	return &class, nil
+}
+
+// FindClassByCode retrieves a tax class by its code
+func (r *GormTaxRepository) FindClassByCode(code string) (*models.TaxClass, error) {
+	var class models.TaxClass
+	if err := r.db.Where("code = ?", code).First(&class).Error; err != nil {
+		return nil, err
+	}
+	return &class, nil
+}
+
+// ListClasses retrieves every tax class by code
+func (r *GormTaxRepository) ListClasses() ([]models.TaxClass, error) {
+	var classes []models.TaxClass
+	err := r.db.Order("code").Find(&classes).Error
+	return classes, err
+}
+
+// DeleteClass removes a tax class with its rates. Its products, including those in
+// the trash, go back to the standard rate.
+func (r *GormTaxRepository) DeleteClass(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var class models.TaxClass
+		if err := tx.Select("id").First(&class, id).Error; err != nil {
+			return err
+		}
+		// UpdateColumn skips the product hooks, which would rewrite other columns
+		err := tx.Unscoped().Model(&models.Product{}).
+			Where("tax_class_id = ?", id).
+			UpdateColumn("tax_class_id", nil).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("tax_class_id = ?", id).Delete(&models.TaxRate{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&class).Error
+	})
+}
+
+// SetProductClass puts a product in a tax class, or back on the standard rate when
+// classID is nil
+func (r *GormTaxRepository) SetProductClass(productID uint, classID *uint) error {
+	result := r.db.Model(&models.Product{}).
+		Where("id = ?", productID).
+		UpdateColumn("tax_class_id", classID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// CreateZone inserts a new tax zone with its rates
+func (r *GormTaxRepository) CreateZone(zone *models.TaxZone) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(zone).Error; err != nil {
+			return err
+		}
+		return setTaxRates(tx, zone)
+	})
+}
+
+// UpdateZone modifies an existing tax zone and replaces its rates
+func (r *GormTaxRepository) UpdateZone(zone *models.TaxZone) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations, "CreatedAt").Save(zone).Error; err != nil {
+			return err
+		}
+		return setTaxRates(tx, zone)
+	})
+}
+
+// FindZone retrieves a tax zone with its rates
+func (r *GormTaxRepository) FindZone(id uint) (*models.TaxZone, error) {
+	var zone models.TaxZone
+	err := r.db.Preload("Rates", orderTaxRates).First(&zone, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// ListZones retrieves one page of the tax zones with their rates, by country and in
+// the order they win
+func (r *GormTaxRepository) ListZones(page, pageSize int) ([]models.TaxZone, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.TaxZone{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var zones []models.TaxZone
+	err := r.db.Preload("Rates", orderTaxRates).
+		Order("country, priority DESC, id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&zones).Error
+	return zones, total, err
+}
+
+// DeleteZone removes a tax zone with its rates
+func (r *GormTaxRepository) DeleteZone(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var zone models.TaxZone
+		if err := tx.Select("id").First(&zone, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tax_zone_id = ?", id).Delete(&models.TaxRate{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&zone).Error
+	})
+}
+
+// ZonesForCountry retrieves the tax zones of a country with their rates, in the
+// order they win on priority
+func (r *GormTaxRepository) ZonesForCountry(country string) ([]models.TaxZone, error) {
+	var zones []models.TaxZone
+	err := r.db.Preload("Rates").
+		Where("country = ?", country).
+		Order("priority DESC, id").
+		Find(&zones).Error
+	return zones, err
+}
+
+// orderTaxRates sorts the preloaded rates of a tax zone by class, the standard rate first
+func orderTaxRates(db *gorm.DB) *gorm.DB {
+	return db.Order("tax_class_id")
+}
+
+// setTaxRates replaces the rates of a tax zone with the ones it holds within tx
+func setTaxRates(tx *gorm.DB, zone *models.TaxZone) error {
+	if err := tx.Where("tax_zone_id = ?", zone.ID).Delete(&models.TaxRate{}).Error; err != nil {
+		return err
+	}
+	for i := range zone.Rates {
+		zone.Rates[i].ID = 0
+		zone.Rates[i].TaxZoneID = zone.ID
+		if err := tx.Create(&zone.Rates[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
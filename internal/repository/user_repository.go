@@ -3,6 +3,7 @@ package repository
 import (
     "ecommerce-app/internal/models"
     "gorm.io/gorm"
+    "time"
 )
 
 // UserRepository defines the interface for user-related database operations
@@ -10,8 +11,12 @@ type UserRepository interface {
     Create(user *models.User) error
     FindByID(id uint) (*models.User, error)
     FindByEmail(email string) (*models.User, error)
+    FindDeletedByEmail(email string) (*models.User, error)
     Update(user *models.User) error
     Delete(id uint) error
+    ListDeleted(page, pageSize int) ([]models.User, int64, error)
+    Restore(id uint) error
+    PurgeDeleted(before time.Time, limit int) (PurgeResult, error)
     List() ([]models.User, error)
     ListPaginated(page, pageSize int) ([]models.User, error)
     Count() (int64, error)
@@ -54,14 +59,104 @@ func (r *GormUserRepository) FindByEmail(email string) (*models.User, error) {
     return &user, nil
 }
 
+// FindDeletedByEmail retrieves the user in the trash with the given email, the most
+// recently deleted one if there are several
+func (r *GormUserRepository) FindDeletedByEmail(email string) (*models.User, error) {
+    var user models.User
+    err := r.db.Unscoped().Where("email = ? AND deleted_at IS NOT NULL", email).Order("deleted_at DESC").First(&user).Error
+    if err != nil {
+        return nil, err
+    }
+    return &user, nil
+}
+
 // Update modifies an existing user in the database
 func (r *GormUserRepository) Update(user *models.User) error {
     return r.db.Save(user).Error
 }
 
-// Delete removes a user from the database
+// Delete moves a user to the trash. The user can no longer sign in, but keeps the
+// email address until purged.
 func (r *GormUserRepository) Delete(id uint) error {
-    return r.db.Delete(&models.User{}, id).Error
+    result := r.db.Delete(&models.User{}, id)
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return ErrRecordNotFound
+    }
+    return nil
+}
+
+// ListDeleted retrieves the users in the trash, most recently deleted first, along
+// with their total number
+func (r *GormUserRepository) ListDeleted(page, pageSize int) ([]models.User, int64, error) {
+    var total int64
+    if err := r.db.Unscoped().Model(&models.User{}).Where("deleted_at IS NOT NULL").Count(&total).Error; err != nil {
+        return nil, 0, err
+    }
+
+    var users []models.User
+    err := r.db.Unscoped().Where("deleted_at IS NOT NULL").
+        Order("deleted_at DESC, id DESC").
+        Offset((page - 1) * pageSize).
+        Limit(pageSize).
+        Find(&users).Error
+    return users, total, err
+}
+
+// Restore takes a user out of the trash
+func (r *GormUserRepository) Restore(id uint) error {
+    return restore(r.db, &models.User{}, id)
+}
+
+// PurgeDeleted permanently removes at most limit users that were moved to the
+// trash before the given time, along with their carts. Users with orders, even
+// trashed ones, stay in the trash until their orders are purged.
+func (r *GormUserRepository) PurgeDeleted(before time.Time, limit int) (PurgeResult, error) {
+    var result PurgeResult
+
+    ids, err := trashedBefore(r.db, &models.User{}, before, limit)
+    if err != nil {
+        return result, err
+    }
+    for _, id := range ids {
+        purged := false
+        err := r.db.Transaction(func(tx *gorm.DB) error {
+            var orders int64
+            if err := tx.Unscoped().Model(&models.Order{}).Where("user_id = ?", id).Count(&orders).Error; err != nil {
+                return err
+            }
+            if orders > 0 {
+                return nil
+            }
+
+            carts := tx.Model(&models.Cart{}).Select("id").Where("user_id = ?", id)
+            if err := tx.Where("cart_id IN (?)", carts).Delete(&models.CartItem{}).Error; err != nil {
+                return err
+            }
+            if err := tx.Exec("DELETE FROM cart_coupons WHERE cart_id IN (?)", carts).Error; err != nil {
+                return err
+            }
+            if err := tx.Where("user_id = ?", id).Delete(&models.Cart{}).Error; err != nil {
+                return err
+            }
+            if err := tx.Where("user_id = ?", id).Delete(&models.StockSubscription{}).Error; err != nil {
+                return err
+            }
+            purged = true
+            return tx.Unscoped().Delete(&models.User{}, id).Error
+        })
+        if err != nil {
+            return result, err
+        }
+        if purged {
+            result.Purged = append(result.Purged, id)
+        } else {
+            result.Kept = append(result.Kept, id)
+        }
+    }
+    return result, nil
 }
 
 // List retrieves all users
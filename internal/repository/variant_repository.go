@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VariantMatrix is the complete set of options and variants of a product
+type VariantMatrix struct {
+	Options  []models.ProductOption // With their values, in display order
+	Variants []MatrixVariant
+}
+
+// MatrixVariant is a variant of a VariantMatrix. Values holds, for each option in
+// order, the index of the variant's value within that option's values.
+type MatrixVariant struct {
+	Variant models.ProductVariant // Keeps an existing variant when its ID is set
+	Values  []int
+}
+
+// VariantRepository defines the interface for product option and variant database operations
+type VariantRepository interface {
+	FindByID(id uint) (*models.ProductVariant, error)
+	FindBySKU(sku string) (*models.ProductVariant, error)
+	ListByProduct(productID uint) ([]models.ProductVariant, error)
+	ListOptions(productID uint) ([]models.ProductOption, error)
+	CountByProduct(productID uint) (int64, error)
+	ReplaceMatrix(productID uint, matrix VariantMatrix) ([]models.ProductVariant, error)
+	Update(variant *models.ProductVariant) error
+}
+
+// GormVariantRepository implements VariantRepository using GORM
+type GormVariantRepository struct {
+	db *gorm.DB
+}
+
+// NewVariantRepository creates a new instance of GormVariantRepository
+func NewVariantRepository(db *gorm.DB) VariantRepository {
+	return &GormVariantRepository{
+		db: db,
+	}
+}
+
+// FindByID retrieves a variant by its ID, with its available stock
+func (r *GormVariantRepository) FindByID(id uint) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	if err := r.db.First(&variant, id).Error; err != nil {
+		return nil, err
+	}
+	variants := []models.ProductVariant{variant}
+	if err := setVariantAvailability(r.db, variants); err != nil {
+		return nil, err
+	}
+	return &variants[0], nil
+}
+
+// FindBySKU retrieves a variant by its SKU
+func (r *GormVariantRepository) FindBySKU(sku string) (*models.ProductVariant, error) {
+	var variant models.ProductVariant
+	if err := r.db.Where("sku = ?", sku).First(&variant).Error; err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// ListByProduct retrieves the variants of a product with their option values and available stock
+func (r *GormVariantRepository) ListByProduct(productID uint) ([]models.ProductVariant, error) {
+	var variants []models.ProductVariant
+	err := r.db.Preload("OptionValues").Where("product_id = ?", productID).Order("id").Find(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+	return variants, setVariantAvailability(r.db, variants)
+}
+
+// ListOptions retrieves the options of a product with their values, in display order
+func (r *GormVariantRepository) ListOptions(productID uint) ([]models.ProductOption, error) {
+	var options []models.ProductOption
+	err := r.db.Preload("Values", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	}).Where("product_id = ?", productID).Order("position, id").Find(&options).Error
+	return options, err
+}
+
+// CountByProduct returns the number of variants of a product
+func (r *GormVariantRepository) CountByProduct(productID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ProductVariant{}).Where("product_id = ?", productID).Count(&count).Error
+	return count, err
+}
+
+// ReplaceMatrix replaces the options and variants of a product in a single transaction.
+// Variants missing from the matrix are deleted along with the cart lines holding them,
+// and the product's stock is reset to the sum of its variant stock. The stock ledger
+// records the stock moving from the product to its variants as adjustments.
+func (r *GormVariantRepository) ReplaceMatrix(productID uint, matrix VariantMatrix) ([]models.ProductVariant, error) {
+	var variants []models.ProductVariant
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the product so concurrent edits and checkouts see a consistent matrix
+		var product models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, productID).Error; err != nil {
+			return err
+		}
+
+		existing := tx.Model(&models.ProductVariant{}).Select("id").Where("product_id = ?", productID)
+		if err := tx.Exec("DELETE FROM product_variant_option_values WHERE product_variant_id IN (?)", existing).Error; err != nil {
+			return err
+		}
+		options := tx.Model(&models.ProductOption{}).Select("id").Where("product_id = ?", productID)
+		if err := tx.Where("option_id IN (?)", options).Delete(&models.ProductOptionValue{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", productID).Delete(&models.ProductOption{}).Error; err != nil {
+			return err
+		}
+
+		for i := range matrix.Options {
+			matrix.Options[i].ID = 0
+			matrix.Options[i].ProductID = productID
+			matrix.Options[i].Position = i
+			for j := range matrix.Options[i].Values {
+				matrix.Options[i].Values[j].ID = 0
+				matrix.Options[i].Values[j].Position = j
+			}
+			if err := tx.Create(&matrix.Options[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		// Drop variants missing from the matrix first so their SKUs can be reused. Cart lines
+		// of removed variants, or added before the product had variants, can no longer be bought.
+		keep := make([]uint, 0, len(matrix.Variants))
+		for _, entry := range matrix.Variants {
+			if entry.Variant.ID != 0 {
+				keep = append(keep, entry.Variant.ID)
+			}
+		}
+		removed := tx.Where("product_id = ?", productID)
+		cartLines := tx.Where("product_id = ?", productID)
+		if len(keep) > 0 {
+			removed = removed.Where("id NOT IN ?", keep)
+			cartLines = cartLines.Where("variant_id NOT IN ?", keep)
+		} else if len(matrix.Variants) == 0 {
+			cartLines = cartLines.Where("variant_id <> 0")
+		}
+		if err := cartLines.Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		// Shoppers waiting for a removed variant can no longer be notified about it
+		subscriptions := tx.Where("product_id = ? AND variant_id <> 0 AND notified_at IS NULL", productID)
+		if len(keep) > 0 {
+			subscriptions = subscriptions.Where("variant_id NOT IN ?", keep)
+		}
+		if err := subscriptions.Delete(&models.StockSubscription{}).Error; err != nil {
+			return err
+		}
+		if err := removed.Delete(&models.ProductVariant{}).Error; err != nil {
+			return err
+		}
+
+		stock := 0
+		for _, entry := range matrix.Variants {
+			variant := entry.Variant
+			variant.ProductID = productID
+			variant.OptionValues = nil
+			if err := tx.Omit(clause.Associations).Save(&variant).Error; err != nil {
+				return err
+			}
+
+			values := make([]models.ProductOptionValue, len(entry.Values))
+			for i, index := range entry.Values {
+				values[i] = matrix.Options[i].Values[index]
+			}
+			if err := tx.Model(&variant).Association("OptionValues").Append(values); err != nil {
+				return err
+			}
+			variant.OptionValues = values
+
+			stock += variant.Stock
+			variants = append(variants, variant)
+		}
+
+		if len(matrix.Variants) > 0 {
+			if err := tx.Model(&product).UpdateColumn("stock", stock).Error; err != nil {
+				return err
+			}
+		}
+		return syncLedger(tx, productID, "system")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+// Update modifies a variant and brings its product's stock back in line with the
+// sum of its variant stock. A change to its stock is recorded in the stock ledger as
+// an adjustment.
+func (r *GormVariantRepository) Update(variant *models.ProductVariant) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, variant.ProductID); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(variant).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.Product{}).
+			Where("id = ?", variant.ProductID).
+			UpdateColumn("stock", tx.Model(&models.ProductVariant{}).
+				Select("COALESCE(SUM(stock), 0)").
+				Where("product_id = ?", variant.ProductID)).Error
+		if err != nil {
+			return err
+		}
+		return syncLedger(tx, variant.ProductID, "system")
+	})
+}
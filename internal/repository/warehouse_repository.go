@@ -0,0 +1,516 @@
+package repository
+
+import (
+	"ecommerce-app/internal/models"
+	"errors"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInsufficientLocationStock is returned when a movement would take a warehouse's
+	// stock level below zero
+	ErrInsufficientLocationStock = errors.New("not enough stock at the warehouse")
+	// ErrNoWarehouse is returned when stock has to be placed but no warehouse exists
+	ErrNoWarehouse = errors.New("no warehouse to hold stock")
+)
+
+// MovementFilter narrows down stock movement listings. Zero values leave a criterion unused.
+type MovementFilter struct {
+	WarehouseID uint
+	ProductID   uint
+	VariantID   uint
+	Type        string
+}
+
+// LocationBalance compares the stock level of a product variant at a warehouse with
+// the sum of the ledger entries recorded there
+type LocationBalance struct {
+	WarehouseID uint `json:"warehouse_id"`
+	OnHand      int  `json:"on_hand"`
+	Ledger      int  `json:"ledger"`
+}
+
+// ReconciliationRow compares the stock of a product variant, as used for sales, with
+// its stock levels and its ledger
+type ReconciliationRow struct {
+	ProductID uint              `json:"product_id"`
+	VariantID uint              `json:"variant_id,omitempty"`
+	Name      string            `json:"name"`
+	Stock     int               `json:"stock"`
+	OnHand    int               `json:"on_hand"`
+	Ledger    int               `json:"ledger"`
+	Balanced  bool              `json:"balanced"`
+	Locations []LocationBalance `json:"locations"`
+}
+
+// WarehouseRepository defines the interface for warehouse and stock ledger database operations
+type WarehouseRepository interface {
+	Create(warehouse *models.Warehouse) error
+	Update(warehouse *models.Warehouse) error
+	FindByID(id uint) (*models.Warehouse, error)
+	FindByCode(code string) (*models.Warehouse, error)
+	List() ([]models.Warehouse, error)
+	CountActive() (int64, error)
+	ListLevels(warehouseID uint, page, pageSize int) ([]models.StockLevel, int64, error)
+	ListMovements(filter MovementFilter, page, pageSize int) ([]models.StockMovement, int64, error)
+	RecordMovement(movement *models.StockMovement) error
+	Transfer(out, in *models.StockMovement) error
+	Reconcile() ([]ReconciliationRow, error)
+}
+
+// GormWarehouseRepository implements WarehouseRepository using GORM
+type GormWarehouseRepository struct {
+	db *gorm.DB
+}
+
+// NewWarehouseRepository creates a new instance of GormWarehouseRepository
+func NewWarehouseRepository(db *gorm.DB) WarehouseRepository {
+	return &GormWarehouseRepository{
+		db: db,
+	}
+}
+
+// Create inserts a new warehouse into the database
+func (r *GormWarehouseRepository) Create(warehouse *models.Warehouse) error {
+	return r.db.Create(warehouse).Error
+}
+
+// Update modifies an existing warehouse in the database
+func (r *GormWarehouseRepository) Update(warehouse *models.Warehouse) error {
+	return r.db.Save(warehouse).Error
+}
+
+// FindByID retrieves a warehouse by its ID
+func (r *GormWarehouseRepository) FindByID(id uint) (*models.Warehouse, error) {
+	var warehouse models.Warehouse
+	if err := r.db.First(&warehouse, id).Error; err != nil {
+		return nil, err
+	}
+	return &warehouse, nil
+}
+
+// FindByCode retrieves a warehouse by its code
+func (r *GormWarehouseRepository) FindByCode(code string) (*models.Warehouse, error) {
+	var warehouse models.Warehouse
+	if err := r.db.Where("code = ?", code).First(&warehouse).Error; err != nil {
+		return nil, err
+	}
+	return &warehouse, nil
+}
+
+// List retrieves all warehouses in priority order
+func (r *GormWarehouseRepository) List() ([]models.Warehouse, error) {
+	var warehouses []models.Warehouse
+	err := r.db.Order("priority, id").Find(&warehouses).Error
+	return warehouses, err
+}
+
+// CountActive returns the number of active warehouses
+func (r *GormWarehouseRepository) CountActive() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Warehouse{}).Where("active").Count(&count).Error
+	return count, err
+}
+
+// ListLevels retrieves one page of the non-zero stock levels of a warehouse, along
+// with their total number
+func (r *GormWarehouseRepository) ListLevels(warehouseID uint, page, pageSize int) ([]models.StockLevel, int64, error) {
+	levels := r.db.Model(&models.StockLevel{}).Where("warehouse_id = ? AND quantity <> 0", warehouseID)
+
+	var total int64
+	if err := levels.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var result []models.StockLevel
+	err := r.db.Where("warehouse_id = ? AND quantity <> 0", warehouseID).
+		Order("product_id, variant_id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&result).Error
+	return result, total, err
+}
+
+// ListMovements retrieves one page of the ledger entries matching the filter, newest
+// first, along with their total number
+func (r *GormWarehouseRepository) ListMovements(filter MovementFilter, page, pageSize int) ([]models.StockMovement, int64, error) {
+	var total int64
+	if err := filterMovements(r.db.Model(&models.StockMovement{}), filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var movements []models.StockMovement
+	err := filterMovements(r.db, filter).
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&movements).Error
+	return movements, total, err
+}
+
+// filterMovements adds the WHERE clauses of a movement filter
+func filterMovements(db *gorm.DB, filter MovementFilter) *gorm.DB {
+	if filter.WarehouseID != 0 {
+		db = db.Where("warehouse_id = ?", filter.WarehouseID)
+	}
+	if filter.ProductID != 0 {
+		db = db.Where("product_id = ?", filter.ProductID)
+	}
+	if filter.VariantID != 0 {
+		db = db.Where("variant_id = ?", filter.VariantID)
+	}
+	if filter.Type != "" {
+		db = db.Where("type = ?", filter.Type)
+	}
+	return db
+}
+
+// RecordMovement appends a movement to the ledger and applies it to the warehouse's
+// stock level and to the product and variant stock, in a single transaction. The
+// product is locked so that concurrent movements and sales apply one at a time.
+func (r *GormWarehouseRepository) RecordMovement(movement *models.StockMovement) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, movement.ProductID); err != nil {
+			return err
+		}
+		if movement.Quantity < 0 {
+			if err := checkLevel(tx, movement); err != nil {
+				return err
+			}
+		}
+		if err := postMovement(tx, movement); err != nil {
+			return err
+		}
+
+		// Product stock is the sum of variant stock, so both change together
+		if movement.VariantID != 0 {
+			err := tx.Model(&models.ProductVariant{}).
+				Where("id = ?", movement.VariantID).
+				UpdateColumn("stock", gorm.Expr("stock + ?", movement.Quantity)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Product{}).
+			Where("id = ?", movement.ProductID).
+			UpdateColumn("stock", gorm.Expr("stock + ?", movement.Quantity)).Error
+	})
+}
+
+// Transfer records the two legs of a move between warehouses in a single
+// transaction. The product's total stock does not change.
+func (r *GormWarehouseRepository) Transfer(out, in *models.StockMovement) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, out.ProductID); err != nil {
+			return err
+		}
+		if err := checkLevel(tx, out); err != nil {
+			return err
+		}
+		if err := postMovement(tx, out); err != nil {
+			return err
+		}
+		return postMovement(tx, in)
+	})
+}
+
+// Reconcile compares, for every product variant including trashed ones, the stock
+// used for sales with the sum of its stock levels and the sum of its ledger entries,
+// per warehouse and overall
+func (r *GormWarehouseRepository) Reconcile() ([]ReconciliationRow, error) {
+	type key struct{ productID, variantID uint }
+	type locationKey struct {
+		key
+		warehouseID uint
+	}
+
+	var stock []struct {
+		ProductID uint
+		VariantID uint
+		Name      string
+		Stock     int
+	}
+	err := r.db.Raw(`
+		SELECT p.id AS product_id, 0 AS variant_id, p.name, p.stock
+		FROM products p
+		WHERE NOT EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = p.id)
+		UNION ALL
+		SELECT v.product_id, v.id, p.name || ' (' || COALESCE(NULLIF(v.title, ''), v.sku) || ')', v.stock
+		FROM product_variants v JOIN products p ON p.id = v.product_id`).Scan(&stock).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var levels, ledger []struct {
+		WarehouseID uint
+		ProductID   uint
+		VariantID   uint
+		Quantity    int
+	}
+	err = r.db.Model(&models.StockLevel{}).
+		Select("warehouse_id, product_id, variant_id, quantity").
+		Scan(&levels).Error
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.Model(&models.StockMovement{}).
+		Select("warehouse_id, product_id, variant_id, SUM(quantity) AS quantity").
+		Group("warehouse_id, product_id, variant_id").
+		Scan(&ledger).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make(map[key]*ReconciliationRow, len(stock))
+	row := func(k key) *ReconciliationRow {
+		if rows[k] == nil {
+			rows[k] = &ReconciliationRow{ProductID: k.productID, VariantID: k.variantID}
+		}
+		return rows[k]
+	}
+	for _, s := range stock {
+		entry := row(key{s.ProductID, s.VariantID})
+		entry.Name = s.Name
+		entry.Stock = s.Stock
+	}
+
+	locations := make(map[locationKey]*LocationBalance)
+	location := func(k locationKey) *LocationBalance {
+		if locations[k] == nil {
+			locations[k] = &LocationBalance{WarehouseID: k.warehouseID}
+		}
+		return locations[k]
+	}
+	for _, level := range levels {
+		k := key{level.ProductID, level.VariantID}
+		row(k).OnHand += level.Quantity
+		location(locationKey{k, level.WarehouseID}).OnHand = level.Quantity
+	}
+	for _, entry := range ledger {
+		k := key{entry.ProductID, entry.VariantID}
+		row(k).Ledger += entry.Quantity
+		location(locationKey{k, entry.WarehouseID}).Ledger = entry.Quantity
+	}
+
+	for k, balance := range locations {
+		if balance.OnHand == 0 && balance.Ledger == 0 {
+			continue
+		}
+		entry := rows[k.key]
+		entry.Locations = append(entry.Locations, *balance)
+	}
+
+	result := make([]ReconciliationRow, 0, len(rows))
+	for _, entry := range rows {
+		entry.Balanced = entry.Stock == entry.OnHand && entry.OnHand == entry.Ledger
+		for _, balance := range entry.Locations {
+			if balance.OnHand != balance.Ledger {
+				entry.Balanced = false
+			}
+		}
+		slices.SortFunc(entry.Locations, func(a, b LocationBalance) int {
+			return int(a.WarehouseID) - int(b.WarehouseID)
+		})
+		result = append(result, *entry)
+	}
+	slices.SortFunc(result, func(a, b ReconciliationRow) int {
+		if a.ProductID != b.ProductID {
+			return int(a.ProductID) - int(b.ProductID)
+		}
+		return int(a.VariantID) - int(b.VariantID)
+	})
+	return result, nil
+}
+
+// lockProduct locks a product row FOR UPDATE within tx
+func lockProduct(tx *gorm.DB, productID uint) error {
+	var product models.Product
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&product, productID).Error
+}
+
+// checkLevel fails with ErrInsufficientLocationStock when a movement would take the
+// stock level of its location below zero
+func checkLevel(tx *gorm.DB, movement *models.StockMovement) error {
+	var level models.StockLevel
+	err := tx.Where("warehouse_id = ? AND product_id = ? AND variant_id = ?",
+		movement.WarehouseID, movement.ProductID, movement.VariantID).
+		Limit(1).
+		Find(&level).Error
+	if err != nil {
+		return err
+	}
+	if level.Quantity+movement.Quantity < 0 {
+		return ErrInsufficientLocationStock
+	}
+	return nil
+}
+
+// postMovement appends a movement to the ledger and applies it to the stock level of
+// its location within tx. Product and variant stock are left to the caller.
+func postMovement(tx *gorm.DB, movement *models.StockMovement) error {
+	if err := tx.Create(movement).Error; err != nil {
+		return err
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "warehouse_id"}, {Name: "product_id"}, {Name: "variant_id"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "quantity"}, Value: gorm.Expr("stock_levels.quantity + EXCLUDED.quantity")},
+			{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("CURRENT_TIMESTAMP")},
+		},
+	}).Create(&models.StockLevel{
+		WarehouseID: movement.WarehouseID,
+		ProductID:   movement.ProductID,
+		VariantID:   movement.VariantID,
+		Quantity:    movement.Quantity,
+	}).Error
+}
+
+// takeStock posts movements removing quantity units of a product variant, drawing on
+// the active warehouses in priority order. Units the warehouses do not hold are taken
+// from the default warehouse, leaving its level negative until the stock is counted.
+func takeStock(tx *gorm.DB, template models.StockMovement, quantity int) error {
+	var locations []struct {
+		WarehouseID uint
+		Quantity    int
+	}
+	err := tx.Raw(`
+		SELECT w.id AS warehouse_id, COALESCE(l.quantity, 0) AS quantity
+		FROM warehouses w
+		LEFT JOIN stock_levels l ON l.warehouse_id = w.id AND l.product_id = ? AND l.variant_id = ?
+		WHERE w.active
+		ORDER BY w.priority, w.id`, template.ProductID, template.VariantID).Scan(&locations).Error
+	if err != nil {
+		return err
+	}
+	if len(locations) == 0 {
+		defaultID, err := defaultWarehouse(tx)
+		if err != nil {
+			return err
+		}
+		locations = append(locations, struct {
+			WarehouseID uint
+			Quantity    int
+		}{WarehouseID: defaultID})
+	}
+
+	// Whatever the warehouses do not hold comes out of the default one
+	taken := make([]int, len(locations))
+	remaining := quantity
+	for i, location := range locations {
+		taken[i] = max(min(location.Quantity, remaining), 0)
+		remaining -= taken[i]
+	}
+	taken[0] += remaining
+
+	for i, location := range locations {
+		if taken[i] == 0 {
+			continue
+		}
+		movement := template
+		movement.WarehouseID = location.WarehouseID
+		movement.Quantity = -taken[i]
+		if err := postMovement(tx, &movement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// giveStock posts a movement adding quantity units of a product variant to the
+// default warehouse
+func giveStock(tx *gorm.DB, template models.StockMovement, quantity int) error {
+	warehouseID, err := defaultWarehouse(tx)
+	if err != nil {
+		return err
+	}
+	movement := template
+	movement.WarehouseID = warehouseID
+	movement.Quantity = quantity
+	return postMovement(tx, &movement)
+}
+
+// defaultWarehouse returns the ID of the first active warehouse in priority order,
+// falling back to the first inactive one
+func defaultWarehouse(tx *gorm.DB) (uint, error) {
+	var warehouse models.Warehouse
+	err := tx.Select("id").Order("active DESC, priority, id").Limit(1).Find(&warehouse).Error
+	if err != nil {
+		return 0, err
+	}
+	if warehouse.ID == 0 {
+		return 0, ErrNoWarehouse
+	}
+	return warehouse.ID, nil
+}
+
+// syncLedger records adjustments bringing the stock levels of a product in line with
+// its product and variant stock after they were set directly within tx, e.g. by an
+// admin edit or an import. Levels of variants that no longer exist, or of the product
+// itself once it has variants, are brought back to zero.
+func syncLedger(tx *gorm.DB, productID uint, createdBy string) error {
+	var product models.Product
+	if err := tx.Unscoped().Select("id", "stock").First(&product, productID).Error; err != nil {
+		return err
+	}
+	var variants []models.ProductVariant
+	if err := tx.Select("id", "stock").Where("product_id = ?", productID).Find(&variants).Error; err != nil {
+		return err
+	}
+
+	targets := map[uint]int{0: product.Stock}
+	if len(variants) > 0 {
+		targets[0] = 0
+		for _, variant := range variants {
+			targets[variant.ID] = variant.Stock
+		}
+	}
+
+	var levels []struct {
+		VariantID uint
+		Quantity  int
+	}
+	err := tx.Model(&models.StockLevel{}).
+		Select("variant_id, SUM(quantity) AS quantity").
+		Where("product_id = ?", productID).
+		Group("variant_id").
+		Scan(&levels).Error
+	if err != nil {
+		return err
+	}
+	current := make(map[uint]int, len(levels))
+	for _, level := range levels {
+		current[level.VariantID] = level.Quantity
+		if _, ok := targets[level.VariantID]; !ok {
+			targets[level.VariantID] = 0
+		}
+	}
+
+	variantIDs := make([]uint, 0, len(targets))
+	for variantID := range targets {
+		variantIDs = append(variantIDs, variantID)
+	}
+	slices.Sort(variantIDs)
+
+	for _, variantID := range variantIDs {
+		template := models.StockMovement{
+			ProductID: productID,
+			VariantID: variantID,
+			Type:      models.MovementAdjustment,
+			Note:      "Stock set directly",
+			CreatedBy: createdBy,
+		}
+		switch diff := targets[variantID] - current[variantID]; {
+		case diff > 0:
+			err = giveStock(tx, template, diff)
+		case diff < 0:
+			err = takeStock(tx, template, -diff)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,32 +1,48 @@
 package router
 
 import (
-	"ecommerce-app/internal/db"
 	"ecommerce-app/internal/handlers"
 	"ecommerce-app/internal/middleware"
-	"ecommerce-app/internal/repository"
 	"ecommerce-app/internal/service"
 	"net/http"
+	"time"
 )
 
 // SetupRoutes configures all application routes
 func SetupRoutes(authService service.AuthService, userService service.UserService, 
-	productService service.ProductService, orderService service.OrderService) {
-	// Initialize cart repository and service
-	cartRepo := repository.NewCartRepository(db.GetDB())
-	cartService := service.NewCartService(cartRepo)
-	
+	productService service.ProductService, orderService service.OrderService, cartService service.CartService,
+	checkoutService service.CheckoutService, categoryService service.CategoryService, variantService service.VariantService,
+	mediaService service.MediaService, transferService service.ProductTransferService,
+	trashService service.TrashService, trashRetention time.Duration, warehouseService service.WarehouseService,
+	alertService service.AlertService, couponService service.CouponService, promotionService service.PromotionService,
+	saleService service.SaleService, priceListService service.PriceListService, taxService service.TaxService,
+	shippingService service.ShippingService) {
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	adminHandler := handlers.NewAdminHandler(productService, orderService, userService)
+	categoryHandler := handlers.NewCategoryHandler(categoryService, priceListService)
+	productHandler := handlers.NewProductHandler(productService, variantService, priceListService)
+	variantHandler := handlers.NewVariantHandler(variantService)
+	mediaHandler := handlers.NewMediaHandler(mediaService)
+	transferHandler := handlers.NewProductTransferHandler(transferService)
+	trashHandler := handlers.NewTrashHandler(trashService, trashRetention)
+	warehouseHandler := handlers.NewWarehouseHandler(warehouseService)
+	alertHandler := handlers.NewAlertHandler(alertService)
+	couponHandler := handlers.NewCouponHandler(couponService)
+	promotionHandler := handlers.NewPromotionHandler(promotionService)
+	saleHandler := handlers.NewSaleHandler(saleService)
+	priceListHandler := handlers.NewPriceListHandler(priceListService)
+	taxHandler := handlers.NewTaxHandler(taxService)
+	shippingHandler := handlers.NewShippingHandler(shippingService)
 	
 	// Setup route groups
 	setupAuthRoutes(authHandler)
-	setupAdminRoutes(adminHandler, authService)
-	setupUserRoutes(userService, authService, cartService)
+	setupAdminRoutes(adminHandler, categoryHandler, variantHandler, mediaHandler, transferHandler, trashHandler, warehouseHandler, alertHandler, couponHandler, promotionHandler, saleHandler, priceListHandler, taxHandler, shippingHandler, authService)
+	setupCatalogRoutes(productHandler, categoryHandler, authService)
+	setupUserRoutes(userService, authService, cartService, checkoutService, orderService, alertService)
 	
 	// Basic handler (to test)
-	http.HandleFunc("/", handlers.HomeHandler(productService))
+	http.HandleFunc("/", middleware.OptionalUserAuth(authService)(handlers.HomeHandler(productService, priceListService)))
 
 	// Swagger documentation route
 }
@@ -41,24 +57,144 @@ func setupAuthRoutes(authHandler *handlers.AuthHandler) {
 }
 
 // setupAdminRoutes configures admin-related routes
-func setupAdminRoutes(adminHandler *handlers.AdminHandler, authService service.AuthService) {
+func setupAdminRoutes(adminHandler *handlers.AdminHandler, categoryHandler *handlers.CategoryHandler,
+	variantHandler *handlers.VariantHandler, mediaHandler *handlers.MediaHandler,
+	transferHandler *handlers.ProductTransferHandler, trashHandler *handlers.TrashHandler,
+	warehouseHandler *handlers.WarehouseHandler, alertHandler *handlers.AlertHandler,
+	couponHandler *handlers.CouponHandler, promotionHandler *handlers.PromotionHandler, saleHandler *handlers.SaleHandler,
+	priceListHandler *handlers.PriceListHandler, taxHandler *handlers.TaxHandler, shippingHandler *handlers.ShippingHandler,
+	authService service.AuthService) {
 	// Admin routes with authentication
 	http.HandleFunc("/admin/dashboard", middleware.AdminAuth(adminHandler.GetDashboardStats))
 	http.HandleFunc("/admin/products", middleware.AdminAuth(adminHandler.ListProducts))
-	http.HandleFunc("/admin/products/create", middleware.AdminAuth(adminHandler.CreateProduct))
+	http.HandleFunc("POST /admin/products/create", middleware.AdminAuth(adminHandler.CreateProduct))
+	http.HandleFunc("PUT /admin/products/{id}", middleware.AdminAuth(adminHandler.ReplaceProduct))
+	http.HandleFunc("PATCH /admin/products/{id}", middleware.AdminAuth(adminHandler.PatchProduct))
+	http.HandleFunc("DELETE /admin/products/{id}", middleware.AdminAuth(adminHandler.DeleteProduct))
+	http.HandleFunc("POST /admin/products/bulk/price", middleware.AdminAuth(adminHandler.BulkUpdatePrices))
+	http.HandleFunc("POST /admin/products/bulk/stock", middleware.AdminAuth(adminHandler.BulkAdjustStock))
+	http.HandleFunc("POST /admin/products/bulk/delete", middleware.AdminAuth(adminHandler.BulkDeleteProducts))
+	http.HandleFunc("POST /admin/products/import", middleware.AdminAuth(transferHandler.ImportProducts))
+	http.HandleFunc("GET /admin/products/export", middleware.AdminAuth(transferHandler.ExportProducts))
 	http.HandleFunc("/admin/orders", middleware.AdminAuth(adminHandler.ListOrders))
-	http.HandleFunc("/admin/orders/update-status", middleware.AdminAuth(adminHandler.UpdateOrderStatus))
+	http.HandleFunc("POST /admin/orders/update-status", middleware.AdminAuth(adminHandler.UpdateOrderStatus))
+	http.HandleFunc("GET /admin/orders/{id}", middleware.AdminAuth(adminHandler.GetOrder))
+	http.HandleFunc("POST /admin/orders/{id}/status", middleware.AdminAuth(adminHandler.TransitionOrderStatus))
+	http.HandleFunc("DELETE /admin/orders/{id}", middleware.AdminAuth(adminHandler.DeleteOrder))
+	http.HandleFunc("DELETE /admin/users/{id}", middleware.AdminAuth(adminHandler.DeleteUser))
+	http.HandleFunc("GET /admin/categories", middleware.AdminAuth(categoryHandler.ListCategories))
+	http.HandleFunc("POST /admin/categories", middleware.AdminAuth(categoryHandler.CreateCategory))
+	http.HandleFunc("PUT /admin/categories/{id}", middleware.AdminAuth(categoryHandler.UpdateCategory))
+	http.HandleFunc("DELETE /admin/categories/{id}", middleware.AdminAuth(categoryHandler.DeleteCategory))
+	http.HandleFunc("PUT /admin/products/{id}/categories", middleware.AdminAuth(categoryHandler.SetProductCategories))
+	http.HandleFunc("GET /admin/products/{id}/variants", middleware.AdminAuth(variantHandler.ListVariants))
+	http.HandleFunc("POST /admin/products/{id}/variants/generate", middleware.AdminAuth(variantHandler.GenerateVariants))
+	http.HandleFunc("PUT /admin/variants/{id}", middleware.AdminAuth(variantHandler.UpdateVariant))
+	http.HandleFunc("GET /admin/products/{id}/images", middleware.AdminAuth(mediaHandler.ListImages))
+	http.HandleFunc("POST /admin/products/{id}/images", middleware.AdminAuth(mediaHandler.UploadImages))
+	http.HandleFunc("PUT /admin/products/{id}/images/order", middleware.AdminAuth(mediaHandler.ReorderImages))
+	http.HandleFunc("POST /admin/products/{id}/images/{image_id}/primary", middleware.AdminAuth(mediaHandler.SetPrimaryImage))
+	http.HandleFunc("DELETE /admin/products/{id}/images/{image_id}", middleware.AdminAuth(mediaHandler.DeleteImage))
+	http.HandleFunc("GET /admin/trash/{kind}", middleware.AdminAuth(trashHandler.ListTrash))
+	http.HandleFunc("POST /admin/trash/{kind}/{id}/restore", middleware.AdminAuth(trashHandler.Restore))
+	http.HandleFunc("POST /admin/trash/purge", middleware.AdminAuth(trashHandler.Purge))
+	http.HandleFunc("GET /admin/warehouses", middleware.AdminAuth(warehouseHandler.ListWarehouses))
+	http.HandleFunc("POST /admin/warehouses", middleware.AdminAuth(warehouseHandler.CreateWarehouse))
+	http.HandleFunc("PUT /admin/warehouses/{id}", middleware.AdminAuth(warehouseHandler.UpdateWarehouse))
+	http.HandleFunc("GET /admin/warehouses/{id}/stock", middleware.AdminAuth(warehouseHandler.GetStockLevels))
+	http.HandleFunc("GET /admin/stock/movements", middleware.AdminAuth(warehouseHandler.ListMovements))
+	http.HandleFunc("POST /admin/stock/movements", middleware.AdminAuth(warehouseHandler.RecordMovement))
+	http.HandleFunc("POST /admin/stock/transfers", middleware.AdminAuth(warehouseHandler.TransferStock))
+	http.HandleFunc("GET /admin/stock/reconciliation", middleware.AdminAuth(warehouseHandler.Reconcile))
+	http.HandleFunc("GET /admin/stock/alerts", middleware.AdminAuth(alertHandler.ListAlerts))
+	http.HandleFunc("PUT /admin/products/{id}/reorder-threshold", middleware.AdminAuth(alertHandler.SetReorderThreshold))
+	http.HandleFunc("GET /admin/products/{id}/sales", middleware.AdminAuth(saleHandler.ListSales))
+	http.HandleFunc("POST /admin/products/{id}/sales", middleware.AdminAuth(saleHandler.CreateSale))
+	http.HandleFunc("GET /admin/products/{id}/price-history", middleware.AdminAuth(saleHandler.PriceHistory))
+	http.HandleFunc("POST /admin/sales/{id}/cancel", middleware.AdminAuth(saleHandler.CancelSale))
+	http.HandleFunc("GET /admin/coupons", middleware.AdminAuth(couponHandler.ListCoupons))
+	http.HandleFunc("POST /admin/coupons", middleware.AdminAuth(couponHandler.CreateCoupon))
+	http.HandleFunc("GET /admin/coupons/{id}", middleware.AdminAuth(couponHandler.GetCoupon))
+	http.HandleFunc("PUT /admin/coupons/{id}", middleware.AdminAuth(couponHandler.UpdateCoupon))
+	http.HandleFunc("DELETE /admin/coupons/{id}", middleware.AdminAuth(couponHandler.DeleteCoupon))
+	http.HandleFunc("GET /admin/coupons/{id}/redemptions", middleware.AdminAuth(couponHandler.ListRedemptions))
+	http.HandleFunc("GET /admin/promotions", middleware.AdminAuth(promotionHandler.ListPromotions))
+	http.HandleFunc("POST /admin/promotions", middleware.AdminAuth(promotionHandler.CreatePromotion))
+	http.HandleFunc("GET /admin/promotions/{id}", middleware.AdminAuth(promotionHandler.GetPromotion))
+	http.HandleFunc("PUT /admin/promotions/{id}", middleware.AdminAuth(promotionHandler.UpdatePromotion))
+	http.HandleFunc("DELETE /admin/promotions/{id}", middleware.AdminAuth(promotionHandler.DeletePromotion))
+	http.HandleFunc("POST /admin/promotions/{id}/preview", middleware.AdminAuth(promotionHandler.PreviewPromotion))
+	http.HandleFunc("GET /admin/customer-groups", middleware.AdminAuth(priceListHandler.ListGroups))
+	http.HandleFunc("POST /admin/customer-groups", middleware.AdminAuth(priceListHandler.CreateGroup))
+	http.HandleFunc("PUT /admin/customer-groups/{id}", middleware.AdminAuth(priceListHandler.UpdateGroup))
+	http.HandleFunc("DELETE /admin/customer-groups/{id}", middleware.AdminAuth(priceListHandler.DeleteGroup))
+	http.HandleFunc("PUT /admin/users/{id}/customer-group", middleware.AdminAuth(priceListHandler.AssignUser))
+	http.HandleFunc("GET /admin/price-lists", middleware.AdminAuth(priceListHandler.ListPriceLists))
+	http.HandleFunc("POST /admin/price-lists", middleware.AdminAuth(priceListHandler.CreatePriceList))
+	http.HandleFunc("GET /admin/price-lists/{id}", middleware.AdminAuth(priceListHandler.GetPriceList))
+	http.HandleFunc("PUT /admin/price-lists/{id}", middleware.AdminAuth(priceListHandler.UpdatePriceList))
+	http.HandleFunc("DELETE /admin/price-lists/{id}", middleware.AdminAuth(priceListHandler.DeletePriceList))
+	http.HandleFunc("GET /admin/tax-classes", middleware.AdminAuth(taxHandler.ListClasses))
+	http.HandleFunc("POST /admin/tax-classes", middleware.AdminAuth(taxHandler.CreateClass))
+	http.HandleFunc("PUT /admin/tax-classes/{id}", middleware.AdminAuth(taxHandler.UpdateClass))
+	http.HandleFunc("DELETE /admin/tax-classes/{id}", middleware.AdminAuth(taxHandler.DeleteClass))
+	http.HandleFunc("PUT /admin/products/{id}/tax-class", middleware.AdminAuth(taxHandler.SetProductClass))
+	http.HandleFunc("GET /admin/tax-zones", middleware.AdminAuth(taxHandler.ListZones))
+	http.HandleFunc("POST /admin/tax-zones", middleware.AdminAuth(taxHandler.CreateZone))
+	http.HandleFunc("GET /admin/tax-zones/{id}", middleware.AdminAuth(taxHandler.GetZone))
+	http.HandleFunc("PUT /admin/tax-zones/{id}", middleware.AdminAuth(taxHandler.UpdateZone))
+	http.HandleFunc("DELETE /admin/tax-zones/{id}", middleware.AdminAuth(taxHandler.DeleteZone))
+	http.HandleFunc("GET /admin/shipping-zones", middleware.AdminAuth(shippingHandler.ListZones))
+	http.HandleFunc("POST /admin/shipping-zones", middleware.AdminAuth(shippingHandler.CreateZone))
+	http.HandleFunc("GET /admin/shipping-zones/{id}", middleware.AdminAuth(shippingHandler.GetZone))
+	http.HandleFunc("PUT /admin/shipping-zones/{id}", middleware.AdminAuth(shippingHandler.UpdateZone))
+	http.HandleFunc("DELETE /admin/shipping-zones/{id}", middleware.AdminAuth(shippingHandler.DeleteZone))
+}
+
+// setupCatalogRoutes configures public catalog routes. Signed-in shoppers see the
+// prices of their customer group.
+func setupCatalogRoutes(productHandler *handlers.ProductHandler, categoryHandler *handlers.CategoryHandler,
+	authService service.AuthService) {
+	shopper := middleware.OptionalUserAuth(authService)
+	http.HandleFunc("GET /products", shopper(productHandler.ListProducts))
+	http.HandleFunc("GET /products/search", shopper(productHandler.Search))
+	http.HandleFunc("GET /products/suggest", productHandler.Suggest)
+	http.HandleFunc("GET /products/{id}", shopper(productHandler.GetProduct))
+	http.HandleFunc("GET /categories", categoryHandler.GetCategoryTree)
+	http.HandleFunc("GET /categories/{slug}/products", shopper(categoryHandler.ListCategoryProducts))
 }
 
 // setupUserRoutes configures user-related routes
-func setupUserRoutes(userService service.UserService, authService service.AuthService, cartService service.CartService) {
-	// Initialize cart handler
+func setupUserRoutes(userService service.UserService, authService service.AuthService, cartService service.CartService, checkoutService service.CheckoutService, orderService service.OrderService, alertService service.AlertService) {
+	// Initialize cart, checkout, order and stock subscription handlers
 	cartHandler := handlers.NewCartHandler(cartService)
+	checkoutHandler := handlers.NewCheckoutHandler(checkoutService)
+	orderHandler := handlers.NewOrderHandler(orderService)
+	subscriptionHandler := handlers.NewStockSubscriptionHandler(alertService)
 	
 	// Cart routes
 	http.HandleFunc("/user/cart", middleware.UserAuth(authService)(cartHandler.GetCart))
 	http.HandleFunc("/user/cart/add", middleware.UserAuth(authService)(cartHandler.AddToCart))
 	http.HandleFunc("/user/cart/remove", middleware.UserAuth(authService)(cartHandler.RemoveFromCart))
+	http.HandleFunc("PUT /user/cart/items/{product_id}", middleware.UserAuth(authService)(cartHandler.UpdateQuantity))
+	http.HandleFunc("POST /user/cart/coupon", middleware.UserAuth(authService)(cartHandler.ApplyCoupon))
+	http.HandleFunc("DELETE /user/cart/coupon/{code}", middleware.UserAuth(authService)(cartHandler.RemoveCoupon))
+	http.HandleFunc("POST /user/cart/shipping-rates", middleware.UserAuth(authService)(cartHandler.QuoteShipping))
+	// Guest cart routes share the cart handlers with the user cart
+	http.HandleFunc("/guest/cart", middleware.GuestCart(cartService)(cartHandler.GetCart))
+	http.HandleFunc("/guest/cart/add", middleware.GuestCart(cartService)(cartHandler.AddToCart))
+	http.HandleFunc("/guest/cart/remove", middleware.GuestCart(cartService)(cartHandler.RemoveFromCart))
+	http.HandleFunc("PUT /guest/cart/items/{product_id}", middleware.GuestCart(cartService)(cartHandler.UpdateQuantity))
+	http.HandleFunc("POST /guest/cart/shipping-rates", middleware.GuestCart(cartService)(cartHandler.QuoteShipping))
+	// Checkout routes
+	http.HandleFunc("POST /user/checkout", middleware.UserAuth(authService)(checkoutHandler.Checkout))
+	// Order history routes
+	http.HandleFunc("GET /user/orders", middleware.UserAuth(authService)(orderHandler.ListOrders))
+	http.HandleFunc("GET /user/orders/{id}", middleware.UserAuth(authService)(orderHandler.GetOrder))
+	// Back-in-stock subscription routes
+	http.HandleFunc("GET /user/stock-subscriptions", middleware.UserAuth(authService)(subscriptionHandler.ListSubscriptions))
+	http.HandleFunc("POST /user/stock-subscriptions", middleware.UserAuth(authService)(subscriptionHandler.Subscribe))
+	http.HandleFunc("DELETE /user/stock-subscriptions/{id}", middleware.UserAuth(authService)(subscriptionHandler.Unsubscribe))
 	// User routes with authentication
 	http.HandleFunc("/user/profile", middleware.UserAuth(authService)(func(w http.ResponseWriter, r *http.Request) {
 		userID, ok := middleware.GetUserID(r)
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are common English words that carry no meaning for product search
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "the": true, "to": true, "with": true,
+}
+
+// token is a word of a text together with its position in the original string
+type token struct {
+	start, end int
+	term       string // Normalised and stemmed form; empty for stop words
+}
+
+// tokenize splits text into runs of letters and digits, lowercases and stems them
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			tokens = append(tokens, newToken(text, start, i))
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, newToken(text, start, len(text)))
+	}
+	return tokens
+}
+
+func newToken(text string, start, end int) token {
+	word := strings.ToLower(text[start:end])
+	if stopWords[word] {
+		return token{start: start, end: end}
+	}
+	return token{start: start, end: end, term: stem(word)}
+}
+
+// analyze returns the terms of a text, without stop words
+func analyze(text string) []string {
+	var terms []string
+	for _, t := range tokenize(text) {
+		if t.term != "" {
+			terms = append(terms, t.term)
+		}
+	}
+	return terms
+}
+
+// stem reduces a lowercase English word to a crude stem by stripping common
+// inflections. It only needs to map related words to the same form consistently,
+// not to produce dictionary words.
+func stem(word string) string {
+	n := len(word)
+	switch {
+	case n > 4 && strings.HasSuffix(word, "ies"):
+		return word[:n-3] + "y"
+	case n > 4 && strings.HasSuffix(word, "sses"):
+		return word[:n-2]
+	case n > 4 && (strings.HasSuffix(word, "xes") || strings.HasSuffix(word, "ches") || strings.HasSuffix(word, "shes")):
+		return word[:n-2]
+	case n > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us"):
+		return word[:n-1]
+	case n > 5 && strings.HasSuffix(word, "ing"):
+		return undouble(word[:n-3])
+	case n > 4 && strings.HasSuffix(word, "ed"):
+		return undouble(word[:n-2])
+	case n > 4 && strings.HasSuffix(word, "ly"):
+		return word[:n-2]
+	}
+	return word
+}
+
+// undouble removes a doubled final consonant left behind by suffix stripping,
+// so "running" and "run" share a stem
+func undouble(word string) string {
+	n := len(word)
+	if n > 2 && word[n-1] == word[n-2] && !strings.ContainsRune("aeiouls", rune(word[n-1])) {
+		return word[:n-1]
+	}
+	return word
+}
+
+// maxEdits returns how many typos are tolerated in a term of the given length
+func maxEdits(term string) int {
+	switch n := len([]rune(term)); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// editDistance computes the optimal string alignment distance between two terms,
+// counting insertions, deletions, substitutions and transpositions. It stops early
+// and returns limit+1 once the distance is known to exceed limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > limit || -d > limit {
+		return limit + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
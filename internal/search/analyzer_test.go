@@ -0,0 +1,118 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		{"batteries", "battery"},
+		{"glasses", "glass"},
+		{"boxes", "box"},
+		{"watches", "watch"},
+		{"brushes", "brush"},
+		{"shoes", "shoe"},
+		{"ties", "tie"},
+		{"dress", "dress"},
+		{"cactus", "cactus"},
+		{"gas", "gas"},
+		{"running", "run"},
+		{"falling", "fall"},
+		{"sing", "sing"},
+		{"packed", "pack"},
+		{"stopped", "stop"},
+		{"bed", "bed"},
+		{"quickly", "quick"},
+	}
+	for _, tt := range tests {
+		if got := stem(tt.word); got != tt.want {
+			t.Errorf("stem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestStemRelatedWordsShareStem(t *testing.T) {
+	tests := [][]string{
+		{"run", "runs", "running"},
+		{"pack", "packs", "packed", "packing"},
+		{"battery", "batteries"},
+		{"watch", "watches"},
+	}
+	for _, words := range tests {
+		want := stem(words[0])
+		for _, word := range words[1:] {
+			if got := stem(word); got != want {
+				t.Errorf("stem(%q) = %q, want %q like %q", word, got, want, words[0])
+			}
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"The Running Shoes", []string{"run", "shoe"}},
+		{"Case for USB-C cables, 2 pack", []string{"case", "usb", "c", "cable", "2", "pack"}},
+		{"of the and", nil},
+	}
+	for _, tt := range tests {
+		if got := analyze(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("analyze(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMaxEdits(t *testing.T) {
+	tests := []struct {
+		term string
+		want int
+	}{
+		{"tv", 0},
+		{"cap", 0},
+		{"shoe", 1},
+		{"jacket", 1},
+		{"keyboard", 2},
+		{"headphones", 2},
+		{"größere", 1}, // 7 runes, 9 bytes
+	}
+	for _, tt := range tests {
+		if got := maxEdits(tt.term); got != tt.want {
+			t.Errorf("maxEdits(%q) = %d, want %d", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"shoe", "shoe", 1, 0},
+		{"shoe", "shoes", 1, 1},
+		{"shoes", "shoe", 1, 1},
+		{"shoe", "shoo", 1, 1},
+		{"shoe", "hsoe", 1, 1},
+		{"laptop", "latpop", 1, 1},
+		{"keyboard", "kyeboadr", 2, 2},
+		{"kitten", "sitting", 3, 3},
+		// Over the limit the distance is reported as limit+1
+		{"kitten", "sitting", 2, 3},
+		{"abc", "abcdef", 2, 3},
+		{"shoe", "boot", 1, 2},
+		// Optimal string alignment edits no substring twice
+		{"ca", "abc", 3, 3},
+		{"café", "cafe", 1, 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.limit); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package search
+
+import "ecommerce-app/internal/models"
+
+// CategoryTree resolves category assignments to the slugs indexed for a document
+type CategoryTree struct {
+	parents map[uint]*uint
+	slugs   map[uint]string
+}
+
+// NewCategoryTree builds a CategoryTree from the full list of categories
+func NewCategoryTree(categories []models.Category) *CategoryTree {
+	tree := &CategoryTree{
+		parents: make(map[uint]*uint, len(categories)),
+		slugs:   make(map[uint]string, len(categories)),
+	}
+	for _, category := range categories {
+		tree.parents[category.ID] = category.ParentID
+		tree.slugs[category.ID] = category.Slug
+	}
+	return tree
+}
+
+// Slugs returns the slugs of the given categories and all of their ancestors, so a
+// product filed under a subcategory is also found under its parents
+func (t *CategoryTree) Slugs(categoryIDs []uint) []string {
+	seen := map[uint]bool{}
+	slugs := []string{}
+	for _, id := range categoryIDs {
+		for current := &id; current != nil && !seen[*current]; current = t.parents[*current] {
+			slug, ok := t.slugs[*current]
+			if !ok {
+				break
+			}
+			seen[*current] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs
+}
@@ -0,0 +1,198 @@
+// Package search defines the product search abstraction used by the catalog and
+// provides an in-memory implementation of it.
+package search
+
+import (
+	"ecommerce-app/pkg/money"
+	"slices"
+	"sort"
+)
+
+// Availability facet values
+const (
+	AvailabilityInStock    = "in_stock"
+	AvailabilityOutOfStock = "out_of_stock"
+)
+
+// Document is the searchable view of a product
+type Document struct {
+	ID          uint
+	Name        string
+	Description string
+	Categories  []string // Slugs of the assigned categories and all of their ancestors
+	Price       money.Money
+	InStock     bool
+}
+
+// Query is a search request. Empty text matches every document, which lets the
+// storefront browse by facets alone.
+type Query struct {
+	Text     string
+	Category string // Category slug
+	MinPrice *money.Money
+	MaxPrice *money.Money
+	InStock  bool
+	Page     int
+	PageSize int
+}
+
+// Hit is a matching document with its relevance and highlighted text
+type Hit struct {
+	ID            uint
+	Score         float64
+	NameHighlight string
+	Snippet       string
+}
+
+// FacetCount is the number of matches sharing a facet value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// PriceBucket is the number of matches priced in [Min, Max). A nil Max is unbounded.
+type PriceBucket struct {
+	Min   money.Money  `json:"min"`
+	Max   *money.Money `json:"max"`
+	Count int          `json:"count"`
+}
+
+// Facets summarise the matches of a query for rendering filter sidebars. Each facet
+// is counted with every filter applied except its own, so selecting one value does
+// not hide the alternatives.
+type Facets struct {
+	Categories   []FacetCount  `json:"categories"`
+	Prices       []PriceBucket `json:"prices"`
+	Availability []FacetCount  `json:"availability"`
+}
+
+// Result is one page of hits together with the total number of matches and facets
+type Result struct {
+	Hits   []Hit
+	Total  int64
+	Facets Facets
+}
+
+// SearchIndex is a product search backend
+type SearchIndex interface {
+	Index(doc Document) error
+	Remove(id uint) error
+	Replace(docs []Document) error // Swaps the whole index for docs, dropping every other document
+	Search(query Query) (*Result, error)
+}
+
+// DefaultPriceBounds returns the price bucket boundaries used when none are configured
+func DefaultPriceBounds(currency string) []money.Money {
+	bounds := make([]money.Money, 0, 4)
+	for _, value := range []string{"25", "50", "100", "250"} {
+		bounds = append(bounds, money.MustParse(value, currency))
+	}
+	return bounds
+}
+
+// NewFacets returns facets with nothing counted yet: no categories, a price bucket
+// below, between and above the given bounds, and both availability values
+func NewFacets(bounds []money.Money) Facets {
+	facets := Facets{
+		Categories:   []FacetCount{},
+		Prices:       make([]PriceBucket, len(bounds)+1),
+		Availability: []FacetCount{{Value: AvailabilityInStock}, {Value: AvailabilityOutOfStock}},
+	}
+	for i := range facets.Prices {
+		if i > 0 {
+			facets.Prices[i].Min = bounds[i-1]
+		} else if len(bounds) > 0 {
+			facets.Prices[i].Min = money.Zero(bounds[0].Currency)
+		}
+		if i < len(bounds) {
+			max := bounds[i]
+			facets.Prices[i].Max = &max
+		}
+	}
+	return facets
+}
+
+// Candidate is a document matching the text of a query, before filters are applied
+type Candidate struct {
+	Document
+	Score float64
+}
+
+// Collect applies the filters of a query to text matches, counts facets and cuts
+// out the requested page. Candidates are ordered by descending score, then by ID.
+// Highlights are left to the backend.
+func Collect(candidates []Candidate, query Query, bounds []money.Money) *Result {
+	facets := NewFacets(bounds)
+
+	categoryCounts := map[string]int{}
+	matches := make([]Candidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		inCategory := query.Category == "" || slices.Contains(candidate.Categories, query.Category)
+		inPrice := priceMatches(candidate.Price, query)
+		inStock := !query.InStock || candidate.InStock
+
+		if inPrice && inStock {
+			for _, slug := range candidate.Categories {
+				categoryCounts[slug]++
+			}
+		}
+		if inCategory && inStock {
+			facets.Prices[bucketOf(candidate.Price, bounds)].Count++
+		}
+		if inCategory && inPrice {
+			if candidate.InStock {
+				facets.Availability[0].Count++
+			} else {
+				facets.Availability[1].Count++
+			}
+		}
+		if inCategory && inPrice && inStock {
+			matches = append(matches, candidate)
+		}
+	}
+
+	for slug, count := range categoryCounts {
+		facets.Categories = append(facets.Categories, FacetCount{Value: slug, Count: count})
+	}
+	sort.Slice(facets.Categories, func(i, j int) bool {
+		a, b := facets.Categories[i], facets.Categories[j]
+		return a.Count > b.Count || a.Count == b.Count && a.Value < b.Value
+	})
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		return a.Score > b.Score || a.Score == b.Score && a.ID < b.ID
+	})
+
+	result := &Result{Hits: []Hit{}, Total: int64(len(matches)), Facets: facets}
+	start := (query.Page - 1) * query.PageSize
+	if start < 0 || start >= len(matches) {
+		return result
+	}
+	end := min(start+query.PageSize, len(matches))
+	for _, match := range matches[start:end] {
+		result.Hits = append(result.Hits, Hit{ID: match.ID, Score: match.Score})
+	}
+	return result
+}
+
+// priceMatches reports whether a price lies within the price range of a query
+func priceMatches(price money.Money, query Query) bool {
+	if query.MinPrice != nil && price.Cmp(*query.MinPrice) < 0 {
+		return false
+	}
+	if query.MaxPrice != nil && price.Cmp(*query.MaxPrice) > 0 {
+		return false
+	}
+	return true
+}
+
+// bucketOf returns the index of the price bucket a price falls into
+func bucketOf(price money.Money, bounds []money.Money) int {
+	for i, bound := range bounds {
+		if price.Cmp(bound) < 0 {
+			return i
+		}
+	}
+	return len(bounds)
+}
@@ -0,0 +1,271 @@
+package search
+
+import (
+	"ecommerce-app/pkg/money"
+	"slices"
+	"testing"
+)
+
+func usd(value string) money.Money {
+	return money.MustParse(value, "USD")
+}
+
+func usdPtr(value string) *money.Money {
+	m := usd(value)
+	return &m
+}
+
+// facetCandidates spread over categories, price buckets and availability so that
+// every filter changes the facets of the others
+func facetCandidates() []Candidate {
+	return []Candidate{
+		{Document: Document{ID: 1, Categories: []string{"electronics", "audio"}, Price: usd("20"), InStock: true}},
+		{Document: Document{ID: 2, Categories: []string{"electronics", "audio"}, Price: usd("60"), InStock: false}},
+		{Document: Document{ID: 3, Categories: []string{"electronics", "computers"}, Price: usd("120"), InStock: true}},
+		{Document: Document{ID: 4, Categories: []string{"home"}, Price: usd("30"), InStock: true}},
+	}
+}
+
+func TestCollectFacets(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        Query
+		hits         []uint
+		categories   []FacetCount
+		prices       []int // Counts of the buckets <25, 25-50, 50-100, 100-250, 250+
+		availability []int // In stock, out of stock
+	}{
+		{
+			name:         "no filters",
+			query:        Query{},
+			hits:         []uint{1, 2, 3, 4},
+			categories:   []FacetCount{{"electronics", 3}, {"audio", 2}, {"computers", 1}, {"home", 1}},
+			prices:       []int{1, 1, 1, 1, 0},
+			availability: []int{3, 1},
+		},
+		{
+			name:  "category",
+			query: Query{Category: "audio"},
+			hits:  []uint{1, 2},
+			// Categories ignore the category filter so siblings stay selectable
+			categories:   []FacetCount{{"electronics", 3}, {"audio", 2}, {"computers", 1}, {"home", 1}},
+			prices:       []int{1, 0, 1, 0, 0},
+			availability: []int{1, 1},
+		},
+		{
+			name:         "in stock",
+			query:        Query{InStock: true},
+			hits:         []uint{1, 3, 4},
+			categories:   []FacetCount{{"electronics", 2}, {"audio", 1}, {"computers", 1}, {"home", 1}},
+			prices:       []int{1, 1, 0, 1, 0},
+			availability: []int{3, 1},
+		},
+		{
+			name:         "price range",
+			query:        Query{MinPrice: usdPtr("25"), MaxPrice: usdPtr("100")},
+			hits:         []uint{2, 4},
+			categories:   []FacetCount{{"audio", 1}, {"electronics", 1}, {"home", 1}},
+			prices:       []int{1, 1, 1, 1, 0},
+			availability: []int{1, 1},
+		},
+		{
+			name:         "all filters",
+			query:        Query{Category: "electronics", InStock: true, MaxPrice: usdPtr("100")},
+			hits:         []uint{1},
+			categories:   []FacetCount{{"audio", 1}, {"electronics", 1}, {"home", 1}},
+			prices:       []int{1, 0, 0, 1, 0},
+			availability: []int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.query
+			query.Page, query.PageSize = 1, 10
+			result := Collect(facetCandidates(), query, DefaultPriceBounds("USD"))
+
+			var hits []uint
+			for _, hit := range result.Hits {
+				hits = append(hits, hit.ID)
+			}
+			if !slices.Equal(hits, tt.hits) || result.Total != int64(len(tt.hits)) {
+				t.Errorf("hits = %v (total %d), want %v", hits, result.Total, tt.hits)
+			}
+			if !slices.Equal(result.Facets.Categories, tt.categories) {
+				t.Errorf("categories = %v, want %v", result.Facets.Categories, tt.categories)
+			}
+
+			var prices []int
+			for _, bucket := range result.Facets.Prices {
+				prices = append(prices, bucket.Count)
+			}
+			if !slices.Equal(prices, tt.prices) {
+				t.Errorf("prices = %v, want %v", prices, tt.prices)
+			}
+
+			availability := []int{result.Facets.Availability[0].Count, result.Facets.Availability[1].Count}
+			if !slices.Equal(availability, tt.availability) {
+				t.Errorf("availability = %v, want %v", availability, tt.availability)
+			}
+		})
+	}
+}
+
+func TestCollectPriceBuckets(t *testing.T) {
+	result := Collect(nil, Query{Page: 1, PageSize: 10}, DefaultPriceBounds("USD"))
+
+	bounds := []string{"0", "25", "50", "100", "250"}
+	if len(result.Facets.Prices) != len(bounds) {
+		t.Fatalf("got %d price buckets, want %d", len(result.Facets.Prices), len(bounds))
+	}
+	for i, bucket := range result.Facets.Prices {
+		if bucket.Min.Cmp(usd(bounds[i])) != 0 {
+			t.Errorf("bucket %d starts at %s, want %s", i, bucket.Min, bounds[i])
+		}
+		if i == len(bounds)-1 {
+			if bucket.Max != nil {
+				t.Errorf("last bucket ends at %s, want no bound", bucket.Max)
+			}
+		} else if bucket.Max == nil || bucket.Max.Cmp(usd(bounds[i+1])) != 0 {
+			t.Errorf("bucket %d ends at %v, want %s", i, bucket.Max, bounds[i+1])
+		}
+	}
+}
+
+func TestCollectPages(t *testing.T) {
+	candidates := facetCandidates()
+	candidates[2].Score = 2
+	candidates[3].Score = 1
+
+	tests := []struct {
+		page, pageSize int
+		want           []uint
+	}{
+		{1, 2, []uint{3, 4}},
+		{2, 2, []uint{1, 2}},
+		{3, 2, nil},
+	}
+	for _, tt := range tests {
+		result := Collect(candidates, Query{Page: tt.page, PageSize: tt.pageSize}, nil)
+		var hits []uint
+		for _, hit := range result.Hits {
+			hits = append(hits, hit.ID)
+		}
+		if !slices.Equal(hits, tt.want) || result.Total != 4 {
+			t.Errorf("page %d = %v (total %d), want %v", tt.page, hits, result.Total, tt.want)
+		}
+	}
+}
+
+func TestMemoryIndexTermMatching(t *testing.T) {
+	idx := NewMemoryIndex(nil)
+	docs := []Document{
+		{ID: 1, Name: "Wireless keyboard"},
+		{ID: 2, Name: "Leather jacket"},
+		{ID: 3, Name: "Running shoes"},
+	}
+	for _, doc := range docs {
+		if err := idx.Index(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		text string
+		want []uint
+	}{
+		{"keyboard", []uint{1}},
+		{"wireless key", []uint{1}},
+		// Only the last term matches as a prefix
+		{"key wireless", nil},
+		{"jack", []uint{2}},
+		// Typos within maxEdits, including transposed letters
+		{"keybaord", []uint{1}},
+		{"jakcet", []uint{2}},
+		{"leathr", []uint{2}},
+		{"kyeboadr", []uint{1}},
+		// Short terms tolerate no typos
+		{"run shoe", []uint{3}},
+		{"rub shoe", nil},
+		// Every term must match
+		{"wireless jacket", nil},
+	}
+	for _, tt := range tests {
+		result, err := idx.Search(Query{Text: tt.text, Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var hits []uint
+		for _, hit := range result.Hits {
+			hits = append(hits, hit.ID)
+		}
+		if !slices.Equal(hits, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.text, hits, tt.want)
+		}
+	}
+}
+
+func TestMemoryIndexReplace(t *testing.T) {
+	idx := NewMemoryIndex(nil)
+	for _, doc := range []Document{{ID: 1, Name: "Wireless keyboard"}, {ID: 2, Name: "Leather jacket"}} {
+		if err := idx.Index(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Product 2 was deleted and product 1 renamed since the index was built
+	if err := idx.Replace([]Document{{ID: 1, Name: "Wireless mouse"}, {ID: 3, Name: "Running shoes"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text string
+		want []uint
+	}{
+		{"", []uint{1, 3}},
+		{"jacket", nil},
+		{"keyboard", nil},
+		{"mouse", []uint{1}},
+	}
+	for _, tt := range tests {
+		result, err := idx.Search(Query{Text: tt.text, Page: 1, PageSize: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var hits []uint
+		for _, hit := range result.Hits {
+			hits = append(hits, hit.ID)
+		}
+		slices.Sort(hits)
+		if !slices.Equal(hits, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.text, hits, tt.want)
+		}
+	}
+}
+
+func TestMemoryIndexEscapesHighlights(t *testing.T) {
+	idx := NewMemoryIndex(nil)
+	err := idx.Index(Document{
+		ID:          1,
+		Name:        `<script>alert(1)</script> Keyboard`,
+		Description: `A "mechanical" keyboard <img src=x onerror=alert(1)> & mouse`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := idx.Search(Query{Text: "keyboard", Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Hits) != 1 {
+		t.Fatalf("got %d hits, want 1", len(result.Hits))
+	}
+	hit := result.Hits[0]
+	if want := "&lt;script&gt;alert(1)&lt;/script&gt; <mark>Keyboard</mark>"; hit.NameHighlight != want {
+		t.Errorf("NameHighlight = %q, want %q", hit.NameHighlight, want)
+	}
+	if want := "A &#34;mechanical&#34; <mark>keyboard</mark> &lt;img src=x onerror=alert(1)&gt; &amp; mouse"; hit.Snippet != want {
+		t.Errorf("Snippet = %q, want %q", hit.Snippet, want)
+	}
+}
@@ -0,0 +1,231 @@
+package search
+
+import (
+	"ecommerce-app/pkg/money"
+	"html"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Field weights and match penalties used for scoring
+const (
+	nameWeight        = 3.0
+	descriptionWeight = 1.0
+	prefixFactor      = 0.8
+	fuzzyFactor       = 0.5
+	snippetWords      = 30
+)
+
+// MemoryIndex is an inverted index held in process memory. It suits a single
+// instance and must be rebuilt on start-up.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	docs     map[uint]Document
+	postings map[string]map[uint]float64 // term -> document ID -> weighted term frequency
+	bounds   []money.Money
+}
+
+// NewMemoryIndex creates an empty in-memory index that buckets prices at the given bounds
+func NewMemoryIndex(bounds []money.Money) *MemoryIndex {
+	return &MemoryIndex{
+		docs:     map[uint]Document{},
+		postings: map[string]map[uint]float64{},
+		bounds:   bounds,
+	}
+}
+
+// Index adds a document, replacing any previous version of it
+func (idx *MemoryIndex) Index(doc Document) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(doc.ID)
+	idx.docs[doc.ID] = doc
+	idx.addTerms(doc.ID, doc.Name, nameWeight)
+	idx.addTerms(doc.ID, doc.Description, descriptionWeight)
+	return nil
+}
+
+// Remove deletes a document from the index
+func (idx *MemoryIndex) Remove(id uint) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+	return nil
+}
+
+// Replace builds a fresh index from docs and swaps it in, so searches see either the
+// old documents or the new ones and documents missing from docs are dropped
+func (idx *MemoryIndex) Replace(docs []Document) error {
+	fresh := NewMemoryIndex(idx.bounds)
+	for _, doc := range docs {
+		fresh.Index(doc)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.docs, idx.postings = fresh.docs, fresh.postings
+	return nil
+}
+
+// Search matches every term of the query text against the index. Terms match
+// exactly, as a prefix when they are the last term, or within a small number of
+// typos. Hits are ranked by TF-IDF with name matches weighted above description matches.
+func (idx *MemoryIndex) Search(query Query) (*Result, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	terms := analyze(query.Text)
+	var candidates []Candidate
+	matched := map[string]bool{}
+
+	if len(terms) == 0 {
+		candidates = make([]Candidate, 0, len(idx.docs))
+		for _, doc := range idx.docs {
+			candidates = append(candidates, Candidate{Document: doc})
+		}
+	} else {
+		scores := map[uint]float64{}
+		for i, term := range terms {
+			termScores := map[uint]float64{}
+			for vocab, factor := range idx.expand(term, i == len(terms)-1) {
+				matched[vocab] = true
+				postings := idx.postings[vocab]
+				idf := math.Log(1 + float64(len(idx.docs))/float64(len(postings)))
+				for id, tf := range postings {
+					termScores[id] += factor * tf * idf
+				}
+			}
+
+			// Every term must match, so keep only documents seen for all terms so far
+			if i == 0 {
+				scores = termScores
+				continue
+			}
+			for id := range scores {
+				if score, ok := termScores[id]; ok {
+					scores[id] += score
+				} else {
+					delete(scores, id)
+				}
+			}
+		}
+
+		candidates = make([]Candidate, 0, len(scores))
+		for id, score := range scores {
+			candidates = append(candidates, Candidate{Document: idx.docs[id], Score: score})
+		}
+	}
+
+	result := Collect(candidates, query, idx.bounds)
+	for i := range result.Hits {
+		doc := idx.docs[result.Hits[i].ID]
+		result.Hits[i].NameHighlight = highlight(doc.Name, matched)
+		result.Hits[i].Snippet = snippet(doc.Description, matched)
+	}
+	return result, nil
+}
+
+// expand returns the indexed terms a query term matches, each with a score factor
+func (idx *MemoryIndex) expand(term string, prefix bool) map[string]float64 {
+	matches := map[string]float64{}
+	if _, ok := idx.postings[term]; ok {
+		matches[term] = 1
+	}
+
+	limit := maxEdits(term)
+	for vocab := range idx.postings {
+		if vocab == term {
+			continue
+		}
+		if prefix && strings.HasPrefix(vocab, term) {
+			matches[vocab] = prefixFactor
+		} else if limit > 0 && editDistance(term, vocab, limit) <= limit {
+			matches[vocab] = fuzzyFactor
+		}
+	}
+	return matches
+}
+
+// addTerms adds the terms of a field to the postings of a document
+func (idx *MemoryIndex) addTerms(id uint, text string, weight float64) {
+	for _, term := range analyze(text) {
+		postings, ok := idx.postings[term]
+		if !ok {
+			postings = map[uint]float64{}
+			idx.postings[term] = postings
+		}
+		postings[id] += weight
+	}
+}
+
+// remove deletes a document and its postings. The caller must hold the write lock.
+func (idx *MemoryIndex) remove(id uint) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for _, term := range append(analyze(doc.Name), analyze(doc.Description)...) {
+		if postings, ok := idx.postings[term]; ok {
+			delete(postings, id)
+			if len(postings) == 0 {
+				delete(idx.postings, term)
+			}
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// highlight escapes text for HTML and wraps the words whose terms matched the query
+// in <mark> tags
+func highlight(text string, matched map[string]bool) string {
+	var b strings.Builder
+	last := 0
+	for _, t := range tokenize(text) {
+		if !matched[t.term] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[last:t.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[t.start:t.end]))
+		b.WriteString("</mark>")
+		last = t.end
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// snippet returns a highlighted excerpt of text starting shortly before the first match
+func snippet(text string, matched map[string]bool) string {
+	words := strings.Fields(text)
+	start := 0
+	for i, word := range words {
+		if hasMatch(word, matched) {
+			start = max(i-5, 0)
+			break
+		}
+	}
+	end := min(start+snippetWords, len(words))
+
+	excerpt := highlight(strings.Join(words[start:end], " "), matched)
+	if start > 0 {
+		excerpt = "... " + excerpt
+	}
+	if end < len(words) {
+		excerpt += " ..."
+	}
+	return excerpt
+}
+
+// hasMatch reports whether any term of a word matched the query
+func hasMatch(word string, matched map[string]bool) bool {
+	for _, t := range tokenize(word) {
+		if matched[t.term] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/notify"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/logger"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// notifyBatchSize limits the back-in-stock notifications a single check sends
+const notifyBatchSize = 200
+
+var (
+	// ErrInvalidThreshold is returned when a reorder threshold is not positive
+	ErrInvalidThreshold = errors.New("invalid reorder threshold")
+	// ErrAlreadyInStock is returned when subscribing to a product or variant that is in stock
+	ErrAlreadyInStock = errors.New("already in stock")
+	// ErrSubscriptionNotFound is returned when a shopper has no such pending subscription
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+)
+
+// StockCheckReport summarizes what a stock check did
+type StockCheckReport struct {
+	Raised   int   // Low-stock alerts opened
+	Resolved int64 // Low-stock alerts closed because stock recovered
+	Notified int   // Shoppers told that a product is back in stock
+	Failed   int   // Notifications that could not be sent; retried by the next check
+}
+
+// AlertService defines the interface for low-stock alerts and back-in-stock subscriptions
+type AlertService interface {
+	SetReorderThreshold(productID uint, threshold *int) error
+	ListLowStockAlerts(openOnly bool, page, pageSize int) ([]models.LowStockAlert, int64, error)
+	Subscribe(userID, productID, variantID uint) (*models.StockSubscription, bool, error)
+	ListSubscriptions(userID uint) ([]models.StockSubscription, error)
+	Unsubscribe(userID, id uint) error
+	CheckStock() (StockCheckReport, error)
+}
+
+// DefaultAlertService implements AlertService
+type DefaultAlertService struct {
+	repo        repository.AlertRepository
+	productRepo repository.ProductRepository
+	variantRepo repository.VariantRepository
+	notifier    notify.Notifier
+	adminEmail  string
+	log         *logger.Logger
+}
+
+// NewAlertService creates a new instance of DefaultAlertService. Low-stock alerts
+// are sent to adminEmail; when it is empty they are only listed.
+func NewAlertService(repo repository.AlertRepository, productRepo repository.ProductRepository,
+	variantRepo repository.VariantRepository, notifier notify.Notifier, adminEmail string) AlertService {
+	return &DefaultAlertService{
+		repo:        repo,
+		productRepo: productRepo,
+		variantRepo: variantRepo,
+		notifier:    notifier,
+		adminEmail:  adminEmail,
+		log:         logger.New(),
+	}
+}
+
+// SetReorderThreshold sets the stock level below which a product raises a low-stock
+// alert. A nil threshold turns alerts off for the product.
+func (s *DefaultAlertService) SetReorderThreshold(productID uint, threshold *int) error {
+	if threshold != nil && *threshold <= 0 {
+		return fmt.Errorf("%w: threshold must be positive", ErrInvalidThreshold)
+	}
+	if err := s.repo.SetReorderThreshold(productID, threshold); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrProductNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ListLowStockAlerts retrieves one page of low-stock alerts, newest first
+func (s *DefaultAlertService) ListLowStockAlerts(openOnly bool, page, pageSize int) ([]models.LowStockAlert, int64, error) {
+	return s.repo.ListAlerts(openOnly, page, pageSize)
+}
+
+// Subscribe asks for the user to be notified when an out-of-stock product, or one of
+// its variants, is back in stock. Subscribing twice returns the pending subscription
+// and false.
+func (s *DefaultAlertService) Subscribe(userID, productID, variantID uint) (*models.StockSubscription, bool, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, false, ErrProductNotFound
+		}
+		return nil, false, err
+	}
+
+	stock := product.Stock
+	if variantID != 0 {
+		variant, err := s.variantRepo.FindByID(variantID)
+		if err != nil || variant.ProductID != productID {
+			if err == nil || errors.Is(err, repository.ErrRecordNotFound) {
+				return nil, false, ErrVariantNotFound
+			}
+			return nil, false, err
+		}
+		stock = variant.Stock
+	}
+	if stock > 0 {
+		return nil, false, ErrAlreadyInStock
+	}
+
+	subscription := &models.StockSubscription{
+		ProductID: productID,
+		VariantID: variantID,
+		UserID:    userID,
+	}
+	created, err := s.repo.Subscribe(subscription)
+	if err != nil {
+		return nil, false, err
+	}
+	return subscription, created, nil
+}
+
+// ListSubscriptions retrieves the pending back-in-stock subscriptions of a user
+func (s *DefaultAlertService) ListSubscriptions(userID uint) ([]models.StockSubscription, error) {
+	return s.repo.ListSubscriptions(userID)
+}
+
+// Unsubscribe removes a pending back-in-stock subscription of a user
+func (s *DefaultAlertService) Unsubscribe(userID, id uint) error {
+	if err := s.repo.DeleteSubscription(userID, id); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrSubscriptionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// CheckStock compares product stock with reorder thresholds and pending
+// subscriptions. It opens alerts for products that fell below their threshold and
+// sends them to the admin, closes the alerts of products that recovered, and tells
+// subscribed shoppers about products that are back in stock.
+func (s *DefaultAlertService) CheckStock() (StockCheckReport, error) {
+	var report StockCheckReport
+
+	resolved, err := s.repo.ResolveLowStockAlerts(time.Now())
+	if err != nil {
+		return report, err
+	}
+	report.Resolved = resolved
+
+	alerts, err := s.repo.RaiseLowStockAlerts()
+	if err != nil {
+		return report, err
+	}
+	report.Raised = len(alerts)
+	if len(alerts) > 0 && s.adminEmail != "" {
+		if err := s.notifier.Send(lowStockMessage(s.adminEmail, alerts)); err != nil {
+			// The alerts stay listed for the admin even when the message is lost
+			s.log.Error("Failed to send low-stock alert: " + err.Error())
+		}
+	}
+
+	subscriptions, err := s.repo.DueSubscriptions(notifyBatchSize)
+	if err != nil {
+		return report, err
+	}
+	for _, subscription := range subscriptions {
+		if err := s.notifier.Send(backInStockMessage(subscription)); err != nil {
+			s.log.Error(fmt.Sprintf("Failed to send back-in-stock notification %d: %s", subscription.ID, err))
+			report.Failed++
+			continue
+		}
+		if err := s.repo.MarkNotified(subscription.ID, time.Now()); err != nil {
+			return report, err
+		}
+		report.Notified++
+	}
+	return report, nil
+}
+
+// lowStockMessage lists newly raised low-stock alerts for the admin
+func lowStockMessage(to string, alerts []models.LowStockAlert) notify.Message {
+	var body strings.Builder
+	body.WriteString("The stock of these products fell below their reorder threshold:\n\n")
+	for _, alert := range alerts {
+		fmt.Fprintf(&body, "- %s (product %d): %d in stock, threshold %d\n",
+			alert.ProductName, alert.ProductID, alert.Stock, alert.Threshold)
+	}
+
+	subject := fmt.Sprintf("Low stock: %s", alerts[0].ProductName)
+	if len(alerts) > 1 {
+		subject = fmt.Sprintf("Low stock: %d products", len(alerts))
+	}
+	return notify.Message{To: to, Subject: subject, Body: body.String()}
+}
+
+// backInStockMessage tells a subscribed shopper that a product is available again.
+// The product, variant and user of the subscription must be loaded.
+func backInStockMessage(subscription models.StockSubscription) notify.Message {
+	name := subscription.Product.Name
+	if subscription.Variant != nil && subscription.Variant.Title != "" {
+		name += " - " + subscription.Variant.Title
+	}
+	return notify.Message{
+		To:      subscription.User.Email,
+		Subject: "Back in stock: " + name,
+		Body:    fmt.Sprintf("Good news! %s is back in stock.\n\nYou asked us to let you know. Stock is limited, so order soon.\n", name),
+	}
+}
@@ -11,10 +11,14 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrEmailInTrash is returned when registering with the email address of a deleted
+// user, which stays reserved so the user can still be restored
+var ErrEmailInTrash = errors.New("an account with this email was deleted; contact support to restore it")
+
 // AuthService defines the interface for authentication-related business logic
 type AuthService interface {
-	Register(email, password string) (*models.User, error)
-	Login(email, password string) (string, error)
+	Register(email, password, guestCartToken string) (*models.User, bool, error)
+	Login(email, password, guestCartToken string) (string, bool, error)
 	ValidateToken(tokenString string) (*TokenClaims, error)
 	ResetPasswordRequest(email string) error
 	ResetPassword(token, newPassword string) error
@@ -30,12 +34,13 @@ type TokenClaims struct {
 // DefaultAuthService implements AuthService
 type DefaultAuthService struct {
 	userService UserService
+	cartService CartService
 	jwtSecret   []byte
 	log         *logger.Logger
 }
 
 // NewAuthService creates a new instance of DefaultAuthService
-func NewAuthService(userService UserService) AuthService {
+func NewAuthService(userService UserService, cartService CartService) AuthService {
 	jwtSecret := os.Getenv("JWT_SECRET")
 	if jwtSecret == "" {
 		jwtSecret = "default_jwt_secret_key" // Not recommended for production
@@ -43,24 +48,29 @@ func NewAuthService(userService UserService) AuthService {
 
 	return &DefaultAuthService{
 		userService: userService,
+		cartService: cartService,
 		jwtSecret:   []byte(jwtSecret),
 		log:         logger.New(),
 	}
 }
 
-// Register creates a new user account
-func (s *DefaultAuthService) Register(email, password string) (*models.User, error) {
+// Register creates a new user account, taking over the guest cart if a token is given.
+// It reports whether the guest cart token is done with and can be dropped.
+func (s *DefaultAuthService) Register(email, password, guestCartToken string) (*models.User, bool, error) {
 	// Check if user already exists
 	existingUser, err := s.userService.GetUserByEmail(email)
 	if err == nil && existingUser != nil {
-		return nil, errors.New("user with this email already exists")
+		return nil, false, errors.New("user with this email already exists")
+	}
+	if _, err := s.userService.GetDeletedUserByEmail(email); err == nil {
+		return nil, false, ErrEmailInTrash
 	}
 
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		s.log.Error("Failed to hash password: " + err.Error())
-		return nil, errors.New("failed to create user")
+		return nil, false, errors.New("failed to create user")
 	}
 
 	// Create new user
@@ -72,24 +82,26 @@ func (s *DefaultAuthService) Register(email, password string) (*models.User, err
 	err = s.userService.CreateUser(user)
 	if err != nil {
 		s.log.Error("Failed to create user: " + err.Error())
-		return nil, errors.New("failed to create user")
+		return nil, false, errors.New("failed to create user")
 	}
 
-	return user, nil
+	return user, s.mergeGuestCart(guestCartToken, user.ID), nil
 }
 
-// Login authenticates a user and returns a JWT token
-func (s *DefaultAuthService) Login(email, password string) (string, error) {
+// Login authenticates a user and returns a JWT token. A guest cart identified by
+// guestCartToken is merged into the user's cart; Login reports whether the token is
+// done with and can be dropped.
+func (s *DefaultAuthService) Login(email, password, guestCartToken string) (string, bool, error) {
 	// Find user by email
 	user, err := s.userService.GetUserByEmail(email)
 	if err != nil {
-		return "", errors.New("invalid email or password")
+		return "", false, errors.New("invalid email or password")
 	}
 
 	// Verify password
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
-		return "", errors.New("invalid email or password")
+		return "", false, errors.New("invalid email or password")
 	}
 
 	// Generate JWT token
@@ -106,10 +118,27 @@ func (s *DefaultAuthService) Login(email, password string) (string, error) {
 	tokenString, err := token.SignedString(s.jwtSecret)
 	if err != nil {
 		s.log.Error("Failed to generate token: " + err.Error())
-		return "", errors.New("failed to generate token")
+		return "", false, errors.New("failed to generate token")
+	}
+
+	return tokenString, s.mergeGuestCart(guestCartToken, user.ID), nil
+}
+
+// mergeGuestCart merges a guest cart into the user's cart and reports whether the
+// token is done with: the cart was merged, or the token no longer names a guest cart.
+// Other failures are logged but never prevent the user from signing in; the token
+// is kept so the next sign-in can merge the cart.
+func (s *DefaultAuthService) mergeGuestCart(guestCartToken string, userID uint) bool {
+	if guestCartToken == "" || s.cartService == nil {
+		return false
 	}
 
-	return tokenString, nil
+	err := s.cartService.MergeGuestCart(guestCartToken, userID)
+	if err != nil && !errors.Is(err, ErrInvalidCartToken) {
+		s.log.Error("Failed to merge guest cart: " + err.Error())
+		return false
+	}
+	return true
 }
 
 // ValidateToken validates a JWT token and returns the claims
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"fmt"
+)
+
+// ErrCurrencyMismatch is returned when an amount is not in the store currency
+var ErrCurrencyMismatch = errors.New("currency does not match the store currency")
+
+// storeAmount checks that an amount entered for field is in the store currency, which
+// carts and orders are totalled in. An amount without currency takes the store
+// currency. Others are reported as both invalid and ErrCurrencyMismatch.
+func storeAmount(amount money.Money, field string, invalid error) (money.Money, error) {
+	if amount.Currency == "" {
+		amount.Currency = money.DefaultCurrency
+	}
+	if amount.Currency != money.DefaultCurrency {
+		return money.Money{}, fmt.Errorf("%w: %w: %s is in %s, store uses %s",
+			invalid, ErrCurrencyMismatch, field, amount.Currency, money.DefaultCurrency)
+	}
+	return amount, nil
+}
+
+// PricingConfig holds the store-wide settings used to price a cart
+type PricingConfig struct {
+	Currency              string
+	ShippingFee           money.Money // Charged when no shipping method is chosen
+	FreeShippingThreshold money.Money // Zero disables free shipping at the flat fee
+}
+
+// SummaryLine is a priced cart line
+type SummaryLine struct {
+	ProductID    uint           `json:"product_id"`
+	VariantID    uint           `json:"variant_id,omitempty"`
+	SKU          string         `json:"sku,omitempty"`
+	Name         string         `json:"name"`
+	VariantTitle string         `json:"variant_title,omitempty"`
+	Quantity     int            `json:"quantity"`
+	UnitPrice    money.Money    `json:"unit_price"`
+	LineTotal    money.Money    `json:"line_total"`
+	PriceAtAdd   money.Money    `json:"price_at_add"`
+	PriceChanged bool           `json:"price_changed"`
+	Stock        int            `json:"stock"`
+	StockChanged bool           `json:"stock_changed"`
+	Discounts    []LineDiscount `json:"discounts,omitempty"` // Promotions that fired on the line
+	Tax          money.Money    `json:"tax"`                 // Tax on the line after all discounts
+	TaxRate      float64        `json:"tax_rate"`
+}
+
+// LineDiscount explains the part of a discount that falls on one cart line
+type LineDiscount struct {
+	ProductID   uint        `json:"-"`
+	VariantID   uint        `json:"-"`
+	Description string      `json:"description"` // Name of the discount
+	Explanation string      `json:"explanation"` // How the discount applies to the line
+	Amount      money.Money `json:"amount"`
+}
+
+// DiscountLine is a discount applied to a cart
+type DiscountLine struct {
+	Code         string         `json:"code,omitempty"`
+	PromotionID  uint           `json:"promotion_id,omitempty"`
+	Description  string         `json:"description"`
+	Amount       money.Money    `json:"amount"`
+	FreeShipping bool           `json:"free_shipping,omitempty"` // Waives the shipping fee instead of reducing the subtotal
+	Lines        []LineDiscount `json:"-"`                       // How the amount splits over the cart lines, when it does
+}
+
+// CouponStatus tells the shopper whether a coupon applied to the cart takes effect
+type CouponStatus struct {
+	Code    string `json:"code"`
+	Applied bool   `json:"applied"`
+	Reason  string `json:"reason,omitempty"` // Why the coupon does not take effect
+}
+
+// CartSummary holds the full price breakdown of a cart. It is the single source of
+// the numbers shown to the shopper and charged at checkout.
+type CartSummary struct {
+	Currency         string           `json:"currency"`
+	Lines            []SummaryLine    `json:"lines"`
+	Subtotal         money.Money      `json:"subtotal"`
+	Discounts        []DiscountLine   `json:"discounts"`
+	Discount         money.Money      `json:"discount"`
+	Tax              money.Money      `json:"tax"`
+	Taxes            []TaxSummaryLine `json:"taxes"`              // Tax at each rate
+	PricesIncludeTax bool             `json:"prices_include_tax"` // Whether Tax is part of Subtotal rather than added to it
+	Shipping         money.Money      `json:"shipping"`
+	ShippingMethod   string           `json:"shipping_method,omitempty"` // Name of the chosen method; empty for the flat fee
+	GrandTotal       money.Money      `json:"grand_total"`
+	HasChanges       bool             `json:"has_changes"`
+	Coupons          []CouponStatus   `json:"coupons,omitempty"`
+}
+
+// PricingContext describes who a cart is priced for and the offers applied to it
+type PricingContext struct {
+	UserID     uint                          // Zero for guests
+	Prices     GroupPrices                   // Prices of the user's customer group; the zero value for list prices
+	Coupons    []repository.AppliedCoupon    // Coupons applied to the cart
+	Promotions []repository.AppliedPromotion // Promotions to evaluate against the cart
+	Address    models.Address                // Where the cart goes; the zero value estimates tax at the store address
+	Method     *models.ShippingMethod        // Shipping method chosen for the cart; nil charges the store's flat fee
+}
+
+// Discounter contributes discount lines to a cart summary
+type Discounter interface {
+	Discounts(lines []SummaryLine, subtotal money.Money, ctx PricingContext) []DiscountLine
+}
+
+// CartPricer computes cart summaries
+type CartPricer interface {
+	Price(items []models.CartItem, ctx PricingContext) (*CartSummary, error)
+}
+
+// DefaultCartPricer implements CartPricer
+type DefaultCartPricer struct {
+	config      PricingConfig
+	tax         TaxCalculator
+	discounters []Discounter
+}
+
+// NewCartPricer creates a new instance of DefaultCartPricer
+func NewCartPricer(config PricingConfig, tax TaxCalculator, discounters ...Discounter) CartPricer {
+	return &DefaultCartPricer{
+		config:      config,
+		tax:         tax,
+		discounters: discounters,
+	}
+}
+
+// Price computes the summary of cart items whose products and variants are loaded. All products
+// must be priced in the store currency.
+func (p *DefaultCartPricer) Price(items []models.CartItem, ctx PricingContext) (*CartSummary, error) {
+	currency := p.config.Currency
+	summary := &CartSummary{
+		Currency:  currency,
+		Lines:     make([]SummaryLine, 0, len(items)),
+		Discounts: []DiscountLine{},
+	}
+
+	subtotal := money.Zero(currency)
+	for _, item := range items {
+		unitPrice := ctx.Prices.UnitPrice(item.Product, item.Variant, item.Quantity)
+		if unitPrice.Currency != currency {
+			return nil, fmt.Errorf("%w: %s is priced in %s", ErrCurrencyMismatch, item.Product.Name, unitPrice.Currency)
+		}
+		lineTotal := unitPrice.Mul(item.Quantity)
+		subtotal = subtotal.Add(lineTotal)
+
+		line := SummaryLine{
+			ProductID:    item.ProductID,
+			VariantID:    item.VariantID,
+			Name:         item.Product.Name,
+			Quantity:     item.Quantity,
+			UnitPrice:    unitPrice,
+			LineTotal:    lineTotal,
+			PriceAtAdd:   item.PriceAtAdd,
+			PriceChanged: !item.PriceAtAdd.IsZero() && item.PriceAtAdd != unitPrice,
+			Stock:        item.AvailableStock(),
+			StockChanged: item.Quantity > item.AvailableStock(),
+		}
+		if item.Variant != nil {
+			line.SKU = item.Variant.SKU
+			line.VariantTitle = item.Variant.Title
+		}
+		summary.HasChanges = summary.HasChanges || line.PriceChanged || line.StockChanged
+		summary.Lines = append(summary.Lines, line)
+	}
+	summary.Subtotal = subtotal
+
+	discount := money.Zero(currency)
+	freeShipping := false
+	for _, discounter := range p.discounters {
+		for _, line := range discounter.Discounts(summary.Lines, subtotal, ctx) {
+			discount = discount.Add(line.Amount)
+			freeShipping = freeShipping || line.FreeShipping
+			summary.Discounts = append(summary.Discounts, line)
+			for _, part := range line.Lines {
+				for i := range summary.Lines {
+					if summary.Lines[i].ProductID == part.ProductID && summary.Lines[i].VariantID == part.VariantID {
+						summary.Lines[i].Discounts = append(summary.Lines[i].Discounts, part)
+					}
+				}
+			}
+		}
+	}
+	summary.Discount = money.Min(discount, subtotal)
+
+	taxable := subtotal.Sub(summary.Discount)
+	taxLines := discountedLines(summary)
+	for i, item := range items {
+		if item.Product.TaxClassID != nil {
+			taxLines[i].TaxClassID = *item.Product.TaxClassID
+		}
+	}
+	tax, err := p.tax.Calculate(taxLines, ctx.Address)
+	if err != nil {
+		return nil, err
+	}
+	for i, line := range tax.Lines {
+		summary.Lines[i].Tax = line.Amount
+		summary.Lines[i].TaxRate = line.Rate
+	}
+	summary.Tax = tax.Total
+	summary.Taxes = tax.Summary
+	summary.PricesIncludeTax = tax.PricesIncludeTax
+
+	summary.Shipping = money.Zero(currency)
+	if len(items) > 0 {
+		if method := ctx.Method; method != nil {
+			cost, ok := shippingCost(*method, items, subtotal)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s cannot carry this cart", ErrShippingUnavailable, method.Name)
+			}
+			summary.Shipping = cost
+			summary.ShippingMethod = method.Name
+		} else {
+			summary.Shipping = p.config.ShippingFee
+			threshold := p.config.FreeShippingThreshold
+			if threshold.IsPositive() && subtotal.Cmp(threshold) >= 0 {
+				summary.Shipping = money.Zero(currency)
+			}
+		}
+		if freeShipping {
+			summary.Shipping = money.Zero(currency)
+		}
+	}
+
+	summary.GrandTotal = taxable.Add(summary.Shipping)
+	if !summary.PricesIncludeTax {
+		summary.GrandTotal = summary.GrandTotal.Add(summary.Tax)
+	}
+
+	return summary, nil
+}
+
+// discountedLines returns what the shopper pays for each line of a summary once its
+// discounts are taken off. Discounts that fall on given lines come off those; the
+// rest of the discount is spread over the lines in proportion to what is left of them.
+func discountedLines(summary *CartSummary) []TaxableLine {
+	lines := make([]TaxableLine, len(summary.Lines))
+	ratios := make([]int64, len(summary.Lines))
+	rest := summary.Discount
+	for i, line := range summary.Lines {
+		amount := line.LineTotal
+		for _, part := range line.Discounts {
+			amount = amount.Sub(part.Amount)
+			rest = rest.Sub(part.Amount)
+		}
+		amount = money.Max(amount, money.Zero(summary.Currency))
+		lines[i].Amount = amount
+		ratios[i] = amount.Amount
+	}
+	if rest.IsPositive() {
+		for i, part := range rest.Allocate(ratios...) {
+			lines[i].Amount = money.Max(lines[i].Amount.Sub(part), money.Zero(summary.Currency))
+		}
+	}
+	return lines
+}
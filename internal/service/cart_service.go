@@ -1,45 +1,624 @@
 package service
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"ecommerce-app/internal/models"
 	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/money"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
+var (
+	// ErrProductNotFound is returned when a referenced product does not exist
+	ErrProductNotFound = errors.New("product not found")
+	// ErrInvalidQuantity is returned when a cart quantity is out of range
+	ErrInvalidQuantity = errors.New("invalid quantity")
+	// ErrInvalidCartToken is returned when a guest cart token is malformed, forged or stale
+	ErrInvalidCartToken = errors.New("invalid cart token")
+	// ErrInvalidMergePolicy is returned for an unknown cart merge policy
+	ErrInvalidMergePolicy = errors.New("invalid cart merge policy")
+)
+
+// GuestCartTTL is how long a guest cart and its token last after the cart last changed
+const GuestCartTTL = 30 * 24 * time.Hour
+
+// CartMergePolicy decides how quantities are combined when a guest cart is merged
+// into a user's cart and both contain the same product
+type CartMergePolicy string
+
+const (
+	// MergeSum adds the guest quantity to the user's quantity
+	MergeSum CartMergePolicy = "sum"
+	// MergeMax keeps the larger of the two quantities
+	MergeMax CartMergePolicy = "max"
+	// MergeKeepUser keeps the user's quantity and only adds products the user did not have
+	MergeKeepUser CartMergePolicy = "keep_user"
+)
+
+// ParseCartMergePolicy converts a configuration value into a CartMergePolicy
+func ParseCartMergePolicy(value string) (CartMergePolicy, error) {
+	switch policy := CartMergePolicy(value); policy {
+	case MergeSum, MergeMax, MergeKeepUser:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidMergePolicy, value)
+	}
+}
+
+// CartRef identifies a cart either by its owning user or by a guest cart token
+type CartRef struct {
+	UserID     uint
+	GuestToken string
+}
+
 // CartService defines the interface for cart-related business logic
 type CartService interface {
-	GetCart(userID uint) ([]models.CartItem, error)
-	AddToCart(userID uint, productID uint, quantity int) error
-	RemoveFromCart(userID uint, productID uint) error
+	GetCart(ref CartRef) ([]models.CartItem, error)
+	GetSummary(ref CartRef) (*CartSummary, error)
+	Summarize(ref CartRef, items []models.CartItem) (*CartSummary, error)
+	QuoteShipping(ref CartRef, input AddressInput) ([]ShippingQuote, error)
+	ApplyCoupon(userID uint, code string) (*CartSummary, error)
+	RemoveCoupon(userID uint, code string) (*CartSummary, error)
+	AddToCart(ref CartRef, productID uint, variantID uint, quantity int) error
+	UpdateQuantity(ref CartRef, productID uint, variantID uint, quantity int) error
+	RemoveFromCart(ref CartRef, productID uint, variantID uint) error
+	AddToGuestCart(token string, productID uint, variantID uint, quantity int) (string, error)
+	ValidateGuestToken(token string) error
+	MergeGuestCart(token string, userID uint) error
+	PurgeAbandonedGuestCarts() (int, error)
 }
 
 // DefaultCartService implements CartService
 type DefaultCartService struct {
-	repo repository.CartRepository
+	repo        repository.CartRepository
+	productRepo repository.ProductRepository
+	variantRepo repository.VariantRepository
+	couponRepo    repository.CouponRepository
+	promotionRepo repository.PromotionRepository
+	priceListRepo repository.PriceListRepository
+	shippingRepo  repository.ShippingRepository
+	pricer        CartPricer
+	mergePolicy CartMergePolicy
+	tokenSecret []byte
 }
 
 // NewCartService creates a new instance of DefaultCartService
-func NewCartService(repo repository.CartRepository) CartService {
+func NewCartService(repo repository.CartRepository, productRepo repository.ProductRepository,
+	variantRepo repository.VariantRepository, couponRepo repository.CouponRepository,
+	promotionRepo repository.PromotionRepository, priceListRepo repository.PriceListRepository,
+	shippingRepo repository.ShippingRepository, pricer CartPricer, mergePolicy CartMergePolicy, tokenSecret string) CartService {
 	return &DefaultCartService{
-		repo: repo,
+		repo:          repo,
+		productRepo:   productRepo,
+		variantRepo:   variantRepo,
+		couponRepo:    couponRepo,
+		promotionRepo: promotionRepo,
+		priceListRepo: priceListRepo,
+		shippingRepo:  shippingRepo,
+		pricer:        pricer,
+		mergePolicy:   mergePolicy,
+		tokenSecret:   []byte(tokenSecret),
+	}
+}
+
+// GetCart retrieves the items of a cart. A cart that does not exist yet is empty.
+func (s *DefaultCartService) GetCart(ref CartRef) ([]models.CartItem, error) {
+	cart, err := s.findCart(ref)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return []models.CartItem{}, nil
+		}
+		return nil, err
+	}
+	return s.repo.GetItems(cart.ID)
+}
+
+// GetSummary prices the cart, including discounts, tax and shipping
+func (s *DefaultCartService) GetSummary(ref CartRef) (*CartSummary, error) {
+	items, err := s.GetCart(ref)
+	if err != nil {
+		return nil, err
+	}
+	return s.Summarize(ref, items)
+}
+
+// Summarize prices already loaded items of the referenced cart at the prices of the
+// user's customer group, with the running promotions and the coupons applied to it.
+// The summary tells which coupons take effect and why others do not.
+func (s *DefaultCartService) Summarize(ref CartRef, items []models.CartItem) (*CartSummary, error) {
+	ctx, err := s.pricingContext(ref, items)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := s.pricer.Price(items, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, applied := range ctx.Coupons {
+		status := CouponStatus{Code: applied.Coupon.Code, Applied: true}
+		problem := couponProblem(applied, summary.Lines, summary.Subtotal, now)
+		if problem == nil {
+			problem = s.couponRepo.CheckLimits(&applied.Coupon, ref.UserID)
+		}
+		if problem != nil {
+			status.Applied = false
+			status.Reason = problem.Error()
+		}
+		summary.Coupons = append(summary.Coupons, status)
+	}
+	return summary, nil
+}
+
+// QuoteShipping prices the referenced cart with every shipping method offered for the
+// address, leaving out those that cannot carry it. Each quote holds the tax and
+// grand total of the cart with the method, as checkout would charge them.
+func (s *DefaultCartService) QuoteShipping(ref CartRef, input AddressInput) ([]ShippingQuote, error) {
+	address, err := checkAddress(input)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.GetCart(ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrEmptyCart
+	}
+	ctx, err := s.pricingContext(ref, items)
+	if err != nil {
+		return nil, err
+	}
+	methods, err := shippingMethods(s.shippingRepo, address)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.Address = address
+	quotes := make([]ShippingQuote, 0, len(methods))
+	for i := range methods {
+		ctx.Method = &methods[i]
+		summary, err := s.pricer.Price(items, ctx)
+		if errors.Is(err, ErrShippingUnavailable) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, ShippingQuote{
+			MethodID:   methods[i].ID,
+			Name:       methods[i].Name,
+			Type:       methods[i].Type,
+			Cost:       summary.Shipping,
+			Tax:        summary.Tax,
+			GrandTotal: summary.GrandTotal,
+		})
+	}
+	return quotes, nil
+}
+
+// pricingContext gathers what the referenced cart is priced with: the prices of the
+// user's customer group, the running promotions and the coupons applied to it
+func (s *DefaultCartService) pricingContext(ref CartRef, items []models.CartItem) (PricingContext, error) {
+	ctx := PricingContext{UserID: ref.UserID}
+	productIDs := cartProductIDs(items)
+	if len(items) > 0 {
+		promotions, err := s.promotionRepo.Running(time.Now(), productIDs)
+		if err != nil {
+			return ctx, err
+		}
+		ctx.Promotions = promotions
+		if ctx.Prices, err = loadGroupPrices(s.priceListRepo, ref.UserID, productIDs); err != nil {
+			return ctx, err
+		}
+	}
+	if ref.UserID != 0 {
+		cart, err := s.repo.FindCart(ref.UserID)
+		if err == nil {
+			ctx.Coupons, err = s.couponRepo.CartCoupons(cart.ID, productIDs)
+		}
+		if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+// ApplyCoupon applies a coupon code to the user's cart and returns the repriced cart.
+// The coupon must be usable on the cart as it is and combine with the coupons
+// already applied; applying a coupon twice has no effect.
+func (s *DefaultCartService) ApplyCoupon(userID uint, code string) (*CartSummary, error) {
+	coupon, err := s.couponRepo.FindByCode(strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, err
+	}
+
+	cart, err := s.repo.GetOrCreateCart(userID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.GetItems(cart.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	productIDs := cartProductIDs(items)
+	applied, err := s.couponRepo.CartCoupons(cart.ID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range applied {
+		if other.Coupon.ID == coupon.ID {
+			return s.Summarize(CartRef{UserID: userID}, items)
+		}
+		if !coupon.Stackable || !other.Coupon.Stackable {
+			return nil, fmt.Errorf("%w: %s and %s", ErrCouponNotStackable, other.Coupon.Code, coupon.Code)
+		}
+	}
+
+	eligibility, err := s.couponRepo.Eligibility(coupon, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := loadGroupPrices(s.priceListRepo, userID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := s.pricer.Price(items, PricingContext{UserID: userID, Prices: prices})
+	if err != nil {
+		return nil, err
+	}
+	if err := couponProblem(eligibility, summary.Lines, summary.Subtotal, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.couponRepo.CheckLimits(coupon, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.couponRepo.AddToCart(cart.ID, coupon.ID); err != nil {
+		return nil, err
+	}
+	return s.Summarize(CartRef{UserID: userID}, items)
+}
+
+// RemoveCoupon takes a coupon code off the user's cart and returns the repriced cart
+func (s *DefaultCartService) RemoveCoupon(userID uint, code string) (*CartSummary, error) {
+	coupon, err := s.couponRepo.FindByCode(strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, err
+	}
+
+	ref := CartRef{UserID: userID}
+	cart, err := s.repo.FindCart(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return s.Summarize(ref, []models.CartItem{})
+		}
+		return nil, err
+	}
+	if err := s.couponRepo.RemoveFromCart(cart.ID, coupon.ID); err != nil {
+		return nil, err
+	}
+	return s.GetSummary(ref)
+}
+
+// AddToCart adds a product to the cart, merging with the quantity already in it. Products
+// with variants need a variant ID; others take zero.
+func (s *DefaultCartService) AddToCart(ref CartRef, productID uint, variantID uint, quantity int) error {
+	if quantity <= 0 {
+		return fmt.Errorf("%w: must be at least 1", ErrInvalidQuantity)
+	}
+
+	cart, err := s.resolveCart(ref)
+	if err != nil {
+		return err
+	}
+
+	current := 0
+	item, err := s.repo.FindItem(cart.ID, productID, variantID)
+	if err == nil {
+		current = item.Quantity
+	} else if !errors.Is(err, repository.ErrRecordNotFound) {
+		return err
+	}
+
+	price, err := s.checkStock(ref.UserID, productID, variantID, current+quantity)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.AddItem(&models.CartItem{
+		CartID:     cart.ID,
+		ProductID:  productID,
+		VariantID:  variantID,
+		Quantity:   quantity,
+		PriceAtAdd: price,
+	})
+}
+
+// UpdateQuantity sets the quantity of a product in the cart. A quantity of
+// zero removes the product from the cart.
+func (s *DefaultCartService) UpdateQuantity(ref CartRef, productID uint, variantID uint, quantity int) error {
+	if quantity < 0 {
+		return fmt.Errorf("%w: must not be negative", ErrInvalidQuantity)
+	}
+	if quantity == 0 {
+		return s.RemoveFromCart(ref, productID, variantID)
+	}
+
+	price, err := s.checkStock(ref.UserID, productID, variantID, quantity)
+	if err != nil {
+		return err
+	}
+
+	cart, err := s.resolveCart(ref)
+	if err != nil {
+		return err
 	}
+
+	return s.repo.SetItemQuantity(&models.CartItem{
+		CartID:     cart.ID,
+		ProductID:  productID,
+		VariantID:  variantID,
+		Quantity:   quantity,
+		PriceAtAdd: price,
+	})
 }
 
-// GetCart retrieves a user's cart items
-func (s *DefaultCartService) GetCart(userID uint) ([]models.CartItem, error) {
-	return s.repo.GetCart(userID)
+// RemoveFromCart removes a product variant from the cart
+func (s *DefaultCartService) RemoveFromCart(ref CartRef, productID uint, variantID uint) error {
+	cart, err := s.findCart(ref)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.repo.RemoveItem(cart.ID, productID, variantID)
 }
 
-// AddToCart adds a product to the user's cart
-func (s *DefaultCartService) AddToCart(userID uint, productID uint, quantity int) error {
-	cartItem := &models.CartItem{
-		CartID:    userID,
-		ProductID: productID,
-		Quantity:  quantity,
+// AddToGuestCart adds a product to the guest cart of the token as AddToCart does and
+// returns the token. A shopper without a token gets a new guest cart, but only once
+// the product is known to be in stock, so requests that add nothing leave no cart behind.
+func (s *DefaultCartService) AddToGuestCart(token string, productID uint, variantID uint, quantity int) (string, error) {
+	if token != "" {
+		return token, s.AddToCart(CartRef{GuestToken: token}, productID, variantID, quantity)
 	}
-	return s.repo.AddToCart(cartItem)
+	if quantity <= 0 {
+		return "", fmt.Errorf("%w: must be at least 1", ErrInvalidQuantity)
+	}
+
+	price, err := s.checkStock(0, productID, variantID, quantity)
+	if err != nil {
+		return "", err
+	}
+
+	// Whole seconds, as the token carries the creation time
+	now := time.Now().Truncate(time.Second)
+	cart := &models.Cart{CreatedAt: now, UpdatedAt: now}
+	err = s.repo.CreateCart(cart, &models.CartItem{
+		ProductID:  productID,
+		VariantID:  variantID,
+		Quantity:   quantity,
+		PriceAtAdd: price,
+	})
+	if err != nil {
+		return "", err
+	}
+	return s.signCartToken(cart), nil
 }
 
-// RemoveFromCart removes a product from the user's cart
-func (s *DefaultCartService) RemoveFromCart(userID uint, productID uint) error {
-	return s.repo.RemoveFromCart(userID, productID)
-}
\ No newline at end of file
+// ValidateGuestToken checks that the token is correctly signed and still refers to a guest cart
+func (s *DefaultCartService) ValidateGuestToken(token string) error {
+	_, err := s.findGuestCart(token)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrInvalidCartToken
+	}
+	return err
+}
+
+// MergeGuestCart moves the contents of a guest cart into the user's cart according to
+// the configured merge policy. Merged quantities are capped at the available stock.
+func (s *DefaultCartService) MergeGuestCart(token string, userID uint) error {
+	guestCart, err := s.findGuestCart(token)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrInvalidCartToken
+		}
+		return err
+	}
+
+	userCart, err := s.repo.GetOrCreateCart(userID)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.MergeCarts(guestCart.ID, userCart.ID, func(existing int, incoming models.CartItem) int {
+		quantity := incoming.Quantity
+		switch s.mergePolicy {
+		case MergeMax:
+			if existing > quantity {
+				quantity = existing
+			}
+		case MergeKeepUser:
+			if existing > 0 {
+				quantity = existing
+			}
+		default:
+			quantity += existing
+		}
+
+		// Never push a line past the stock, but keep what the user already had
+		if limit := max(incoming.AvailableStock(), existing); quantity > limit {
+			quantity = limit
+		}
+		return quantity
+	})
+}
+
+// PurgeAbandonedGuestCarts removes a batch of the guest carts that have not changed
+// for GuestCartTTL, and returns how many it removed
+func (s *DefaultCartService) PurgeAbandonedGuestCarts() (int, error) {
+	return s.repo.PurgeGuestCarts(time.Now().Add(-GuestCartTTL), purgeBatchSize)
+}
+
+// findCart looks up the referenced cart without creating it
+func (s *DefaultCartService) findCart(ref CartRef) (*models.Cart, error) {
+	if ref.UserID != 0 {
+		return s.repo.FindCart(ref.UserID)
+	}
+	if ref.GuestToken == "" {
+		return nil, repository.ErrRecordNotFound
+	}
+	return s.findGuestCart(ref.GuestToken)
+}
+
+// resolveCart looks up the referenced cart, creating a user's cart on first use
+func (s *DefaultCartService) resolveCart(ref CartRef) (*models.Cart, error) {
+	if ref.UserID != 0 {
+		return s.repo.GetOrCreateCart(ref.UserID)
+	}
+
+	cart, err := s.findGuestCart(ref.GuestToken)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrInvalidCartToken
+	}
+	return cart, err
+}
+
+// findGuestCart verifies a guest cart token and loads the cart it refers to. The
+// token must have been issued for that very cart, and the cart must not have expired.
+func (s *DefaultCartService) findGuestCart(token string) (*models.Cart, error) {
+	cartID, created, err := s.parseCartToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := s.repo.FindCartByID(cartID)
+	if err != nil {
+		return nil, err
+	}
+	if cart.UserID != nil || cart.CreatedAt.Unix() != created || time.Since(cart.UpdatedAt) > GuestCartTTL {
+		return nil, ErrInvalidCartToken
+	}
+	return cart, nil
+}
+
+// checkStock verifies that the product variant exists and has at least quantity units
+// in stock that other orders do not hold, and returns the unit price the user pays
+// for that quantity
+func (s *DefaultCartService) checkStock(userID uint, productID uint, variantID uint, quantity int) (money.Money, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return money.Money{}, ErrProductNotFound
+		}
+		return money.Money{}, err
+	}
+
+	if variantID == 0 {
+		variants, err := s.variantRepo.CountByProduct(productID)
+		if err != nil {
+			return money.Money{}, err
+		}
+		if variants > 0 {
+			return money.Money{}, ErrVariantRequired
+		}
+		if quantity > product.Available {
+			return money.Money{}, fmt.Errorf("%w: only %d of %s available", ErrInsufficientStock, product.Available, product.Name)
+		}
+		return s.unitPrice(userID, *product, nil, quantity)
+	}
+
+	variant, err := s.variantRepo.FindByID(variantID)
+	if err != nil || variant.ProductID != productID {
+		if err == nil || errors.Is(err, repository.ErrRecordNotFound) {
+			return money.Money{}, ErrVariantNotFound
+		}
+		return money.Money{}, err
+	}
+	if quantity > variant.Available {
+		return money.Money{}, fmt.Errorf("%w: only %d of %s (%s) available", ErrInsufficientStock, variant.Available, product.Name, variant.Title)
+	}
+	return s.unitPrice(userID, *product, variant, quantity)
+}
+
+// unitPrice returns the unit price the user pays for quantity units of a product
+// or variant
+func (s *DefaultCartService) unitPrice(userID uint, product models.Product, variant *models.ProductVariant, quantity int) (money.Money, error) {
+	prices, err := loadGroupPrices(s.priceListRepo, userID, []uint{product.ID})
+	if err != nil {
+		return money.Money{}, err
+	}
+	return prices.UnitPrice(product, variant, quantity), nil
+}
+
+// cartProductIDs returns the IDs of the products in cart items
+func cartProductIDs(items []models.CartItem) []uint {
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		if !slices.Contains(ids, item.ProductID) {
+			ids = append(ids, item.ProductID)
+		}
+	}
+	return ids
+}
+
+// signCartToken builds a guest cart token of the form "<cart id>.<created>.<signature>",
+// where created is the Unix time the cart was created
+func (s *DefaultCartService) signCartToken(cart *models.Cart) string {
+	payload := strconv.FormatUint(uint64(cart.ID), 10) + "." + strconv.FormatInt(cart.CreatedAt.Unix(), 10)
+	return payload + "." + s.cartTokenSignature(payload)
+}
+
+// parseCartToken verifies the signature of a guest cart token and returns its cart ID
+// and the creation time of the cart it was issued for
+func (s *DefaultCartService) parseCartToken(token string) (uint, int64, error) {
+	cut := strings.LastIndexByte(token, '.')
+	if cut < 0 {
+		return 0, 0, ErrInvalidCartToken
+	}
+	payload, signature := token[:cut], token[cut+1:]
+	if !hmac.Equal([]byte(signature), []byte(s.cartTokenSignature(payload))) {
+		return 0, 0, ErrInvalidCartToken
+	}
+
+	id, created, found := strings.Cut(payload, ".")
+	if !found {
+		return 0, 0, ErrInvalidCartToken
+	}
+	cartID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, 0, ErrInvalidCartToken
+	}
+	createdAt, err := strconv.ParseInt(created, 10, 64)
+	if err != nil {
+		return 0, 0, ErrInvalidCartToken
+	}
+	return uint(cartID), createdAt, nil
+}
+
+// cartTokenSignature computes the HMAC-SHA256 signature of a token payload
+func (s *DefaultCartService) cartTokenSignature(payload string) string {
+	mac := hmac.New(sha256.New, s.tokenSecret)
+	mac.Write([]byte("cart:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"errors"
+	"testing"
+	"time"
+)
+
+// cartLine identifies a line of a cart
+type cartLine struct {
+	cartID, productID, variantID uint
+}
+
+// fakeCartRepo keeps carts and their lines in memory
+type fakeCartRepo struct {
+	repository.CartRepository
+	carts map[uint]*models.Cart
+	lines map[cartLine]int // Quantity of each line
+}
+
+func newFakeCartRepo(carts ...*models.Cart) *fakeCartRepo {
+	repo := &fakeCartRepo{carts: map[uint]*models.Cart{}, lines: map[cartLine]int{}}
+	for _, cart := range carts {
+		repo.carts[cart.ID] = cart
+	}
+	return repo
+}
+
+func (r *fakeCartRepo) FindCart(userID uint) (*models.Cart, error) {
+	for _, cart := range r.carts {
+		if cart.UserID != nil && *cart.UserID == userID {
+			return cart, nil
+		}
+	}
+	return nil, repository.ErrRecordNotFound
+}
+
+func (r *fakeCartRepo) FindCartByID(id uint) (*models.Cart, error) {
+	if cart, ok := r.carts[id]; ok {
+		return cart, nil
+	}
+	return nil, repository.ErrRecordNotFound
+}
+
+func (r *fakeCartRepo) GetOrCreateCart(userID uint) (*models.Cart, error) {
+	if cart, err := r.FindCart(userID); err == nil {
+		return cart, nil
+	}
+	cart := &models.Cart{ID: uint(len(r.carts) + 1), UserID: &userID}
+	r.carts[cart.ID] = cart
+	return cart, nil
+}
+
+func (r *fakeCartRepo) FindItem(cartID uint, productID uint, variantID uint) (*models.CartItem, error) {
+	quantity, ok := r.lines[cartLine{cartID, productID, variantID}]
+	if !ok {
+		return nil, repository.ErrRecordNotFound
+	}
+	return &models.CartItem{CartID: cartID, ProductID: productID, VariantID: variantID, Quantity: quantity}, nil
+}
+
+func (r *fakeCartRepo) AddItem(item *models.CartItem) error {
+	r.lines[cartLine{item.CartID, item.ProductID, item.VariantID}] += item.Quantity
+	return nil
+}
+
+func (r *fakeCartRepo) SetItemQuantity(item *models.CartItem) error {
+	r.lines[cartLine{item.CartID, item.ProductID, item.VariantID}] = item.Quantity
+	return nil
+}
+
+func (r *fakeCartRepo) RemoveItem(cartID uint, productID uint, variantID uint) error {
+	delete(r.lines, cartLine{cartID, productID, variantID})
+	return nil
+}
+
+// MergeCarts moves the lines of the source cart into the target cart and drops the
+// source cart. Incoming lines carry the product stock as their availability.
+func (r *fakeCartRepo) MergeCarts(sourceID uint, targetID uint, merge repository.CartMergeFunc) error {
+	for line, quantity := range r.lines {
+		if line.cartID != sourceID {
+			continue
+		}
+		incoming := models.CartItem{
+			ProductID: line.productID,
+			Quantity:  quantity,
+			Product:   models.Product{ID: line.productID, Available: stockOf(line.productID)},
+		}
+		target := cartLine{targetID, line.productID, line.variantID}
+		r.lines[target] = merge(r.lines[target], incoming)
+		delete(r.lines, line)
+	}
+	delete(r.carts, sourceID)
+	return nil
+}
+
+// stockOf is the available stock of the products in the cart tests: product N has N*2 units
+func stockOf(productID uint) int {
+	return int(productID) * 2
+}
+
+// fakeProductRepo finds the products of the cart tests, none of which has variants
+type fakeProductRepo struct {
+	repository.ProductRepository
+}
+
+func (r *fakeProductRepo) FindByID(id uint) (*models.Product, error) {
+	if id == 0 || id > 10 {
+		return nil, repository.ErrRecordNotFound
+	}
+	return &models.Product{ID: id, Name: "Product", Price: usd("10.00"), Available: stockOf(id)}, nil
+}
+
+// fakeVariantRepo reports that only product 10 has variants
+type fakeVariantRepo struct {
+	repository.VariantRepository
+}
+
+func (r *fakeVariantRepo) CountByProduct(productID uint) (int64, error) {
+	if productID == 10 {
+		return 2, nil
+	}
+	return 0, nil
+}
+
+func newTestCartService(repo *fakeCartRepo, policy CartMergePolicy) *DefaultCartService {
+	return NewCartService(repo, &fakeProductRepo{}, &fakeVariantRepo{}, nil, nil, &fakePriceListRepo{}, nil,
+		nil, policy, "secret").(*DefaultCartService)
+}
+
+func TestAddToCart(t *testing.T) {
+	tests := []struct {
+		name      string
+		productID uint
+		existing  int // Quantity already in the cart
+		quantity  int
+		want      int
+		err       error
+	}{
+		{name: "new line", productID: 3, quantity: 2, want: 2},
+		{name: "adds to the line", productID: 3, existing: 2, quantity: 3, want: 5},
+		{name: "up to the stock", productID: 3, existing: 4, quantity: 2, want: 6},
+		{name: "past the stock with the line", productID: 3, existing: 5, quantity: 2, want: 5, err: ErrInsufficientStock},
+		{name: "past the stock", productID: 1, quantity: 3, err: ErrInsufficientStock},
+		{name: "zero", productID: 3, quantity: 0, err: ErrInvalidQuantity},
+		{name: "negative", productID: 3, quantity: -1, err: ErrInvalidQuantity},
+		{name: "unknown product", productID: 11, quantity: 1, err: ErrProductNotFound},
+		{name: "product with variants", productID: 10, quantity: 1, err: ErrVariantRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeCartRepo()
+			cart, _ := repo.GetOrCreateCart(1)
+			if tt.existing > 0 {
+				repo.lines[cartLine{cart.ID, tt.productID, 0}] = tt.existing
+			}
+
+			err := newTestCartService(repo, MergeSum).AddToCart(CartRef{UserID: 1}, tt.productID, 0, tt.quantity)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("AddToCart() error = %v, want %v", err, tt.err)
+			}
+			if got := repo.lines[cartLine{cart.ID, tt.productID, 0}]; got != tt.want {
+				t.Errorf("quantity = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateQuantity(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity int
+		want     int
+		removed  bool
+		err      error
+	}{
+		{name: "lower", quantity: 1, want: 1},
+		{name: "raise to the stock", quantity: 6, want: 6},
+		{name: "past the stock", quantity: 7, want: 4, err: ErrInsufficientStock},
+		{name: "zero removes the line", quantity: 0, removed: true},
+		{name: "negative", quantity: -1, want: 4, err: ErrInvalidQuantity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeCartRepo()
+			cart, _ := repo.GetOrCreateCart(1)
+			line := cartLine{cart.ID, 3, 0}
+			repo.lines[line] = 4
+
+			err := newTestCartService(repo, MergeSum).UpdateQuantity(CartRef{UserID: 1}, 3, 0, tt.quantity)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("UpdateQuantity() error = %v, want %v", err, tt.err)
+			}
+			got, ok := repo.lines[line]
+			if ok == tt.removed || got != tt.want {
+				t.Errorf("line = %d (present %v), want %d (present %v)", got, ok, tt.want, !tt.removed)
+			}
+		})
+	}
+}
+
+func TestParseCartMergePolicy(t *testing.T) {
+	tests := []struct {
+		value string
+		want  CartMergePolicy
+		err   error
+	}{
+		{"sum", MergeSum, nil},
+		{"max", MergeMax, nil},
+		{"keep_user", MergeKeepUser, nil},
+		{"", "", ErrInvalidMergePolicy},
+		{"Sum", "", ErrInvalidMergePolicy},
+	}
+	for _, tt := range tests {
+		got, err := ParseCartMergePolicy(tt.value)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("ParseCartMergePolicy(%q) = %q, %v, want %q, %v", tt.value, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestMergeGuestCart(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    CartMergePolicy
+		productID uint // Product N has N*2 units in stock
+		user      int  // Quantity in the user's cart, zero for none
+		guest     int
+		want      int
+	}{
+		{"sum", MergeSum, 5, 2, 3, 5},
+		{"sum new line", MergeSum, 5, 0, 3, 3},
+		{"sum capped at the stock", MergeSum, 3, 4, 4, 6},
+		{"max keeps the user's", MergeMax, 5, 4, 2, 4},
+		{"max takes the guest's", MergeMax, 5, 2, 4, 4},
+		{"max capped at the stock", MergeMax, 2, 1, 6, 4},
+		{"keep user", MergeKeepUser, 5, 2, 6, 2},
+		{"keep user new line", MergeKeepUser, 5, 0, 6, 6},
+		// What the user had stays, even when the stock has dropped below it since
+		{"user's line past the stock", MergeSum, 1, 3, 1, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now().Truncate(time.Second)
+			guestCart := &models.Cart{ID: 100, CreatedAt: now, UpdatedAt: now}
+			repo := newFakeCartRepo(guestCart)
+			userCart, _ := repo.GetOrCreateCart(1)
+			repo.lines[cartLine{guestCart.ID, tt.productID, 0}] = tt.guest
+			if tt.user > 0 {
+				repo.lines[cartLine{userCart.ID, tt.productID, 0}] = tt.user
+			}
+
+			service := newTestCartService(repo, tt.policy)
+			if err := service.MergeGuestCart(service.signCartToken(guestCart), 1); err != nil {
+				t.Fatalf("MergeGuestCart() error = %v", err)
+			}
+			if got := repo.lines[cartLine{userCart.ID, tt.productID, 0}]; got != tt.want {
+				t.Errorf("quantity = %d, want %d", got, tt.want)
+			}
+			if _, ok := repo.carts[guestCart.ID]; ok {
+				t.Error("guest cart is left behind")
+			}
+		})
+	}
+}
+
+func TestMergeGuestCartRejectsBadTokens(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	userID := uint(2)
+	tests := []struct {
+		name string
+		cart *models.Cart
+		// token returns the token presented for the cart
+		token func(s *DefaultCartService, cart *models.Cart) string
+	}{
+		{
+			name:  "tampered signature",
+			cart:  &models.Cart{ID: 100, CreatedAt: now, UpdatedAt: now},
+			token: func(s *DefaultCartService, cart *models.Cart) string { return s.signCartToken(cart) + "0" },
+		},
+		{
+			name: "another cart with a reused ID",
+			cart: &models.Cart{ID: 100, CreatedAt: now, UpdatedAt: now},
+			token: func(s *DefaultCartService, cart *models.Cart) string {
+				return s.signCartToken(&models.Cart{ID: cart.ID, CreatedAt: now.Add(-time.Hour)})
+			},
+		},
+		{
+			name:  "expired",
+			cart:  &models.Cart{ID: 100, CreatedAt: now.Add(-GuestCartTTL * 2), UpdatedAt: now.Add(-GuestCartTTL - time.Hour)},
+			token: func(s *DefaultCartService, cart *models.Cart) string { return s.signCartToken(cart) },
+		},
+		{
+			name:  "user's cart",
+			cart:  &models.Cart{ID: 100, UserID: &userID, CreatedAt: now, UpdatedAt: now},
+			token: func(s *DefaultCartService, cart *models.Cart) string { return s.signCartToken(cart) },
+		},
+		{
+			name:  "malformed",
+			cart:  &models.Cart{ID: 100, CreatedAt: now, UpdatedAt: now},
+			token: func(s *DefaultCartService, cart *models.Cart) string { return "100" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestCartService(newFakeCartRepo(tt.cart), MergeSum)
+			if err := service.MergeGuestCart(tt.token(service, tt.cart), 1); !errors.Is(err, ErrInvalidCartToken) {
+				t.Errorf("MergeGuestCart() error = %v, want %v", err, ErrInvalidCartToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/logger"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrCategoryNotFound is returned when a referenced category does not exist
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrInvalidCategory is returned when category data fails validation
+	ErrInvalidCategory = errors.New("invalid category")
+	// ErrCategorySlugTaken is returned when another category already uses the slug
+	ErrCategorySlugTaken = errors.New("category slug already in use")
+	// ErrInvalidCategoryParent is returned when a parent would nest a category inside itself
+	ErrInvalidCategoryParent = errors.New("invalid parent category")
+)
+
+// CategoryNode is a category together with its nested subcategories
+type CategoryNode struct {
+	ID          uint            `json:"id"`
+	Name        string          `json:"name"`
+	Slug        string          `json:"slug"`
+	Description string          `json:"description"`
+	Children    []*CategoryNode `json:"children"`
+}
+
+// CategoryService defines the interface for category-related business logic
+type CategoryService interface {
+	GetCategoryByID(id uint) (*models.Category, error)
+	GetCategoryBySlug(slug string) (*models.Category, error)
+	GetAllCategories() ([]models.Category, error)
+	GetCategoryTree() ([]*CategoryNode, error)
+	GetProductsByCategory(slug string, page, pageSize int) ([]models.Product, int64, error)
+	CreateCategory(category *models.Category) error
+	UpdateCategory(category *models.Category) error
+	DeleteCategory(id uint) error
+	SetProductCategories(productID uint, categoryIDs []uint) error
+}
+
+// DefaultCategoryService implements CategoryService
+type DefaultCategoryService struct {
+	repo        repository.CategoryRepository
+	productRepo repository.ProductRepository
+	indexer     ProductIndexer
+	log         *logger.Logger
+}
+
+// NewCategoryService creates a new instance of DefaultCategoryService. The indexer
+// is told about category changes that affect product search documents.
+func NewCategoryService(repo repository.CategoryRepository, productRepo repository.ProductRepository,
+	indexer ProductIndexer) CategoryService {
+	return &DefaultCategoryService{
+		repo:        repo,
+		productRepo: productRepo,
+		indexer:     indexer,
+		log:         logger.New(),
+	}
+}
+
+// GetCategoryByID retrieves a category by its ID
+func (s *DefaultCategoryService) GetCategoryByID(id uint) (*models.Category, error) {
+	category, err := s.repo.FindByID(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrCategoryNotFound
+	}
+	return category, err
+}
+
+// GetCategoryBySlug retrieves a category by its slug
+func (s *DefaultCategoryService) GetCategoryBySlug(slug string) (*models.Category, error) {
+	category, err := s.repo.FindBySlug(slug)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrCategoryNotFound
+	}
+	return category, err
+}
+
+// GetAllCategories retrieves all categories as a flat list
+func (s *DefaultCategoryService) GetAllCategories() ([]models.Category, error) {
+	return s.repo.List()
+}
+
+// GetCategoryTree retrieves all categories nested under their parents
+func (s *DefaultCategoryService) GetCategoryTree() ([]*CategoryNode, error) {
+	categories, err := s.repo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &CategoryNode{
+			ID:          category.ID,
+			Name:        category.Name,
+			Slug:        category.Slug,
+			Description: category.Description,
+			Children:    []*CategoryNode{},
+		}
+	}
+
+	// categories is already in display order, so appending keeps siblings ordered
+	roots := []*CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots, nil
+}
+
+// GetProductsByCategory retrieves the products of a category and all of its descendants
+func (s *DefaultCategoryService) GetProductsByCategory(slug string, page, pageSize int) ([]models.Product, int64, error) {
+	category, err := s.GetCategoryBySlug(slug)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids, err := s.repo.DescendantIDs(category.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	products, total, err := s.productRepo.Query(repository.ProductQuery{
+		CategoryIDs: ids,
+		Page:        page,
+		PageSize:    pageSize,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	products, _ = resolveSales(products, nil)
+	return products, total, nil
+}
+
+// CreateCategory creates a new category, deriving its slug from the name when none is given
+func (s *DefaultCategoryService) CreateCategory(category *models.Category) error {
+	category.ID = 0
+	if err := s.prepareCategory(category); err != nil {
+		return err
+	}
+	return s.repo.Create(category)
+}
+
+// UpdateCategory updates an existing category
+func (s *DefaultCategoryService) UpdateCategory(category *models.Category) error {
+	existing, err := s.GetCategoryByID(category.ID)
+	if err != nil {
+		return err
+	}
+	category.CreatedAt = existing.CreatedAt
+
+	if err := s.prepareCategory(category); err != nil {
+		return err
+	}
+	if err := s.repo.Update(category); err != nil {
+		return err
+	}
+
+	// Slugs and ancestors are part of every search document below the category
+	if existing.Slug != category.Slug || !sameParent(existing.ParentID, category.ParentID) {
+		s.reindexAll()
+	}
+	return nil
+}
+
+// DeleteCategory deletes a category, moving its subcategories up one level
+func (s *DefaultCategoryService) DeleteCategory(id uint) error {
+	err := s.repo.Delete(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrCategoryNotFound
+	}
+	if err != nil {
+		return err
+	}
+	s.reindexAll()
+	return nil
+}
+
+// SetProductCategories replaces the categories a product is assigned to
+func (s *DefaultCategoryService) SetProductCategories(productID uint, categoryIDs []uint) error {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrProductNotFound
+		}
+		return err
+	}
+
+	for _, id := range categoryIDs {
+		if _, err := s.GetCategoryByID(id); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.SetProductCategories(productID, categoryIDs); err != nil {
+		return err
+	}
+	if err := s.indexer.ReindexProduct(productID); err != nil {
+		s.log.Error("Failed to update search index: " + err.Error())
+	}
+	return nil
+}
+
+// reindexAll rebuilds the search index after a change to the category tree. The
+// change has already been saved, so failures are logged rather than returned.
+func (s *DefaultCategoryService) reindexAll() {
+	if err := s.indexer.ReindexAll(); err != nil {
+		s.log.Error("Failed to rebuild search index: " + err.Error())
+	}
+}
+
+// prepareCategory validates a category and normalises its slug before it is saved
+func (s *DefaultCategoryService) prepareCategory(category *models.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCategory)
+	}
+
+	if category.Slug == "" {
+		category.Slug = category.Name
+	}
+	category.Slug = slugify(category.Slug)
+	if category.Slug == "" {
+		return fmt.Errorf("%w: slug must contain letters or digits", ErrInvalidCategory)
+	}
+
+	other, err := s.repo.FindBySlug(category.Slug)
+	if err == nil && other.ID != category.ID {
+		return fmt.Errorf("%w: %s", ErrCategorySlugTaken, category.Slug)
+	} else if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		return err
+	}
+
+	if category.ParentID == nil {
+		return nil
+	}
+	if _, err := s.GetCategoryByID(*category.ParentID); err != nil {
+		if errors.Is(err, ErrCategoryNotFound) {
+			return fmt.Errorf("%w: parent %d does not exist", ErrInvalidCategoryParent, *category.ParentID)
+		}
+		return err
+	}
+	if category.ID == 0 {
+		return nil
+	}
+
+	// A category cannot be moved below itself or any of its descendants
+	descendants, err := s.repo.DescendantIDs(category.ID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(descendants, *category.ParentID) {
+		return fmt.Errorf("%w: a category cannot be nested inside itself", ErrInvalidCategoryParent)
+	}
+	return nil
+}
+
+// slugify turns a name into a lowercase, hyphen separated URL slug
+func slugify(value string) string {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(value) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			pendingHyphen = false
+		} else {
+			pendingHyphen = true
+		}
+	}
+	return b.String()
+}
+
+// sameParent reports whether two optional parent IDs refer to the same category
+func sameParent(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
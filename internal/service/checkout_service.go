@@ -0,0 +1,273 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/logger"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrEmptyCart is returned when checking out a cart without items
+	ErrEmptyCart = errors.New("cart is empty")
+	// ErrInsufficientStock is returned when a cart item asks for more units than are in stock
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrInvalidAddress is returned when an address given by the shopper fails validation
+	ErrInvalidAddress = errors.New("invalid address")
+)
+
+// AddressInput is an address given by the shopper
+type AddressInput struct {
+	Name       string `json:"name"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"` // Two-letter code, e.g. US
+}
+
+// CheckoutInput holds what the shopper tells the store at checkout
+type CheckoutInput struct {
+	ShippingAddress  *AddressInput `json:"shipping_address"`   // Tax is worked out at the store address without it
+	ShippingMethodID uint          `json:"shipping_method_id"` // One of the methods quoted for the address; required once shipping zones are set up
+}
+
+// CheckoutService defines the interface for turning a user's cart into an order
+type CheckoutService interface {
+	Checkout(userID uint, input CheckoutInput) (*models.Order, time.Time, error)
+}
+
+// DefaultCheckoutService implements CheckoutService
+type DefaultCheckoutService struct {
+	repo           repository.CheckoutRepository
+	promotionRepo  repository.PromotionRepository
+	priceListRepo  repository.PriceListRepository
+	shippingRepo   repository.ShippingRepository
+	pricer         CartPricer
+	indexer        ProductIndexer
+	reservationTTL time.Duration
+	log            *logger.Logger
+}
+
+// NewCheckoutService creates a new instance of DefaultCheckoutService. Orders hold
+// their stock for reservationTTL; unpaid orders are cancelled once it runs out.
+func NewCheckoutService(repo repository.CheckoutRepository, promotionRepo repository.PromotionRepository,
+	priceListRepo repository.PriceListRepository, shippingRepo repository.ShippingRepository, pricer CartPricer,
+	indexer ProductIndexer, reservationTTL time.Duration) CheckoutService {
+	return &DefaultCheckoutService{
+		repo:           repo,
+		promotionRepo:  promotionRepo,
+		priceListRepo:  priceListRepo,
+		shippingRepo:   shippingRepo,
+		pricer:         pricer,
+		indexer:        indexer,
+		reservationTTL: reservationTTL,
+		log:            logger.New(),
+	}
+}
+
+// Checkout places an order for everything in the user's cart and returns it along
+// with the time its stock reservation expires. Prices are snapshotted and stock is
+// checked against the locked product rows, less what other pending orders hold, so
+// the order either succeeds as a whole or leaves the cart and stock untouched. Totals
+// come from the cart pricer, so the order matches the summary the shopper saw. The
+// coupons applied to the cart are redeemed with the order; the coupon rows stay locked
+// until it is placed, so concurrent checkouts cannot exceed their usage limits. Tax
+// is worked out for the shipping address and stored per line and per rate, and the
+// chosen shipping method is stored with its cost. The reserved products are
+// reindexed, as some may have sold out.
+func (s *DefaultCheckoutService) Checkout(userID uint, input CheckoutInput) (*models.Order, time.Time, error) {
+	var address models.Address
+	if input.ShippingAddress != nil {
+		var err error
+		if address, err = checkAddress(*input.ShippingAddress); err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+	method, err := s.shippingMethod(address, input.ShippingMethodID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	reservedUntil := time.Now().Add(s.reservationTTL)
+	order, err := s.repo.PlaceOrder(userID, reservedUntil, func(items []models.CartItem, coupons []repository.AppliedCoupon) (*models.Order, error) {
+		if len(items) == 0 {
+			return nil, ErrEmptyCart
+		}
+
+		for _, item := range items {
+			if item.Quantity > item.AvailableStock() {
+				return nil, fmt.Errorf("%w: %s", ErrInsufficientStock, item.Product.Name)
+			}
+		}
+
+		// Price the locked items with the same pricer, group prices and promotions the
+		// cart summary uses
+		now := time.Now()
+		productIDs := cartProductIDs(items)
+		promotions, err := s.promotionRepo.Running(now, productIDs)
+		if err != nil {
+			return nil, err
+		}
+		prices, err := loadGroupPrices(s.priceListRepo, userID, productIDs)
+		if err != nil {
+			return nil, err
+		}
+		summary, err := s.pricer.Price(items, PricingContext{
+			UserID:     userID,
+			Prices:     prices,
+			Coupons:    coupons,
+			Promotions: promotions,
+			Address:    address,
+			Method:     method,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// A coupon that no longer applies fails the checkout rather than being
+		// silently dropped from an order the shopper expects it on
+		for _, applied := range coupons {
+			if err := couponProblem(applied, summary.Lines, summary.Subtotal, now); err != nil {
+				return nil, err
+			}
+		}
+
+		order := &models.Order{
+			UserID:           userID,
+			Status:           models.OrderStatusPending,
+			ShippingAddress:  address,
+			ShippingMethod:   summary.ShippingMethod,
+			Subtotal:         summary.Subtotal,
+			Discount:         summary.Discount,
+			Tax:              summary.Tax,
+			PricesIncludeTax: summary.PricesIncludeTax,
+			Shipping:         summary.Shipping,
+			Total:            summary.GrandTotal,
+			OrderItems:       make([]models.OrderItem, 0, len(summary.Lines)),
+		}
+		if method != nil {
+			order.ShippingMethodID = &method.ID
+		}
+
+		for _, line := range summary.Lines {
+			order.OrderItems = append(order.OrderItems, models.OrderItem{
+				ProductID:    line.ProductID,
+				ProductName:  line.Name,
+				VariantID:    line.VariantID,
+				SKU:          line.SKU,
+				VariantTitle: line.VariantTitle,
+				Quantity:     line.Quantity,
+				PriceAtTime:  line.UnitPrice,
+				Tax:          line.Tax,
+				TaxRate:      line.TaxRate,
+			})
+		}
+
+		for _, tax := range summary.Taxes {
+			order.Taxes = append(order.Taxes, models.OrderTax{
+				Name:    tax.Name,
+				Rate:    tax.Rate,
+				Taxable: tax.Taxable,
+				Amount:  tax.Amount,
+			})
+		}
+
+		for _, applied := range coupons {
+			redemption := models.CouponRedemption{CouponID: applied.Coupon.ID, Code: applied.Coupon.Code}
+			for _, discount := range summary.Discounts {
+				if discount.Code == applied.Coupon.Code {
+					redemption.Amount = discount.Amount
+				}
+			}
+			order.Coupons = append(order.Coupons, redemption)
+		}
+
+		return order, nil
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	productIDs := make([]uint, 0, len(order.OrderItems))
+	for _, item := range order.OrderItems {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	if err := s.indexer.ReindexProducts(productIDs); err != nil {
+		s.log.Error("Failed to update search index: " + err.Error())
+	}
+	return order, reservedUntil, nil
+}
+
+// shippingMethod returns the shipping method with the given ID among those offered
+// for the address, or nil when none is chosen. Once shipping zones are set up a
+// method must be chosen; until then orders are charged the store's flat fee. Methods
+// other than local pickup need a street address to ship to.
+func (s *DefaultCheckoutService) shippingMethod(address models.Address, methodID uint) (*models.ShippingMethod, error) {
+	if methodID == 0 {
+		hasZones, err := s.shippingRepo.HasZones()
+		if err != nil {
+			return nil, err
+		}
+		if hasZones {
+			return nil, ErrShippingMethodRequired
+		}
+		return nil, nil
+	}
+
+	if address.Country == "" {
+		return nil, fmt.Errorf("%w: a shipping address is required", ErrInvalidAddress)
+	}
+	methods, err := shippingMethods(s.shippingRepo, address)
+	if err != nil {
+		return nil, err
+	}
+	for i := range methods {
+		if methods[i].ID != methodID {
+			continue
+		}
+		if methods[i].Type != models.ShippingLocalPickup && (address.Line1 == "" || address.City == "") {
+			return nil, fmt.Errorf("%w: line1 and city are required to ship to", ErrInvalidAddress)
+		}
+		return &methods[i], nil
+	}
+	return nil, fmt.Errorf("%w: method %d is not offered for the address", ErrShippingUnavailable, methodID)
+}
+
+// checkAddress validates an address given by the shopper and turns it into a model.
+// Only the country is required; what else an address needs depends on its use.
+func checkAddress(input AddressInput) (models.Address, error) {
+	address := normalizeAddress(models.Address{
+		Name:       input.Name,
+		Line1:      input.Line1,
+		Line2:      input.Line2,
+		City:       input.City,
+		Region:     input.Region,
+		PostalCode: input.PostalCode,
+		Country:    input.Country,
+	})
+	if !validCountry(address.Country) {
+		return models.Address{}, fmt.Errorf("%w: country must be a two-letter code", ErrInvalidAddress)
+	}
+	for _, part := range []struct {
+		field string
+		value string
+		max   int
+	}{
+		{"name", address.Name, 255},
+		{"line1", address.Line1, 255},
+		{"line2", address.Line2, 255},
+		{"city", address.City, 100},
+		{"region", address.Region, 100},
+		{"postal_code", address.PostalCode, 20},
+	} {
+		if utf8.RuneCountInString(part.value) > part.max {
+			return models.Address{}, fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidAddress, part.field, part.max)
+		}
+	}
+	return address, nil
+}
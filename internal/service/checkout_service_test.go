@@ -0,0 +1,256 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func usd(value string) money.Money {
+	return money.MustParse(value, "USD")
+}
+
+// cartItem is a line of a cart for a product with the given price and available stock
+func cartItem(productID uint, price string, quantity, available int) models.CartItem {
+	return models.CartItem{
+		ProductID: productID,
+		Product:   models.Product{ID: productID, Name: "Product", Price: usd(price), Available: available},
+		Quantity:  quantity,
+	}
+}
+
+// fakeCheckoutRepo hands a fixed cart to the order builder, as the repository does
+// once it has locked the cart and its products
+type fakeCheckoutRepo struct {
+	repository.CheckoutRepository
+	items []models.CartItem
+}
+
+func (r *fakeCheckoutRepo) PlaceOrder(userID uint, reserveUntil time.Time, build repository.OrderBuilder) (*models.Order, error) {
+	return build(r.items, nil)
+}
+
+type fakePromotionRepo struct {
+	repository.PromotionRepository
+}
+
+func (r *fakePromotionRepo) Running(at time.Time, productIDs []uint) ([]repository.AppliedPromotion, error) {
+	return nil, nil
+}
+
+type fakePriceListRepo struct {
+	repository.PriceListRepository
+}
+
+func (r *fakePriceListRepo) UserEntries(userID uint, productIDs []uint) ([]models.PriceListEntry, error) {
+	return nil, nil
+}
+
+type fakeShippingRepo struct {
+	repository.ShippingRepository
+	hasZones bool
+}
+
+func (r *fakeShippingRepo) HasZones() (bool, error) {
+	return r.hasZones, nil
+}
+
+// flatTax taxes every line at one rate on top of its price
+type flatTax struct {
+	rate float64
+}
+
+func (t flatTax) Calculate(lines []TaxableLine, address models.Address) (*TaxBreakdown, error) {
+	breakdown := &TaxBreakdown{Lines: make([]LineTax, len(lines)), Total: money.Zero("USD")}
+	for i, line := range lines {
+		breakdown.Lines[i] = LineTax{Name: "Tax", Rate: t.rate, Amount: line.Amount.MulRate(t.rate)}
+		breakdown.Total = breakdown.Total.Add(breakdown.Lines[i].Amount)
+	}
+	return breakdown, nil
+}
+
+// fakeIndexer records the products it was asked to reindex
+type fakeIndexer struct {
+	reindexed []uint
+}
+
+func (f *fakeIndexer) ReindexProduct(id uint) error {
+	f.reindexed = append(f.reindexed, id)
+	return nil
+}
+
+func (f *fakeIndexer) ReindexProducts(ids []uint) error {
+	f.reindexed = append(f.reindexed, ids...)
+	return nil
+}
+
+func (f *fakeIndexer) ReindexAll() error {
+	return nil
+}
+
+func TestCheckout(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    []models.CartItem
+		hasZones bool
+		err      error
+		subtotal string
+		tax      string
+		shipping string
+		total    string
+	}{
+		{
+			name:     "flat shipping fee",
+			items:    []models.CartItem{cartItem(1, "10.00", 2, 5), cartItem(2, "15.50", 1, 1)},
+			subtotal: "35.50",
+			tax:      "3.55",
+			shipping: "5.00",
+			total:    "44.05",
+		},
+		{
+			name:     "free shipping over the threshold",
+			items:    []models.CartItem{cartItem(1, "60.00", 2, 2)},
+			subtotal: "120.00",
+			tax:      "12.00",
+			shipping: "0.00",
+			total:    "132.00",
+		},
+		{
+			name:  "empty cart",
+			items: nil,
+			err:   ErrEmptyCart,
+		},
+		{
+			name:  "more than the available stock",
+			items: []models.CartItem{cartItem(1, "10.00", 1, 5), cartItem(2, "10.00", 3, 2)},
+			err:   ErrInsufficientStock,
+		},
+		{
+			name: "product in another currency",
+			items: []models.CartItem{{
+				ProductID: 1,
+				Product:   models.Product{ID: 1, Price: money.MustParse("10.00", "EUR"), Available: 1},
+				Quantity:  1,
+			}},
+			err: ErrCurrencyMismatch,
+		},
+		{
+			name:     "shipping zones need a method",
+			items:    []models.CartItem{cartItem(1, "10.00", 1, 1)},
+			hasZones: true,
+			err:      ErrShippingMethodRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pricer := NewCartPricer(PricingConfig{
+				Currency:              "USD",
+				ShippingFee:           usd("5.00"),
+				FreeShippingThreshold: usd("100.00"),
+			}, flatTax{rate: 0.1})
+			indexer := &fakeIndexer{}
+			checkout := NewCheckoutService(&fakeCheckoutRepo{items: tt.items}, &fakePromotionRepo{},
+				&fakePriceListRepo{}, &fakeShippingRepo{hasZones: tt.hasZones}, pricer, indexer, 15*time.Minute)
+
+			before := time.Now()
+			order, reservedUntil, err := checkout.Checkout(1, CheckoutInput{})
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Checkout() error = %v, want %v", err, tt.err)
+				}
+				if len(indexer.reindexed) > 0 {
+					t.Errorf("failed checkout reindexed products %v", indexer.reindexed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Checkout() error = %v", err)
+			}
+
+			if order.Status != models.OrderStatusPending {
+				t.Errorf("status = %q, want %q", order.Status, models.OrderStatusPending)
+			}
+			for _, got := range []struct {
+				field string
+				value money.Money
+				want  string
+			}{
+				{"subtotal", order.Subtotal, tt.subtotal},
+				{"tax", order.Tax, tt.tax},
+				{"shipping", order.Shipping, tt.shipping},
+				{"total", order.Total, tt.total},
+			} {
+				if got.value != usd(got.want) {
+					t.Errorf("%s = %s, want %s", got.field, got.value, got.want)
+				}
+			}
+
+			if len(order.OrderItems) != len(tt.items) {
+				t.Fatalf("got %d order items, want %d", len(order.OrderItems), len(tt.items))
+			}
+			var productIDs []uint
+			for i, item := range order.OrderItems {
+				if item.Quantity != tt.items[i].Quantity || item.PriceAtTime != tt.items[i].Product.Price {
+					t.Errorf("item %d = %d at %s, want %d at %s", i, item.Quantity, item.PriceAtTime,
+						tt.items[i].Quantity, tt.items[i].Product.Price)
+				}
+				productIDs = append(productIDs, item.ProductID)
+			}
+			if !slices.Equal(indexer.reindexed, productIDs) {
+				t.Errorf("reindexed %v, want %v", indexer.reindexed, productIDs)
+			}
+
+			if reservedUntil.Before(before.Add(15 * time.Minute)) {
+				t.Errorf("reserved until %s, want 15 minutes from now", reservedUntil)
+			}
+		})
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	tests := []struct {
+		name  string
+		input AddressInput
+		want  models.Address
+		err   error
+	}{
+		{
+			name:  "normalized",
+			input: AddressInput{Name: " Ann ", Line1: "1 Main St", City: "Springfield", Region: "il", PostalCode: " 62701 ", Country: "us"},
+			want:  models.Address{Name: "Ann", Line1: "1 Main St", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "US"},
+		},
+		{
+			name:  "country only",
+			input: AddressInput{Country: "DE"},
+			want:  models.Address{Country: "DE"},
+		},
+		{
+			name:  "missing country",
+			input: AddressInput{Line1: "1 Main St"},
+			err:   ErrInvalidAddress,
+		},
+		{
+			name:  "country name instead of code",
+			input: AddressInput{Country: "USA"},
+			err:   ErrInvalidAddress,
+		},
+		{
+			name:  "postal code too long",
+			input: AddressInput{PostalCode: "123456789012345678901", Country: "US"},
+			err:   ErrInvalidAddress,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkAddress(tt.input)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("checkAddress() = %+v, %v, want %+v, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrCouponNotFound is returned when a referenced coupon does not exist
+	ErrCouponNotFound = errors.New("coupon not found")
+	// ErrInvalidCoupon is returned when coupon data fails validation
+	ErrInvalidCoupon = errors.New("invalid coupon")
+	// ErrCouponCodeTaken is returned when another coupon already uses the code
+	ErrCouponCodeTaken = errors.New("coupon code already in use")
+	// ErrCouponRedeemed is returned when deleting a coupon that orders have redeemed
+	ErrCouponRedeemed = errors.New("coupon has been redeemed; deactivate it instead")
+	// ErrCouponNotApplicable is returned when a coupon cannot be used on the cart
+	ErrCouponNotApplicable = errors.New("coupon cannot be applied")
+	// ErrCouponLimitReached is returned when the shopper cannot redeem a coupon again.
+	// The checkout transaction reports it from the repository, so the two are the same.
+	ErrCouponLimitReached = repository.ErrCouponLimitReached
+	// ErrCouponNotStackable is returned when a coupon cannot be combined with the coupons in the cart
+	ErrCouponNotStackable = errors.New("coupon cannot be combined with other coupons")
+)
+
+// couponCodePattern lists the characters a coupon code may consist of
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,50}$`)
+
+// CouponInput is the data of a coupon to create or update. Products and categories
+// restrict the coupon to them; without any it applies to the whole cart.
+type CouponInput struct {
+	Code         string      `json:"code"`
+	Description  string      `json:"description"`
+	Type         string      `json:"type"`
+	Percent      float64     `json:"percent"`
+	Amount       money.Money `json:"amount"`
+	MinSubtotal  money.Money `json:"min_subtotal"`
+	StartsAt     *time.Time  `json:"starts_at"`
+	EndsAt       *time.Time  `json:"ends_at"`
+	UsageLimit   int         `json:"usage_limit"`
+	PerUserLimit int         `json:"per_user_limit"`
+	Stackable    bool        `json:"stackable"`
+	Active       *bool       `json:"active"` // Defaults to true
+	ProductIDs   []uint      `json:"product_ids"`
+	CategoryIDs  []uint      `json:"category_ids"`
+}
+
+// CouponService defines the interface for the admin management of coupons
+type CouponService interface {
+	ListCoupons(page, pageSize int) ([]models.Coupon, int64, error)
+	GetCoupon(id uint) (*models.Coupon, error)
+	CreateCoupon(input CouponInput) (*models.Coupon, error)
+	UpdateCoupon(id uint, input CouponInput) (*models.Coupon, error)
+	DeleteCoupon(id uint) error
+	ListRedemptions(id uint, page, pageSize int) ([]models.CouponRedemption, int64, error)
+}
+
+// DefaultCouponService implements CouponService
+type DefaultCouponService struct {
+	repo         repository.CouponRepository
+	productRepo  repository.ProductRepository
+	categoryRepo repository.CategoryRepository
+}
+
+// NewCouponService creates a new instance of DefaultCouponService
+func NewCouponService(repo repository.CouponRepository, productRepo repository.ProductRepository,
+	categoryRepo repository.CategoryRepository) CouponService {
+	return &DefaultCouponService{
+		repo:         repo,
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+	}
+}
+
+// ListCoupons retrieves one page of coupons, newest first
+func (s *DefaultCouponService) ListCoupons(page, pageSize int) ([]models.Coupon, int64, error) {
+	return s.repo.List(page, pageSize)
+}
+
+// GetCoupon retrieves a coupon with the products and categories it is restricted to
+func (s *DefaultCouponService) GetCoupon(id uint) (*models.Coupon, error) {
+	coupon, err := s.repo.FindByID(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrCouponNotFound
+	}
+	return coupon, err
+}
+
+// CreateCoupon validates and stores a new coupon
+func (s *DefaultCouponService) CreateCoupon(input CouponInput) (*models.Coupon, error) {
+	coupon := &models.Coupon{}
+	if err := s.apply(coupon, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(coupon, input.ProductIDs, input.CategoryIDs); err != nil {
+		return nil, err
+	}
+	return s.GetCoupon(coupon.ID)
+}
+
+// UpdateCoupon validates and replaces the data of an existing coupon. Orders that
+// already redeemed it keep the discount they got.
+func (s *DefaultCouponService) UpdateCoupon(id uint, input CouponInput) (*models.Coupon, error) {
+	coupon, err := s.GetCoupon(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(coupon, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(coupon, input.ProductIDs, input.CategoryIDs); err != nil {
+		return nil, err
+	}
+	return s.GetCoupon(id)
+}
+
+// DeleteCoupon removes a coupon that was never redeemed
+func (s *DefaultCouponService) DeleteCoupon(id uint) error {
+	err := s.repo.Delete(id)
+	switch {
+	case errors.Is(err, repository.ErrRecordNotFound):
+		return ErrCouponNotFound
+	case errors.Is(err, repository.ErrCouponRedeemed):
+		return ErrCouponRedeemed
+	}
+	return err
+}
+
+// ListRedemptions retrieves one page of the redemptions of a coupon, newest first
+func (s *DefaultCouponService) ListRedemptions(id uint, page, pageSize int) ([]models.CouponRedemption, int64, error) {
+	if _, err := s.GetCoupon(id); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.ListRedemptions(id, page, pageSize)
+}
+
+// apply validates the input and copies it onto the coupon
+func (s *DefaultCouponService) apply(coupon *models.Coupon, input CouponInput) error {
+	code := strings.ToUpper(strings.TrimSpace(input.Code))
+	if !couponCodePattern.MatchString(code) {
+		return fmt.Errorf("%w: code must be 3 to 50 letters, digits, dashes or underscores", ErrInvalidCoupon)
+	}
+	existing, err := s.repo.FindByCode(code)
+	if err == nil && existing.ID != coupon.ID {
+		return ErrCouponCodeTaken
+	}
+	if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		return err
+	}
+
+	description := strings.TrimSpace(input.Description)
+	if utf8.RuneCountInString(description) > 255 {
+		return fmt.Errorf("%w: description must be at most 255 characters", ErrInvalidCoupon)
+	}
+
+	amount := money.Zero(money.DefaultCurrency)
+	percent := 0.0
+	switch input.Type {
+	case models.CouponPercentage:
+		if math.IsNaN(input.Percent) || input.Percent <= 0 || input.Percent > 100 {
+			return fmt.Errorf("%w: percent must be above 0 and at most 100", ErrInvalidCoupon)
+		}
+		percent = math.Round(input.Percent*100) / 100
+	case models.CouponFixedAmount:
+		if amount, err = storeAmount(input.Amount, "amount", ErrInvalidCoupon); err != nil {
+			return err
+		}
+		if !amount.IsPositive() {
+			return fmt.Errorf("%w: amount must be positive", ErrInvalidCoupon)
+		}
+	case models.CouponFreeShipping:
+	default:
+		return fmt.Errorf("%w: type must be %s, %s or %s", ErrInvalidCoupon,
+			models.CouponPercentage, models.CouponFixedAmount, models.CouponFreeShipping)
+	}
+
+	minSubtotal, err := storeAmount(input.MinSubtotal, "min_subtotal", ErrInvalidCoupon)
+	if err != nil {
+		return err
+	}
+	if minSubtotal.IsNegative() {
+		return fmt.Errorf("%w: min_subtotal must not be negative", ErrInvalidCoupon)
+	}
+	if input.StartsAt != nil && input.EndsAt != nil && !input.EndsAt.After(*input.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidCoupon)
+	}
+	if input.UsageLimit < 0 || input.PerUserLimit < 0 {
+		return fmt.Errorf("%w: usage limits must not be negative", ErrInvalidCoupon)
+	}
+	if err := checkOfferScope(s.productRepo, s.categoryRepo, input.ProductIDs, input.CategoryIDs, ErrInvalidCoupon); err != nil {
+		return err
+	}
+
+	coupon.Code = code
+	coupon.Description = description
+	coupon.Type = input.Type
+	coupon.Percent = percent
+	coupon.Amount = amount
+	coupon.MinSubtotal = minSubtotal
+	coupon.StartsAt = input.StartsAt
+	coupon.EndsAt = input.EndsAt
+	coupon.UsageLimit = input.UsageLimit
+	coupon.PerUserLimit = input.PerUserLimit
+	coupon.Stackable = input.Stackable
+	coupon.Active = input.Active == nil || *input.Active
+	return nil
+}
+
+// checkOfferScope makes sure the products and categories a coupon or promotion is
+// restricted to exist, reporting missing ones as invalid
+func checkOfferScope(productRepo repository.ProductRepository, categoryRepo repository.CategoryRepository,
+	productIDs, categoryIDs []uint, invalid error) error {
+	if len(productIDs) > 0 {
+		products, err := productRepo.FindByIDs(productIDs)
+		if err != nil {
+			return err
+		}
+		for _, id := range productIDs {
+			if !slices.ContainsFunc(products, func(product models.Product) bool { return product.ID == id }) {
+				return fmt.Errorf("%w: product %d does not exist", invalid, id)
+			}
+		}
+	}
+	for _, id := range categoryIDs {
+		if _, err := categoryRepo.FindByID(id); err != nil {
+			if errors.Is(err, repository.ErrRecordNotFound) {
+				return fmt.Errorf("%w: category %d does not exist", invalid, id)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// CouponDiscounter turns the coupons applied to a cart into discount lines
+type CouponDiscounter struct{}
+
+// NewCouponDiscounter creates a new instance of CouponDiscounter
+func NewCouponDiscounter() *CouponDiscounter {
+	return &CouponDiscounter{}
+}
+
+// Discounts returns a line for every applied coupon that can be used on the cart.
+// Usage limits are checked when a coupon is applied and again at checkout.
+func (d *CouponDiscounter) Discounts(lines []SummaryLine, subtotal money.Money, ctx PricingContext) []DiscountLine {
+	now := time.Now()
+	discounts := make([]DiscountLine, 0, len(ctx.Coupons))
+	for _, applied := range ctx.Coupons {
+		if couponProblem(applied, lines, subtotal, now) != nil {
+			continue
+		}
+		discounts = append(discounts, couponDiscount(applied, lines))
+	}
+	return discounts
+}
+
+// couponProblem explains why a coupon cannot be used on cart lines, or returns nil
+func couponProblem(applied repository.AppliedCoupon, lines []SummaryLine, subtotal money.Money, now time.Time) error {
+	coupon := applied.Coupon
+	switch {
+	case !coupon.Active:
+		return fmt.Errorf("%w: %s is no longer available", ErrCouponNotApplicable, coupon.Code)
+	case coupon.StartsAt != nil && now.Before(*coupon.StartsAt):
+		return fmt.Errorf("%w: %s is not valid yet", ErrCouponNotApplicable, coupon.Code)
+	case coupon.EndsAt != nil && !now.Before(*coupon.EndsAt):
+		return fmt.Errorf("%w: %s has expired", ErrCouponNotApplicable, coupon.Code)
+	case coupon.MinSubtotal.IsPositive() && subtotal.Cmp(coupon.MinSubtotal) < 0:
+		return fmt.Errorf("%w: %s needs a subtotal of at least %s", ErrCouponNotApplicable, coupon.Code, coupon.MinSubtotal)
+	}
+
+	for _, line := range lines {
+		if applied.Applies(line.ProductID) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s does not apply to any product in the cart", ErrCouponNotApplicable, coupon.Code)
+}
+
+// couponDiscount computes the discount a usable coupon gives on cart lines
+func couponDiscount(applied repository.AppliedCoupon, lines []SummaryLine) DiscountLine {
+	coupon := applied.Coupon
+	eligible := money.Zero(money.DefaultCurrency)
+	for _, line := range lines {
+		if applied.Applies(line.ProductID) {
+			eligible = eligible.Add(line.LineTotal)
+		}
+	}
+
+	discount := DiscountLine{Code: coupon.Code, Description: coupon.Description, Amount: money.Zero(eligible.Currency)}
+	switch coupon.Type {
+	case models.CouponPercentage:
+		discount.Amount = eligible.MulRate(coupon.Percent / 100)
+		if discount.Description == "" {
+			discount.Description = strconv.FormatFloat(coupon.Percent, 'f', -1, 64) + "% off"
+		}
+	case models.CouponFixedAmount:
+		discount.Amount = money.Min(coupon.Amount, eligible)
+		if discount.Description == "" {
+			discount.Description = coupon.Amount.String() + " off"
+		}
+	case models.CouponFreeShipping:
+		discount.FreeShipping = true
+		if discount.Description == "" {
+			discount.Description = "Free shipping"
+		}
+	}
+	return discount
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"errors"
+	"testing"
+	"time"
+)
+
+// summaryLines are cart lines of products 1 and 2 worth 30.00 and 20.00
+func summaryLines() []SummaryLine {
+	return []SummaryLine{
+		{ProductID: 1, Quantity: 3, UnitPrice: usd("10.00"), LineTotal: usd("30.00")},
+		{ProductID: 2, Quantity: 1, UnitPrice: usd("20.00"), LineTotal: usd("20.00")},
+	}
+}
+
+func TestCouponProblem(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		coupon  models.Coupon
+		only    []uint // Products the coupon is restricted to; nil for all
+		wantErr bool
+	}{
+		{name: "active", coupon: models.Coupon{Active: true}},
+		{name: "inactive", coupon: models.Coupon{}, wantErr: true},
+		{name: "within its window", coupon: models.Coupon{Active: true, StartsAt: &before, EndsAt: &after}},
+		{name: "not started", coupon: models.Coupon{Active: true, StartsAt: &after}, wantErr: true},
+		{name: "starts now", coupon: models.Coupon{Active: true, StartsAt: &now}},
+		{name: "ended", coupon: models.Coupon{Active: true, EndsAt: &before}, wantErr: true},
+		{name: "ends now", coupon: models.Coupon{Active: true, EndsAt: &now}, wantErr: true},
+		{name: "subtotal at the minimum", coupon: models.Coupon{Active: true, MinSubtotal: usd("50.00")}},
+		{name: "subtotal under the minimum", coupon: models.Coupon{Active: true, MinSubtotal: usd("50.01")}, wantErr: true},
+		{name: "restricted to a product in the cart", coupon: models.Coupon{Active: true}, only: []uint{2, 9}},
+		{name: "restricted to other products", coupon: models.Coupon{Active: true}, only: []uint{9}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.coupon.Code = "SAVE"
+			applied := repository.AppliedCoupon{Coupon: tt.coupon}
+			if tt.only != nil {
+				applied.Eligible = map[uint]bool{}
+				for _, id := range tt.only {
+					applied.Eligible[id] = true
+				}
+			}
+
+			err := couponProblem(applied, summaryLines(), usd("50.00"), now)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("couponProblem() = %v, want an error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrCouponNotApplicable) {
+				t.Errorf("couponProblem() = %v, want %v", err, ErrCouponNotApplicable)
+			}
+		})
+	}
+}
+
+func TestCouponDiscount(t *testing.T) {
+	tests := []struct {
+		name         string
+		coupon       models.Coupon
+		only         []uint
+		amount       string
+		freeShipping bool
+		description  string
+	}{
+		{
+			name:        "percentage",
+			coupon:      models.Coupon{Type: models.CouponPercentage, Percent: 10},
+			amount:      "5.00",
+			description: "10% off",
+		},
+		{
+			name:        "percentage rounds half away from zero",
+			coupon:      models.Coupon{Type: models.CouponPercentage, Percent: 12.5},
+			only:        []uint{2},
+			amount:      "2.50",
+			description: "12.5% off",
+		},
+		{
+			name:        "percentage of a restricted product",
+			coupon:      models.Coupon{Type: models.CouponPercentage, Percent: 15, Description: "Spring sale"},
+			only:        []uint{1},
+			amount:      "4.50",
+			description: "Spring sale",
+		},
+		{
+			name:        "fixed amount",
+			coupon:      models.Coupon{Type: models.CouponFixedAmount, Amount: usd("7.50")},
+			amount:      "7.50",
+			description: "7.50 USD off",
+		},
+		{
+			name:        "fixed amount capped at the eligible lines",
+			coupon:      models.Coupon{Type: models.CouponFixedAmount, Amount: usd("25.00")},
+			only:        []uint{2},
+			amount:      "20.00",
+			description: "25.00 USD off",
+		},
+		{
+			name:         "free shipping",
+			coupon:       models.Coupon{Type: models.CouponFreeShipping},
+			amount:       "0.00",
+			freeShipping: true,
+			description:  "Free shipping",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.coupon.Code = "SAVE"
+			applied := repository.AppliedCoupon{Coupon: tt.coupon}
+			if tt.only != nil {
+				applied.Eligible = map[uint]bool{}
+				for _, id := range tt.only {
+					applied.Eligible[id] = true
+				}
+			}
+
+			got := couponDiscount(applied, summaryLines())
+			if got.Amount != usd(tt.amount) || got.FreeShipping != tt.freeShipping || got.Code != "SAVE" {
+				t.Errorf("couponDiscount() = %s (free shipping %v, code %q), want %s (free shipping %v, code SAVE)",
+					got.Amount, got.FreeShipping, got.Code, tt.amount, tt.freeShipping)
+			}
+			if got.Description != tt.description {
+				t.Errorf("description = %q, want %q", got.Description, tt.description)
+			}
+		})
+	}
+}
+
+func TestCouponDiscounterSkipsUnusableCoupons(t *testing.T) {
+	coupons := []repository.AppliedCoupon{
+		{Coupon: models.Coupon{Code: "TEN", Type: models.CouponPercentage, Percent: 10, Active: true}},
+		{Coupon: models.Coupon{Code: "OFF", Type: models.CouponFixedAmount, Amount: usd("5.00")}},
+		{Coupon: models.Coupon{Code: "BIG", Type: models.CouponFixedAmount, Amount: usd("5.00"), Active: true, MinSubtotal: usd("100.00")}},
+		{Coupon: models.Coupon{Code: "SHIP", Type: models.CouponFreeShipping, Active: true}},
+	}
+
+	discounts := NewCouponDiscounter().Discounts(summaryLines(), usd("50.00"), PricingContext{Coupons: coupons})
+	var codes []string
+	for _, discount := range discounts {
+		codes = append(codes, discount.Code)
+	}
+	if len(codes) != 2 || codes[0] != "TEN" || codes[1] != "SHIP" {
+		t.Errorf("discounts from %v, want [TEN SHIP]", codes)
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/logger"
+	"errors"
+	"time"
+)
+
+// expiryBatchSize limits the orders whose reservations a single sweep releases
+const expiryBatchSize = 500
+
+// ErrReservationExpired is returned when paying for an order whose stock is no longer held
+var ErrReservationExpired = errors.New("stock reservation expired")
+
+// InventoryService defines the interface for stock reservation housekeeping
+type InventoryService interface {
+	ReleaseExpiredReservations() ([]uint, error)
+}
+
+// DefaultInventoryService implements InventoryService
+type DefaultInventoryService struct {
+	repo    repository.InventoryRepository
+	indexer ProductIndexer
+	log     *logger.Logger
+}
+
+// NewInventoryService creates a new instance of DefaultInventoryService
+func NewInventoryService(repo repository.InventoryRepository, indexer ProductIndexer) InventoryService {
+	return &DefaultInventoryService{
+		repo:    repo,
+		indexer: indexer,
+		log:     logger.New(),
+	}
+}
+
+// ReleaseExpiredReservations gives the stock of lapsed reservations back and cancels
+// the unpaid orders holding them. Each call handles a bounded batch of orders; the
+// rest are picked up by the next sweep. It returns the IDs of the cancelled orders.
+// The products whose units came back are reindexed, as they may be in stock again.
+func (s *DefaultInventoryService) ReleaseExpiredReservations() ([]uint, error) {
+	expired, err := s.repo.ExpireReservations(time.Now(), expiryBatchSize)
+	if indexErr := s.indexer.ReindexProducts(expired.ProductIDs); indexErr != nil {
+		s.log.Error("Failed to update search index: " + indexErr.Error())
+	}
+	return expired.Cancelled, err
+}
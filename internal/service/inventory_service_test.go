@@ -0,0 +1,97 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeInventoryRepo returns a fixed outcome for every sweep
+type fakeInventoryRepo struct {
+	expired repository.ExpiredReservations
+	err     error
+}
+
+func (r *fakeInventoryRepo) ExpireReservations(now time.Time, limit int) (repository.ExpiredReservations, error) {
+	return r.expired, r.err
+}
+
+func TestReleaseExpiredReservations(t *testing.T) {
+	failed := errors.New("connection reset")
+	tests := []struct {
+		name    string
+		expired repository.ExpiredReservations
+		err     error
+	}{
+		{
+			name:    "cancels unpaid orders",
+			expired: repository.ExpiredReservations{Cancelled: []uint{1, 2}, ProductIDs: []uint{5, 6, 7}},
+		},
+		{
+			// Orders paid for before the sweep keep their status; their lapsed
+			// reservations are still given back
+			name:    "releases without cancelling",
+			expired: repository.ExpiredReservations{ProductIDs: []uint{5}},
+		},
+		{
+			name: "nothing expired",
+		},
+		{
+			name:    "reindexes what was released before a failure",
+			expired: repository.ExpiredReservations{Cancelled: []uint{1}, ProductIDs: []uint{5}},
+			err:     failed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := &fakeIndexer{}
+			service := NewInventoryService(&fakeInventoryRepo{expired: tt.expired, err: tt.err}, indexer)
+			cancelled, err := service.ReleaseExpiredReservations()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ReleaseExpiredReservations() error = %v, want %v", err, tt.err)
+			}
+			if !slices.Equal(cancelled, tt.expired.Cancelled) {
+				t.Errorf("cancelled = %v, want %v", cancelled, tt.expired.Cancelled)
+			}
+			if !slices.Equal(indexer.reindexed, tt.expired.ProductIDs) {
+				t.Errorf("reindexed %v, want %v", indexer.reindexed, tt.expired.ProductIDs)
+			}
+		})
+	}
+}
+
+func TestAvailableStock(t *testing.T) {
+	tests := []struct {
+		name string
+		item models.CartItem
+		want int
+	}{
+		{
+			name: "product",
+			item: models.CartItem{Product: models.Product{Stock: 10, Available: 4}},
+			want: 4,
+		},
+		{
+			name: "variant",
+			item: models.CartItem{
+				Product: models.Product{Stock: 10, Available: 4},
+				Variant: &models.ProductVariant{Stock: 3, Available: 1},
+			},
+			want: 1,
+		},
+		{
+			name: "fully reserved",
+			item: models.CartItem{Product: models.Product{Stock: 2, Available: 0}},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.item.AvailableStock(); got != tt.want {
+			t.Errorf("%s: AvailableStock() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
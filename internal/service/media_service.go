@@ -0,0 +1,240 @@
+package service
+
+import (
+	"crypto/rand"
+	"ecommerce-app/internal/media"
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/internal/storage"
+	"ecommerce-app/pkg/logger"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrImageNotFound is returned when an image does not exist or belongs to another product
+	ErrImageNotFound = errors.New("image not found")
+	// ErrInvalidImage is returned when an upload is not a supported image
+	ErrInvalidImage = errors.New("invalid image")
+	// ErrInvalidImageOrder is returned when a new image order does not list every image of the product once
+	ErrInvalidImageOrder = errors.New("invalid image order")
+)
+
+// ImageUpload is an uploaded image file
+type ImageUpload struct {
+	Filename string
+	Data     []byte
+	AltText  string
+}
+
+// MediaService defines the interface for product image business logic
+type MediaService interface {
+	GetImages(productID uint) ([]models.ProductImage, error)
+	UploadImages(productID uint, uploads []ImageUpload) ([]models.ProductImage, error)
+	ReorderImages(productID uint, imageIDs []uint) ([]models.ProductImage, error)
+	SetPrimaryImage(productID uint, imageID uint) ([]models.ProductImage, error)
+	DeleteImage(productID uint, imageID uint) error
+	RemoveImageFiles(images []models.ProductImage)
+}
+
+// DefaultMediaService implements MediaService
+type DefaultMediaService struct {
+	repo        repository.MediaRepository
+	productRepo repository.ProductRepository
+	store       storage.BlobStore
+	log         *logger.Logger
+}
+
+// NewMediaService creates a new instance of DefaultMediaService storing files in store
+func NewMediaService(repo repository.MediaRepository, productRepo repository.ProductRepository,
+	store storage.BlobStore) MediaService {
+	return &DefaultMediaService{
+		repo:        repo,
+		productRepo: productRepo,
+		store:       store,
+		log:         logger.New(),
+	}
+}
+
+// GetImages retrieves the images of a product in display order
+func (s *DefaultMediaService) GetImages(productID uint) ([]models.ProductImage, error) {
+	if err := s.checkProduct(productID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByProduct(productID)
+}
+
+// UploadImages adds images to the end of a product's gallery and returns the
+// created images. Every upload is validated and resized before anything is stored,
+// so one bad file rejects the whole batch.
+func (s *DefaultMediaService) UploadImages(productID uint, uploads []ImageUpload) ([]models.ProductImage, error) {
+	if err := s.checkProduct(productID); err != nil {
+		return nil, err
+	}
+	if len(uploads) == 0 {
+		return nil, fmt.Errorf("%w: no files uploaded", ErrInvalidImage)
+	}
+
+	processed := make([]*media.ProcessedImage, len(uploads))
+	for i, upload := range uploads {
+		image, err := media.Process(upload.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidImage, upload.Filename, err)
+		}
+		processed[i] = image
+	}
+
+	created := make([]models.ProductImage, 0, len(uploads))
+	for i, upload := range uploads {
+		image, err := s.storeImage(productID, processed[i], strings.TrimSpace(upload.AltText))
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, *image)
+	}
+	return created, nil
+}
+
+// ReorderImages sets the display order of a product's images. imageIDs must list
+// every image of the product exactly once.
+func (s *DefaultMediaService) ReorderImages(productID uint, imageIDs []uint) ([]models.ProductImage, error) {
+	images, err := s.GetImages(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(imageIDs) != len(images) {
+		return nil, fmt.Errorf("%w: expected %d image IDs, got %d", ErrInvalidImageOrder, len(images), len(imageIDs))
+	}
+	remaining := make(map[uint]bool, len(images))
+	for _, image := range images {
+		remaining[image.ID] = true
+	}
+	for _, id := range imageIDs {
+		if !remaining[id] {
+			return nil, fmt.Errorf("%w: image %d is unknown or listed twice", ErrInvalidImageOrder, id)
+		}
+		delete(remaining, id)
+	}
+
+	if err := s.repo.Reorder(productID, imageIDs); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByProduct(productID)
+}
+
+// SetPrimaryImage makes an image the primary image of its product
+func (s *DefaultMediaService) SetPrimaryImage(productID uint, imageID uint) ([]models.ProductImage, error) {
+	if err := s.checkProduct(productID); err != nil {
+		return nil, err
+	}
+	err := s.repo.SetPrimary(productID, imageID)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrImageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListByProduct(productID)
+}
+
+// DeleteImage removes an image and its stored files. Files that cannot be removed
+// are logged and left behind rather than failing the request.
+func (s *DefaultMediaService) DeleteImage(productID uint, imageID uint) error {
+	image, err := s.repo.Delete(productID, imageID)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrImageNotFound
+	}
+	if err != nil {
+		return err
+	}
+	s.deleteFiles(image.Keys())
+	return nil
+}
+
+// RemoveImageFiles removes the stored files of images whose records are already deleted
+func (s *DefaultMediaService) RemoveImageFiles(images []models.ProductImage) {
+	for _, image := range images {
+		s.deleteFiles(image.Keys())
+	}
+}
+
+// storeImage uploads an image and its renditions and records it. The files are
+// removed again when the record cannot be created.
+func (s *DefaultMediaService) storeImage(productID uint, processed *media.ProcessedImage, altText string) (*models.ProductImage, error) {
+	prefix, err := randomPrefix()
+	if err != nil {
+		return nil, err
+	}
+	base := fmt.Sprintf("products/%d/%s", productID, prefix)
+
+	renditions := []struct {
+		name      string
+		rendition media.Rendition
+	}{
+		{"original", processed.Original},
+		{"thumbnail", processed.Thumbnail},
+		{"medium", processed.Medium},
+	}
+	keys := make([]string, 0, len(renditions))
+	for _, r := range renditions {
+		key := base + "/" + r.name + "." + r.rendition.Extension
+		if err := s.store.Put(key, r.rendition.Data, r.rendition.ContentType); err != nil {
+			s.deleteFiles(keys)
+			return nil, fmt.Errorf("failed to store image: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	image := &models.ProductImage{
+		ProductID:    productID,
+		URL:          s.store.URL(keys[0]),
+		ThumbnailURL: s.store.URL(keys[1]),
+		MediumURL:    s.store.URL(keys[2]),
+		OriginalKey:  keys[0],
+		ThumbnailKey: keys[1],
+		MediumKey:    keys[2],
+		ContentType:  processed.Original.ContentType,
+		Width:        processed.Original.Width,
+		Height:       processed.Original.Height,
+		AltText:      altText,
+	}
+	if err := s.repo.Create(image); err != nil {
+		s.deleteFiles(keys)
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+	return image, nil
+}
+
+// deleteFiles removes stored files, logging the ones that could not be removed
+func (s *DefaultMediaService) deleteFiles(keys []string) {
+	for _, key := range keys {
+		if err := s.store.Delete(key); err != nil {
+			s.log.Error("Failed to delete stored file " + key + ": " + err.Error())
+		}
+	}
+}
+
+// checkProduct makes sure a product exists
+func (s *DefaultMediaService) checkProduct(productID uint) error {
+	_, err := s.productRepo.FindByID(productID)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrProductNotFound
+	}
+	return err
+}
+
+// randomPrefix returns a random name for the storage folder of an image, so
+// image URLs cannot be guessed and never get reused
+func randomPrefix() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
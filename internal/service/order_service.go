@@ -3,31 +3,67 @@ package service
 import (
     "ecommerce-app/internal/models"
     "ecommerce-app/internal/repository"
+    "ecommerce-app/pkg/logger"
+    "errors"
+    "fmt"
 )
 
+var (
+    // ErrOrderNotFound is returned when an order does not exist
+    ErrOrderNotFound = errors.New("order not found")
+    // ErrInvalidOrderStatus is returned for a status that is not part of the order lifecycle
+    ErrInvalidOrderStatus = errors.New("invalid order status")
+    // ErrInvalidStatusTransition is returned when the lifecycle does not allow moving between two statuses
+    ErrInvalidStatusTransition = errors.New("invalid order status transition")
+)
+
+// orderTransitions lists, for every order status, the statuses it may move to next
+var orderTransitions = map[string][]string{
+    models.OrderStatusPending:   {models.OrderStatusPaid, models.OrderStatusCancelled},
+    models.OrderStatusPaid:      {models.OrderStatusFulfilled, models.OrderStatusRefunded},
+    models.OrderStatusFulfilled: {models.OrderStatusShipped, models.OrderStatusRefunded},
+    models.OrderStatusShipped:   {models.OrderStatusDelivered, models.OrderStatusRefunded},
+    models.OrderStatusDelivered: {models.OrderStatusRefunded},
+    models.OrderStatusCancelled: {},
+    models.OrderStatusRefunded:  {},
+}
+
+// OrderDetail bundles an order with its status history
+type OrderDetail struct {
+    Order   *models.Order               `json:"order"`
+    History []models.OrderStatusHistory `json:"history"`
+}
+
 // OrderService defines the interface for order-related business logic
 type OrderService interface {
     GetOrderByID(id uint) (*models.Order, error)
+    GetOrderDetail(id uint) (*OrderDetail, error)
+    GetUserOrder(userID, orderID uint) (*models.Order, error)
+    GetUserOrdersPaginated(userID uint, filter repository.OrderFilter, page, pageSize int) ([]models.Order, int64, error)
     GetAllOrders() ([]models.Order, error)
     GetOrdersPaginated(page, pageSize int) ([]models.Order, error)
     GetAllOrdersWithUser() ([]models.Order, error)
     GetOrdersWithUserPaginated(page, pageSize int) ([]models.Order, error)
     CreateOrder(order *models.Order) error
-    UpdateOrder(order *models.Order) error
-    UpdateOrderStatus(id uint, status string) error
+    UpdateOrderStatus(id uint, status, changedBy, note string) error
+    AllowedTransitions(status string) []string
     DeleteOrder(id uint) error
     CountOrders() (int64, error)
 }
 
 // DefaultOrderService implements OrderService
 type DefaultOrderService struct {
-    repo repository.OrderRepository
+    repo    repository.OrderRepository
+    indexer ProductIndexer
+    log     *logger.Logger
 }
 
 // NewOrderService creates a new instance of DefaultOrderService
-func NewOrderService(repo repository.OrderRepository) OrderService {
+func NewOrderService(repo repository.OrderRepository, indexer ProductIndexer) OrderService {
     return &DefaultOrderService{
-        repo: repo,
+        repo:    repo,
+        indexer: indexer,
+        log:     logger.New(),
     }
 }
 
@@ -36,6 +72,59 @@ func (s *DefaultOrderService) GetOrderByID(id uint) (*models.Order, error) {
     return s.repo.FindByID(id)
 }
 
+// GetOrderDetail retrieves an order with its user, items and status history
+func (s *DefaultOrderService) GetOrderDetail(id uint) (*OrderDetail, error) {
+    order, err := s.repo.FindByIDWithDetails(id)
+    if err != nil {
+        if errors.Is(err, repository.ErrRecordNotFound) {
+            return nil, ErrOrderNotFound
+        }
+        return nil, err
+    }
+
+    history, err := s.repo.ListStatusHistory(id)
+    if err != nil {
+        return nil, err
+    }
+
+    return &OrderDetail{Order: order, History: history}, nil
+}
+
+// GetUserOrder retrieves one of the user's orders with its items. Orders belonging
+// to other users are reported as not found.
+func (s *DefaultOrderService) GetUserOrder(userID, orderID uint) (*models.Order, error) {
+    order, err := s.repo.FindByIDForUser(orderID, userID)
+    if err != nil {
+        if errors.Is(err, repository.ErrRecordNotFound) {
+            return nil, ErrOrderNotFound
+        }
+        return nil, err
+    }
+    return order, nil
+}
+
+// GetUserOrdersPaginated retrieves a page of the user's orders together with the
+// total number of orders matching the filter
+func (s *DefaultOrderService) GetUserOrdersPaginated(userID uint, filter repository.OrderFilter, page, pageSize int) ([]models.Order, int64, error) {
+    if filter.Status != "" {
+        if _, ok := orderTransitions[filter.Status]; !ok {
+            return nil, 0, fmt.Errorf("%w: %q", ErrInvalidOrderStatus, filter.Status)
+        }
+    }
+
+    orders, err := s.repo.ListByUser(userID, filter, page, pageSize)
+    if err != nil {
+        return nil, 0, err
+    }
+
+    total, err := s.repo.CountByUser(userID, filter)
+    if err != nil {
+        return nil, 0, err
+    }
+
+    return orders, total, nil
+}
+
 // GetAllOrders retrieves all orders
 func (s *DefaultOrderService) GetAllOrders() ([]models.Order, error) {
     return s.repo.List()
@@ -61,19 +150,82 @@ func (s *DefaultOrderService) CreateOrder(order *models.Order) error {
     return s.repo.Create(order)
 }
 
-// UpdateOrder updates an existing order
-func (s *DefaultOrderService) UpdateOrder(order *models.Order) error {
-    return s.repo.Update(order)
+// UpdateOrderStatus moves an order to a new status if the lifecycle allows it,
+// recording who made the change in the order's status history. Paying for an order
+// fails once its stock reservation has expired. Paying or cancelling moves stock, so
+// the order's products are reindexed afterwards.
+func (s *DefaultOrderService) UpdateOrderStatus(id uint, status, changedBy, note string) error {
+    if _, ok := orderTransitions[status]; !ok {
+        return fmt.Errorf("%w: %q", ErrInvalidOrderStatus, status)
+    }
+
+    order, err := s.repo.FindByID(id)
+    if err != nil {
+        if errors.Is(err, repository.ErrRecordNotFound) {
+            return ErrOrderNotFound
+        }
+        return err
+    }
+
+    if !canTransition(order.Status, status) {
+        return fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, order.Status, status)
+    }
+
+    err = s.repo.TransitionStatus(id, order.Status, status, changedBy, note)
+    switch {
+    case errors.Is(err, repository.ErrStatusChanged):
+        return fmt.Errorf("%w: order is no longer %s", ErrInvalidStatusTransition, order.Status)
+    case errors.Is(err, repository.ErrReservationExpired):
+        return fmt.Errorf("%w: order %d can no longer be paid", ErrReservationExpired, id)
+    case errors.Is(err, repository.ErrStockUnavailable):
+        return fmt.Errorf("%w: reserved units of order %d are no longer in stock", ErrInsufficientStock, id)
+    case err != nil:
+        return err
+    }
+
+    if status == models.OrderStatusPaid || status == models.OrderStatusCancelled {
+        s.reindex(id)
+    }
+    return nil
+}
+
+// AllowedTransitions returns the statuses an order in the given status may move to
+func (s *DefaultOrderService) AllowedTransitions(status string) []string {
+    return orderTransitions[status]
 }
 
-// UpdateOrderStatus updates just the status of an order
-func (s *DefaultOrderService) UpdateOrderStatus(id uint, status string) error {
-    return s.repo.UpdateStatus(id, status)
+// canTransition reports whether the lifecycle allows moving from one status to another
+func canTransition(from, to string) bool {
+    for _, next := range orderTransitions[from] {
+        if next == to {
+            return true
+        }
+    }
+    return false
 }
 
-// DeleteOrder deletes an order by its ID
+// DeleteOrder moves an order to the trash, giving its reserved stock back
 func (s *DefaultOrderService) DeleteOrder(id uint) error {
-    return s.repo.Delete(id)
+    if err := s.repo.Delete(id); err != nil {
+        if errors.Is(err, repository.ErrRecordNotFound) {
+            return ErrOrderNotFound
+        }
+        return err
+    }
+    s.reindex(id)
+    return nil
+}
+
+// reindex refreshes the search documents of the products on an order whose stock
+// moved. The change has already been saved, so failures are logged rather than returned.
+func (s *DefaultOrderService) reindex(orderID uint) {
+    productIDs, err := s.repo.ProductIDs(orderID)
+    if err == nil {
+        err = s.indexer.ReindexProducts(productIDs)
+    }
+    if err != nil {
+        s.log.Error("Failed to update search index: " + err.Error())
+    }
 }
 
 // CountOrders returns the total number of orders
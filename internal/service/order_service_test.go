@@ -0,0 +1,164 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// fakeOrderRepo holds a single order and records the status transitions made on it
+type fakeOrderRepo struct {
+	repository.OrderRepository
+	order         *models.Order
+	productIDs    []uint
+	transitionErr error // Returned by TransitionStatus instead of applying it
+	transitions   []string
+}
+
+func (r *fakeOrderRepo) FindByID(id uint) (*models.Order, error) {
+	if r.order == nil || r.order.ID != id {
+		return nil, repository.ErrRecordNotFound
+	}
+	order := *r.order
+	return &order, nil
+}
+
+func (r *fakeOrderRepo) TransitionStatus(id uint, from, to, changedBy, note string) error {
+	if r.transitionErr != nil {
+		return r.transitionErr
+	}
+	if r.order.Status != from {
+		return repository.ErrStatusChanged
+	}
+	r.order.Status = to
+	r.transitions = append(r.transitions, from+" -> "+to)
+	return nil
+}
+
+func (r *fakeOrderRepo) ProductIDs(orderID uint) ([]uint, error) {
+	return r.productIDs, nil
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{models.OrderStatusPending, models.OrderStatusPaid, true},
+		{models.OrderStatusPending, models.OrderStatusCancelled, true},
+		{models.OrderStatusPending, models.OrderStatusShipped, false},
+		{models.OrderStatusPending, models.OrderStatusRefunded, false},
+		{models.OrderStatusPaid, models.OrderStatusFulfilled, true},
+		{models.OrderStatusPaid, models.OrderStatusRefunded, true},
+		{models.OrderStatusPaid, models.OrderStatusCancelled, false},
+		{models.OrderStatusPaid, models.OrderStatusPending, false},
+		{models.OrderStatusFulfilled, models.OrderStatusShipped, true},
+		{models.OrderStatusShipped, models.OrderStatusDelivered, true},
+		{models.OrderStatusShipped, models.OrderStatusFulfilled, false},
+		{models.OrderStatusDelivered, models.OrderStatusRefunded, true},
+		// Cancelled and refunded orders are final
+		{models.OrderStatusCancelled, models.OrderStatusPending, false},
+		{models.OrderStatusCancelled, models.OrderStatusPaid, false},
+		{models.OrderStatusRefunded, models.OrderStatusPaid, false},
+		// A status never moves to itself
+		{models.OrderStatusPaid, models.OrderStatusPaid, false},
+		{"unknown", models.OrderStatusPaid, false},
+	}
+	for _, tt := range tests {
+		if got := canTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("canTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		from          string
+		to            string
+		transitionErr error
+		err           error
+		reindexed     bool
+	}{
+		{name: "pay", from: models.OrderStatusPending, to: models.OrderStatusPaid, reindexed: true},
+		{name: "cancel", from: models.OrderStatusPending, to: models.OrderStatusCancelled, reindexed: true},
+		{name: "ship", from: models.OrderStatusFulfilled, to: models.OrderStatusShipped},
+		{name: "unknown status", from: models.OrderStatusPending, to: "lost", err: ErrInvalidOrderStatus},
+		{name: "skip a step", from: models.OrderStatusPending, to: models.OrderStatusShipped, err: ErrInvalidStatusTransition},
+		{name: "reopen", from: models.OrderStatusCancelled, to: models.OrderStatusPending, err: ErrInvalidStatusTransition},
+		{
+			name:          "changed concurrently",
+			from:          models.OrderStatusPending,
+			to:            models.OrderStatusPaid,
+			transitionErr: repository.ErrStatusChanged,
+			err:           ErrInvalidStatusTransition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeOrderRepo{
+				order:         &models.Order{ID: 7, Status: tt.from},
+				productIDs:    []uint{3, 4},
+				transitionErr: tt.transitionErr,
+			}
+			indexer := &fakeIndexer{}
+			err := NewOrderService(repo, indexer).UpdateOrderStatus(7, tt.to, "admin", "")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("UpdateOrderStatus() error = %v, want %v", err, tt.err)
+			}
+
+			var transitions []string
+			if tt.err == nil {
+				transitions = []string{tt.from + " -> " + tt.to}
+			}
+			if !slices.Equal(repo.transitions, transitions) {
+				t.Errorf("transitions = %v, want %v", repo.transitions, transitions)
+			}
+
+			var reindexed []uint
+			if tt.reindexed {
+				reindexed = repo.productIDs
+			}
+			if !slices.Equal(indexer.reindexed, reindexed) {
+				t.Errorf("reindexed %v, want %v", indexer.reindexed, reindexed)
+			}
+		})
+	}
+}
+
+func TestUpdateOrderStatusMissingOrder(t *testing.T) {
+	err := NewOrderService(&fakeOrderRepo{}, &fakeIndexer{}).UpdateOrderStatus(1, models.OrderStatusPaid, "admin", "")
+	if !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("UpdateOrderStatus() error = %v, want %v", err, ErrOrderNotFound)
+	}
+}
+
+func TestPayingAfterReservationLapsed(t *testing.T) {
+	tests := []struct {
+		name          string
+		transitionErr error
+		err           error
+	}{
+		{"reservation expired", repository.ErrReservationExpired, ErrReservationExpired},
+		{"reserved stock gone", repository.ErrStockUnavailable, ErrInsufficientStock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeOrderRepo{
+				order:         &models.Order{ID: 7, Status: models.OrderStatusPending},
+				transitionErr: tt.transitionErr,
+			}
+			indexer := &fakeIndexer{}
+			err := NewOrderService(repo, indexer).UpdateOrderStatus(7, models.OrderStatusPaid, "admin", "")
+			if !errors.Is(err, tt.err) {
+				t.Errorf("UpdateOrderStatus() error = %v, want %v", err, tt.err)
+			}
+			if len(indexer.reindexed) > 0 {
+				t.Errorf("failed payment reindexed products %v", indexer.reindexed)
+			}
+		})
+	}
+}
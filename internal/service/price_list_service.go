@@ -0,0 +1,424 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrCustomerGroupNotFound is returned when a referenced customer group does not exist
+	ErrCustomerGroupNotFound = errors.New("customer group not found")
+	// ErrInvalidCustomerGroup is returned when customer group data fails validation
+	ErrInvalidCustomerGroup = errors.New("invalid customer group")
+	// ErrCustomerGroupNameTaken is returned when another customer group already uses the name
+	ErrCustomerGroupNameTaken = errors.New("customer group name already in use")
+	// ErrPriceListNotFound is returned when a referenced price list does not exist
+	ErrPriceListNotFound = errors.New("price list not found")
+	// ErrInvalidPriceList is returned when price list data fails validation
+	ErrInvalidPriceList = errors.New("invalid price list")
+)
+
+// CustomerGroupInput is the data of a customer group to create or update
+type CustomerGroupInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PriceListInput is the data of a price list to create or update. Its entries
+// replace the ones the list had.
+type PriceListInput struct {
+	CustomerGroupID uint                  `json:"customer_group_id"`
+	Name            string                `json:"name"`
+	Priority        int                   `json:"priority"`
+	Active          *bool                 `json:"active"` // Defaults to true
+	Entries         []PriceListEntryInput `json:"entries"`
+}
+
+// PriceListEntryInput is the price of a product or variant from a minimum quantity on
+type PriceListEntryInput struct {
+	ProductID   uint        `json:"product_id"`
+	VariantID   uint        `json:"variant_id"`   // Zero for the product and its variants without a price override
+	MinQuantity int         `json:"min_quantity"` // Defaults to 1
+	Price       money.Money `json:"price"`
+}
+
+// groupPriceKey identifies the product, or variant of it, a price list entry prices
+type groupPriceKey struct {
+	productID uint
+	variantID uint
+}
+
+// GroupPrices resolves the prices a customer pays from the price lists of their
+// customer group. The zero value, used for guests and customers without a group,
+// leaves every price at the list price.
+type GroupPrices struct {
+	entries map[groupPriceKey][]models.PriceListEntry // Entries of the winning list, lowest quantity first
+}
+
+// NewGroupPrices builds the prices of a customer from their price list entries, in
+// the order PriceListRepository.UserEntries returns them. For every product and
+// variant only the entries of the first list that prices it are kept.
+func NewGroupPrices(entries []models.PriceListEntry) GroupPrices {
+	prices := GroupPrices{entries: map[groupPriceKey][]models.PriceListEntry{}}
+	winners := map[groupPriceKey]uint{}
+	for _, entry := range entries {
+		key := groupPriceKey{productID: entry.ProductID, variantID: entry.VariantID}
+		if winner, ok := winners[key]; ok && winner != entry.PriceListID {
+			continue
+		}
+		winners[key] = entry.PriceListID
+		prices.entries[key] = append(prices.entries[key], entry)
+	}
+	return prices
+}
+
+// Tiers returns the quantity tiers of a product, or of a variant of it when variant
+// is not nil, lowest quantity first. A variant with its own price only takes the
+// tiers set for it; other variants fall back to those of the product. No tier
+// charges more than the current price, so a running sale still applies.
+func (p GroupPrices) Tiers(product models.Product, variant *models.ProductVariant) []models.TierPrice {
+	current := product.CurrentPrice()
+	key := groupPriceKey{productID: product.ID}
+	if variant != nil {
+		current = variant.UnitPrice(product)
+		key.variantID = variant.ID
+		if _, ok := p.entries[key]; !ok && variant.Price == nil {
+			key.variantID = 0
+		}
+	}
+
+	entries := p.entries[key]
+	if len(entries) == 0 {
+		return nil
+	}
+	tiers := make([]models.TierPrice, 0, len(entries))
+	for _, entry := range entries {
+		tiers = append(tiers, models.TierPrice{MinQuantity: entry.MinQuantity, Price: money.Min(entry.Price, current)})
+	}
+	return tiers
+}
+
+// UnitPrice returns the price of one unit of a product, or of a variant of it when
+// variant is not nil, when buying quantity units: that of the highest tier the
+// quantity reaches, or the current price below the first tier
+func (p GroupPrices) UnitPrice(product models.Product, variant *models.ProductVariant, quantity int) money.Money {
+	price := product.CurrentPrice()
+	if variant != nil {
+		price = variant.UnitPrice(product)
+	}
+	for _, tier := range p.Tiers(product, variant) {
+		if quantity >= tier.MinQuantity {
+			price = tier.Price
+		}
+	}
+	return price
+}
+
+// loadGroupPrices loads the prices a user pays for the given products. Guests pay
+// list prices.
+func loadGroupPrices(repo repository.PriceListRepository, userID uint, productIDs []uint) (GroupPrices, error) {
+	if userID == 0 {
+		return GroupPrices{}, nil
+	}
+	entries, err := repo.UserEntries(userID, productIDs)
+	if err != nil {
+		return GroupPrices{}, err
+	}
+	return NewGroupPrices(entries), nil
+}
+
+// PriceListService defines the interface for customer groups, their price lists and
+// the prices they resolve to
+type PriceListService interface {
+	ListGroups() ([]models.CustomerGroup, error)
+	CreateGroup(input CustomerGroupInput) (*models.CustomerGroup, error)
+	UpdateGroup(id uint, input CustomerGroupInput) (*models.CustomerGroup, error)
+	DeleteGroup(id uint) error
+	AssignUser(userID uint, groupID *uint) error
+	ListPriceLists(groupID uint, page, pageSize int) ([]models.PriceList, int64, error)
+	GetPriceList(id uint) (*models.PriceList, error)
+	CreatePriceList(input PriceListInput) (*models.PriceList, error)
+	UpdatePriceList(id uint, input PriceListInput) (*models.PriceList, error)
+	DeletePriceList(id uint) error
+	ApplyGroupPrices(userID uint, products []models.Product) error
+}
+
+// DefaultPriceListService implements PriceListService
+type DefaultPriceListService struct {
+	repo        repository.PriceListRepository
+	productRepo repository.ProductRepository
+	variantRepo repository.VariantRepository
+}
+
+// NewPriceListService creates a new instance of DefaultPriceListService
+func NewPriceListService(repo repository.PriceListRepository, productRepo repository.ProductRepository,
+	variantRepo repository.VariantRepository) PriceListService {
+	return &DefaultPriceListService{
+		repo:        repo,
+		productRepo: productRepo,
+		variantRepo: variantRepo,
+	}
+}
+
+// ListGroups retrieves every customer group by name
+func (s *DefaultPriceListService) ListGroups() ([]models.CustomerGroup, error) {
+	return s.repo.ListGroups()
+}
+
+// CreateGroup validates and stores a new customer group
+func (s *DefaultPriceListService) CreateGroup(input CustomerGroupInput) (*models.CustomerGroup, error) {
+	group := &models.CustomerGroup{}
+	if err := s.applyGroup(group, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateGroup(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// UpdateGroup validates and replaces the data of an existing customer group
+func (s *DefaultPriceListService) UpdateGroup(id uint, input CustomerGroupInput) (*models.CustomerGroup, error) {
+	group, err := s.group(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyGroup(group, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateGroup(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// DeleteGroup removes a customer group with its price lists. Its customers go back
+// to list prices.
+func (s *DefaultPriceListService) DeleteGroup(id uint) error {
+	err := s.repo.DeleteGroup(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrCustomerGroupNotFound
+	}
+	return err
+}
+
+// AssignUser puts a user in a customer group, or back on list prices when groupID is nil
+func (s *DefaultPriceListService) AssignUser(userID uint, groupID *uint) error {
+	if groupID != nil {
+		if _, err := s.group(*groupID); err != nil {
+			return err
+		}
+	}
+	err := s.repo.AssignUser(userID, groupID)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// ListPriceLists retrieves one page of the price lists of a customer group, or of
+// every group when groupID is zero
+func (s *DefaultPriceListService) ListPriceLists(groupID uint, page, pageSize int) ([]models.PriceList, int64, error) {
+	if groupID != 0 {
+		if _, err := s.group(groupID); err != nil {
+			return nil, 0, err
+		}
+	}
+	return s.repo.List(groupID, page, pageSize)
+}
+
+// GetPriceList retrieves a price list with its entries
+func (s *DefaultPriceListService) GetPriceList(id uint) (*models.PriceList, error) {
+	list, err := s.repo.FindByID(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrPriceListNotFound
+	}
+	return list, err
+}
+
+// CreatePriceList validates and stores a new price list
+func (s *DefaultPriceListService) CreatePriceList(input PriceListInput) (*models.PriceList, error) {
+	list := &models.PriceList{}
+	if err := s.applyPriceList(list, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(list); err != nil {
+		return nil, err
+	}
+	return s.GetPriceList(list.ID)
+}
+
+// UpdatePriceList validates and replaces the data and entries of an existing price list
+func (s *DefaultPriceListService) UpdatePriceList(id uint, input PriceListInput) (*models.PriceList, error) {
+	list, err := s.GetPriceList(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyPriceList(list, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(list); err != nil {
+		return nil, err
+	}
+	return s.GetPriceList(id)
+}
+
+// DeletePriceList removes a price list with its entries
+func (s *DefaultPriceListService) DeletePriceList(id uint) error {
+	err := s.repo.Delete(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrPriceListNotFound
+	}
+	return err
+}
+
+// ApplyGroupPrices sets the quantity tiers of the user's customer group on products
+// loaded for display. Guests and customers without a group get none.
+func (s *DefaultPriceListService) ApplyGroupPrices(userID uint, products []models.Product) error {
+	if userID == 0 || len(products) == 0 {
+		return nil
+	}
+	productIDs := make([]uint, len(products))
+	for i, product := range products {
+		productIDs[i] = product.ID
+	}
+	prices, err := loadGroupPrices(s.repo, userID, productIDs)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		products[i].GroupPrices = prices.Tiers(products[i], nil)
+	}
+	return nil
+}
+
+// group retrieves a customer group by its ID
+func (s *DefaultPriceListService) group(id uint) (*models.CustomerGroup, error) {
+	group, err := s.repo.FindGroup(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrCustomerGroupNotFound
+	}
+	return group, err
+}
+
+// applyGroup validates the input and copies it onto the customer group
+func (s *DefaultPriceListService) applyGroup(group *models.CustomerGroup, input CustomerGroupInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return fmt.Errorf("%w: name must be 1 to 100 characters", ErrInvalidCustomerGroup)
+	}
+	existing, err := s.repo.FindGroupByName(name)
+	if err == nil && existing.ID != group.ID {
+		return ErrCustomerGroupNameTaken
+	}
+	if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		return err
+	}
+	description := strings.TrimSpace(input.Description)
+	if utf8.RuneCountInString(description) > 255 {
+		return fmt.Errorf("%w: description must be at most 255 characters", ErrInvalidCustomerGroup)
+	}
+
+	group.Name = name
+	group.Description = description
+	return nil
+}
+
+// applyPriceList validates the input and copies it onto the price list
+func (s *DefaultPriceListService) applyPriceList(list *models.PriceList, input PriceListInput) error {
+	if _, err := s.group(input.CustomerGroupID); err != nil {
+		if errors.Is(err, ErrCustomerGroupNotFound) {
+			return fmt.Errorf("%w: customer group %d does not exist", ErrInvalidPriceList, input.CustomerGroupID)
+		}
+		return err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return fmt.Errorf("%w: name must be 1 to 100 characters", ErrInvalidPriceList)
+	}
+
+	entries, err := s.checkEntries(input.Entries)
+	if err != nil {
+		return err
+	}
+
+	list.CustomerGroupID = input.CustomerGroupID
+	list.Name = name
+	list.Priority = input.Priority
+	list.Active = input.Active == nil || *input.Active
+	list.Entries = entries
+	return nil
+}
+
+// checkEntries validates price list entries and turns them into models. Products
+// must exist, variants must belong to their product and a product or variant may
+// have only one price per minimum quantity.
+func (s *DefaultPriceListService) checkEntries(inputs []PriceListEntryInput) ([]models.PriceListEntry, error) {
+	productIDs := make([]uint, 0, len(inputs))
+	for _, input := range inputs {
+		productIDs = append(productIDs, input.ProductID)
+	}
+	products, err := s.productRepo.FindByIDs(productIDs)
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[uint]bool, len(products))
+	for _, product := range products {
+		exists[product.ID] = true
+	}
+
+	type tierKey struct {
+		productID, variantID uint
+		minQuantity          int
+	}
+	seen := make(map[tierKey]bool, len(inputs))
+	entries := make([]models.PriceListEntry, 0, len(inputs))
+	for _, input := range inputs {
+		if !exists[input.ProductID] {
+			return nil, fmt.Errorf("%w: product %d does not exist", ErrInvalidPriceList, input.ProductID)
+		}
+		if input.VariantID != 0 {
+			variant, err := s.variantRepo.FindByID(input.VariantID)
+			if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+				return nil, err
+			}
+			if err != nil || variant.ProductID != input.ProductID {
+				return nil, fmt.Errorf("%w: variant %d does not belong to product %d", ErrInvalidPriceList, input.VariantID, input.ProductID)
+			}
+		}
+
+		minQuantity := input.MinQuantity
+		if minQuantity == 0 {
+			minQuantity = 1
+		}
+		if minQuantity < 1 {
+			return nil, fmt.Errorf("%w: min_quantity must be at least 1", ErrInvalidPriceList)
+		}
+		key := tierKey{productID: input.ProductID, variantID: input.VariantID, minQuantity: minQuantity}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: product %d has more than one price from %d units", ErrInvalidPriceList, input.ProductID, minQuantity)
+		}
+		seen[key] = true
+
+		price, err := storeAmount(input.Price, "price", ErrInvalidPriceList)
+		if err != nil {
+			return nil, err
+		}
+		if !price.IsPositive() {
+			return nil, fmt.Errorf("%w: price must be positive", ErrInvalidPriceList)
+		}
+
+		entries = append(entries, models.PriceListEntry{
+			ProductID:   input.ProductID,
+			VariantID:   input.VariantID,
+			MinQuantity: minQuantity,
+			Price:       price,
+		})
+	}
+	return entries, nil
+}
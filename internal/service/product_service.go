@@ -3,62 +3,697 @@ package service
 import (
     "ecommerce-app/internal/models"
     "ecommerce-app/internal/repository"
+    "ecommerce-app/internal/search"
+    "ecommerce-app/pkg/logger"
+    "ecommerce-app/pkg/money"
+    "errors"
+    "fmt"
+    "math"
+    "strings"
+    "time"
+    "unicode/utf8"
 )
 
+var (
+    // ErrInvalidProductFilter is returned when a catalog query has out-of-range parameters
+    ErrInvalidProductFilter = errors.New("invalid product filter")
+    // ErrInvalidProduct is returned when product data fails validation
+    ErrInvalidProduct = errors.New("invalid product")
+    // ErrInvalidBulkRequest is returned when a bulk operation is malformed
+    ErrInvalidBulkRequest = errors.New("invalid bulk request")
+)
+
+const (
+    // maxProductPageSize caps the page size of catalog listings
+    maxProductPageSize = 100
+    // suggestionLimit is the number of autocomplete suggestions returned
+    suggestionLimit = 10
+    // maxBulkProducts caps the number of products a bulk operation may touch
+    maxBulkProducts = 1000
+)
+
+// Statuses of the products in a bulk result
+const (
+    BulkStatusUpdated  = "updated"
+    BulkStatusDeleted  = "deleted"
+    BulkStatusFailed   = "failed"
+    BulkStatusNotFound = "not_found"
+)
+
+// ProductPatch holds the product fields to change. Nil fields are left as they are.
+type ProductPatch struct {
+    Name        *string      `json:"name"`
+    Description *string      `json:"description"`
+    Price       *money.Money `json:"price"`
+    Stock       *int         `json:"stock"`
+}
+
+// BulkFilter selects products for a bulk operation by catalog filters
+type BulkFilter struct {
+    Search   string       `json:"search"`
+    Category string       `json:"category"` // Category slug; products of its descendants are included
+    MinPrice *money.Money `json:"min_price"`
+    MaxPrice *money.Money `json:"max_price"`
+    InStock  bool         `json:"in_stock"`
+}
+
+// BulkSelection selects the products of a bulk operation, either by ID or by filter.
+// Unless AllowPartial is set, a failure on any product rolls the whole operation back.
+type BulkSelection struct {
+    IDs          []uint      `json:"ids"`
+    Filter       *BulkFilter `json:"filter"`
+    AllowPartial bool        `json:"allow_partial"`
+}
+
+// BulkPriceChange changes the price of the selected products, and of their variant
+// price overrides, by a percentage; -10 is a 10% discount
+type BulkPriceChange struct {
+    BulkSelection
+    Percent float64 `json:"percent"`
+}
+
+// BulkStockChange adds Delta to the stock of the selected products
+type BulkStockChange struct {
+    BulkSelection
+    Delta int `json:"delta"`
+}
+
+// BulkItemResult reports the outcome of a bulk operation for one product
+type BulkItemResult struct {
+    ID     uint   `json:"id"`
+    Status string `json:"status"`
+    Error  string `json:"error,omitempty"`
+}
+
+// BulkResult reports the outcome of a bulk operation. When Committed is false no
+// product was changed, whatever the status of the individual items.
+type BulkResult struct {
+    Committed bool             `json:"committed"`
+    Succeeded int              `json:"succeeded"`
+    Failed    int              `json:"failed"`
+    Results   []BulkItemResult `json:"results"`
+}
+
+// ProductFilter describes a catalog listing requested by a shopper
+type ProductFilter struct {
+    Search   string
+    Category string // Category slug; products of its descendants are included
+    MinPrice *money.Money
+    MaxPrice *money.Money
+    InStock  bool
+    Sort     string // One of "price", "-price", "newest" or "name"
+    Page     int
+    PageSize int
+}
+
+// ProductSearchHit is a search match with its product loaded
+type ProductSearchHit struct {
+    Product       models.Product `json:"product"`
+    Score         float64        `json:"score"`
+    NameHighlight string         `json:"name_highlight"`
+    Snippet       string         `json:"snippet"`
+}
+
+// ProductSearchResult is one page of search hits with the facets of all matches
+type ProductSearchResult struct {
+    Hits   []ProductSearchHit `json:"hits"`
+    Total  int64              `json:"total"`
+    Facets search.Facets      `json:"facets"`
+}
+
+// ProductIndexer keeps the search index in line with product data changed elsewhere
+type ProductIndexer interface {
+    ReindexProduct(id uint) error
+    ReindexProducts(ids []uint) error
+    ReindexAll() error
+}
+
 // ProductService defines the interface for product-related business logic
 type ProductService interface {
     GetProductByID(id uint) (*models.Product, error)
     GetAllProducts() ([]models.Product, error)
     GetProductsPaginated(page, pageSize int) ([]models.Product, error)
+    SearchProducts(filter ProductFilter) ([]models.Product, int64, error)
+    Search(query search.Query) (*ProductSearchResult, error)
+    Suggest(prefix string) ([]string, error)
     CreateProduct(product *models.Product) error
     UpdateProduct(product *models.Product) error
+    PatchProduct(id uint, patch ProductPatch) (*models.Product, error)
     DeleteProduct(id uint) error
+    BulkUpdatePrices(change BulkPriceChange) (*BulkResult, error)
+    BulkAdjustStock(change BulkStockChange) (*BulkResult, error)
+    BulkDelete(selection BulkSelection) (*BulkResult, error)
     CountProducts() (int64, error)
+    ReindexProduct(id uint) error
+    ReindexProducts(ids []uint) error
+    ReindexAll() error
 }
 
 // DefaultProductService implements ProductService
 type DefaultProductService struct {
-    repo repository.ProductRepository
+    repo         repository.ProductRepository
+    categoryRepo repository.CategoryRepository
+    index        search.SearchIndex
+    log          *logger.Logger
 }
 
 // NewProductService creates a new instance of DefaultProductService
-func NewProductService(repo repository.ProductRepository) ProductService {
+func NewProductService(repo repository.ProductRepository, categoryRepo repository.CategoryRepository,
+    index search.SearchIndex) ProductService {
     return &DefaultProductService{
-        repo: repo,
+        repo:         repo,
+        categoryRepo: categoryRepo,
+        index:        index,
+        log:          logger.New(),
     }
 }
 
 // GetProductByID retrieves a product by its ID
 func (s *DefaultProductService) GetProductByID(id uint) (*models.Product, error) {
-    return s.repo.FindByID(id)
+    product, err := s.repo.FindByID(id)
+    if errors.Is(err, repository.ErrRecordNotFound) {
+        return nil, ErrProductNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    resolveSale(product, time.Now())
+    return product, nil
 }
 
 // GetAllProducts retrieves all products
 func (s *DefaultProductService) GetAllProducts() ([]models.Product, error) {
-    return s.repo.List()
+    return resolveSales(s.repo.List())
 }
 
 // GetProductsPaginated retrieves products with pagination
 func (s *DefaultProductService) GetProductsPaginated(page, pageSize int) ([]models.Product, error) {
-    return s.repo.ListPaginated(page, pageSize)
+    return resolveSales(s.repo.ListPaginated(page, pageSize))
+}
+
+// SearchProducts retrieves one page of the catalog matching the filter, along with
+// the total number of matches
+func (s *DefaultProductService) SearchProducts(filter ProductFilter) ([]models.Product, int64, error) {
+    query := repository.ProductQuery{
+        Search:   strings.TrimSpace(filter.Search),
+        MinPrice: filter.MinPrice,
+        MaxPrice: filter.MaxPrice,
+        InStock:  filter.InStock,
+        Sort:     repository.ProductSort(filter.Sort),
+        Page:     filter.Page,
+        PageSize: filter.PageSize,
+    }
+
+    switch query.Sort {
+    case repository.ProductSortDefault, repository.ProductSortPriceAsc, repository.ProductSortPriceDesc,
+        repository.ProductSortNewest, repository.ProductSortName:
+    default:
+        return nil, 0, fmt.Errorf("%w: unknown sort %q", ErrInvalidProductFilter, filter.Sort)
+    }
+
+    if query.MinPrice != nil && query.MaxPrice != nil && query.MinPrice.Cmp(*query.MaxPrice) > 0 {
+        return nil, 0, fmt.Errorf("%w: min price is above max price", ErrInvalidProductFilter)
+    }
+
+    if query.Page < 1 {
+        query.Page = 1
+    }
+    if query.PageSize < 1 || query.PageSize > maxProductPageSize {
+        return nil, 0, fmt.Errorf("%w: page size must be between 1 and %d", ErrInvalidProductFilter, maxProductPageSize)
+    }
+
+    var err error
+    if query.CategoryIDs, err = s.categoryIDs(filter.Category); err != nil {
+        return nil, 0, err
+    }
+
+    products, total, err := s.repo.Query(query)
+    if err != nil {
+        return nil, 0, err
+    }
+    products, _ = resolveSales(products, nil)
+    return products, total, nil
+}
+
+// Search queries the search index and loads the products of the returned page.
+// Empty text matches the whole catalog so the storefront can browse by facets.
+func (s *DefaultProductService) Search(query search.Query) (*ProductSearchResult, error) {
+    if query.PageSize < 1 || query.PageSize > maxProductPageSize {
+        return nil, fmt.Errorf("%w: page size must be between 1 and %d", ErrInvalidProductFilter, maxProductPageSize)
+    }
+    if query.Page < 1 {
+        query.Page = 1
+    }
+    if query.MinPrice != nil && query.MaxPrice != nil && query.MinPrice.Cmp(*query.MaxPrice) > 0 {
+        return nil, fmt.Errorf("%w: min price is above max price", ErrInvalidProductFilter)
+    }
+
+    result, err := s.index.Search(query)
+    if err != nil {
+        return nil, err
+    }
+
+    ids := make([]uint, len(result.Hits))
+    for i, hit := range result.Hits {
+        ids[i] = hit.ID
+    }
+    products, err := s.repo.FindByIDs(ids)
+    if err != nil {
+        return nil, err
+    }
+    now := time.Now()
+    byID := make(map[uint]models.Product, len(products))
+    for _, product := range products {
+        resolveSale(&product, now)
+        byID[product.ID] = product
+    }
+
+    hits := make([]ProductSearchHit, 0, len(result.Hits))
+    for _, hit := range result.Hits {
+        product, ok := byID[hit.ID]
+        if !ok {
+            continue // Deleted since it was indexed
+        }
+        hits = append(hits, ProductSearchHit{
+            Product:       product,
+            Score:         hit.Score,
+            NameHighlight: hit.NameHighlight,
+            Snippet:       hit.Snippet,
+        })
+    }
+
+    return &ProductSearchResult{
+        Hits:   hits,
+        Total:  result.Total,
+        Facets: result.Facets,
+    }, nil
+}
+
+// Suggest returns product names completing a partially typed search
+func (s *DefaultProductService) Suggest(prefix string) ([]string, error) {
+    return s.repo.Suggest(prefix, suggestionLimit)
 }
 
 // CreateProduct creates a new product
 func (s *DefaultProductService) CreateProduct(product *models.Product) error {
-    return s.repo.Create(product)
+    if err := validateProduct(product); err != nil {
+        return err
+    }
+    if err := s.repo.Create(product); err != nil {
+        return err
+    }
+    s.reindex(product.ID)
+    return nil
 }
 
 // UpdateProduct updates an existing product
 func (s *DefaultProductService) UpdateProduct(product *models.Product) error {
-    return s.repo.Update(product)
+    if err := validateProduct(product); err != nil {
+        return err
+    }
+    if err := s.repo.Update(product); err != nil {
+        return err
+    }
+    s.reindex(product.ID)
+    return nil
 }
 
-// DeleteProduct deletes a product by its ID
+// PatchProduct changes the given fields of a product. The stock of a product with
+// variants is the sum of the variant stock and can only be changed per variant.
+func (s *DefaultProductService) PatchProduct(id uint, patch ProductPatch) (*models.Product, error) {
+    product, err := s.GetProductByID(id)
+    if err != nil {
+        return nil, err
+    }
+
+    if patch.Name != nil {
+        product.Name = *patch.Name
+    }
+    if patch.Description != nil {
+        product.Description = *patch.Description
+    }
+    if patch.Price != nil {
+        product.Price = *patch.Price
+    }
+    if patch.Stock != nil && *patch.Stock != product.Stock {
+        hasVariants, err := s.repo.HasVariants(id)
+        if err != nil {
+            return nil, err
+        }
+        if hasVariants {
+            return nil, fmt.Errorf("%w: stock is managed per variant for this product", ErrInvalidProduct)
+        }
+        product.Stock = *patch.Stock
+    }
+
+    if err := s.UpdateProduct(product); err != nil {
+        return nil, err
+    }
+    return product, nil
+}
+
+// DeleteProduct moves a product to the trash and takes it out of the catalog.
+// Its variants and images are kept until the product is purged.
 func (s *DefaultProductService) DeleteProduct(id uint) error {
-    return s.repo.Delete(id)
+    if err := s.repo.Delete(id); err != nil {
+        if errors.Is(err, repository.ErrRecordNotFound) {
+            return ErrProductNotFound
+        }
+        return err
+    }
+    if err := s.index.Remove(id); err != nil {
+        s.log.Error("Failed to remove product from search index: " + err.Error())
+    }
+    return nil
+}
+
+// BulkUpdatePrices changes the prices of the selected products by a percentage
+func (s *DefaultProductService) BulkUpdatePrices(change BulkPriceChange) (*BulkResult, error) {
+    if math.IsNaN(change.Percent) || math.IsInf(change.Percent, 0) || change.Percent <= -100 || change.Percent > 1000 {
+        return nil, fmt.Errorf("%w: percent must be above -100 and at most 1000", ErrInvalidBulkRequest)
+    }
+    if change.Percent == 0 {
+        return nil, fmt.Errorf("%w: percent must not be zero", ErrInvalidBulkRequest)
+    }
+    rate := 1 + change.Percent/100
+
+    return s.bulkUpdate(change.BulkSelection, func(product *models.Product) error {
+        product.Price = product.Price.MulRate(rate)
+        if !product.Price.IsPositive() {
+            return fmt.Errorf("%w: price would drop to %s", ErrInvalidProduct, product.Price)
+        }
+        for i := range product.Variants {
+            if product.Variants[i].Price != nil {
+                price := product.Variants[i].Price.MulRate(rate)
+                if !price.IsPositive() {
+                    return fmt.Errorf("%w: price of variant %s would drop to %s", ErrInvalidProduct, product.Variants[i].SKU, price)
+                }
+                product.Variants[i].Price = &price
+            }
+        }
+        return nil
+    })
+}
+
+// BulkAdjustStock adds to the stock of the selected products. Products with variants
+// and products whose stock would go negative fail.
+func (s *DefaultProductService) BulkAdjustStock(change BulkStockChange) (*BulkResult, error) {
+    if change.Delta == 0 {
+        return nil, fmt.Errorf("%w: delta must not be zero", ErrInvalidBulkRequest)
+    }
+
+    return s.bulkUpdate(change.BulkSelection, func(product *models.Product) error {
+        if len(product.Variants) > 0 {
+            return fmt.Errorf("%w: stock is managed per variant for this product", ErrInvalidProduct)
+        }
+        if product.Stock+change.Delta < 0 {
+            return fmt.Errorf("%w: stock would drop to %d", ErrInvalidProduct, product.Stock+change.Delta)
+        }
+        product.Stock += change.Delta
+        return nil
+    })
+}
+
+// BulkDelete moves the selected products to the trash
+func (s *DefaultProductService) BulkDelete(selection BulkSelection) (*BulkResult, error) {
+    ids, err := s.selectProducts(selection)
+    if err != nil {
+        return nil, err
+    }
+
+    outcomes, committed, err := s.repo.BulkDelete(ids, !selection.AllowPartial)
+    if err != nil {
+        return nil, err
+    }
+
+    result := s.bulkResult(outcomes, committed, BulkStatusDeleted)
+    if committed {
+        for _, outcome := range outcomes {
+            if outcome.Err != nil {
+                continue
+            }
+            if err := s.index.Remove(outcome.ID); err != nil {
+                s.log.Error("Failed to remove product from search index: " + err.Error())
+            }
+        }
+    }
+    return result, nil
+}
+
+// bulkUpdate applies change to the selected products and refreshes the search
+// documents of the changed ones
+func (s *DefaultProductService) bulkUpdate(selection BulkSelection, change repository.ProductChangeFunc) (*BulkResult, error) {
+    ids, err := s.selectProducts(selection)
+    if err != nil {
+        return nil, err
+    }
+
+    outcomes, committed, err := s.repo.BulkUpdate(ids, change, !selection.AllowPartial)
+    if err != nil {
+        return nil, err
+    }
+
+    if committed {
+        for _, outcome := range outcomes {
+            if outcome.Err == nil {
+                s.reindex(outcome.ID)
+            }
+        }
+    }
+    return s.bulkResult(outcomes, committed, BulkStatusUpdated), nil
+}
+
+// selectProducts resolves the product IDs of a bulk selection
+func (s *DefaultProductService) selectProducts(selection BulkSelection) ([]uint, error) {
+    if (len(selection.IDs) == 0) == (selection.Filter == nil) {
+        return nil, fmt.Errorf("%w: give either ids or a filter", ErrInvalidBulkRequest)
+    }
+
+    if len(selection.IDs) > 0 {
+        if len(selection.IDs) > maxBulkProducts {
+            return nil, fmt.Errorf("%w: at most %d products per operation", ErrInvalidBulkRequest, maxBulkProducts)
+        }
+        seen := make(map[uint]bool, len(selection.IDs))
+        ids := make([]uint, 0, len(selection.IDs))
+        for _, id := range selection.IDs {
+            if !seen[id] {
+                seen[id] = true
+                ids = append(ids, id)
+            }
+        }
+        return ids, nil
+    }
+
+    filter := selection.Filter
+    if filter.MinPrice != nil && filter.MaxPrice != nil && filter.MinPrice.Cmp(*filter.MaxPrice) > 0 {
+        return nil, fmt.Errorf("%w: min price is above max price", ErrInvalidBulkRequest)
+    }
+    query := repository.ProductQuery{
+        Search:   strings.TrimSpace(filter.Search),
+        MinPrice: filter.MinPrice,
+        MaxPrice: filter.MaxPrice,
+        InStock:  filter.InStock,
+    }
+    var err error
+    if query.CategoryIDs, err = s.categoryIDs(filter.Category); err != nil {
+        return nil, err
+    }
+
+    // Fetch one more than allowed to tell a full selection from an oversized one
+    ids, err := s.repo.MatchingIDs(query, maxBulkProducts+1)
+    if err != nil {
+        return nil, err
+    }
+    if len(ids) > maxBulkProducts {
+        return nil, fmt.Errorf("%w: filter matches more than %d products", ErrInvalidBulkRequest, maxBulkProducts)
+    }
+    return ids, nil
+}
+
+// categoryIDs returns the IDs of the category with the given slug and of its
+// descendants, or nil when no slug is given
+func (s *DefaultProductService) categoryIDs(slug string) ([]uint, error) {
+    if slug == "" {
+        return nil, nil
+    }
+    category, err := s.categoryRepo.FindBySlug(slug)
+    if err != nil {
+        if errors.Is(err, repository.ErrRecordNotFound) {
+            return nil, ErrCategoryNotFound
+        }
+        return nil, err
+    }
+    return s.categoryRepo.DescendantIDs(category.ID)
+}
+
+// bulkResult turns repository outcomes into a bulk result, using status for the
+// products that succeeded
+func (s *DefaultProductService) bulkResult(outcomes []repository.BulkOutcome, committed bool, status string) *BulkResult {
+    result := &BulkResult{
+        Committed: committed,
+        Results:   make([]BulkItemResult, 0, len(outcomes)),
+    }
+    for _, outcome := range outcomes {
+        item := BulkItemResult{ID: outcome.ID, Status: status}
+        switch {
+        case errors.Is(outcome.Err, repository.ErrRecordNotFound):
+            item.Status = BulkStatusNotFound
+            item.Error = ErrProductNotFound.Error()
+        case errors.Is(outcome.Err, ErrInvalidProduct):
+            item.Status = BulkStatusFailed
+            item.Error = outcome.Err.Error()
+        case outcome.Err != nil:
+            s.log.Error(fmt.Sprintf("Bulk operation failed for product %d: %s", outcome.ID, outcome.Err.Error()))
+            item.Status = BulkStatusFailed
+            item.Error = "internal error"
+        }
+        if outcome.Err == nil {
+            result.Succeeded++
+        } else {
+            result.Failed++
+        }
+        result.Results = append(result.Results, item)
+    }
+    return result
 }
 
 // CountProducts returns the total number of products
 func (s *DefaultProductService) CountProducts() (int64, error) {
     return s.repo.Count()
-}
\ No newline at end of file
+}
+
+// ReindexProduct refreshes the search document of a product
+func (s *DefaultProductService) ReindexProduct(id uint) error {
+    return s.ReindexProducts([]uint{id})
+}
+
+// ReindexProducts refreshes the search documents of the given products, dropping
+// the ones that no longer exist
+func (s *DefaultProductService) ReindexProducts(ids []uint) error {
+    if len(ids) == 0 {
+        return nil
+    }
+    products, err := s.repo.FindByIDs(ids)
+    if err != nil {
+        return err
+    }
+    docs, err := s.documents(products)
+    if err != nil {
+        return err
+    }
+
+    found := make(map[uint]bool, len(docs))
+    for _, doc := range docs {
+        if err := s.index.Index(doc); err != nil {
+            return err
+        }
+        found[doc.ID] = true
+    }
+    for _, id := range ids {
+        if !found[id] {
+            if err := s.index.Remove(id); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// ReindexAll rebuilds the search index from every product. The rebuilt documents
+// replace the whole index, so products deleted since the last build drop out.
+func (s *DefaultProductService) ReindexAll() error {
+    products, err := s.repo.ListWithCategories()
+    if err != nil {
+        return err
+    }
+    docs, err := s.documents(products)
+    if err != nil {
+        return err
+    }
+    return s.index.Replace(docs)
+}
+
+// documents builds the search documents of products whose categories and available
+// stock are loaded
+func (s *DefaultProductService) documents(products []models.Product) ([]search.Document, error) {
+    categories, err := s.categoryRepo.List()
+    if err != nil {
+        return nil, err
+    }
+    tree := search.NewCategoryTree(categories)
+
+    docs := make([]search.Document, 0, len(products))
+    for _, product := range products {
+        categoryIDs := make([]uint, len(product.Categories))
+        for i, category := range product.Categories {
+            categoryIDs[i] = category.ID
+        }
+
+        docs = append(docs, search.Document{
+            ID:          product.ID,
+            Name:        product.Name,
+            Description: product.Description,
+            Categories:  tree.Slugs(categoryIDs),
+            Price:       product.CurrentPrice(),
+            InStock:     product.Available > 0,
+        })
+    }
+    return docs, nil
+}
+
+// reindex refreshes the search document of a product after a write. The write has
+// already succeeded, so failures are logged rather than returned.
+func (s *DefaultProductService) reindex(id uint) {
+    if err := s.ReindexProduct(id); err != nil {
+        s.log.Error("Failed to update search index: " + err.Error())
+    }
+}
+
+// resolveSales clears the sale price of the products whose sale is over, passing
+// through the error of the read that loaded them
+func resolveSales(products []models.Product, err error) ([]models.Product, error) {
+    if err != nil {
+        return nil, err
+    }
+    now := time.Now()
+    for i := range products {
+        resolveSale(&products[i], now)
+    }
+    return products, nil
+}
+
+// resolveSale clears the sale price of a product whose sale is over at a time but
+// has not been reverted by the sale scheduler yet, so reads show the price in effect
+func resolveSale(product *models.Product, at time.Time) {
+    if !product.OnSale(at) {
+        product.SalePrice = nil
+        product.SaleEndsAt = nil
+    }
+}
+
+// validateProduct checks the name, price and stock of a product and makes sure it
+// is priced in the store currency
+func validateProduct(product *models.Product) error {
+    product.Name = strings.TrimSpace(product.Name)
+    if product.Name == "" {
+        return fmt.Errorf("%w: name is required", ErrInvalidProduct)
+    }
+    if utf8.RuneCountInString(product.Name) > 255 {
+        return fmt.Errorf("%w: name must be at most 255 characters", ErrInvalidProduct)
+    }
+    if !product.Price.IsPositive() {
+        return fmt.Errorf("%w: price must be positive", ErrInvalidProduct)
+    }
+    if product.Stock < 0 {
+        return fmt.Errorf("%w: stock must not be negative", ErrInvalidProduct)
+    }
+    if product.Weight < 0 || product.Length < 0 || product.Width < 0 || product.Height < 0 {
+        return fmt.Errorf("%w: weight and dimensions must not be negative", ErrInvalidProduct)
+    }
+    price, err := storeAmount(product.Price, "price", ErrInvalidProduct)
+    if err != nil {
+        return err
+    }
+    product.Price = price
+    return nil
+}
@@ -0,0 +1,464 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/logger"
+	"ecommerce-app/pkg/money"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidImport is returned when an import cannot be read at all, as opposed to
+// individual lines failing validation
+var ErrInvalidImport = errors.New("invalid import")
+
+// Product import and export formats
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+const (
+	// importBatchSize is the number of rows written per transaction
+	importBatchSize = 500
+	// exportBatchSize is the number of products read from the database at a time
+	exportBatchSize = 500
+	// maxImportErrors caps the line errors kept in an import report
+	maxImportErrors = 1000
+	// maxImportLine caps the length of a JSON line
+	maxImportLine = 1 << 20
+	// categorySeparator separates category slugs in CSV files
+	categorySeparator = "|"
+)
+
+// csvColumns are the columns of exported CSV files. Imports need sku, name, price
+// and stock; description and categories are optional.
+var csvColumns = []string{"sku", "name", "description", "price", "stock", "categories"}
+
+// ProductRecord is a product as it appears in import and export files. A missing
+// categories field leaves the categories of an existing product unchanged.
+type ProductRecord struct {
+	SKU         string      `json:"sku"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Price       money.Money `json:"price"`
+	Stock       int         `json:"stock"`
+	Categories  *[]string   `json:"categories,omitempty"`
+}
+
+// ImportLineError is a validation error of one line of an import
+type ImportLineError struct {
+	Line  int    `json:"line"`
+	SKU   string `json:"sku,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportReport summarizes an import. In a dry run Created and Updated count the
+// products that would have been written.
+type ImportReport struct {
+	DryRun          bool              `json:"dry_run"`
+	Lines           int               `json:"lines"`
+	Created         int               `json:"created"`
+	Updated         int               `json:"updated"`
+	Failed          int               `json:"failed"`
+	Errors          []ImportLineError `json:"errors"`
+	ErrorsTruncated bool              `json:"errors_truncated"`
+}
+
+// ProductTransferService defines the interface for bulk product import and export
+type ProductTransferService interface {
+	ImportProducts(r io.Reader, format string, dryRun bool) (*ImportReport, error)
+	ExportProducts(w io.Writer, format string) error
+}
+
+// DefaultProductTransferService implements ProductTransferService
+type DefaultProductTransferService struct {
+	productRepo  repository.ProductRepository
+	categoryRepo repository.CategoryRepository
+	indexer      ProductIndexer
+	log          *logger.Logger
+}
+
+// NewProductTransferService creates a new instance of DefaultProductTransferService.
+// The indexer is told to rebuild the search index after an import.
+func NewProductTransferService(productRepo repository.ProductRepository, categoryRepo repository.CategoryRepository,
+	indexer ProductIndexer) ProductTransferService {
+	return &DefaultProductTransferService{
+		productRepo:  productRepo,
+		categoryRepo: categoryRepo,
+		indexer:      indexer,
+		log:          logger.New(),
+	}
+}
+
+// recordReader yields the records of an import file with their line numbers. It
+// returns io.EOF at the end of the file and a *lineError for unreadable lines.
+type recordReader func() (ProductRecord, int, error)
+
+// lineError is a problem with a single line that does not stop the import
+type lineError struct {
+	line int
+	err  error
+}
+
+func (e *lineError) Error() string { return e.err.Error() }
+
+// ImportProducts reads products from a CSV or JSON-lines file and upserts them by
+// SKU. The file is streamed: valid rows are written in batches, each in its own
+// transaction, and invalid lines are reported without stopping the import. A dry
+// run validates every line and reports what would be written without writing.
+func (s *DefaultProductTransferService) ImportProducts(r io.Reader, format string, dryRun bool) (*ImportReport, error) {
+	next, err := newRecordReader(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.categorySlugs()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{DryRun: dryRun, Errors: []ImportLineError{}}
+	seen := map[string]int{}
+	batch := make([]repository.ProductImportRow, 0, importBatchSize)
+
+	for {
+		record, line, err := next()
+		if err == io.EOF {
+			break
+		}
+		var lineErr *lineError
+		if errors.As(err, &lineErr) {
+			report.Lines++
+			report.addError(lineErr.line, "", lineErr.err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		report.Lines++
+
+		row, err := importRow(record, categories)
+		if err == nil {
+			if first, ok := seen[record.SKU]; ok {
+				err = fmt.Errorf("duplicate SKU, first seen on line %d", first)
+			}
+		}
+		if err != nil {
+			report.addError(line, record.SKU, err)
+			continue
+		}
+		seen[record.SKU] = line
+
+		batch = append(batch, row)
+		if len(batch) == importBatchSize {
+			if err := s.writeBatch(batch, report); err != nil {
+				return nil, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := s.writeBatch(batch, report); err != nil {
+		return nil, err
+	}
+
+	if !dryRun && report.Created+report.Updated > 0 {
+		if err := s.indexer.ReindexAll(); err != nil {
+			s.log.Error("Failed to update search index after import: " + err.Error())
+		}
+	}
+	return report, nil
+}
+
+// ExportProducts writes every product to w as CSV or JSON lines, reading the
+// products from the database in batches
+func (s *DefaultProductTransferService) ExportProducts(w io.Writer, format string) error {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvColumns); err != nil {
+			return err
+		}
+		err := s.productRepo.StreamAll(exportBatchSize, func(products []models.Product) error {
+			for _, product := range products {
+				record := exportRecord(product)
+				err := writer.Write([]string{
+					record.SKU,
+					record.Name,
+					record.Description,
+					record.Price.Decimal(),
+					strconv.Itoa(record.Stock),
+					strings.Join(*record.Categories, categorySeparator),
+				})
+				if err != nil {
+					return err
+				}
+			}
+			writer.Flush()
+			return writer.Error()
+		})
+		if err != nil {
+			return err
+		}
+		writer.Flush()
+		return writer.Error()
+	case FormatJSONL:
+		encoder := json.NewEncoder(w)
+		return s.productRepo.StreamAll(exportBatchSize, func(products []models.Product) error {
+			for _, product := range products {
+				if err := encoder.Encode(exportRecord(product)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	default:
+		return fmt.Errorf("%w: unknown format %q (want csv or jsonl)", ErrInvalidImport, format)
+	}
+}
+
+// writeBatch upserts a batch of rows and counts them in the report. In a dry run
+// the rows are only counted, using the SKUs that already exist.
+func (s *DefaultProductTransferService) writeBatch(batch []repository.ProductImportRow, report *ImportReport) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if report.DryRun {
+		skus := make([]string, len(batch))
+		for i, row := range batch {
+			skus[i] = *row.Product.SKU
+		}
+		existing, err := s.productRepo.ExistingSKUs(skus)
+		if err != nil {
+			return err
+		}
+		report.Updated += len(existing)
+		report.Created += len(batch) - len(existing)
+		return nil
+	}
+
+	created, err := s.productRepo.UpsertBySKU(batch)
+	if err != nil {
+		return err
+	}
+	report.Created += created
+	report.Updated += len(batch) - created
+	return nil
+}
+
+// categorySlugs maps the slug of every category to its ID
+func (s *DefaultProductTransferService) categorySlugs() (map[string]uint, error) {
+	categories, err := s.categoryRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	slugs := make(map[string]uint, len(categories))
+	for _, category := range categories {
+		slugs[category.Slug] = category.ID
+	}
+	return slugs, nil
+}
+
+// addError records a failed line, keeping at most maxImportErrors of them
+func (report *ImportReport) addError(line int, sku string, err error) {
+	report.Failed++
+	if len(report.Errors) >= maxImportErrors {
+		report.ErrorsTruncated = true
+		return
+	}
+	report.Errors = append(report.Errors, ImportLineError{Line: line, SKU: sku, Error: err.Error()})
+}
+
+// importRow validates a record and turns it into a row to upsert
+func importRow(record ProductRecord, categories map[string]uint) (repository.ProductImportRow, error) {
+	sku := strings.TrimSpace(record.SKU)
+	if sku == "" {
+		return repository.ProductImportRow{}, errors.New("sku is required")
+	}
+	if len(sku) > 100 {
+		return repository.ProductImportRow{}, errors.New("sku must be at most 100 characters")
+	}
+
+	row := repository.ProductImportRow{
+		Product: models.Product{
+			SKU:         &sku,
+			Name:        record.Name,
+			Description: record.Description,
+			Price:       record.Price,
+			Stock:       record.Stock,
+		},
+	}
+	if err := validateProduct(&row.Product); err != nil {
+		return repository.ProductImportRow{}, err
+	}
+
+	if record.Categories != nil {
+		row.SetCategories = true
+		for _, slug := range *record.Categories {
+			id, ok := categories[strings.TrimSpace(slug)]
+			if !ok {
+				return repository.ProductImportRow{}, fmt.Errorf("unknown category %q", slug)
+			}
+			row.CategoryIDs = append(row.CategoryIDs, id)
+		}
+	}
+	return row, nil
+}
+
+// exportRecord turns a product with its categories into a file record
+func exportRecord(product models.Product) ProductRecord {
+	categories := make([]string, len(product.Categories))
+	for i, category := range product.Categories {
+		categories[i] = category.Slug
+	}
+
+	record := ProductRecord{
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		Stock:       product.Stock,
+		Categories:  &categories,
+	}
+	if product.SKU != nil {
+		record.SKU = *product.SKU
+	}
+	return record
+}
+
+// newRecordReader returns a reader for the records of an import file
+func newRecordReader(r io.Reader, format string) (recordReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatJSONL:
+		return newJSONLReader(r), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q (want csv or jsonl)", ErrInvalidImport, format)
+	}
+}
+
+// newCSVReader reads records from a CSV file with a header row naming the columns
+func newCSVReader(r io.Reader) (recordReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		known := false
+		for _, column := range csvColumns {
+			known = known || column == name
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidImport, name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidImport, name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"sku", "name", "price", "stock"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidImport, required)
+		}
+	}
+
+	return func() (ProductRecord, int, error) {
+		for {
+			fields, err := reader.Read()
+			if err == io.EOF {
+				return ProductRecord{}, 0, io.EOF
+			}
+			line, _ := reader.FieldPos(0)
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return ProductRecord{}, parseErr.StartLine, &lineError{line: parseErr.StartLine, err: parseErr.Err}
+			}
+			if err != nil {
+				return ProductRecord{}, 0, err
+			}
+			if len(fields) == 1 && strings.TrimSpace(fields[0]) == "" {
+				continue // Blank line
+			}
+
+			field := func(name string) string {
+				if i, ok := columns[name]; ok && i < len(fields) {
+					return strings.TrimSpace(fields[i])
+				}
+				return ""
+			}
+			if len(fields) != len(header) {
+				return ProductRecord{}, line, &lineError{line: line, err: fmt.Errorf("expected %d fields, got %d", len(header), len(fields))}
+			}
+
+			record := ProductRecord{
+				SKU:         field("sku"),
+				Name:        field("name"),
+				Description: field("description"),
+			}
+			if record.Price, err = money.Parse(field("price"), money.DefaultCurrency); err != nil {
+				return ProductRecord{}, line, &lineError{line: line, err: fmt.Errorf("invalid price %q", field("price"))}
+			}
+			if record.Stock, err = strconv.Atoi(field("stock")); err != nil {
+				return ProductRecord{}, line, &lineError{line: line, err: fmt.Errorf("invalid stock %q", field("stock"))}
+			}
+			if _, ok := columns["categories"]; ok {
+				categories := []string{}
+				for _, slug := range strings.Split(field("categories"), categorySeparator) {
+					if slug = strings.TrimSpace(slug); slug != "" {
+						categories = append(categories, slug)
+					}
+				}
+				record.Categories = &categories
+			}
+			return record, line, nil
+		}
+	}, nil
+}
+
+// newJSONLReader reads records from a file holding one JSON object per line
+func newJSONLReader(r io.Reader) recordReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	line := 0
+
+	return func() (ProductRecord, int, error) {
+		for scanner.Scan() {
+			line++
+			data := bytes.TrimSpace(scanner.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+
+			var record ProductRecord
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&record); err != nil {
+				return ProductRecord{}, line, &lineError{line: line, err: fmt.Errorf("invalid JSON: %v", err)}
+			}
+			return record, line, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return ProductRecord{}, line, err
+		}
+		return ProductRecord{}, line, io.EOF
+	}
+}
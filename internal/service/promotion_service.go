@@ -0,0 +1,557 @@
+package service
+
+import (
+	"cmp"
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrPromotionNotFound is returned when a referenced promotion does not exist
+	ErrPromotionNotFound = errors.New("promotion not found")
+	// ErrInvalidPromotion is returned when promotion data fails validation
+	ErrInvalidPromotion = errors.New("invalid promotion")
+)
+
+// PromotionTierInput is a step of a spend_tiers promotion
+type PromotionTierInput struct {
+	Threshold money.Money `json:"threshold"`
+	Amount    money.Money `json:"amount"`
+}
+
+// PromotionInput is the data of a promotion to create or update. Products and
+// categories restrict the promotion to them; bundles consist of the products.
+type PromotionInput struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Type        string               `json:"type"`
+	Priority    int                  `json:"priority"`
+	Exclusive   bool                 `json:"exclusive"`
+	BuyQuantity int                  `json:"buy_quantity"`
+	GetQuantity int                  `json:"get_quantity"`
+	Percent     float64              `json:"percent"`
+	Amount      money.Money          `json:"amount"`
+	Tiers       []PromotionTierInput `json:"tiers"`
+	StartsAt    *time.Time           `json:"starts_at"`
+	EndsAt      *time.Time           `json:"ends_at"`
+	Active      *bool                `json:"active"` // Defaults to true
+	ProductIDs  []uint               `json:"product_ids"`
+	CategoryIDs []uint               `json:"category_ids"`
+}
+
+// SampleCartItem is a line of a sample cart to preview promotions on
+type SampleCartItem struct {
+	ProductID uint `json:"product_id"`
+	VariantID uint `json:"variant_id"`
+	Quantity  int  `json:"quantity"`
+}
+
+// PromotionPreview is the effect of a promotion on a sample cart
+type PromotionPreview struct {
+	Running bool         `json:"running"` // Whether the promotion currently applies to shoppers' carts
+	Fired   bool         `json:"fired"`   // Whether it discounts the sample cart
+	Summary *CartSummary `json:"summary"`
+}
+
+// PromotionService defines the interface for the admin management of promotions
+type PromotionService interface {
+	ListPromotions(page, pageSize int) ([]models.Promotion, int64, error)
+	GetPromotion(id uint) (*models.Promotion, error)
+	CreatePromotion(input PromotionInput) (*models.Promotion, error)
+	UpdatePromotion(id uint, input PromotionInput) (*models.Promotion, error)
+	DeletePromotion(id uint) error
+	PreviewPromotion(id uint, items []SampleCartItem, combine bool) (*PromotionPreview, error)
+}
+
+// DefaultPromotionService implements PromotionService
+type DefaultPromotionService struct {
+	repo         repository.PromotionRepository
+	productRepo  repository.ProductRepository
+	variantRepo  repository.VariantRepository
+	categoryRepo repository.CategoryRepository
+	pricer       CartPricer
+}
+
+// NewPromotionService creates a new instance of DefaultPromotionService. Previews
+// are priced with the pricer used for carts.
+func NewPromotionService(repo repository.PromotionRepository, productRepo repository.ProductRepository,
+	variantRepo repository.VariantRepository, categoryRepo repository.CategoryRepository, pricer CartPricer) PromotionService {
+	return &DefaultPromotionService{
+		repo:         repo,
+		productRepo:  productRepo,
+		variantRepo:  variantRepo,
+		categoryRepo: categoryRepo,
+		pricer:       pricer,
+	}
+}
+
+// ListPromotions retrieves one page of promotions in evaluation order
+func (s *DefaultPromotionService) ListPromotions(page, pageSize int) ([]models.Promotion, int64, error) {
+	return s.repo.List(page, pageSize)
+}
+
+// GetPromotion retrieves a promotion with its tiers, products and categories
+func (s *DefaultPromotionService) GetPromotion(id uint) (*models.Promotion, error) {
+	promotion, err := s.repo.FindByID(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrPromotionNotFound
+	}
+	return promotion, err
+}
+
+// CreatePromotion validates and stores a new promotion
+func (s *DefaultPromotionService) CreatePromotion(input PromotionInput) (*models.Promotion, error) {
+	promotion := &models.Promotion{}
+	productIDs, err := s.apply(promotion, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(promotion, productIDs, input.CategoryIDs); err != nil {
+		return nil, err
+	}
+	return s.GetPromotion(promotion.ID)
+}
+
+// UpdatePromotion validates and replaces the data of an existing promotion
+func (s *DefaultPromotionService) UpdatePromotion(id uint, input PromotionInput) (*models.Promotion, error) {
+	promotion, err := s.GetPromotion(id)
+	if err != nil {
+		return nil, err
+	}
+	productIDs, err := s.apply(promotion, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(promotion, productIDs, input.CategoryIDs); err != nil {
+		return nil, err
+	}
+	return s.GetPromotion(id)
+}
+
+// DeletePromotion removes a promotion
+func (s *DefaultPromotionService) DeletePromotion(id uint) error {
+	err := s.repo.Delete(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrPromotionNotFound
+	}
+	return err
+}
+
+// PreviewPromotion prices a sample cart with a promotion, whether or not it is
+// running. With combine, the promotions currently running are evaluated as well, so
+// the preview shows how priorities and exclusivity play out.
+func (s *DefaultPromotionService) PreviewPromotion(id uint, sample []SampleCartItem, combine bool) (*PromotionPreview, error) {
+	promotion, err := s.GetPromotion(id)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.sampleCart(sample)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	productIDs := cartProductIDs(items)
+	applied, err := s.repo.Eligibility(promotion, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	ctx := PricingContext{Promotions: []repository.AppliedPromotion{applied}}
+	if combine {
+		running, err := s.repo.Running(now, productIDs)
+		if err != nil {
+			return nil, err
+		}
+		running = slices.DeleteFunc(running, func(other repository.AppliedPromotion) bool {
+			return other.Promotion.ID == id
+		})
+		ctx.Promotions = append(running, applied)
+	}
+
+	summary, err := s.pricer.Price(items, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &PromotionPreview{
+		Running: promotion.Running(now),
+		Fired: slices.ContainsFunc(summary.Discounts, func(discount DiscountLine) bool {
+			return discount.PromotionID == id
+		}),
+		Summary: summary,
+	}, nil
+}
+
+// sampleCart loads the products and variants of a sample cart. Stock is not checked.
+func (s *DefaultPromotionService) sampleCart(sample []SampleCartItem) ([]models.CartItem, error) {
+	if len(sample) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	items := make([]models.CartItem, 0, len(sample))
+	for _, line := range sample {
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: must be at least 1", ErrInvalidQuantity)
+		}
+		i := slices.IndexFunc(items, func(item models.CartItem) bool {
+			return item.ProductID == line.ProductID && item.VariantID == line.VariantID
+		})
+		if i >= 0 {
+			items[i].Quantity += line.Quantity
+			continue
+		}
+
+		product, err := s.productRepo.FindByID(line.ProductID)
+		if err != nil {
+			if errors.Is(err, repository.ErrRecordNotFound) {
+				return nil, ErrProductNotFound
+			}
+			return nil, err
+		}
+		item := models.CartItem{ProductID: line.ProductID, VariantID: line.VariantID, Quantity: line.Quantity, Product: *product}
+		if line.VariantID == 0 {
+			variants, err := s.variantRepo.CountByProduct(line.ProductID)
+			if err != nil {
+				return nil, err
+			}
+			if variants > 0 {
+				return nil, ErrVariantRequired
+			}
+		} else {
+			variant, err := s.variantRepo.FindByID(line.VariantID)
+			if err != nil || variant.ProductID != line.ProductID {
+				if err == nil || errors.Is(err, repository.ErrRecordNotFound) {
+					return nil, ErrVariantNotFound
+				}
+				return nil, err
+			}
+			item.Variant = variant
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// apply validates the input and copies it onto the promotion, returning the
+// products it is restricted to without duplicates
+func (s *DefaultPromotionService) apply(promotion *models.Promotion, input PromotionInput) ([]uint, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return nil, fmt.Errorf("%w: name must be 1 to 100 characters", ErrInvalidPromotion)
+	}
+	description := strings.TrimSpace(input.Description)
+	if utf8.RuneCountInString(description) > 255 {
+		return nil, fmt.Errorf("%w: description must be at most 255 characters", ErrInvalidPromotion)
+	}
+	if input.StartsAt != nil && input.EndsAt != nil && !input.EndsAt.After(*input.StartsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidPromotion)
+	}
+
+	productIDs := slices.Clone(input.ProductIDs)
+	slices.Sort(productIDs)
+	productIDs = slices.Compact(productIDs)
+
+	rules := models.Promotion{Amount: money.Zero(money.DefaultCurrency)}
+	var err error
+	switch input.Type {
+	case models.PromotionBuyXGetY:
+		if input.BuyQuantity < 1 || input.BuyQuantity > 100 || input.GetQuantity < 1 || input.GetQuantity > 100 {
+			return nil, fmt.Errorf("%w: buy_quantity and get_quantity must be 1 to 100", ErrInvalidPromotion)
+		}
+		if rules.Percent, err = promotionPercent(input.Percent); err != nil {
+			return nil, err
+		}
+		rules.BuyQuantity = input.BuyQuantity
+		rules.GetQuantity = input.GetQuantity
+	case models.PromotionSpendTiers:
+		if len(input.Tiers) == 0 || len(input.Tiers) > 10 {
+			return nil, fmt.Errorf("%w: spend_tiers needs 1 to 10 tiers", ErrInvalidPromotion)
+		}
+		for _, tier := range input.Tiers {
+			threshold, err := storeAmount(tier.Threshold, "threshold", ErrInvalidPromotion)
+			if err != nil {
+				return nil, err
+			}
+			amount, err := storeAmount(tier.Amount, "amount", ErrInvalidPromotion)
+			if err != nil {
+				return nil, err
+			}
+			if !threshold.IsPositive() || !amount.IsPositive() || amount.Cmp(threshold) > 0 {
+				return nil, fmt.Errorf("%w: tiers need a positive threshold and an amount off up to the threshold", ErrInvalidPromotion)
+			}
+			if slices.ContainsFunc(rules.Tiers, func(other models.PromotionTier) bool { return other.Threshold == threshold }) {
+				return nil, fmt.Errorf("%w: more than one tier with threshold %s", ErrInvalidPromotion, threshold)
+			}
+			rules.Tiers = append(rules.Tiers, models.PromotionTier{Threshold: threshold, Amount: amount})
+		}
+		slices.SortFunc(rules.Tiers, func(a, b models.PromotionTier) int { return a.Threshold.Cmp(b.Threshold) })
+	case models.PromotionBundle:
+		if len(productIDs) < 2 || len(input.CategoryIDs) > 0 {
+			return nil, fmt.Errorf("%w: a bundle consists of at least 2 products and no categories", ErrInvalidPromotion)
+		}
+		amount, err := storeAmount(input.Amount, "amount", ErrInvalidPromotion)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case input.Percent != 0 && amount.IsZero():
+			if rules.Percent, err = promotionPercent(input.Percent); err != nil {
+				return nil, err
+			}
+		case input.Percent == 0 && amount.IsPositive():
+			rules.Amount = amount
+		default:
+			return nil, fmt.Errorf("%w: a bundle takes either a percent or a positive amount off", ErrInvalidPromotion)
+		}
+	case models.PromotionCategorySale:
+		if len(input.CategoryIDs) == 0 {
+			return nil, fmt.Errorf("%w: a category sale needs at least one category", ErrInvalidPromotion)
+		}
+		if rules.Percent, err = promotionPercent(input.Percent); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: type must be %s, %s, %s or %s", ErrInvalidPromotion, models.PromotionBuyXGetY,
+			models.PromotionSpendTiers, models.PromotionBundle, models.PromotionCategorySale)
+	}
+
+	if err := checkOfferScope(s.productRepo, s.categoryRepo, productIDs, input.CategoryIDs, ErrInvalidPromotion); err != nil {
+		return nil, err
+	}
+
+	promotion.Name = name
+	promotion.Description = description
+	promotion.Type = input.Type
+	promotion.Priority = input.Priority
+	promotion.Exclusive = input.Exclusive
+	promotion.BuyQuantity = rules.BuyQuantity
+	promotion.GetQuantity = rules.GetQuantity
+	promotion.Percent = rules.Percent
+	promotion.Amount = rules.Amount
+	promotion.Tiers = rules.Tiers
+	promotion.StartsAt = input.StartsAt
+	promotion.EndsAt = input.EndsAt
+	promotion.Active = input.Active == nil || *input.Active
+	return productIDs, nil
+}
+
+// promotionPercent validates a percentage off and rounds it to the stored precision
+func promotionPercent(percent float64) (float64, error) {
+	if math.IsNaN(percent) || percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("%w: percent must be above 0 and at most 100", ErrInvalidPromotion)
+	}
+	return math.Round(percent*100) / 100, nil
+}
+
+// PromotionDiscounter evaluates the promotions of the pricing context against a
+// cart. Promotions are evaluated from the highest priority down. An exclusive
+// promotion only fires when no promotion fired before it, and none fire after it.
+// The promotions on a line never take more than the line total off.
+type PromotionDiscounter struct{}
+
+// NewPromotionDiscounter creates a new instance of PromotionDiscounter
+func NewPromotionDiscounter() *PromotionDiscounter {
+	return &PromotionDiscounter{}
+}
+
+// Discounts implements Discounter
+func (d *PromotionDiscounter) Discounts(lines []SummaryLine, subtotal money.Money, ctx PricingContext) []DiscountLine {
+	promotions := slices.Clone(ctx.Promotions)
+	slices.SortStableFunc(promotions, func(a, b repository.AppliedPromotion) int {
+		if a.Promotion.Priority != b.Promotion.Priority {
+			return cmp.Compare(b.Promotion.Priority, a.Promotion.Priority)
+		}
+		return cmp.Compare(a.Promotion.ID, b.Promotion.ID)
+	})
+
+	// What is left to take off each line
+	remaining := make([]money.Money, len(lines))
+	for i, line := range lines {
+		remaining[i] = line.LineTotal
+	}
+
+	discounts := []DiscountLine{}
+	for _, applied := range promotions {
+		if applied.Promotion.Exclusive && len(discounts) > 0 {
+			continue
+		}
+
+		shares, notes := evaluatePromotion(applied, lines, subtotal.Currency)
+		discount := DiscountLine{
+			PromotionID: applied.Promotion.ID,
+			Description: applied.Promotion.Name,
+			Amount:      money.Zero(subtotal.Currency),
+		}
+		for i := range shares {
+			shares[i] = money.Min(shares[i], remaining[i])
+			share := shares[i]
+			if !share.IsPositive() {
+				continue
+			}
+			discount.Amount = discount.Amount.Add(share)
+			discount.Lines = append(discount.Lines, LineDiscount{
+				ProductID:   lines[i].ProductID,
+				VariantID:   lines[i].VariantID,
+				Description: applied.Promotion.Name,
+				Explanation: notes[i],
+				Amount:      share,
+			})
+		}
+		if !discount.Amount.IsPositive() {
+			continue
+		}
+
+		for i, share := range shares {
+			if share.IsPositive() {
+				remaining[i] = remaining[i].Sub(share)
+			}
+		}
+		discounts = append(discounts, discount)
+		if applied.Promotion.Exclusive {
+			break
+		}
+	}
+	return discounts
+}
+
+// evaluatePromotion works out what a promotion takes off each cart line, and why
+func evaluatePromotion(applied repository.AppliedPromotion, lines []SummaryLine, currency string) ([]money.Money, []string) {
+	promotion := applied.Promotion
+	shares := make([]money.Money, len(lines))
+	notes := make([]string, len(lines))
+	for i := range shares {
+		shares[i] = money.Zero(currency)
+	}
+	percent := strconv.FormatFloat(promotion.Percent, 'f', -1, 64) + "%"
+
+	switch promotion.Type {
+	case models.PromotionCategorySale:
+		for i, line := range lines {
+			if applied.Applies(line.ProductID) {
+				shares[i] = line.LineTotal.MulRate(promotion.Percent / 100)
+				notes[i] = percent + " off"
+			}
+		}
+
+	case models.PromotionBuyXGetY:
+		// Units are grouped most expensive first; the cheapest units of every
+		// complete group are discounted
+		order := make([]int, 0, len(lines))
+		units := 0
+		for i, line := range lines {
+			if applied.Applies(line.ProductID) {
+				order = append(order, i)
+				units += line.Quantity
+			}
+		}
+		slices.SortStableFunc(order, func(a, b int) int { return lines[b].UnitPrice.Cmp(lines[a].UnitPrice) })
+
+		group := promotion.BuyQuantity + promotion.GetQuantity
+		complete := units / group * group
+		discounted := func(position int) int {
+			position = min(position, complete)
+			return position/group*promotion.GetQuantity + max(0, position%group-promotion.BuyQuantity)
+		}
+		position := 0
+		for _, i := range order {
+			count := discounted(position+lines[i].Quantity) - discounted(position)
+			position += lines[i].Quantity
+			if count == 0 {
+				continue
+			}
+			shares[i] = lines[i].UnitPrice.Mul(count).MulRate(promotion.Percent / 100)
+			if promotion.Percent == 100 {
+				notes[i] = fmt.Sprintf("%d of %d free", count, lines[i].Quantity)
+			} else {
+				notes[i] = fmt.Sprintf("%d of %d at %s off", count, lines[i].Quantity, percent)
+			}
+		}
+
+	case models.PromotionSpendTiers:
+		eligible := money.Zero(currency)
+		ratios := make([]int64, len(lines))
+		for i, line := range lines {
+			if applied.Applies(line.ProductID) {
+				eligible = eligible.Add(line.LineTotal)
+				ratios[i] = line.LineTotal.Amount
+			}
+		}
+		var reached *models.PromotionTier
+		for i, tier := range promotion.Tiers {
+			if eligible.Cmp(tier.Threshold) >= 0 {
+				reached = &promotion.Tiers[i]
+			}
+		}
+		if reached == nil {
+			break
+		}
+		for i, share := range money.Min(reached.Amount, eligible).Allocate(ratios...) {
+			if share.IsPositive() {
+				shares[i] = share
+				notes[i] = fmt.Sprintf("Share of %s off for spending %s", reached.Amount, reached.Threshold)
+			}
+		}
+
+	case models.PromotionBundle:
+		// Complete sets are made of the cheapest units of each product
+		sets := -1
+		for _, product := range promotion.Products {
+			quantity := 0
+			for _, line := range lines {
+				if line.ProductID == product.ID {
+					quantity += line.Quantity
+				}
+			}
+			if sets < 0 || quantity < sets {
+				sets = quantity
+			}
+		}
+		if sets <= 0 {
+			break
+		}
+
+		bundled := make([]int, len(lines))
+		value := make([]int64, len(lines))
+		for _, product := range promotion.Products {
+			order := make([]int, 0, len(lines))
+			for i, line := range lines {
+				if line.ProductID == product.ID {
+					order = append(order, i)
+				}
+			}
+			slices.SortStableFunc(order, func(a, b int) int { return lines[a].UnitPrice.Cmp(lines[b].UnitPrice) })
+			left := sets
+			for _, i := range order {
+				bundled[i] = min(left, lines[i].Quantity)
+				value[i] = lines[i].UnitPrice.Mul(bundled[i]).Amount
+				left -= bundled[i]
+			}
+		}
+
+		if promotion.Percent > 0 {
+			for i := range lines {
+				shares[i] = money.New(value[i], currency).MulRate(promotion.Percent / 100)
+			}
+		} else {
+			total := money.Zero(currency)
+			for _, v := range value {
+				total = total.Add(money.New(v, currency))
+			}
+			copy(shares, money.Min(promotion.Amount.Mul(sets), total).Allocate(value...))
+		}
+		for i := range lines {
+			if bundled[i] > 0 {
+				notes[i] = fmt.Sprintf("%d of %d in %d bundle(s)", bundled[i], lines[i].Quantity, sets)
+			}
+		}
+	}
+	return shares, notes
+}
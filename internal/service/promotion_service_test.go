@@ -0,0 +1,233 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// summaryLine is a cart line of a product at a unit price
+func summaryLine(productID uint, unitPrice string, quantity int) SummaryLine {
+	return SummaryLine{
+		ProductID: productID,
+		Quantity:  quantity,
+		UnitPrice: usd(unitPrice),
+		LineTotal: usd(unitPrice).Mul(quantity),
+	}
+}
+
+// appliedPromotion applies a promotion to the given products, or to every product without any
+func appliedPromotion(promotion models.Promotion, productIDs ...uint) repository.AppliedPromotion {
+	applied := repository.AppliedPromotion{Promotion: promotion}
+	if len(productIDs) > 0 {
+		applied.Eligible = map[uint]bool{}
+		for _, id := range productIDs {
+			applied.Eligible[id] = true
+		}
+	}
+	return applied
+}
+
+func TestEvaluatePromotion(t *testing.T) {
+	tiers := []models.PromotionTier{
+		{Threshold: usd("50.00"), Amount: usd("5.00")},
+		{Threshold: usd("100.00"), Amount: usd("15.00")},
+	}
+	bundle := []models.Product{{ID: 1}, {ID: 2}}
+
+	tests := []struct {
+		name    string
+		applied repository.AppliedPromotion
+		lines   []SummaryLine
+		shares  []string
+		notes   []string
+	}{
+		{
+			name: "category sale on the eligible products",
+			applied: appliedPromotion(models.Promotion{
+				Type: models.PromotionCategorySale, Percent: 12.5,
+			}, 2),
+			lines:  []SummaryLine{summaryLine(1, "10.00", 3), summaryLine(2, "9.99", 2)},
+			shares: []string{"0.00", "2.50"},
+			notes:  []string{"", "12.5% off"},
+		},
+		{
+			name: "buy two get the cheapest free",
+			applied: appliedPromotion(models.Promotion{
+				Type: models.PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Percent: 100,
+			}),
+			lines:  []SummaryLine{summaryLine(1, "4.00", 1), summaryLine(2, "10.00", 2)},
+			shares: []string{"4.00", "0.00"},
+			notes:  []string{"1 of 1 free", ""},
+		},
+		{
+			name: "buy one get one half off, incomplete groups pay full price",
+			applied: appliedPromotion(models.Promotion{
+				Type: models.PromotionBuyXGetY, BuyQuantity: 1, GetQuantity: 1, Percent: 50,
+			}),
+			lines:  []SummaryLine{summaryLine(1, "10.00", 1), summaryLine(2, "4.00", 2)},
+			shares: []string{"0.00", "2.00"},
+			notes:  []string{"", "1 of 2 at 50% off"},
+		},
+		{
+			name: "highest spend tier reached",
+			applied: appliedPromotion(models.Promotion{
+				Type: models.PromotionSpendTiers, Tiers: tiers,
+			}),
+			lines:  []SummaryLine{summaryLine(1, "10.00", 3), summaryLine(2, "20.00", 4)},
+			shares: []string{"4.10", "10.90"},
+			notes:  []string{"Share of 15.00 USD off for spending 100.00 USD", "Share of 15.00 USD off for spending 100.00 USD"},
+		},
+		{
+			name: "spend tier reached exactly",
+			applied: appliedPromotion(models.Promotion{
+				Type: models.PromotionSpendTiers, Tiers: tiers,
+			}),
+			lines:  []SummaryLine{summaryLine(1, "10.00", 3), summaryLine(2, "20.00", 1)},
+			shares: []string{"3.00", "2.00"},
+			notes:  []string{"Share of 5.00 USD off for spending 50.00 USD", "Share of 5.00 USD off for spending 50.00 USD"},
+		},
+		{
+			name: "spend tiers only count eligible products",
+			applied: appliedPromotion(models.Promotion{
+				Type: models.PromotionSpendTiers, Tiers: tiers,
+			}, 1),
+			lines:  []SummaryLine{summaryLine(1, "10.00", 3), summaryLine(2, "20.00", 1)},
+			shares: []string{"0.00", "0.00"},
+			notes:  []string{"", ""},
+		},
+		{
+			name: "bundle percentage off the complete sets",
+			applied: appliedPromotion(models.Promotion{
+				Type: models.PromotionBundle, Percent: 10, Products: bundle,
+			}),
+			lines:  []SummaryLine{summaryLine(1, "10.00", 3), summaryLine(2, "20.00", 1), summaryLine(3, "5.00", 1)},
+			shares: []string{"1.00", "2.00", "0.00"},
+			notes:  []string{"1 of 3 in 1 bundle(s)", "1 of 1 in 1 bundle(s)", ""},
+		},
+		{
+			name: "bundle amount split over the set",
+			applied: appliedPromotion(models.Promotion{
+				Type: models.PromotionBundle, Amount: usd("5.00"), Products: bundle,
+			}),
+			lines:  []SummaryLine{summaryLine(1, "10.00", 2), summaryLine(2, "20.00", 2)},
+			shares: []string{"3.34", "6.66"},
+			notes:  []string{"2 of 2 in 2 bundle(s)", "2 of 2 in 2 bundle(s)"},
+		},
+		{
+			name: "incomplete bundle",
+			applied: appliedPromotion(models.Promotion{
+				Type: models.PromotionBundle, Amount: usd("5.00"), Products: bundle,
+			}),
+			lines:  []SummaryLine{summaryLine(1, "10.00", 2)},
+			shares: []string{"0.00"},
+			notes:  []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, notes := evaluatePromotion(tt.applied, tt.lines, "USD")
+			got := make([]string, len(shares))
+			for i, share := range shares {
+				got[i] = share.String()
+			}
+			want := make([]string, len(tt.shares))
+			for i, share := range tt.shares {
+				want[i] = usd(share).String()
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("shares = %v, want %v", got, want)
+			}
+			if !slices.Equal(notes, tt.notes) {
+				t.Errorf("notes = %q, want %q", notes, tt.notes)
+			}
+		})
+	}
+}
+
+func TestPromotionStacking(t *testing.T) {
+	// sale takes 10% off everything, clearance 50% off product 1
+	sale := func(id uint, priority int, exclusive bool) repository.AppliedPromotion {
+		return appliedPromotion(models.Promotion{
+			ID: id, Name: "Sale", Type: models.PromotionCategorySale, Percent: 10, Priority: priority, Exclusive: exclusive,
+		})
+	}
+	clearance := func(id uint, priority int, exclusive bool) repository.AppliedPromotion {
+		return appliedPromotion(models.Promotion{
+			ID: id, Name: "Clearance", Type: models.PromotionCategorySale, Percent: 50, Priority: priority, Exclusive: exclusive,
+		}, 1)
+	}
+
+	tests := []struct {
+		name       string
+		promotions []repository.AppliedPromotion
+		want       []string // Promotion ID and amount of each discount, in order
+	}{
+		{
+			name:       "higher priority first",
+			promotions: []repository.AppliedPromotion{sale(1, 1, false), clearance(2, 5, false)},
+			want:       []string{"2: 15.00 USD", "1: 5.00 USD"},
+		},
+		{
+			name:       "equal priorities by ID",
+			promotions: []repository.AppliedPromotion{clearance(2, 0, false), sale(1, 0, false)},
+			want:       []string{"1: 5.00 USD", "2: 15.00 USD"},
+		},
+		{
+			name:       "exclusive first stops the rest",
+			promotions: []repository.AppliedPromotion{sale(1, 1, false), clearance(2, 5, true)},
+			want:       []string{"2: 15.00 USD"},
+		},
+		{
+			name:       "exclusive skipped once another fired",
+			promotions: []repository.AppliedPromotion{sale(1, 5, false), clearance(2, 1, true)},
+			want:       []string{"1: 5.00 USD"},
+		},
+		{
+			name: "exclusive that takes nothing off lets the rest fire",
+			promotions: []repository.AppliedPromotion{
+				appliedPromotion(models.Promotion{ID: 3, Type: models.PromotionCategorySale, Percent: 50, Priority: 9, Exclusive: true}, 9),
+				sale(1, 1, false),
+			},
+			want: []string{"1: 5.00 USD"},
+		},
+		{
+			name: "stacked promotions stop at the line total",
+			promotions: []repository.AppliedPromotion{
+				appliedPromotion(models.Promotion{ID: 3, Type: models.PromotionCategorySale, Percent: 100, Priority: 9}, 1),
+				clearance(2, 5, false),
+				sale(1, 1, false),
+			},
+			want: []string{"3: 30.00 USD", "1: 2.00 USD"},
+		},
+		{
+			name: "none",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := []SummaryLine{summaryLine(1, "10.00", 3), summaryLine(2, "20.00", 1)}
+			discounts := NewPromotionDiscounter().Discounts(lines, usd("50.00"), PricingContext{Promotions: tt.promotions})
+
+			got := []string{}
+			for _, discount := range discounts {
+				got = append(got, fmt.Sprintf("%d: %s", discount.PromotionID, discount.Amount))
+				lineTotal := usd("0.00")
+				for _, line := range discount.Lines {
+					lineTotal = lineTotal.Add(line.Amount)
+				}
+				if lineTotal != discount.Amount {
+					t.Errorf("promotion %d lines add up to %s, want %s", discount.PromotionID, lineTotal, discount.Amount)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("discounts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
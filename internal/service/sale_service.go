@@ -0,0 +1,167 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/logger"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// saleBatchSize limits the sales of each kind a single scheduler run starts or ends
+const saleBatchSize = 500
+
+var (
+	// ErrSaleNotFound is returned when a referenced sale does not exist
+	ErrSaleNotFound = errors.New("sale not found")
+	// ErrInvalidSale is returned when sale data fails validation
+	ErrInvalidSale = errors.New("invalid sale")
+	// ErrSaleOverlap is returned when a sale would run at the same time as another
+	// sale of the product. The repository detects it, so the two are the same.
+	ErrSaleOverlap = repository.ErrSaleOverlap
+	// ErrSaleClosed is returned when cancelling a sale that has ended or was cancelled
+	ErrSaleClosed = repository.ErrSaleClosed
+)
+
+// SaleInput is the data of a sale to schedule
+type SaleInput struct {
+	Price    money.Money `json:"price"`
+	StartsAt *time.Time  `json:"starts_at"` // Defaults to now
+	EndsAt   *time.Time  `json:"ends_at"`   // Optional; runs until cancelled without it
+}
+
+// SaleService defines the interface for scheduled sale prices and price history
+type SaleService interface {
+	ListSales(productID uint, page, pageSize int) ([]models.ProductSale, int64, error)
+	CreateSale(productID uint, input SaleInput) (*models.ProductSale, error)
+	CancelSale(id uint) (*models.ProductSale, error)
+	ApplySchedule() (repository.SaleSchedule, error)
+	PriceHistory(productID uint, page, pageSize int) ([]models.PriceChange, int64, error)
+}
+
+// DefaultSaleService implements SaleService
+type DefaultSaleService struct {
+	repo        repository.SaleRepository
+	productRepo repository.ProductRepository
+	indexer     ProductIndexer
+	log         *logger.Logger
+}
+
+// NewSaleService creates a new instance of DefaultSaleService. The indexer is told
+// about the products whose price changes when a sale starts or ends.
+func NewSaleService(repo repository.SaleRepository, productRepo repository.ProductRepository,
+	indexer ProductIndexer) SaleService {
+	return &DefaultSaleService{
+		repo:        repo,
+		productRepo: productRepo,
+		indexer:     indexer,
+		log:         logger.New(),
+	}
+}
+
+// ListSales retrieves one page of the sales of a product, latest start first
+func (s *DefaultSaleService) ListSales(productID uint, page, pageSize int) ([]models.ProductSale, int64, error) {
+	if _, err := s.product(productID); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.ListByProduct(productID, page, pageSize)
+}
+
+// CreateSale validates and schedules a sale price for a product. The scheduler sets
+// the price when the sale starts, so a sale starting now takes effect on its next run.
+func (s *DefaultSaleService) CreateSale(productID uint, input SaleInput) (*models.ProductSale, error) {
+	product, err := s.product(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := storeAmount(input.Price, "price", ErrInvalidSale)
+	if err != nil {
+		return nil, err
+	}
+	if !price.IsPositive() {
+		return nil, fmt.Errorf("%w: price must be positive", ErrInvalidSale)
+	}
+	if price.Cmp(product.Price) >= 0 {
+		return nil, fmt.Errorf("%w: price must be below the regular price of %s", ErrInvalidSale, product.Price)
+	}
+
+	now := time.Now()
+	startsAt := now
+	if input.StartsAt != nil {
+		startsAt = *input.StartsAt
+	}
+	if input.EndsAt != nil {
+		if !input.EndsAt.After(startsAt) {
+			return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidSale)
+		}
+		if !input.EndsAt.After(now) {
+			return nil, fmt.Errorf("%w: ends_at must be in the future", ErrInvalidSale)
+		}
+	}
+
+	sale := &models.ProductSale{
+		ProductID: productID,
+		Price:     price,
+		StartsAt:  startsAt,
+		EndsAt:    input.EndsAt,
+	}
+	err = s.repo.Create(sale)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sale, nil
+}
+
+// CancelSale cancels a scheduled or active sale, reverting the price of an active one
+func (s *DefaultSaleService) CancelSale(id uint) (*models.ProductSale, error) {
+	sale, err := s.repo.Cancel(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrSaleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.reindex(sale.ProductID)
+	return sale, nil
+}
+
+// ApplySchedule starts the sales that are due and ends the ones that are over. Each
+// call handles a bounded batch; the rest are picked up by the next run.
+func (s *DefaultSaleService) ApplySchedule() (repository.SaleSchedule, error) {
+	schedule, err := s.repo.ApplySchedule(time.Now(), saleBatchSize)
+	for _, productID := range schedule.ProductIDs {
+		s.reindex(productID)
+	}
+	return schedule, err
+}
+
+// PriceHistory retrieves one page of the price changes of a product, latest first
+func (s *DefaultSaleService) PriceHistory(productID uint, page, pageSize int) ([]models.PriceChange, int64, error) {
+	if _, err := s.product(productID); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.PriceHistory(productID, page, pageSize)
+}
+
+// product retrieves the product a sale belongs to
+func (s *DefaultSaleService) product(id uint) (*models.Product, error) {
+	product, err := s.productRepo.FindByID(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrProductNotFound
+	}
+	return product, err
+}
+
+// reindex refreshes the search document of a product whose sale price changed. The
+// change has already been saved, so failures are logged rather than returned.
+func (s *DefaultSaleService) reindex(productID uint) {
+	if err := s.indexer.ReindexProduct(productID); err != nil {
+		s.log.Error("Failed to update search index: " + err.Error())
+	}
+}
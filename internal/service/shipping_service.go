@@ -0,0 +1,297 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrShippingZoneNotFound is returned when a referenced shipping zone does not exist
+	ErrShippingZoneNotFound = errors.New("shipping zone not found")
+	// ErrInvalidShippingZone is returned when shipping zone data fails validation
+	ErrInvalidShippingZone = errors.New("invalid shipping zone")
+	// ErrShippingMethodRequired is returned when checking out without choosing a shipping method
+	ErrShippingMethodRequired = errors.New("shipping method required")
+	// ErrShippingUnavailable is returned when a shipping method is not offered for the
+	// address or cannot carry the cart
+	ErrShippingUnavailable = errors.New("shipping method not available")
+)
+
+// ShippingQuote is what a cart costs with one shipping method
+type ShippingQuote struct {
+	MethodID   uint        `json:"method_id"`
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Cost       money.Money `json:"cost"`
+	Tax        money.Money `json:"tax"`
+	GrandTotal money.Money `json:"grand_total"`
+}
+
+// shippingMethods returns the active methods offered for a normalized address: those
+// of the shipping zone it is in. Of the zones that cover it, the one with the
+// highest priority wins, then the most specific one, as with tax zones.
+func shippingMethods(repo repository.ShippingRepository, address models.Address) ([]models.ShippingMethod, error) {
+	if address.Country == "" {
+		return nil, nil
+	}
+	zones, err := repo.ZonesForCountry(address.Country)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *models.ShippingZone
+	for i := range zones {
+		zone := &zones[i]
+		if !areaCovers(zone.Region, zone.PostalPattern, address) {
+			continue
+		}
+		if best == nil || zone.Priority > best.Priority || zone.Priority == best.Priority &&
+			areaSpecificity(zone.Region, zone.PostalPattern) > areaSpecificity(best.Region, best.PostalPattern) {
+			best = zone
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return best.Methods, nil
+}
+
+// billableWeight returns the weight in kilograms a shipping method charges for the
+// items: the actual weight of every unit, or its volumetric weight when the method
+// has a divisor and that is higher. Weights are rounded to the gram.
+func billableWeight(method models.ShippingMethod, items []models.CartItem) float64 {
+	weight := 0.0
+	for _, item := range items {
+		unit := item.Product.Weight
+		if method.VolumetricDivisor > 0 {
+			volume := item.Product.Length * item.Product.Width * item.Product.Height
+			unit = math.Max(unit, volume/float64(method.VolumetricDivisor))
+		}
+		weight += unit * float64(item.Quantity)
+	}
+	return math.Round(weight*1000) / 1000
+}
+
+// shippingCost returns what a shipping method charges for the items of a cart with
+// the given subtotal. It reports false when the items weigh more than the method
+// carries.
+func shippingCost(method models.ShippingMethod, items []models.CartItem, subtotal money.Money) (money.Money, bool) {
+	weight := billableWeight(method, items)
+	if method.MaxWeight > 0 && weight > method.MaxWeight {
+		return money.Money{}, false
+	}
+	switch method.Type {
+	case models.ShippingLocalPickup:
+		return money.Zero(subtotal.Currency), true
+	case models.ShippingWeightBased:
+		return method.Rate.Add(method.PerKg.Mul(int(math.Ceil(weight)))), true
+	case models.ShippingFreeOverThreshold:
+		if subtotal.Cmp(method.Threshold) >= 0 {
+			return money.Zero(subtotal.Currency), true
+		}
+		return method.Rate, true
+	default:
+		return method.Rate, true
+	}
+}
+
+// ShippingZoneInput is the data of a shipping zone to create or update. Its methods
+// replace the ones the zone had.
+type ShippingZoneInput struct {
+	Name          string                `json:"name"`
+	Country       string                `json:"country"`
+	Region        string                `json:"region"`         // Empty for the whole country
+	PostalPattern string                `json:"postal_pattern"` // Glob such as 90*; empty for every postal code
+	Priority      int                   `json:"priority"`
+	Methods       []ShippingMethodInput `json:"methods"`
+}
+
+// ShippingMethodInput is a way of shipping to a zone and what it costs
+type ShippingMethodInput struct {
+	Name              string      `json:"name"`
+	Type              string      `json:"type"`
+	Rate              money.Money `json:"rate"`
+	PerKg             money.Money `json:"per_kg"`             // Weight-based methods only
+	Threshold         money.Money `json:"threshold"`          // Free-over-threshold methods only
+	MaxWeight         float64     `json:"max_weight"`         // Kilograms; zero for no limit
+	VolumetricDivisor int         `json:"volumetric_divisor"` // Cubic centimetres per kilogram, e.g. 5000; zero to charge actual weight only
+	Active            *bool       `json:"active"`             // Defaults to true
+}
+
+// ShippingService defines the interface for the admin management of shipping zones
+// and their methods
+type ShippingService interface {
+	ListZones(page, pageSize int) ([]models.ShippingZone, int64, error)
+	GetZone(id uint) (*models.ShippingZone, error)
+	CreateZone(input ShippingZoneInput) (*models.ShippingZone, error)
+	UpdateZone(id uint, input ShippingZoneInput) (*models.ShippingZone, error)
+	DeleteZone(id uint) error
+}
+
+// DefaultShippingService implements ShippingService
+type DefaultShippingService struct {
+	repo repository.ShippingRepository
+}
+
+// NewShippingService creates a new instance of DefaultShippingService
+func NewShippingService(repo repository.ShippingRepository) ShippingService {
+	return &DefaultShippingService{
+		repo: repo,
+	}
+}
+
+// ListZones retrieves one page of the shipping zones with their methods
+func (s *DefaultShippingService) ListZones(page, pageSize int) ([]models.ShippingZone, int64, error) {
+	return s.repo.ListZones(page, pageSize)
+}
+
+// GetZone retrieves a shipping zone with its methods
+func (s *DefaultShippingService) GetZone(id uint) (*models.ShippingZone, error) {
+	zone, err := s.repo.FindZone(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrShippingZoneNotFound
+	}
+	return zone, err
+}
+
+// CreateZone validates and stores a new shipping zone
+func (s *DefaultShippingService) CreateZone(input ShippingZoneInput) (*models.ShippingZone, error) {
+	zone := &models.ShippingZone{}
+	if err := applyShippingZone(zone, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateZone(zone); err != nil {
+		return nil, err
+	}
+	return s.GetZone(zone.ID)
+}
+
+// UpdateZone validates and replaces the data and methods of an existing shipping
+// zone. The methods get new IDs, so quotes made before no longer check out.
+func (s *DefaultShippingService) UpdateZone(id uint, input ShippingZoneInput) (*models.ShippingZone, error) {
+	zone, err := s.GetZone(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyShippingZone(zone, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateZone(zone); err != nil {
+		return nil, err
+	}
+	return s.GetZone(id)
+}
+
+// DeleteZone removes a shipping zone with its methods
+func (s *DefaultShippingService) DeleteZone(id uint) error {
+	err := s.repo.DeleteZone(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrShippingZoneNotFound
+	}
+	return err
+}
+
+// applyShippingZone validates the input and copies it onto the shipping zone.
+// Country, region and postal pattern are kept in upper case, as addresses are
+// matched against them.
+func applyShippingZone(zone *models.ShippingZone, input ShippingZoneInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return fmt.Errorf("%w: name must be 1 to 100 characters", ErrInvalidShippingZone)
+	}
+	area := normalizeAddress(models.Address{Country: input.Country, Region: input.Region, PostalCode: input.PostalPattern})
+	if !validCountry(area.Country) {
+		return fmt.Errorf("%w: country must be a two-letter code", ErrInvalidShippingZone)
+	}
+	if utf8.RuneCountInString(area.Region) > 100 {
+		return fmt.Errorf("%w: region must be at most 100 characters", ErrInvalidShippingZone)
+	}
+	if len(area.PostalCode) > 50 {
+		return fmt.Errorf("%w: postal_pattern must be at most 50 characters", ErrInvalidShippingZone)
+	}
+	if _, err := path.Match(area.PostalCode, ""); err != nil {
+		return fmt.Errorf("%w: postal_pattern is malformed", ErrInvalidShippingZone)
+	}
+
+	methods := make([]models.ShippingMethod, 0, len(input.Methods))
+	for _, input := range input.Methods {
+		method, err := checkShippingMethod(input)
+		if err != nil {
+			return err
+		}
+		methods = append(methods, method)
+	}
+
+	zone.Name = name
+	zone.Country = area.Country
+	zone.Region = area.Region
+	zone.PostalPattern = area.PostalCode
+	zone.Priority = input.Priority
+	zone.Methods = methods
+	return nil
+}
+
+// checkShippingMethod validates a shipping method and turns it into a model. Amounts
+// are in the store currency and only those the type uses may be set.
+func checkShippingMethod(input ShippingMethodInput) (models.ShippingMethod, error) {
+	method := models.ShippingMethod{
+		Name:              strings.TrimSpace(input.Name),
+		Type:              input.Type,
+		MaxWeight:         input.MaxWeight,
+		VolumetricDivisor: input.VolumetricDivisor,
+		Active:            input.Active == nil || *input.Active,
+	}
+	if method.Name == "" || utf8.RuneCountInString(method.Name) > 100 {
+		return method, fmt.Errorf("%w: method name must be 1 to 100 characters", ErrInvalidShippingZone)
+	}
+
+	var err error
+	if method.Rate, err = storeAmount(input.Rate, "rate", ErrInvalidShippingZone); err != nil {
+		return method, err
+	}
+	if method.PerKg, err = storeAmount(input.PerKg, "per_kg", ErrInvalidShippingZone); err != nil {
+		return method, err
+	}
+	if method.Threshold, err = storeAmount(input.Threshold, "threshold", ErrInvalidShippingZone); err != nil {
+		return method, err
+	}
+	if method.Rate.IsNegative() || method.PerKg.IsNegative() || method.Threshold.IsNegative() {
+		return method, fmt.Errorf("%w: amounts must not be negative", ErrInvalidShippingZone)
+	}
+	if method.MaxWeight < 0 || method.VolumetricDivisor < 0 {
+		return method, fmt.Errorf("%w: max_weight and volumetric_divisor must not be negative", ErrInvalidShippingZone)
+	}
+
+	switch method.Type {
+	case models.ShippingFlatRate:
+	case models.ShippingWeightBased:
+		if !method.PerKg.IsPositive() {
+			return method, fmt.Errorf("%w: weight-based methods need a positive per_kg", ErrInvalidShippingZone)
+		}
+	case models.ShippingFreeOverThreshold:
+		if !method.Threshold.IsPositive() {
+			return method, fmt.Errorf("%w: free-over-threshold methods need a positive threshold", ErrInvalidShippingZone)
+		}
+	case models.ShippingLocalPickup:
+		if !method.Rate.IsZero() {
+			return method, fmt.Errorf("%w: local pickup is free of charge", ErrInvalidShippingZone)
+		}
+	default:
+		return method, fmt.Errorf("%w: type must be one of %s, %s, %s or %s", ErrInvalidShippingZone,
+			models.ShippingFlatRate, models.ShippingWeightBased, models.ShippingFreeOverThreshold, models.ShippingLocalPickup)
+	}
+	if method.Type != models.ShippingWeightBased && !method.PerKg.IsZero() {
+		return method, fmt.Errorf("%w: only weight-based methods take per_kg", ErrInvalidShippingZone)
+	}
+	if method.Type != models.ShippingFreeOverThreshold && !method.Threshold.IsZero() {
+		return method, fmt.Errorf("%w: only free-over-threshold methods take a threshold", ErrInvalidShippingZone)
+	}
+	return method, nil
+}
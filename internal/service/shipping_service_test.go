@@ -0,0 +1,142 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// parcel is a cart line of units with a packed weight in kilograms and size in centimetres
+func parcel(quantity int, weight, length, width, height float64) models.CartItem {
+	return models.CartItem{
+		Quantity: quantity,
+		Product:  models.Product{Weight: weight, Length: length, Width: width, Height: height},
+	}
+}
+
+func TestBillableWeight(t *testing.T) {
+	tests := []struct {
+		name    string
+		divisor int
+		items   []models.CartItem
+		want    float64
+	}{
+		{name: "actual weight", items: []models.CartItem{parcel(2, 1.2, 0, 0, 0), parcel(1, 0.3, 0, 0, 0)}, want: 2.7},
+		{name: "volume ignored without a divisor", items: []models.CartItem{parcel(2, 0.5, 30, 20, 10)}, want: 1},
+		{name: "volumetric weight when higher", divisor: 5000, items: []models.CartItem{parcel(2, 0.5, 30, 20, 10)}, want: 2.4},
+		{name: "actual weight when higher", divisor: 5000, items: []models.CartItem{parcel(2, 3, 30, 20, 10)}, want: 6},
+		{name: "per unit", divisor: 5000, items: []models.CartItem{parcel(1, 0.5, 30, 20, 10), parcel(1, 3, 10, 10, 10)}, want: 4.2},
+		{name: "rounded to the gram", items: []models.CartItem{parcel(3, 0.0004, 0, 0, 0), parcel(1, 0.1, 0, 0, 0), parcel(1, 0.2, 0, 0, 0)}, want: 0.301},
+		{name: "empty", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := models.ShippingMethod{VolumetricDivisor: tt.divisor}
+			if got := billableWeight(method, tt.items); got != tt.want {
+				t.Errorf("billableWeight() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShippingCost(t *testing.T) {
+	flat := models.ShippingMethod{Type: models.ShippingFlatRate, Rate: usd("4.99")}
+	weightBased := models.ShippingMethod{Type: models.ShippingWeightBased, Rate: usd("3.00"), PerKg: usd("1.50")}
+	freeOver := models.ShippingMethod{Type: models.ShippingFreeOverThreshold, Rate: usd("5.00"), Threshold: usd("50.00")}
+	pickup := models.ShippingMethod{Type: models.ShippingLocalPickup}
+	limited := func(method models.ShippingMethod, maxWeight float64, divisor int) models.ShippingMethod {
+		method.MaxWeight = maxWeight
+		method.VolumetricDivisor = divisor
+		return method
+	}
+
+	tests := []struct {
+		name     string
+		method   models.ShippingMethod
+		weight   float64 // Of the single unit in the cart, in kilograms
+		subtotal string
+		want     string
+		ok       bool
+	}{
+		{name: "flat rate", method: flat, weight: 2.5, subtotal: "20.00", want: "4.99", ok: true},
+		{name: "flat rate over the free shipping threshold of others", method: flat, weight: 2.5, subtotal: "500.00", want: "4.99", ok: true},
+		{name: "local pickup", method: pickup, weight: 2.5, subtotal: "20.00", want: "0.00", ok: true},
+		{name: "weight-based per started kilogram", method: weightBased, weight: 2.5, subtotal: "20.00", want: "7.50", ok: true},
+		{name: "weight-based on a whole kilogram", method: weightBased, weight: 2, subtotal: "20.00", want: "6.00", ok: true},
+		{name: "weight-based just over a kilogram", method: weightBased, weight: 2.001, subtotal: "20.00", want: "7.50", ok: true},
+		{name: "weightless", method: weightBased, weight: 0, subtotal: "20.00", want: "3.00", ok: true},
+		{name: "under the threshold", method: freeOver, weight: 2.5, subtotal: "49.99", want: "5.00", ok: true},
+		{name: "at the threshold", method: freeOver, weight: 2.5, subtotal: "50.00", want: "0.00", ok: true},
+		{name: "over the threshold", method: freeOver, weight: 2.5, subtotal: "50.01", want: "0.00", ok: true},
+		{name: "at the max weight", method: limited(flat, 2.5, 0), weight: 2.5, subtotal: "20.00", want: "4.99", ok: true},
+		{name: "over the max weight", method: limited(flat, 2.5, 0), weight: 2.501, subtotal: "20.00"},
+		{name: "free pickup still has a max weight", method: limited(pickup, 2, 0), weight: 2.5, subtotal: "20.00"},
+		{name: "free shipping still has a max weight", method: limited(freeOver, 2, 0), weight: 2.5, subtotal: "100.00"},
+		{name: "volumetric weight over the max weight", method: limited(flat, 2.5, 5000), weight: 1, subtotal: "20.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A box of 50 x 50 x 10 cm weighs 5 kg by volume at a divisor of 5000
+			items := []models.CartItem{parcel(1, tt.weight, 50, 50, 10)}
+			got, ok := shippingCost(tt.method, items, usd(tt.subtotal))
+			if ok != tt.ok {
+				t.Fatalf("shippingCost() available = %v, want %v", ok, tt.ok)
+			}
+			if ok && got != usd(tt.want) {
+				t.Errorf("shippingCost() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckShippingMethod(t *testing.T) {
+	inactive := false
+	tests := []struct {
+		name  string
+		input ShippingMethodInput
+		err   error
+	}{
+		{name: "flat rate", input: ShippingMethodInput{Name: "Standard", Type: models.ShippingFlatRate, Rate: usd("4.99")}},
+		{name: "free flat rate", input: ShippingMethodInput{Name: "Standard", Type: models.ShippingFlatRate}},
+		{name: "weight-based", input: ShippingMethodInput{Name: "Freight", Type: models.ShippingWeightBased, Rate: usd("3.00"), PerKg: usd("1.50"), MaxWeight: 30, VolumetricDivisor: 5000}},
+		{name: "free over a threshold", input: ShippingMethodInput{Name: "Saver", Type: models.ShippingFreeOverThreshold, Rate: usd("5.00"), Threshold: usd("50.00")}},
+		{name: "local pickup", input: ShippingMethodInput{Name: "Pickup", Type: models.ShippingLocalPickup, Active: &inactive}},
+		{name: "amounts without currency", input: ShippingMethodInput{Name: "Standard", Type: models.ShippingFlatRate, Rate: money.Money{Amount: 499}}},
+		{name: "no name", input: ShippingMethodInput{Name: "  ", Type: models.ShippingFlatRate}, err: ErrInvalidShippingZone},
+		{name: "long name", input: ShippingMethodInput{Name: strings.Repeat("a", 101), Type: models.ShippingFlatRate}, err: ErrInvalidShippingZone},
+		{name: "unknown type", input: ShippingMethodInput{Name: "Drone", Type: "drone"}, err: ErrInvalidShippingZone},
+		{name: "negative rate", input: ShippingMethodInput{Name: "Standard", Type: models.ShippingFlatRate, Rate: usd("-1.00")}, err: ErrInvalidShippingZone},
+		{name: "rate in another currency", input: ShippingMethodInput{Name: "Standard", Type: models.ShippingFlatRate, Rate: money.MustParse("4.99", "EUR")}, err: ErrCurrencyMismatch},
+		{name: "negative max weight", input: ShippingMethodInput{Name: "Standard", Type: models.ShippingFlatRate, MaxWeight: -1}, err: ErrInvalidShippingZone},
+		{name: "negative divisor", input: ShippingMethodInput{Name: "Standard", Type: models.ShippingFlatRate, VolumetricDivisor: -5000}, err: ErrInvalidShippingZone},
+		{name: "weight-based without per kg", input: ShippingMethodInput{Name: "Freight", Type: models.ShippingWeightBased, Rate: usd("3.00")}, err: ErrInvalidShippingZone},
+		{name: "free over no threshold", input: ShippingMethodInput{Name: "Saver", Type: models.ShippingFreeOverThreshold, Rate: usd("5.00")}, err: ErrInvalidShippingZone},
+		{name: "paid pickup", input: ShippingMethodInput{Name: "Pickup", Type: models.ShippingLocalPickup, Rate: usd("1.00")}, err: ErrInvalidShippingZone},
+		{name: "per kg on a flat rate", input: ShippingMethodInput{Name: "Standard", Type: models.ShippingFlatRate, PerKg: usd("1.00")}, err: ErrInvalidShippingZone},
+		{name: "threshold on a weight-based rate", input: ShippingMethodInput{Name: "Freight", Type: models.ShippingWeightBased, PerKg: usd("1.00"), Threshold: usd("50.00")}, err: ErrInvalidShippingZone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, err := checkShippingMethod(tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("checkShippingMethod() error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if method.Name != strings.TrimSpace(tt.input.Name) || method.Type != tt.input.Type {
+				t.Errorf("method = %q of type %q, want %q of type %q", method.Name, method.Type, tt.input.Name, tt.input.Type)
+			}
+			if method.Rate.Currency != money.DefaultCurrency || method.Rate.Amount != tt.input.Rate.Amount {
+				t.Errorf("rate = %s, want %d in %s", method.Rate, tt.input.Rate.Amount, money.DefaultCurrency)
+			}
+			if method.Active != (tt.input.Active == nil || *tt.input.Active) {
+				t.Errorf("active = %v, want %v", method.Active, !method.Active)
+			}
+		})
+	}
+}
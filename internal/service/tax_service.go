@@ -0,0 +1,528 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrTaxClassNotFound is returned when a referenced tax class does not exist
+	ErrTaxClassNotFound = errors.New("tax class not found")
+	// ErrInvalidTaxClass is returned when tax class data fails validation
+	ErrInvalidTaxClass = errors.New("invalid tax class")
+	// ErrTaxClassCodeTaken is returned when another tax class already uses the code
+	ErrTaxClassCodeTaken = errors.New("tax class code already in use")
+	// ErrTaxZoneNotFound is returned when a referenced tax zone does not exist
+	ErrTaxZoneNotFound = errors.New("tax zone not found")
+	// ErrInvalidTaxZone is returned when tax zone data fails validation
+	ErrInvalidTaxZone = errors.New("invalid tax zone")
+)
+
+// TaxConfig holds the store-wide tax settings
+type TaxConfig struct {
+	Currency    string
+	PriceMode   string         // models.TaxExclusive or models.TaxInclusive
+	Rounding    string         // models.TaxRoundLine or models.TaxRoundOrder
+	DefaultRate float64        // Rate for addresses no tax zone covers, e.g. 0.2 for 20%
+	Origin      models.Address // Address of the store; carts are taxed as if sent there until the shopper gives theirs
+}
+
+// TaxableLine is a cart line as the tax calculator sees it
+type TaxableLine struct {
+	TaxClassID uint        // Zero for the standard rate
+	Amount     money.Money // What the shopper pays for the line, after discounts
+}
+
+// LineTax is the tax on one cart line
+type LineTax struct {
+	Name   string
+	Rate   float64
+	Amount money.Money
+}
+
+// TaxSummaryLine is the tax of a cart at one rate
+type TaxSummaryLine struct {
+	Name    string      `json:"name"`
+	Rate    float64     `json:"rate"`
+	Taxable money.Money `json:"taxable"` // Amount taxed at the rate, net of tax
+	Amount  money.Money `json:"amount"`
+}
+
+// TaxBreakdown is the tax on a set of cart lines
+type TaxBreakdown struct {
+	Lines            []LineTax        // Tax of each line, in the order of the lines
+	Summary          []TaxSummaryLine // Tax at each rate above zero
+	Total            money.Money
+	PricesIncludeTax bool // Whether the line amounts already hold the tax rather than having it added
+}
+
+// TaxCalculator works out the tax on priced cart lines sent to an address
+type TaxCalculator interface {
+	Calculate(lines []TaxableLine, address models.Address) (*TaxBreakdown, error)
+}
+
+// DefaultTaxCalculator implements TaxCalculator with the tax zones and rates of the store
+type DefaultTaxCalculator struct {
+	repo   repository.TaxRepository
+	config TaxConfig
+}
+
+// NewTaxCalculator creates a new instance of DefaultTaxCalculator
+func NewTaxCalculator(repo repository.TaxRepository, config TaxConfig) TaxCalculator {
+	return &DefaultTaxCalculator{
+		repo:   repo,
+		config: config,
+	}
+}
+
+// taxRateKey identifies the rate a line is taxed at in a breakdown
+type taxRateKey struct {
+	name string
+	rate float64
+}
+
+// Calculate taxes every line at the rate its tax class has in the zone of the
+// address, or of the store address when the address has no country. Lines of a
+// class the zone has no rate for are taxed at the standard rate of the zone, and
+// addresses no zone covers at the default rate. With inclusive prices the tax is
+// the part of the line amount it already holds. Rounding per order rounds the tax
+// at each rate once and spreads it over the lines in proportion to their amounts.
+func (c *DefaultTaxCalculator) Calculate(lines []TaxableLine, address models.Address) (*TaxBreakdown, error) {
+	if address.Country == "" {
+		address = c.config.Origin
+	}
+	zone, err := c.zone(normalizeAddress(address))
+	if err != nil {
+		return nil, err
+	}
+
+	inclusive := c.config.PriceMode == models.TaxInclusive
+	breakdown := &TaxBreakdown{
+		Lines:            make([]LineTax, len(lines)),
+		Summary:          []TaxSummaryLine{},
+		Total:            money.Zero(c.config.Currency),
+		PricesIncludeTax: inclusive,
+	}
+
+	// Group the lines by the rate they are taxed at, in the order the rates first appear
+	type rateGroup struct {
+		rate  models.TaxRate
+		lines []int
+	}
+	var groups []*rateGroup
+	byRate := map[taxRateKey]*rateGroup{}
+	for i, line := range lines {
+		rate := c.rate(zone, line.TaxClassID)
+		key := taxRateKey{name: rate.Name, rate: rate.Rate}
+		group, ok := byRate[key]
+		if !ok {
+			group = &rateGroup{rate: rate}
+			byRate[key] = group
+			groups = append(groups, group)
+		}
+		group.lines = append(group.lines, i)
+	}
+
+	for _, group := range groups {
+		taxable := money.Zero(c.config.Currency)
+		ratios := make([]int64, len(group.lines))
+		for j, i := range group.lines {
+			taxable = taxable.Add(lines[i].Amount)
+			ratios[j] = lines[i].Amount.Amount
+		}
+
+		amounts := make([]money.Money, len(group.lines))
+		if c.config.Rounding == models.TaxRoundOrder {
+			amounts = taxOn(taxable, group.rate.Rate, inclusive).Allocate(ratios...)
+		} else {
+			for j, i := range group.lines {
+				amounts[j] = taxOn(lines[i].Amount, group.rate.Rate, inclusive)
+			}
+		}
+
+		amount := money.Zero(c.config.Currency)
+		for j, i := range group.lines {
+			breakdown.Lines[i] = LineTax{Name: group.rate.Name, Rate: group.rate.Rate, Amount: amounts[j]}
+			amount = amount.Add(amounts[j])
+		}
+		if group.rate.Rate == 0 {
+			continue
+		}
+		if inclusive {
+			taxable = taxable.Sub(amount)
+		}
+		breakdown.Summary = append(breakdown.Summary, TaxSummaryLine{
+			Name:    group.rate.Name,
+			Rate:    group.rate.Rate,
+			Taxable: taxable,
+			Amount:  amount,
+		})
+		breakdown.Total = breakdown.Total.Add(amount)
+	}
+	return breakdown, nil
+}
+
+// zone returns the tax zone an address is in, or nil when none covers it. Of the
+// zones that cover it, the one with the highest priority wins; at equal priority a
+// zone narrowed down to postal codes beats one narrowed down to a region, which
+// beats one for the whole country.
+func (c *DefaultTaxCalculator) zone(address models.Address) (*models.TaxZone, error) {
+	if address.Country == "" {
+		return nil, nil
+	}
+	zones, err := c.repo.ZonesForCountry(address.Country)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *models.TaxZone
+	for i := range zones {
+		zone := &zones[i]
+		if !areaCovers(zone.Region, zone.PostalPattern, address) {
+			continue
+		}
+		if best == nil || zone.Priority > best.Priority || zone.Priority == best.Priority &&
+			areaSpecificity(zone.Region, zone.PostalPattern) > areaSpecificity(best.Region, best.PostalPattern) {
+			best = zone
+		}
+	}
+	return best, nil
+}
+
+// rate returns the rate the products of a tax class are taxed at in a zone, or at
+// the default rate when there is no zone
+func (c *DefaultTaxCalculator) rate(zone *models.TaxZone, classID uint) models.TaxRate {
+	if zone == nil {
+		return models.TaxRate{Name: "Tax", Rate: c.config.DefaultRate}
+	}
+	standard := models.TaxRate{Name: zone.Name}
+	for _, rate := range zone.Rates {
+		if rate.TaxClassID == classID {
+			return rate
+		}
+		if rate.TaxClassID == 0 {
+			standard = rate
+		}
+	}
+	return standard
+}
+
+// taxOn returns the tax at a rate on an amount that excludes it, or the part of an
+// amount that includes it
+func taxOn(amount money.Money, rate float64, inclusive bool) money.Money {
+	if inclusive {
+		return amount.MulRate(rate / (1 + rate))
+	}
+	return amount.MulRate(rate)
+}
+
+// areaCovers reports whether a normalized address lies in the part of its country a
+// tax or shipping zone is narrowed down to. Empty region and postal pattern cover
+// the whole country.
+func areaCovers(region, postalPattern string, address models.Address) bool {
+	if region != "" && region != address.Region {
+		return false
+	}
+	if postalPattern != "" {
+		matched, err := path.Match(postalPattern, address.PostalCode)
+		return err == nil && matched
+	}
+	return true
+}
+
+// areaSpecificity ranks how narrowly a tax or shipping zone is drawn within its country
+func areaSpecificity(region, postalPattern string) int {
+	specificity := 0
+	if postalPattern != "" {
+		specificity += 2
+	}
+	if region != "" {
+		specificity++
+	}
+	return specificity
+}
+
+// normalizeAddress trims the parts of an address and upper-cases the codes in it,
+// the way tax and shipping zones store them
+func normalizeAddress(address models.Address) models.Address {
+	return models.Address{
+		Name:       strings.TrimSpace(address.Name),
+		Line1:      strings.TrimSpace(address.Line1),
+		Line2:      strings.TrimSpace(address.Line2),
+		City:       strings.TrimSpace(address.City),
+		Region:     strings.ToUpper(strings.TrimSpace(address.Region)),
+		PostalCode: strings.ToUpper(strings.TrimSpace(address.PostalCode)),
+		Country:    strings.ToUpper(strings.TrimSpace(address.Country)),
+	}
+}
+
+// validCountry reports whether a normalized country is a two-letter code
+func validCountry(country string) bool {
+	if len(country) != 2 {
+		return false
+	}
+	for _, r := range country {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// TaxClassInput is the data of a tax class to create or update
+type TaxClassInput struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// TaxZoneInput is the data of a tax zone to create or update. Its rates replace the
+// ones the zone had.
+type TaxZoneInput struct {
+	Name          string         `json:"name"`
+	Country       string         `json:"country"`
+	Region        string         `json:"region"`         // Empty for the whole country
+	PostalPattern string         `json:"postal_pattern"` // Glob such as 90*; empty for every postal code
+	Priority      int            `json:"priority"`
+	Rates         []TaxRateInput `json:"rates"`
+}
+
+// TaxRateInput is the rate at which a zone taxes the products of a class
+type TaxRateInput struct {
+	TaxClassID uint    `json:"tax_class_id"` // Zero for the standard rate
+	Name       string  `json:"name"`
+	Rate       float64 `json:"rate"` // Fraction, e.g. 0.2 for 20%
+}
+
+// TaxService defines the interface for the admin management of tax classes and zones
+type TaxService interface {
+	ListClasses() ([]models.TaxClass, error)
+	CreateClass(input TaxClassInput) (*models.TaxClass, error)
+	UpdateClass(id uint, input TaxClassInput) (*models.TaxClass, error)
+	DeleteClass(id uint) error
+	SetProductClass(productID uint, classID *uint) error
+	ListZones(page, pageSize int) ([]models.TaxZone, int64, error)
+	GetZone(id uint) (*models.TaxZone, error)
+	CreateZone(input TaxZoneInput) (*models.TaxZone, error)
+	UpdateZone(id uint, input TaxZoneInput) (*models.TaxZone, error)
+	DeleteZone(id uint) error
+}
+
+// DefaultTaxService implements TaxService
+type DefaultTaxService struct {
+	repo repository.TaxRepository
+}
+
+// NewTaxService creates a new instance of DefaultTaxService
+func NewTaxService(repo repository.TaxRepository) TaxService {
+	return &DefaultTaxService{
+		repo: repo,
+	}
+}
+
+// ListClasses retrieves every tax class by code
+func (s *DefaultTaxService) ListClasses() ([]models.TaxClass, error) {
+	return s.repo.ListClasses()
+}
+
+// CreateClass validates and stores a new tax class
+func (s *DefaultTaxService) CreateClass(input TaxClassInput) (*models.TaxClass, error) {
+	class := &models.TaxClass{}
+	if err := s.applyClass(class, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateClass(class); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+// UpdateClass validates and replaces the data of an existing tax class
+func (s *DefaultTaxService) UpdateClass(id uint, input TaxClassInput) (*models.TaxClass, error) {
+	class, err := s.class(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyClass(class, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateClass(class); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+// DeleteClass removes a tax class with its rates. Its products go back to the
+// standard rate.
+func (s *DefaultTaxService) DeleteClass(id uint) error {
+	err := s.repo.DeleteClass(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrTaxClassNotFound
+	}
+	return err
+}
+
+// SetProductClass puts a product in a tax class, or back on the standard rate when
+// classID is nil
+func (s *DefaultTaxService) SetProductClass(productID uint, classID *uint) error {
+	if classID != nil {
+		if _, err := s.class(*classID); err != nil {
+			return err
+		}
+	}
+	err := s.repo.SetProductClass(productID, classID)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrProductNotFound
+	}
+	return err
+}
+
+// ListZones retrieves one page of the tax zones with their rates
+func (s *DefaultTaxService) ListZones(page, pageSize int) ([]models.TaxZone, int64, error) {
+	return s.repo.ListZones(page, pageSize)
+}
+
+// GetZone retrieves a tax zone with its rates
+func (s *DefaultTaxService) GetZone(id uint) (*models.TaxZone, error) {
+	zone, err := s.repo.FindZone(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrTaxZoneNotFound
+	}
+	return zone, err
+}
+
+// CreateZone validates and stores a new tax zone
+func (s *DefaultTaxService) CreateZone(input TaxZoneInput) (*models.TaxZone, error) {
+	zone := &models.TaxZone{}
+	if err := s.applyZone(zone, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateZone(zone); err != nil {
+		return nil, err
+	}
+	return s.GetZone(zone.ID)
+}
+
+// UpdateZone validates and replaces the data and rates of an existing tax zone
+func (s *DefaultTaxService) UpdateZone(id uint, input TaxZoneInput) (*models.TaxZone, error) {
+	zone, err := s.GetZone(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyZone(zone, input); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateZone(zone); err != nil {
+		return nil, err
+	}
+	return s.GetZone(id)
+}
+
+// DeleteZone removes a tax zone with its rates
+func (s *DefaultTaxService) DeleteZone(id uint) error {
+	err := s.repo.DeleteZone(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrTaxZoneNotFound
+	}
+	return err
+}
+
+// class retrieves a tax class by its ID
+func (s *DefaultTaxService) class(id uint) (*models.TaxClass, error) {
+	class, err := s.repo.FindClass(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrTaxClassNotFound
+	}
+	return class, err
+}
+
+// applyClass validates the input and copies it onto the tax class. Codes are kept
+// in lower case.
+func (s *DefaultTaxService) applyClass(class *models.TaxClass, input TaxClassInput) error {
+	code := strings.ToLower(strings.TrimSpace(input.Code))
+	if code == "" || len(code) > 50 {
+		return fmt.Errorf("%w: code must be 1 to 50 characters", ErrInvalidTaxClass)
+	}
+	for _, r := range code {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return fmt.Errorf("%w: code may only hold letters, digits, dashes and underscores", ErrInvalidTaxClass)
+		}
+	}
+	existing, err := s.repo.FindClassByCode(code)
+	if err == nil && existing.ID != class.ID {
+		return ErrTaxClassCodeTaken
+	}
+	if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		return err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return fmt.Errorf("%w: name must be 1 to 100 characters", ErrInvalidTaxClass)
+	}
+
+	class.Code = code
+	class.Name = name
+	return nil
+}
+
+// applyZone validates the input and copies it onto the tax zone. Country, region and
+// postal pattern are kept in upper case, as addresses are matched against them.
+// Every rate must be below 100%, name an existing tax class and be the only one of
+// the zone for its class.
+func (s *DefaultTaxService) applyZone(zone *models.TaxZone, input TaxZoneInput) error {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return fmt.Errorf("%w: name must be 1 to 100 characters", ErrInvalidTaxZone)
+	}
+	area := normalizeAddress(models.Address{Country: input.Country, Region: input.Region, PostalCode: input.PostalPattern})
+	if !validCountry(area.Country) {
+		return fmt.Errorf("%w: country must be a two-letter code", ErrInvalidTaxZone)
+	}
+	if utf8.RuneCountInString(area.Region) > 100 {
+		return fmt.Errorf("%w: region must be at most 100 characters", ErrInvalidTaxZone)
+	}
+	if len(area.PostalCode) > 50 {
+		return fmt.Errorf("%w: postal_pattern must be at most 50 characters", ErrInvalidTaxZone)
+	}
+	if _, err := path.Match(area.PostalCode, ""); err != nil {
+		return fmt.Errorf("%w: postal_pattern is malformed", ErrInvalidTaxZone)
+	}
+
+	seen := make(map[uint]bool, len(input.Rates))
+	rates := make([]models.TaxRate, 0, len(input.Rates))
+	for _, rate := range input.Rates {
+		if seen[rate.TaxClassID] {
+			return fmt.Errorf("%w: more than one rate for tax class %d", ErrInvalidTaxZone, rate.TaxClassID)
+		}
+		seen[rate.TaxClassID] = true
+		if rate.TaxClassID != 0 {
+			if _, err := s.class(rate.TaxClassID); err != nil {
+				if errors.Is(err, ErrTaxClassNotFound) {
+					return fmt.Errorf("%w: tax class %d does not exist", ErrInvalidTaxZone, rate.TaxClassID)
+				}
+				return err
+			}
+		}
+		rateName := strings.TrimSpace(rate.Name)
+		if rateName == "" || utf8.RuneCountInString(rateName) > 100 {
+			return fmt.Errorf("%w: rate name must be 1 to 100 characters", ErrInvalidTaxZone)
+		}
+		if rate.Rate < 0 || rate.Rate >= 1 {
+			return fmt.Errorf("%w: rate must be at least 0 and below 1", ErrInvalidTaxZone)
+		}
+		rates = append(rates, models.TaxRate{TaxClassID: rate.TaxClassID, Name: rateName, Rate: rate.Rate})
+	}
+
+	zone.Name = name
+	zone.Country = area.Country
+	zone.Region = area.Region
+	zone.PostalPattern = area.PostalCode
+	zone.Priority = input.Priority
+	zone.Rates = rates
+	return nil
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"slices"
+	"testing"
+)
+
+// fakeTaxRepo holds the tax zones of every country
+type fakeTaxRepo struct {
+	repository.TaxRepository
+	zones []models.TaxZone
+}
+
+func (r *fakeTaxRepo) ZonesForCountry(country string) ([]models.TaxZone, error) {
+	var zones []models.TaxZone
+	for _, zone := range r.zones {
+		if zone.Country == country {
+			zones = append(zones, zone)
+		}
+	}
+	return zones, nil
+}
+
+// usTaxZones are a country-wide zone, a state with a reduced rate for class 2 and
+// an exemption for class 3, a city within that state, and a state whose zone
+// outranks the narrower city zone by priority
+var usTaxZones = []models.TaxZone{
+	{Name: "US", Country: "US", Rates: []models.TaxRate{{Name: "Sales tax", Rate: 0.05}}},
+	{Name: "California", Country: "US", Region: "CA", Rates: []models.TaxRate{
+		{Name: "CA tax", Rate: 0.0725},
+		{TaxClassID: 2, Name: "CA reduced", Rate: 0.025},
+		{TaxClassID: 3, Name: "Exempt", Rate: 0},
+	}},
+	{Name: "Los Angeles", Country: "US", Region: "CA", PostalPattern: "900*", Rates: []models.TaxRate{{Name: "LA tax", Rate: 0.095}}},
+	{Name: "New York City", Country: "US", Region: "NY", PostalPattern: "100*", Rates: []models.TaxRate{{Name: "NYC tax", Rate: 0.08875}}},
+	{Name: "New York", Country: "US", Region: "NY", Priority: 1, Rates: []models.TaxRate{{Name: "NY tax", Rate: 0.04}}},
+}
+
+func TestTaxOn(t *testing.T) {
+	tests := []struct {
+		amount    string
+		rate      float64
+		inclusive bool
+		want      string
+	}{
+		{"10.00", 0.2, false, "2.00"},
+		{"12.00", 0.2, true, "2.00"},
+		{"0.10", 0.05, false, "0.01"}, // 0.005 rounds up
+		{"0.10", 0.04, false, "0.00"},
+		{"1.00", 0.19, true, "0.16"},
+		{"19.99", 0.0725, false, "1.45"},
+		{"10.00", 0, false, "0.00"},
+		{"10.00", 0, true, "0.00"},
+	}
+	for _, tt := range tests {
+		if got := taxOn(usd(tt.amount), tt.rate, tt.inclusive); got != usd(tt.want) {
+			t.Errorf("taxOn(%s, %v, inclusive %v) = %s, want %s", tt.amount, tt.rate, tt.inclusive, got, tt.want)
+		}
+	}
+}
+
+func TestAreaCovers(t *testing.T) {
+	tests := []struct {
+		region, postalPattern string
+		address               models.Address
+		want                  bool
+	}{
+		{"", "", models.Address{Country: "US"}, true},
+		{"CA", "", models.Address{Region: "CA", Country: "US"}, true},
+		{"CA", "", models.Address{Region: "NY", Country: "US"}, false},
+		{"CA", "", models.Address{Country: "US"}, false},
+		{"", "900*", models.Address{PostalCode: "90012", Country: "US"}, true},
+		{"", "900*", models.Address{PostalCode: "94105", Country: "US"}, false},
+		{"CA", "900*", models.Address{Region: "NY", PostalCode: "90012", Country: "US"}, false},
+		{"", "SW1?", models.Address{PostalCode: "SW1A", Country: "GB"}, true},
+		{"", "[", models.Address{PostalCode: "[", Country: "GB"}, false}, // Malformed patterns match nothing
+	}
+	for _, tt := range tests {
+		if got := areaCovers(tt.region, tt.postalPattern, tt.address); got != tt.want {
+			t.Errorf("areaCovers(%q, %q, %+v) = %v, want %v", tt.region, tt.postalPattern, tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestValidCountry(t *testing.T) {
+	for country, want := range map[string]bool{"US": true, "DE": true, "us": false, "USA": false, "U": false, "": false, "U1": false} {
+		if got := validCountry(country); got != want {
+			t.Errorf("validCountry(%q) = %v, want %v", country, got, want)
+		}
+	}
+}
+
+func TestTaxZoneAndRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		address models.Address
+		classID uint
+		rate    string // Name of the rate the line is taxed at
+		amount  string // Tax on 100.00
+	}{
+		{name: "whole country", address: models.Address{Region: "TX", Country: "US"}, rate: "Sales tax", amount: "5.00"},
+		{name: "region", address: models.Address{Region: "CA", PostalCode: "94105", Country: "US"}, rate: "CA tax", amount: "7.25"},
+		{name: "normalized address", address: models.Address{Region: " ca ", Country: "us"}, rate: "CA tax", amount: "7.25"},
+		{name: "postal code beats region", address: models.Address{Region: "CA", PostalCode: "90012", Country: "US"}, rate: "LA tax", amount: "9.50"},
+		{name: "priority beats postal code", address: models.Address{Region: "NY", PostalCode: "10001", Country: "US"}, rate: "NY tax", amount: "4.00"},
+		{name: "class rate", address: models.Address{Region: "CA", Country: "US"}, classID: 2, rate: "CA reduced", amount: "2.50"},
+		{name: "exempt class", address: models.Address{Region: "CA", Country: "US"}, classID: 3, rate: "Exempt", amount: "0.00"},
+		{name: "class without a rate in the zone", address: models.Address{Region: "CA", Country: "US"}, classID: 4, rate: "CA tax", amount: "7.25"},
+		{name: "no zone", address: models.Address{Country: "DE"}, rate: "Tax", amount: "20.00"},
+		{name: "store address until the shopper gives theirs", address: models.Address{}, rate: "Sales tax", amount: "5.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calculator := NewTaxCalculator(&fakeTaxRepo{zones: usTaxZones}, TaxConfig{
+				Currency:    "USD",
+				PriceMode:   models.TaxExclusive,
+				Rounding:    models.TaxRoundLine,
+				DefaultRate: 0.2,
+				Origin:      models.Address{Region: "TX", Country: "US"},
+			})
+			breakdown, err := calculator.Calculate([]TaxableLine{{TaxClassID: tt.classID, Amount: usd("100.00")}}, tt.address)
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+			line := breakdown.Lines[0]
+			if line.Name != tt.rate || line.Amount != usd(tt.amount) {
+				t.Errorf("line taxed %s at %q, want %s at %q", line.Amount, line.Name, tt.amount, tt.rate)
+			}
+			if breakdown.Total != usd(tt.amount) {
+				t.Errorf("total = %s, want %s", breakdown.Total, tt.amount)
+			}
+			// Rates of zero are left out of the summary
+			if want := usd(tt.amount).IsPositive(); (len(breakdown.Summary) == 1) != want {
+				t.Errorf("summary = %+v, want a line %v", breakdown.Summary, want)
+			}
+		})
+	}
+}
+
+func TestTaxRounding(t *testing.T) {
+	tests := []struct {
+		name      string
+		priceMode string
+		rounding  string
+		rate      float64
+		amounts   []string
+		lines     []string
+		total     string
+		taxable   string
+	}{
+		{
+			name:      "exclusive per line",
+			priceMode: models.TaxExclusive,
+			rounding:  models.TaxRoundLine,
+			rate:      0.1,
+			amounts:   []string{"0.05", "0.05", "0.05"},
+			lines:     []string{"0.01", "0.01", "0.01"},
+			total:     "0.03",
+			taxable:   "0.15",
+		},
+		{
+			name:      "exclusive per order",
+			priceMode: models.TaxExclusive,
+			rounding:  models.TaxRoundOrder,
+			rate:      0.1,
+			amounts:   []string{"0.05", "0.05", "0.05"},
+			lines:     []string{"0.01", "0.01", "0.00"},
+			total:     "0.02",
+			taxable:   "0.15",
+		},
+		{
+			name:      "exclusive per order in proportion to the lines",
+			priceMode: models.TaxExclusive,
+			rounding:  models.TaxRoundOrder,
+			rate:      0.2,
+			amounts:   []string{"30.00", "10.00"},
+			lines:     []string{"6.00", "2.00"},
+			total:     "8.00",
+			taxable:   "40.00",
+		},
+		{
+			name:      "inclusive per line",
+			priceMode: models.TaxInclusive,
+			rounding:  models.TaxRoundLine,
+			rate:      0.2,
+			amounts:   []string{"0.33", "0.33", "0.33"},
+			lines:     []string{"0.06", "0.06", "0.06"},
+			total:     "0.18",
+			taxable:   "0.81",
+		},
+		{
+			name:      "inclusive per order",
+			priceMode: models.TaxInclusive,
+			rounding:  models.TaxRoundOrder,
+			rate:      0.2,
+			amounts:   []string{"0.33", "0.33", "0.33"},
+			lines:     []string{"0.06", "0.06", "0.05"},
+			total:     "0.17",
+			taxable:   "0.82",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calculator := NewTaxCalculator(&fakeTaxRepo{}, TaxConfig{
+				Currency:    "USD",
+				PriceMode:   tt.priceMode,
+				Rounding:    tt.rounding,
+				DefaultRate: tt.rate,
+			})
+			lines := make([]TaxableLine, len(tt.amounts))
+			for i, amount := range tt.amounts {
+				lines[i] = TaxableLine{Amount: usd(amount)}
+			}
+
+			breakdown, err := calculator.Calculate(lines, models.Address{Country: "DE"})
+			if err != nil {
+				t.Fatalf("Calculate() error = %v", err)
+			}
+			got := make([]string, len(breakdown.Lines))
+			for i, line := range breakdown.Lines {
+				got[i] = line.Amount.Decimal()
+			}
+			if !slices.Equal(got, tt.lines) {
+				t.Errorf("line taxes = %v, want %v", got, tt.lines)
+			}
+			if breakdown.Total != usd(tt.total) {
+				t.Errorf("total = %s, want %s", breakdown.Total, tt.total)
+			}
+			if len(breakdown.Summary) != 1 || breakdown.Summary[0].Taxable != usd(tt.taxable) || breakdown.Summary[0].Amount != usd(tt.total) {
+				t.Errorf("summary = %+v, want %s taxed %s", breakdown.Summary, tt.taxable, tt.total)
+			}
+			if breakdown.PricesIncludeTax != (tt.priceMode == models.TaxInclusive) {
+				t.Errorf("prices include tax = %v, want %v", breakdown.PricesIncludeTax, !breakdown.PricesIncludeTax)
+			}
+		})
+	}
+}
+
+func TestTaxRatesSummarizedSeparately(t *testing.T) {
+	calculator := NewTaxCalculator(&fakeTaxRepo{zones: usTaxZones}, TaxConfig{
+		Currency:  "USD",
+		PriceMode: models.TaxExclusive,
+		Rounding:  models.TaxRoundOrder,
+	})
+	lines := []TaxableLine{
+		{Amount: usd("10.00")},
+		{TaxClassID: 2, Amount: usd("20.00")},
+		{TaxClassID: 3, Amount: usd("5.00")},
+		{TaxClassID: 4, Amount: usd("30.00")},
+	}
+	breakdown, err := calculator.Calculate(lines, models.Address{Region: "CA", Country: "US"})
+	if err != nil {
+		t.Fatalf("Calculate() error = %v", err)
+	}
+
+	var got []string
+	for _, line := range breakdown.Summary {
+		got = append(got, line.Name+" "+line.Taxable.Decimal()+" "+line.Amount.Decimal())
+	}
+	want := []string{"CA tax 40.00 2.90", "CA reduced 20.00 0.50"}
+	if !slices.Equal(got, want) {
+		t.Errorf("summary = %v, want %v", got, want)
+	}
+	if breakdown.Total != usd("3.40") {
+		t.Errorf("total = %s, want 3.40", breakdown.Total)
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/logger"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kinds of records kept in the trash
+const (
+	TrashProducts = "products"
+	TrashUsers    = "users"
+	TrashOrders   = "orders"
+)
+
+// purgeBatchSize limits the rows of each kind removed by a single purge run
+const purgeBatchSize = 500
+
+var (
+	// ErrUnknownTrashKind is returned for a trash kind other than products, users or orders
+	ErrUnknownTrashKind = errors.New("unknown trash kind")
+	// ErrTrashItemNotFound is returned when restoring a record that is not in the trash
+	ErrTrashItemNotFound = errors.New("item not found in trash")
+)
+
+// TrashedUser is a user in the trash, without credentials
+type TrashedUser struct {
+	ID        uint      `json:"id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// PurgeReport lists the records removed for good by a purge run, and the ones
+// kept because other records still refer to them
+type PurgeReport struct {
+	Orders       []uint `json:"orders"`
+	Products     []uint `json:"products"`
+	Users        []uint `json:"users"`
+	KeptProducts []uint `json:"kept_products"`
+	KeptUsers    []uint `json:"kept_users"`
+}
+
+// TrashService defines the interface for browsing, restoring and purging deleted records
+type TrashService interface {
+	ListTrash(kind string, page, pageSize int) (interface{}, int64, error)
+	Restore(kind string, id uint) error
+	Purge(retention time.Duration) (*PurgeReport, error)
+}
+
+// DefaultTrashService implements TrashService
+type DefaultTrashService struct {
+	productRepo    repository.ProductRepository
+	userRepo       repository.UserRepository
+	orderRepo      repository.OrderRepository
+	productService ProductService
+	mediaService   MediaService
+	log            *logger.Logger
+}
+
+// NewTrashService creates a new instance of DefaultTrashService. The product
+// service returns restored products to the search index, and
+// the media service removes the image files of purged products.
+func NewTrashService(productRepo repository.ProductRepository, userRepo repository.UserRepository,
+	orderRepo repository.OrderRepository, productService ProductService, mediaService MediaService) TrashService {
+	return &DefaultTrashService{
+		productRepo:    productRepo,
+		userRepo:       userRepo,
+		orderRepo:      orderRepo,
+		productService: productService,
+		mediaService:   mediaService,
+		log:            logger.New(),
+	}
+}
+
+// ListTrash retrieves one page of the records of a kind in the trash, most
+// recently deleted first, along with their total number
+func (s *DefaultTrashService) ListTrash(kind string, page, pageSize int) (interface{}, int64, error) {
+	switch kind {
+	case TrashProducts:
+		return s.productRepo.ListDeleted(page, pageSize)
+	case TrashOrders:
+		return s.orderRepo.ListDeleted(page, pageSize)
+	case TrashUsers:
+		users, total, err := s.userRepo.ListDeleted(page, pageSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		trashed := make([]TrashedUser, len(users))
+		for i, user := range users {
+			trashed[i] = TrashedUser{
+				ID:        user.ID,
+				Email:     user.Email,
+				CreatedAt: user.CreatedAt,
+				DeletedAt: user.DeletedAt.Time,
+			}
+		}
+		return trashed, total, nil
+	}
+	return nil, 0, fmt.Errorf("%w: %q", ErrUnknownTrashKind, kind)
+}
+
+// Restore takes a record out of the trash. Restored products return to the
+// search index.
+func (s *DefaultTrashService) Restore(kind string, id uint) error {
+	var err error
+	switch kind {
+	case TrashProducts:
+		err = s.productRepo.Restore(id)
+	case TrashOrders:
+		err = s.orderRepo.Restore(id)
+	case TrashUsers:
+		err = s.userRepo.Restore(id)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownTrashKind, kind)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrTrashItemNotFound
+		}
+		return err
+	}
+
+	if kind == TrashProducts {
+		if err := s.productService.ReindexProduct(id); err != nil {
+			s.log.Error("Failed to index restored product: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// Purge permanently removes the records that have been in the trash for longer
+// than retention. Orders go first so that the products and users they referred
+// to can be purged in the same run; products still referenced by orders outside
+// the trash, and users who still have orders, are kept.
+func (s *DefaultTrashService) Purge(retention time.Duration) (*PurgeReport, error) {
+	before := time.Now().Add(-retention)
+	report := &PurgeReport{}
+
+	orders, err := s.orderRepo.PurgeDeleted(before, purgeBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("purge orders: %w", err)
+	}
+	report.Orders = orders.Purged
+
+	products, images, err := s.productRepo.PurgeDeleted(before, purgeBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("purge products: %w", err)
+	}
+	report.Products = products.Purged
+	report.KeptProducts = products.Kept
+	s.mediaService.RemoveImageFiles(images)
+
+	users, err := s.userRepo.PurgeDeleted(before, purgeBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("purge users: %w", err)
+	}
+	report.Users = users.Purged
+	report.KeptUsers = users.Kept
+
+	return report, nil
+}
@@ -3,12 +3,17 @@ package service
 import (
     "ecommerce-app/internal/models"
     "ecommerce-app/internal/repository"
+    "errors"
 )
 
+// ErrUserNotFound is returned when a user does not exist
+var ErrUserNotFound = errors.New("user not found")
+
 // UserService defines the interface for user-related business logic
 type UserService interface {
     GetUserByID(id uint) (*models.User, error)
     GetUserByEmail(email string) (*models.User, error)
+    GetDeletedUserByEmail(email string) (*models.User, error)
     GetAllUsers() ([]models.User, error)
     GetUsersPaginated(page, pageSize int) ([]models.User, error)
     CreateUser(user *models.User) error
@@ -39,6 +44,11 @@ func (s *DefaultUserService) GetUserByEmail(email string) (*models.User, error)
     return s.repo.FindByEmail(email)
 }
 
+// GetDeletedUserByEmail retrieves the user in the trash with the given email
+func (s *DefaultUserService) GetDeletedUserByEmail(email string) (*models.User, error) {
+    return s.repo.FindDeletedByEmail(email)
+}
+
 // GetAllUsers retrieves all users
 func (s *DefaultUserService) GetAllUsers() ([]models.User, error) {
     return s.repo.List()
@@ -59,9 +69,15 @@ func (s *DefaultUserService) UpdateUser(user *models.User) error {
     return s.repo.Update(user)
 }
 
-// DeleteUser deletes a user by its ID
+// DeleteUser moves a user to the trash
 func (s *DefaultUserService) DeleteUser(id uint) error {
-    return s.repo.Delete(id)
+    if err := s.repo.Delete(id); err != nil {
+        if errors.Is(err, repository.ErrRecordNotFound) {
+            return ErrUserNotFound
+        }
+        return err
+    }
+    return nil
 }
 
 // CountUsers returns the total number of users
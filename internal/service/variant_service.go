@@ -0,0 +1,422 @@
+package service
+
+import (
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/logger"
+	"ecommerce-app/pkg/money"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrVariantNotFound is returned when a referenced variant does not exist or belongs to another product
+	ErrVariantNotFound = errors.New("variant not found")
+	// ErrVariantRequired is returned when a product with variants is referenced without one
+	ErrVariantRequired = errors.New("a variant must be chosen for this product")
+	// ErrInvalidVariantOptions is returned when options or variant data fail validation
+	ErrInvalidVariantOptions = errors.New("invalid variant options")
+	// ErrSKUTaken is returned when another variant already uses a SKU
+	ErrSKUTaken = errors.New("SKU already in use")
+)
+
+// maxVariants caps the size of a generated variant matrix
+const maxVariants = 100
+
+// OptionInput is an option and its values as entered by an admin
+type OptionInput struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// GenerateVariantsInput describes the variant matrix to generate for a product. Price
+// and Stock only apply to newly created variants; existing combinations keep theirs.
+type GenerateVariantsInput struct {
+	Options   []OptionInput `json:"options"`
+	SKUPrefix string        `json:"sku_prefix"`
+	Price     *money.Money  `json:"price"`
+	Stock     int           `json:"stock"`
+}
+
+// VariantUpdate holds the editable fields of a variant. A nil Price removes the override.
+type VariantUpdate struct {
+	SKU     string       `json:"sku"`
+	Barcode string       `json:"barcode"`
+	Price   *money.Money `json:"price"`
+	Stock   int          `json:"stock"`
+}
+
+// OptionSummary lists the values of a product option
+type OptionSummary struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// VariantAvailability is the price and stock of one option combination
+type VariantAvailability struct {
+	ID          uint               `json:"id"`
+	SKU         string             `json:"sku"`
+	Title       string             `json:"title"`
+	Options     map[string]string  `json:"options"`
+	Price       money.Money        `json:"price"`
+	GroupPrices []models.TierPrice `json:"group_prices,omitempty"` // Tiers of the shopper's customer group
+	Stock       int                `json:"stock"`                  // Units not held by pending orders
+	Available   bool               `json:"available"`
+}
+
+// ProductAvailability lists the options of a product and the availability of every combination
+type ProductAvailability struct {
+	Options  []OptionSummary       `json:"options"`
+	Variants []VariantAvailability `json:"variants"`
+}
+
+// VariantService defines the interface for product option and variant business logic
+type VariantService interface {
+	GetVariants(productID uint) ([]models.ProductVariant, error)
+	GetAvailability(productID, userID uint) (*ProductAvailability, error)
+	GenerateVariants(productID uint, input GenerateVariantsInput) ([]models.ProductVariant, error)
+	UpdateVariant(id uint, update VariantUpdate) (*models.ProductVariant, error)
+}
+
+// DefaultVariantService implements VariantService
+type DefaultVariantService struct {
+	repo          repository.VariantRepository
+	productRepo   repository.ProductRepository
+	priceListRepo repository.PriceListRepository
+	indexer       ProductIndexer
+	log           *logger.Logger
+}
+
+// NewVariantService creates a new instance of DefaultVariantService. The indexer is
+// told about stock changes that affect product search documents.
+func NewVariantService(repo repository.VariantRepository, productRepo repository.ProductRepository,
+	priceListRepo repository.PriceListRepository, indexer ProductIndexer) VariantService {
+	return &DefaultVariantService{
+		repo:          repo,
+		productRepo:   productRepo,
+		priceListRepo: priceListRepo,
+		indexer:       indexer,
+		log:           logger.New(),
+	}
+}
+
+// GetVariants retrieves the variants of a product
+func (s *DefaultVariantService) GetVariants(productID uint) ([]models.ProductVariant, error) {
+	if _, err := s.findProduct(productID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByProduct(productID)
+}
+
+// GetAvailability lists the options of a product and the price and stock of each
+// combination, with the quantity tiers of the user's customer group. Guests take zero.
+func (s *DefaultVariantService) GetAvailability(productID, userID uint) (*ProductAvailability, error) {
+	product, err := s.findProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := s.repo.ListOptions(productID)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := s.repo.ListByProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := loadGroupPrices(s.priceListRepo, userID, []uint{productID})
+	if err != nil {
+		return nil, err
+	}
+
+	availability := &ProductAvailability{
+		Options:  make([]OptionSummary, 0, len(options)),
+		Variants: make([]VariantAvailability, 0, len(variants)),
+	}
+	optionNames := map[uint]string{}
+	for _, option := range options {
+		summary := OptionSummary{Name: option.Name, Values: make([]string, 0, len(option.Values))}
+		for _, value := range option.Values {
+			summary.Values = append(summary.Values, value.Value)
+			optionNames[value.ID] = option.Name
+		}
+		availability.Options = append(availability.Options, summary)
+	}
+
+	for i, variant := range variants {
+		combination := make(map[string]string, len(variant.OptionValues))
+		for _, value := range variant.OptionValues {
+			combination[optionNames[value.ID]] = value.Value
+		}
+		availability.Variants = append(availability.Variants, VariantAvailability{
+			ID:          variant.ID,
+			SKU:         variant.SKU,
+			Title:       variant.Title,
+			Options:     combination,
+			Price:       variant.UnitPrice(*product),
+			GroupPrices: prices.Tiers(*product, &variants[i]),
+			Stock:       variant.Available,
+			Available:   variant.Available > 0,
+		})
+	}
+	return availability, nil
+}
+
+// GenerateVariants replaces the options of a product and creates a variant for every
+// combination of their values. Combinations that already exist keep their variant,
+// including its SKU, price and stock; variants of dropped combinations are deleted.
+// An empty option list turns the product back into a product without variants.
+func (s *DefaultVariantService) GenerateVariants(productID uint, input GenerateVariantsInput) ([]models.ProductVariant, error) {
+	if _, err := s.findProduct(productID); err != nil {
+		return nil, err
+	}
+	if err := validateOptions(input.Options); err != nil {
+		return nil, err
+	}
+	if input.Stock < 0 {
+		return nil, fmt.Errorf("%w: stock must not be negative", ErrInvalidVariantOptions)
+	}
+	if input.Price != nil {
+		if err := checkVariantPrice(input.Price); err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := s.existingCombinations(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.TrimSpace(input.SKUPrefix)
+	if prefix == "" {
+		prefix = fmt.Sprintf("P%d", productID)
+	}
+
+	matrix := repository.VariantMatrix{Options: make([]models.ProductOption, len(input.Options))}
+	for i, option := range input.Options {
+		matrix.Options[i].Name = strings.TrimSpace(option.Name)
+		for _, value := range option.Values {
+			matrix.Options[i].Values = append(matrix.Options[i].Values, models.ProductOptionValue{Value: strings.TrimSpace(value)})
+		}
+	}
+
+	kept := map[uint]bool{}
+	skus := map[string]bool{}
+	for _, combination := range combinations(input.Options) {
+		names := make([]string, len(combination))
+		keyParts := make([]string, len(combination))
+		skuParts := []string{prefix}
+		for i, index := range combination {
+			value := matrix.Options[i].Values[index].Value
+			names[i] = value
+			keyParts[i] = combinationKey(matrix.Options[i].Name, value)
+			skuParts = append(skuParts, strings.ToUpper(slugify(value)))
+		}
+
+		slices.Sort(keyParts)
+		variant, ok := existing[strings.Join(keyParts, "|")]
+		if ok {
+			kept[variant.ID] = true
+		} else {
+			variant = models.ProductVariant{
+				SKU:   strings.Join(skuParts, "-"),
+				Price: input.Price,
+				Stock: input.Stock,
+			}
+		}
+		variant.Title = strings.Join(names, " / ")
+
+		if skus[variant.SKU] {
+			return nil, fmt.Errorf("%w: %s is generated twice, use distinct option values", ErrSKUTaken, variant.SKU)
+		}
+		skus[variant.SKU] = true
+		matrix.Variants = append(matrix.Variants, repository.MatrixVariant{Variant: variant, Values: combination})
+	}
+
+	// A generated SKU may only collide with a variant of this product that is being removed
+	for _, entry := range matrix.Variants {
+		if entry.Variant.ID != 0 {
+			continue
+		}
+		other, err := s.repo.FindBySKU(entry.Variant.SKU)
+		if err == nil && (other.ProductID != productID || kept[other.ID]) {
+			return nil, fmt.Errorf("%w: %s", ErrSKUTaken, entry.Variant.SKU)
+		} else if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	variants, err := s.repo.ReplaceMatrix(productID, matrix)
+	if err != nil {
+		return nil, err
+	}
+	s.reindex(productID)
+	return variants, nil
+}
+
+// UpdateVariant updates the SKU, barcode, price override and stock of a variant
+func (s *DefaultVariantService) UpdateVariant(id uint, update VariantUpdate) (*models.ProductVariant, error) {
+	variant, err := s.repo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+
+	update.SKU = strings.TrimSpace(update.SKU)
+	if update.SKU == "" {
+		return nil, fmt.Errorf("%w: SKU is required", ErrInvalidVariantOptions)
+	}
+	if update.Stock < 0 {
+		return nil, fmt.Errorf("%w: stock must not be negative", ErrInvalidVariantOptions)
+	}
+	if update.Price != nil {
+		if err := checkVariantPrice(update.Price); err != nil {
+			return nil, err
+		}
+	}
+
+	other, err := s.repo.FindBySKU(update.SKU)
+	if err == nil && other.ID != variant.ID {
+		return nil, fmt.Errorf("%w: %s", ErrSKUTaken, update.SKU)
+	} else if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	variant.SKU = update.SKU
+	variant.Barcode = strings.TrimSpace(update.Barcode)
+	variant.Price = update.Price
+	variant.Stock = update.Stock
+	if err := s.repo.Update(variant); err != nil {
+		return nil, err
+	}
+	s.reindex(variant.ProductID)
+	return variant, nil
+}
+
+// findProduct loads a product, mapping a missing row to ErrProductNotFound
+func (s *DefaultVariantService) findProduct(productID uint) (*models.Product, error) {
+	product, err := s.productRepo.FindByID(productID)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrProductNotFound
+	}
+	return product, err
+}
+
+// existingCombinations maps the combination key of each current variant of a product to the variant
+func (s *DefaultVariantService) existingCombinations(productID uint) (map[string]models.ProductVariant, error) {
+	options, err := s.repo.ListOptions(productID)
+	if err != nil {
+		return nil, err
+	}
+	variants, err := s.repo.ListByProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[uint]string{}
+	for _, option := range options {
+		for _, value := range option.Values {
+			keys[value.ID] = combinationKey(option.Name, value.Value)
+		}
+	}
+
+	existing := make(map[string]models.ProductVariant, len(variants))
+	for _, variant := range variants {
+		parts := make([]string, 0, len(variant.OptionValues))
+		for _, value := range variant.OptionValues {
+			parts = append(parts, keys[value.ID])
+		}
+		// Sorted parts make the key independent of the order options are listed in
+		slices.Sort(parts)
+		variant.OptionValues = nil
+		existing[strings.Join(parts, "|")] = variant
+	}
+	return existing, nil
+}
+
+// reindex refreshes the search document of a product after its stock changed. The
+// change has already been saved, so failures are logged rather than returned.
+func (s *DefaultVariantService) reindex(productID uint) {
+	if err := s.indexer.ReindexProduct(productID); err != nil {
+		s.log.Error("Failed to update search index: " + err.Error())
+	}
+}
+
+// validateOptions checks that options and their values are named and unique
+func validateOptions(options []OptionInput) error {
+	count := 1
+	names := map[string]bool{}
+	for _, option := range options {
+		name := strings.ToLower(strings.TrimSpace(option.Name))
+		if name == "" {
+			return fmt.Errorf("%w: option name is required", ErrInvalidVariantOptions)
+		}
+		if names[name] {
+			return fmt.Errorf("%w: duplicate option %q", ErrInvalidVariantOptions, option.Name)
+		}
+		names[name] = true
+
+		if len(option.Values) == 0 {
+			return fmt.Errorf("%w: option %q has no values", ErrInvalidVariantOptions, option.Name)
+		}
+		values := map[string]bool{}
+		for _, value := range option.Values {
+			key := strings.ToLower(strings.TrimSpace(value))
+			if key == "" {
+				return fmt.Errorf("%w: option %q has an empty value", ErrInvalidVariantOptions, option.Name)
+			}
+			if values[key] {
+				return fmt.Errorf("%w: duplicate value %q for option %q", ErrInvalidVariantOptions, value, option.Name)
+			}
+			values[key] = true
+		}
+
+		count *= len(option.Values)
+		if count > maxVariants {
+			return fmt.Errorf("%w: more than %d combinations", ErrInvalidVariantOptions, maxVariants)
+		}
+	}
+	return nil
+}
+
+// checkVariantPrice validates a variant price override, which must be in the store currency
+func checkVariantPrice(price *money.Money) error {
+	checked, err := storeAmount(*price, "price", ErrInvalidVariantOptions)
+	if err != nil {
+		return err
+	}
+	*price = checked
+	if price.IsNegative() {
+		return fmt.Errorf("%w: price must not be negative", ErrInvalidVariantOptions)
+	}
+	return nil
+}
+
+// combinations returns every combination of option values as value indexes, in option order
+func combinations(options []OptionInput) [][]int {
+	if len(options) == 0 {
+		return nil
+	}
+
+	result := [][]int{{}}
+	for _, option := range options {
+		next := make([][]int, 0, len(result)*len(option.Values))
+		for _, prefix := range result {
+			for i := range option.Values {
+				combination := append(append([]int{}, prefix...), i)
+				next = append(next, combination)
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// combinationKey identifies an option value independently of IDs and letter case
+func combinationKey(option, value string) string {
+	return strings.ToLower(strings.TrimSpace(option)) + "=" + strings.ToLower(strings.TrimSpace(value))
+}
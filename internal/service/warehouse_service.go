@@ -0,0 +1,339 @@
+package service
+
+import (
+	"crypto/rand"
+	"ecommerce-app/internal/models"
+	"ecommerce-app/internal/repository"
+	"ecommerce-app/pkg/logger"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrWarehouseNotFound is returned when a referenced warehouse does not exist
+	ErrWarehouseNotFound = errors.New("warehouse not found")
+	// ErrInvalidWarehouse is returned when warehouse data fails validation
+	ErrInvalidWarehouse = errors.New("invalid warehouse")
+	// ErrWarehouseCodeTaken is returned when another warehouse already uses the code
+	ErrWarehouseCodeTaken = errors.New("warehouse code already in use")
+	// ErrInvalidStockMovement is returned when a stock movement or transfer is malformed
+	ErrInvalidStockMovement = errors.New("invalid stock movement")
+	// ErrInsufficientLocationStock is returned when a warehouse does not hold the units a movement takes out
+	ErrInsufficientLocationStock = errors.New("not enough stock at the warehouse")
+)
+
+// StockMovementRequest is a movement recorded by hand. Receipts and returns bring
+// units in; adjustments correct the level either way.
+type StockMovementRequest struct {
+	WarehouseID uint   `json:"warehouse_id"`
+	ProductID   uint   `json:"product_id"`
+	VariantID   uint   `json:"variant_id"`
+	Type        string `json:"type"`
+	Quantity    int    `json:"quantity"`
+	Reference   string `json:"reference"`
+	Note        string `json:"note"`
+}
+
+// StockTransferRequest moves units of a product variant between two warehouses
+type StockTransferRequest struct {
+	FromWarehouseID uint   `json:"from_warehouse_id"`
+	ToWarehouseID   uint   `json:"to_warehouse_id"`
+	ProductID       uint   `json:"product_id"`
+	VariantID       uint   `json:"variant_id"`
+	Quantity        int    `json:"quantity"`
+	Note            string `json:"note"`
+}
+
+// ReconciliationReport lists how the stock of product variants compares with their
+// stock levels and ledger
+type ReconciliationReport struct {
+	Checked    int                            `json:"checked"`
+	Unbalanced int                            `json:"unbalanced"`
+	Rows       []repository.ReconciliationRow `json:"rows"`
+}
+
+// WarehouseService defines the interface for warehouse and stock ledger business logic
+type WarehouseService interface {
+	ListWarehouses() ([]models.Warehouse, error)
+	CreateWarehouse(warehouse *models.Warehouse) error
+	UpdateWarehouse(warehouse *models.Warehouse) error
+	GetStockLevels(warehouseID uint, page, pageSize int) ([]models.StockLevel, int64, error)
+	ListMovements(filter repository.MovementFilter, page, pageSize int) ([]models.StockMovement, int64, error)
+	RecordMovement(request StockMovementRequest, createdBy string) (*models.StockMovement, error)
+	Transfer(request StockTransferRequest, createdBy string) ([]models.StockMovement, error)
+	Reconcile(onlyUnbalanced bool) (*ReconciliationReport, error)
+}
+
+// DefaultWarehouseService implements WarehouseService
+type DefaultWarehouseService struct {
+	repo        repository.WarehouseRepository
+	productRepo repository.ProductRepository
+	variantRepo repository.VariantRepository
+	indexer     ProductIndexer
+	log         *logger.Logger
+}
+
+// NewWarehouseService creates a new instance of DefaultWarehouseService. The indexer
+// refreshes the search documents of products whose stock a movement changes.
+func NewWarehouseService(repo repository.WarehouseRepository, productRepo repository.ProductRepository,
+	variantRepo repository.VariantRepository, indexer ProductIndexer) WarehouseService {
+	return &DefaultWarehouseService{
+		repo:        repo,
+		productRepo: productRepo,
+		variantRepo: variantRepo,
+		indexer:     indexer,
+		log:         logger.New(),
+	}
+}
+
+// ListWarehouses retrieves all warehouses in priority order
+func (s *DefaultWarehouseService) ListWarehouses() ([]models.Warehouse, error) {
+	return s.repo.List()
+}
+
+// CreateWarehouse validates and stores a new warehouse
+func (s *DefaultWarehouseService) CreateWarehouse(warehouse *models.Warehouse) error {
+	if err := s.prepareWarehouse(warehouse); err != nil {
+		return err
+	}
+	return s.repo.Create(warehouse)
+}
+
+// UpdateWarehouse validates and saves changes to an existing warehouse. The last
+// active warehouse cannot be deactivated, as sales need somewhere to take stock from.
+func (s *DefaultWarehouseService) UpdateWarehouse(warehouse *models.Warehouse) error {
+	existing, err := s.getWarehouse(warehouse.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.prepareWarehouse(warehouse); err != nil {
+		return err
+	}
+
+	if existing.Active && !warehouse.Active {
+		active, err := s.repo.CountActive()
+		if err != nil {
+			return err
+		}
+		if active <= 1 {
+			return fmt.Errorf("%w: at least one warehouse must stay active", ErrInvalidWarehouse)
+		}
+	}
+
+	warehouse.CreatedAt = existing.CreatedAt
+	return s.repo.Update(warehouse)
+}
+
+// GetStockLevels retrieves one page of the stock held at a warehouse
+func (s *DefaultWarehouseService) GetStockLevels(warehouseID uint, page, pageSize int) ([]models.StockLevel, int64, error) {
+	if _, err := s.getWarehouse(warehouseID); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.ListLevels(warehouseID, page, pageSize)
+}
+
+// ListMovements retrieves one page of the stock ledger, newest first
+func (s *DefaultWarehouseService) ListMovements(filter repository.MovementFilter, page, pageSize int) ([]models.StockMovement, int64, error) {
+	return s.repo.ListMovements(filter, page, pageSize)
+}
+
+// RecordMovement appends a receipt, return or adjustment to the stock ledger and
+// applies it to the warehouse and the product's stock
+func (s *DefaultWarehouseService) RecordMovement(request StockMovementRequest, createdBy string) (*models.StockMovement, error) {
+	switch request.Type {
+	case models.MovementReceipt, models.MovementReturn:
+		if request.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: a %s must bring in a positive quantity", ErrInvalidStockMovement, request.Type)
+		}
+	case models.MovementAdjustment:
+		if request.Quantity == 0 {
+			return nil, fmt.Errorf("%w: quantity must not be zero", ErrInvalidStockMovement)
+		}
+	case models.MovementSale, models.MovementTransfer:
+		return nil, fmt.Errorf("%w: %s movements are recorded by orders and transfers", ErrInvalidStockMovement, request.Type)
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidStockMovement, request.Type)
+	}
+	if utf8.RuneCountInString(request.Reference) > 100 {
+		return nil, fmt.Errorf("%w: reference must be at most 100 characters", ErrInvalidStockMovement)
+	}
+
+	if _, err := s.getWarehouse(request.WarehouseID); err != nil {
+		return nil, err
+	}
+	if err := s.checkVariant(request.ProductID, request.VariantID); err != nil {
+		return nil, err
+	}
+
+	movement := &models.StockMovement{
+		WarehouseID: request.WarehouseID,
+		ProductID:   request.ProductID,
+		VariantID:   request.VariantID,
+		Type:        request.Type,
+		Quantity:    request.Quantity,
+		Reference:   strings.TrimSpace(request.Reference),
+		Note:        strings.TrimSpace(request.Note),
+		CreatedBy:   createdBy,
+	}
+	if err := s.repo.RecordMovement(movement); err != nil {
+		return nil, s.movementError(err)
+	}
+
+	if err := s.indexer.ReindexProduct(request.ProductID); err != nil {
+		s.log.Error("Failed to update search index: " + err.Error())
+	}
+	return movement, nil
+}
+
+// Transfer moves units between two warehouses and returns the two ledger entries,
+// outgoing first. The product's total stock does not change.
+func (s *DefaultWarehouseService) Transfer(request StockTransferRequest, createdBy string) ([]models.StockMovement, error) {
+	if request.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidStockMovement)
+	}
+	if request.FromWarehouseID == request.ToWarehouseID {
+		return nil, fmt.Errorf("%w: source and destination must differ", ErrInvalidStockMovement)
+	}
+	if _, err := s.getWarehouse(request.FromWarehouseID); err != nil {
+		return nil, err
+	}
+	destination, err := s.getWarehouse(request.ToWarehouseID)
+	if err != nil {
+		return nil, err
+	}
+	if !destination.Active {
+		return nil, fmt.Errorf("%w: warehouse %s is inactive", ErrInvalidStockMovement, destination.Code)
+	}
+	if err := s.checkVariant(request.ProductID, request.VariantID); err != nil {
+		return nil, err
+	}
+
+	reference, err := transferReference()
+	if err != nil {
+		return nil, err
+	}
+	out := models.StockMovement{
+		WarehouseID: request.FromWarehouseID,
+		ProductID:   request.ProductID,
+		VariantID:   request.VariantID,
+		Type:        models.MovementTransfer,
+		Quantity:    -request.Quantity,
+		Reference:   reference,
+		Note:        strings.TrimSpace(request.Note),
+		CreatedBy:   createdBy,
+	}
+	in := out
+	in.WarehouseID = request.ToWarehouseID
+	in.Quantity = request.Quantity
+
+	if err := s.repo.Transfer(&out, &in); err != nil {
+		return nil, s.movementError(err)
+	}
+	return []models.StockMovement{out, in}, nil
+}
+
+// Reconcile compares the stock of every product variant with its stock levels and
+// ledger, optionally listing only the ones that disagree
+func (s *DefaultWarehouseService) Reconcile(onlyUnbalanced bool) (*ReconciliationReport, error) {
+	rows, err := s.repo.Reconcile()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReconciliationReport{
+		Checked: len(rows),
+		Rows:    make([]repository.ReconciliationRow, 0, len(rows)),
+	}
+	for _, row := range rows {
+		if !row.Balanced {
+			report.Unbalanced++
+		} else if onlyUnbalanced {
+			continue
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	return report, nil
+}
+
+// getWarehouse retrieves a warehouse, mapping a missing one to ErrWarehouseNotFound
+func (s *DefaultWarehouseService) getWarehouse(id uint) (*models.Warehouse, error) {
+	warehouse, err := s.repo.FindByID(id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrWarehouseNotFound
+	}
+	return warehouse, err
+}
+
+// checkVariant verifies that a product exists and that the variant is one of its
+// variants, or zero when the product has none
+func (s *DefaultWarehouseService) checkVariant(productID, variantID uint) error {
+	if _, err := s.productRepo.FindByID(productID); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrProductNotFound
+		}
+		return err
+	}
+
+	if variantID == 0 {
+		variants, err := s.variantRepo.CountByProduct(productID)
+		if err != nil {
+			return err
+		}
+		if variants > 0 {
+			return ErrVariantRequired
+		}
+		return nil
+	}
+
+	variant, err := s.variantRepo.FindByID(variantID)
+	if err != nil || variant.ProductID != productID {
+		if err == nil || errors.Is(err, repository.ErrRecordNotFound) {
+			return ErrVariantNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// movementError maps repository errors of ledger writes to service errors
+func (s *DefaultWarehouseService) movementError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrInsufficientLocationStock):
+		return ErrInsufficientLocationStock
+	case errors.Is(err, repository.ErrRecordNotFound):
+		return ErrProductNotFound
+	}
+	return err
+}
+
+// prepareWarehouse validates a warehouse and normalises its code before it is saved
+func (s *DefaultWarehouseService) prepareWarehouse(warehouse *models.Warehouse) error {
+	warehouse.Code = strings.ToUpper(strings.TrimSpace(warehouse.Code))
+	warehouse.Name = strings.TrimSpace(warehouse.Name)
+	if warehouse.Code == "" || utf8.RuneCountInString(warehouse.Code) > 50 {
+		return fmt.Errorf("%w: code must be 1 to 50 characters", ErrInvalidWarehouse)
+	}
+	if warehouse.Name == "" || utf8.RuneCountInString(warehouse.Name) > 255 {
+		return fmt.Errorf("%w: name must be 1 to 255 characters", ErrInvalidWarehouse)
+	}
+
+	other, err := s.repo.FindByCode(warehouse.Code)
+	if err == nil && other.ID != warehouse.ID {
+		return fmt.Errorf("%w: %s", ErrWarehouseCodeTaken, warehouse.Code)
+	} else if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+// transferReference returns a random reference shared by the two legs of a transfer
+func transferReference() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "transfer:" + hex.EncodeToString(buf), nil
+}
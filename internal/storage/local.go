@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps blobs as files below a directory on the local disk
+type LocalStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStore creates a LocalStore writing below dir and serving files under baseURL
+func NewLocalStore(dir, baseURL string) *LocalStore {
+	return &LocalStore{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Put writes a blob. The file is written under a temporary name and renamed into
+// place, so readers never see a partial file.
+func (s *LocalStore) Put(key string, data []byte, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete removes a blob. Deleting a missing blob is not an error.
+func (s *LocalStore) Delete(key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// URL returns the public URL of a blob
+func (s *LocalStore) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// BaseURL returns the URL prefix the blobs are served under
+func (s *LocalStore) BaseURL() string {
+	return s.baseURL
+}
+
+// FileServer serves the stored blobs, without directory listings. It expects the
+// base URL to be stripped from the request path.
+func (s *LocalStore) FileServer() http.Handler {
+	files := http.FileServer(http.Dir(s.dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// path returns the file path of a blob
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
@@ -0,0 +1,30 @@
+// Package storage provides blob storage backends for uploaded media.
+package storage
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidKey is returned for keys that are empty or try to escape the store
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// BlobStore stores binary objects under slash-separated keys and knows the public URL of each
+type BlobStore interface {
+	Put(key string, data []byte, contentType string) error
+	Delete(key string) error
+	URL(key string) string
+}
+
+// validateKey rejects keys that are empty, absolute or contain . or .. segments
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,436 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the store currency, used for amounts given without a currency
+// and for amounts read from decimal columns before the row's currency is applied
+var DefaultCurrency = "USD"
+
+// exponents maps the supported ISO 4217 currency codes to their number of minor
+// unit digits. Database columns have a scale of 2, so currencies with three minor
+// digits are not supported.
+var exponents = map[string]int{
+	"AED": 2, "AUD": 2, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2, "DKK": 2,
+	"EGP": 2, "EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "INR": 2, "MAD": 2, "MXN": 2,
+	"NOK": 2, "NZD": 2, "PLN": 2, "SAR": 2, "SEK": 2, "SGD": 2, "TRY": 2, "USD": 2,
+	"ZAR": 2,
+	"CLP": 0, "ISK": 0, "JPY": 0, "KRW": 0, "VND": 0,
+}
+
+var (
+	// ErrUnsupportedCurrency is returned for currency codes this package does not know
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	// ErrInvalidAmount is returned when a decimal amount cannot be represented exactly
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+// Money is an exact amount of a currency, stored as an integer number of minor units
+// (cents for USD). The zero value has no currency and can be added to any amount.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// New creates an amount of minor units in the given currency
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Zero returns a zero amount in the given currency
+func Zero(currency string) Money {
+	return Money{Currency: currency}
+}
+
+// IsSupported reports whether a currency code is supported
+func IsSupported(currency string) bool {
+	_, ok := exponents[currency]
+	return ok
+}
+
+// Exponent returns the number of minor unit digits of a currency
+func Exponent(currency string) int {
+	if exp, ok := exponents[currency]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Parse converts a decimal string such as "12.34" into an exact amount. Values with
+// more fractional digits than the currency allows are rejected rather than rounded.
+func Parse(value, currency string) (Money, error) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if !IsSupported(currency) {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+
+	amount, err := parseMinor(strings.TrimSpace(value), Exponent(currency))
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// MustParse is like Parse but panics on invalid input. It is meant for constants.
+func MustParse(value, currency string) Money {
+	m, err := Parse(value, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// FromFloat converts a float amount, rounding half away from zero to the nearest minor unit
+func FromFloat(value float64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	// Go through the shortest decimal form so 0.285 rounds like the decimal it denotes
+	rat, ok := new(big.Rat).SetString(strconv.FormatFloat(value, 'f', -1, 64))
+	if !ok {
+		return Money{Currency: currency}
+	}
+	return Money{Amount: roundRat(rat.Mul(rat, pow10Rat(Exponent(currency)))), Currency: currency}
+}
+
+// Add returns the sum of two amounts
+func (m Money) Add(other Money) Money {
+	currency := m.mustMatch(other)
+	return Money{Amount: m.Amount + other.Amount, Currency: currency}
+}
+
+// Sub returns the difference of two amounts
+func (m Money) Sub(other Money) Money {
+	currency := m.mustMatch(other)
+	return Money{Amount: m.Amount - other.Amount, Currency: currency}
+}
+
+// Mul multiplies the amount by a quantity
+func (m Money) Mul(quantity int) Money {
+	return Money{Amount: m.Amount * int64(quantity), Currency: m.Currency}
+}
+
+// MulRate multiplies the amount by a decimal rate such as 0.2, rounding half away
+// from zero to the nearest minor unit
+func (m Money) MulRate(rate float64) Money {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	if !ok {
+		return Money{Currency: m.Currency}
+	}
+	return Money{Amount: roundRat(r.Mul(r, big.NewRat(m.Amount, 1))), Currency: m.Currency}
+}
+
+// Neg returns the amount with its sign flipped
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Allocate splits the amount proportionally to the given ratios without losing a
+// single minor unit: what is left after rounding down is handed out one unit at a
+// time to the first parts. Zero or negative totals of ratios yield zero parts.
+func (m Money) Allocate(ratios ...int64) []Money {
+	parts := make([]Money, len(ratios))
+	var total int64
+	for _, ratio := range ratios {
+		if ratio > 0 {
+			total += ratio
+		}
+	}
+	if total == 0 {
+		for i := range parts {
+			parts[i] = Money{Currency: m.Currency}
+		}
+		return parts
+	}
+
+	remainder := m.Amount
+	for i, ratio := range ratios {
+		share := int64(0)
+		if ratio > 0 {
+			share = new(big.Int).Quo(
+				new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(ratio)),
+				big.NewInt(total),
+			).Int64()
+		}
+		parts[i] = Money{Amount: share, Currency: m.Currency}
+		remainder -= share
+	}
+
+	step := int64(1)
+	if remainder < 0 {
+		step = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(parts) {
+		if ratios[i] <= 0 {
+			continue
+		}
+		parts[i].Amount += step
+		remainder -= step
+	}
+	return parts
+}
+
+// Split divides the amount into n parts that differ by at most one minor unit
+func (m Money) Split(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+	ratios := make([]int64, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	return m.Allocate(ratios...)
+}
+
+// Cmp compares two amounts, returning -1, 0 or +1
+func (m Money) Cmp(other Money) int {
+	m.mustMatch(other)
+	switch {
+	case m.Amount < other.Amount:
+		return -1
+	case m.Amount > other.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// IsPositive reports whether the amount is above zero
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// Min returns the smaller of two amounts
+func Min(a, b Money) Money {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+// Max returns the larger of two amounts
+func Max(a, b Money) Money {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// WithCurrency expresses the same decimal value in another currency's minor units.
+// It is used to apply a row's currency to an amount scanned from a decimal column.
+func (m Money) WithCurrency(currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	amount := rescale(m.Amount, Exponent(m.currencyOrDefault()), Exponent(currency))
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Decimal formats the amount as a plain decimal string such as "12.34"
+func (m Money) Decimal() string {
+	exp := Exponent(m.currencyOrDefault())
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	if exp == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+	unit := pow10(exp)
+	return fmt.Sprintf("%s%d.%0*d", sign, amount/unit, exp, amount%unit)
+}
+
+// Float returns the amount as a float, for display and reporting only
+func (m Money) Float() float64 {
+	f, _ := strconv.ParseFloat(m.Decimal(), 64)
+	return f
+}
+
+// String formats the amount with its currency, e.g. "12.34 USD"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currencyOrDefault()
+}
+
+// jsonMoney is the JSON representation of Money
+type jsonMoney struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as {"amount": "12.34", "currency": "USD"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{
+		Amount:   m.Decimal(),
+		Currency: m.currencyOrDefault(),
+	})
+}
+
+// UnmarshalJSON accepts {"amount": "12.34", "currency": "USD"} with the amount as a
+// string or number, or a bare number or string in the default currency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "null" {
+		return nil
+	}
+
+	var value jsonMoney
+	if strings.HasPrefix(trimmed, "{") {
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+	} else {
+		value.Amount = json.Number(strings.Trim(trimmed, `"`))
+	}
+
+	parsed, err := Parse(value.Amount.String(), value.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount in a decimal column
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
+
+// Scan reads the amount from a decimal column in the default currency. Models apply
+// their row currency afterwards with WithCurrency.
+func (m *Money) Scan(src interface{}) error {
+	var value string
+	switch v := src.(type) {
+	case nil:
+		*m = Money{Currency: DefaultCurrency}
+		return nil
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	case int64:
+		value = strconv.FormatInt(v, 10)
+	case float64:
+		*m = FromFloat(v, DefaultCurrency)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into money", src)
+	}
+
+	// Columns have a scale of 2; read them at that precision and convert afterwards
+	amount, err := parseMinor(value, 2)
+	if err != nil {
+		return err
+	}
+	*m = Money{Amount: rescale(amount, 2, Exponent(DefaultCurrency)), Currency: DefaultCurrency}
+	return nil
+}
+
+// currencyOrDefault returns the currency, falling back to the default currency
+func (m Money) currencyOrDefault() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// mustMatch returns the common currency of two amounts, treating an empty currency as a
+// wildcard. Mixing currencies is a programming error and panics.
+func (m Money) mustMatch(other Money) string {
+	switch {
+	case m.Currency == "":
+		return other.Currency
+	case other.Currency == "" || other.Currency == m.Currency:
+		return m.Currency
+	default:
+		panic(fmt.Sprintf("money: currency mismatch %s vs %s", m.Currency, other.Currency))
+	}
+}
+
+// parseMinor parses a decimal string into minor units with the given exponent
+func parseMinor(value string, exp int) (int64, error) {
+	if value == "" {
+		return 0, fmt.Errorf("%w: empty", ErrInvalidAmount)
+	}
+
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+	}
+	rat.Mul(rat, pow10Rat(exp))
+	if !rat.IsInt() {
+		return 0, fmt.Errorf("%w: %q has too many decimal places", ErrInvalidAmount, value)
+	}
+	if !rat.Num().IsInt64() {
+		return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, value)
+	}
+	return rat.Num().Int64(), nil
+}
+
+// rescale converts minor units between two exponents, rounding half away from zero
+func rescale(amount int64, from, to int) int64 {
+	switch {
+	case to > from:
+		return amount * pow10(to-from)
+	case to < from:
+		return roundRat(big.NewRat(amount, pow10(from-to)))
+	default:
+		return amount
+	}
+}
+
+// roundRat rounds a rational number half away from zero
+func roundRat(r *big.Rat) int64 {
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+	negative := num.Sign() < 0
+	num.Abs(num)
+
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if remainder.Mul(remainder, big.NewInt(2)).Cmp(den) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if negative {
+		quotient.Neg(quotient)
+	}
+	return quotient.Int64()
+}
+
+// pow10 returns 10 to the power of n
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// pow10Rat returns 10 to the power of n as a rational number
+func pow10Rat(n int) *big.Rat {
+	return new(big.Rat).SetInt64(pow10(n))
+}
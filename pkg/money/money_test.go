@@ -0,0 +1,214 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// amounts returns the minor units of parts, checking they share a currency
+func amounts(t *testing.T, parts []Money, currency string) []int64 {
+	t.Helper()
+	var result []int64
+	for _, part := range parts {
+		if part.Currency != currency {
+			t.Errorf("part %s has currency %q, want %q", part, part.Currency, currency)
+		}
+		result = append(result, part.Amount)
+	}
+	return result
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		amount int64
+		ratios []int64
+		want   []int64
+	}{
+		{100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{100, []int64{70, 20, 10}, []int64{70, 20, 10}},
+		{1, []int64{1, 1, 1}, []int64{1, 0, 0}},
+		{1000, []int64{1, 2}, []int64{334, 666}},
+		{-100, []int64{1, 1, 1}, []int64{-34, -33, -33}},
+		// Parts with no ratio get nothing, not even remainders
+		{5, []int64{0, 1, 1}, []int64{0, 3, 2}},
+		{5, []int64{-1, 1}, []int64{0, 5}},
+		{100, []int64{0, 0}, []int64{0, 0}},
+		{0, []int64{1, 1}, []int64{0, 0}},
+	}
+	for _, tt := range tests {
+		parts := New(tt.amount, "USD").Allocate(tt.ratios...)
+		if got := amounts(t, parts, "USD"); !slices.Equal(got, tt.want) {
+			t.Errorf("New(%d).Allocate(%v) = %v, want %v", tt.amount, tt.ratios, got, tt.want)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		amount int64
+		n      int
+		want   []int64
+	}{
+		{100, 3, []int64{34, 33, 33}},
+		{101, 4, []int64{26, 25, 25, 25}},
+		{2, 3, []int64{1, 1, 0}},
+		{-7, 2, []int64{-4, -3}},
+		{100, 1, []int64{100}},
+		{100, 0, nil},
+		{100, -1, nil},
+	}
+	for _, tt := range tests {
+		parts := New(tt.amount, "EUR").Split(tt.n)
+		if got := amounts(t, parts, "EUR"); !slices.Equal(got, tt.want) {
+			t.Errorf("New(%d).Split(%d) = %v, want %v", tt.amount, tt.n, got, tt.want)
+		}
+
+		var sum int64
+		for _, part := range parts {
+			sum += part.Amount
+		}
+		if tt.n > 0 && sum != tt.amount {
+			t.Errorf("New(%d).Split(%d) sums to %d", tt.amount, tt.n, sum)
+		}
+	}
+}
+
+func TestMulRate(t *testing.T) {
+	tests := []struct {
+		amount int64
+		rate   float64
+		want   int64
+	}{
+		{1000, 0.2, 200},
+		{999, 0.5, 500},
+		{-999, 0.5, -500},
+		{998, 0.5, 499},
+		{100, 0.285, 29},
+		{1999, 0.0825, 165},
+		{1, 0.005, 0},
+		{1000, 0, 0},
+		{1000, 1.5, 1500},
+	}
+	for _, tt := range tests {
+		if got := New(tt.amount, "USD").MulRate(tt.rate); got.Amount != tt.want || got.Currency != "USD" {
+			t.Errorf("New(%d).MulRate(%v) = %v, want %d minor units", tt.amount, tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value, currency string
+		want            Money
+		err             error
+	}{
+		{"12.34", "USD", New(1234, "USD"), nil},
+		{"12.3", "USD", New(1230, "USD"), nil},
+		{"-0.5", "", New(-50, "USD"), nil},
+		{"1500", "JPY", New(1500, "JPY"), nil},
+		{"12.345", "USD", Money{}, ErrInvalidAmount},
+		{"1.5", "JPY", Money{}, ErrInvalidAmount},
+		{"twelve", "USD", Money{}, ErrInvalidAmount},
+		{"", "USD", Money{}, ErrInvalidAmount},
+		{"1.00", "XYZ", Money{}, ErrUnsupportedCurrency},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.value, tt.currency)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("Parse(%q, %q) = %v, %v, want %v, %v", tt.value, tt.currency, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want Money
+		err  error
+	}{
+		{`"12.34"`, New(1234, "USD"), nil},
+		{`12.34`, New(1234, "USD"), nil},
+		{`"-0.5"`, New(-50, "USD"), nil},
+		{`-0.5`, New(-50, "USD"), nil},
+		{`{"amount": "19.99", "currency": "EUR"}`, New(1999, "EUR"), nil},
+		{`{"amount": 19.99, "currency": "EUR"}`, New(1999, "EUR"), nil},
+		{`{"amount": "500", "currency": "JPY"}`, New(500, "JPY"), nil},
+		{`"12.345"`, Money{}, ErrInvalidAmount},
+		{`12.345`, Money{}, ErrInvalidAmount},
+		{`{"amount": "1", "currency": "XYZ"}`, Money{}, ErrUnsupportedCurrency},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.data), &got)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v, %v", tt.data, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestUnmarshalJSONNullKeepsValue(t *testing.T) {
+	got := New(100, "USD")
+	if err := json.Unmarshal([]byte("null"), &got); err != nil || got != New(100, "USD") {
+		t.Errorf("Unmarshal(null) = %v, %v, want the value untouched", got, err)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		value Money
+		json  string
+	}{
+		{New(1234, "USD"), `{"amount":"12.34","currency":"USD"}`},
+		{New(-50, "USD"), `{"amount":"-0.50","currency":"USD"}`},
+		{New(5, "EUR"), `{"amount":"0.05","currency":"EUR"}`},
+		{New(1500, "JPY"), `{"amount":"1500","currency":"JPY"}`},
+		{New(0, "GBP"), `{"amount":"0.00","currency":"GBP"}`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.value)
+		if err != nil || string(data) != tt.json {
+			t.Errorf("Marshal(%v) = %s, %v, want %s", tt.value, data, err, tt.json)
+			continue
+		}
+		var got Money
+		if err := json.Unmarshal(data, &got); err != nil || got != tt.value {
+			t.Errorf("Unmarshal(%s) = %v, %v, want %v", data, got, err, tt.value)
+		}
+	}
+}
+
+func TestCurrencyMismatch(t *testing.T) {
+	usd, eur := New(100, "USD"), New(100, "EUR")
+	tests := []struct {
+		name string
+		op   func()
+	}{
+		{"Add", func() { usd.Add(eur) }},
+		{"Sub", func() { usd.Sub(eur) }},
+		{"Cmp", func() { usd.Cmp(eur) }},
+		{"Min", func() { Min(usd, eur) }},
+		{"Max", func() { Max(usd, eur) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s of USD and EUR did not panic", tt.name)
+				}
+			}()
+			tt.op()
+		})
+	}
+}
+
+func TestZeroValueMatchesAnyCurrency(t *testing.T) {
+	var zero Money
+	if got := zero.Add(New(100, "EUR")); got != New(100, "EUR") {
+		t.Errorf("zero.Add(100 EUR) = %v, want 1.00 EUR", got)
+	}
+	if got := New(100, "JPY").Sub(zero); got != New(100, "JPY") {
+		t.Errorf("100 JPY.Sub(zero) = %v, want 100 JPY", got)
+	}
+}